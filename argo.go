@@ -85,6 +85,9 @@ type Format struct {
 
 	// The Serializer for this format.
 	Serializer Serializer
+
+	// A Serializer producing abbreviated, human-friendly output, or nil if the format has none.
+	PrettySerializer Serializer
 }
 
 // A map from format IDs to the corresponding Format objects.
@@ -99,6 +102,7 @@ var Formats = map[string]*Format{
 		OtherExtensions:    []string{".xml"},
 		Parser:             ParseRDFXML,
 		Serializer:         SerializeRDFXML,
+		PrettySerializer:   SerializePrettyRDFXML,
 	},
 
 	// http://www.w3.org/2001/sw/RDFCore/ntriples/
@@ -113,6 +117,18 @@ var Formats = map[string]*Format{
 		Serializer:         SerializeNTriples,
 	},
 
+	// http://www.w3.org/TR/n-quads/
+	"nquads": &Format{
+		ID:                 "nquads",
+		Name:               "N-Quads",
+		PreferredMIMEType:  "application/n-quads",
+		PreferredExtension: ".nq",
+		OtherMIMETypes:     []string{"text/x-nquads"},
+		OtherExtensions:    []string{},
+		Parser:             ParseNQuads,
+		Serializer:         SerializeNQuads,
+	},
+
 	// http://docs.api.talis.com/platform-api/output-types/rdf-json
 	"json": &Format{
 		ID:                 "json",
@@ -121,22 +137,34 @@ var Formats = map[string]*Format{
 		PreferredExtension: ".json",
 		OtherMIMETypes:     []string{"application/json", "text/json"},
 		OtherExtensions:    []string{},
-		Parser:             nil,
+		Parser:             ParseJSON,
 		Serializer:         SerializeJSON,
 	},
 
-	// http://www.w3.org/TeamSubmission/turtle/
+	// http://www.w3.org/TR/turtle/
 	"turtle": &Format{
 		ID:                 "turtle",
 		Name:               "Turtle",
 		PreferredMIMEType:  "text/turtle",
 		PreferredExtension: ".ttl",
-		OtherMIMETypes:     []string{},
+		OtherMIMETypes:     []string{"application/x-turtle"},
 		OtherExtensions:    []string{},
-		Parser:             nil,
+		Parser:             ParseTurtle,
 		Serializer:         SerializeTurtle,
 	},
 
+	// http://www.w3.org/TR/trig/
+	"trig": &Format{
+		ID:                 "trig",
+		Name:               "TriG",
+		PreferredMIMEType:  "application/trig",
+		PreferredExtension: ".trig",
+		OtherMIMETypes:     []string{"application/x-trig"},
+		OtherExtensions:    []string{},
+		Parser:             ParseTriG,
+		Serializer:         SerializeTriG,
+	},
+
 	// No specs yet
 	"squirtle": &Format{
 		ID:                 "squirtle",
@@ -177,15 +205,21 @@ func Serializers() (serializers map[string]*Format) {
 }
 
 // Function FormatFromMIMEType takes a MIME type and returns the Format it represents, or nil if it
-// could not be determined.
+// could not be determined. Parameters (such as "; charset=utf-8") and letter case are ignored.
 func FormatFromMIMEType(mimeType string) (format *Format) {
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+
 	for _, format = range Formats {
-		if strings.Index(mimeType, format.PreferredMIMEType) > 0 {
+		if mimeType == format.PreferredMIMEType {
 			return format
 		}
 
 		for _, m := range format.OtherMIMETypes {
-			if strings.Index(mimeType, m) > 0 {
+			if mimeType == m {
 				return format
 			}
 		}
@@ -0,0 +1,288 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"sync"
+
+	"github.com/kierdavis/argo/iri"
+)
+
+// A BlankNodeAllocator generates blank nodes with fresh IDs. Implementations must be safe for
+// concurrent use.
+type BlankNodeAllocator interface {
+	NewBlankNode() Term
+}
+
+// DefaultAllocator is the allocator used by NewAnonNode, and by graphs and readers that have not
+// been given one of their own.
+var DefaultAllocator BlankNodeAllocator = NewUUIDAllocator()
+
+// A CounterAllocator generates blank nodes numbered in order of allocation ("b0", "b1", ...), so
+// that the same sequence of operations always produces the same IDs. The IDs are only unique among
+// those generated by the same allocator.
+type CounterAllocator struct {
+	Prefix string
+
+	mutex sync.Mutex
+	next  uint64
+}
+
+// Function NewCounterAllocator returns a CounterAllocator whose IDs start with prefix.
+func NewCounterAllocator(prefix string) (alloc *CounterAllocator) {
+	return &CounterAllocator{Prefix: prefix}
+}
+
+// Method NewBlankNode returns a blank node with the next number.
+func (alloc *CounterAllocator) NewBlankNode() (term Term) {
+	alloc.mutex.Lock()
+	defer alloc.mutex.Unlock()
+
+	term = NewBlankNode(fmt.Sprintf("%s%d", alloc.Prefix, alloc.next))
+	alloc.next++
+	return term
+}
+
+// A SeededAllocator generates blank nodes with pseudo-random IDs drawn from a fixed seed, so that
+// repeated runs produce the same IDs.
+type SeededAllocator struct {
+	mutex sync.Mutex
+	rng   *mrand.Rand
+}
+
+// Function NewSeededAllocator returns a SeededAllocator using the given seed.
+func NewSeededAllocator(seed int64) (alloc *SeededAllocator) {
+	return &SeededAllocator{rng: mrand.New(mrand.NewSource(seed))}
+}
+
+// Method NewBlankNode returns a blank node with the next pseudo-random ID.
+func (alloc *SeededAllocator) NewBlankNode() (term Term) {
+	alloc.mutex.Lock()
+	defer alloc.mutex.Unlock()
+
+	return NewBlankNode(fmt.Sprintf("anon%016x", alloc.rng.Int63()))
+}
+
+// A UUIDAllocator generates blank nodes with IDs derived from random (version 4) UUIDs, which are
+// unique for all practical purposes even across separate runs and documents.
+type UUIDAllocator struct{}
+
+// Function NewUUIDAllocator returns a UUIDAllocator.
+func NewUUIDAllocator() (alloc *UUIDAllocator) {
+	return &UUIDAllocator{}
+}
+
+// Method NewBlankNode returns a blank node with a fresh random ID.
+func (alloc *UUIDAllocator) NewBlankNode() (term Term) {
+	var uuid [16]byte
+	_, err := rand.Read(uuid[:])
+	if err != nil {
+		panic(err) // As crypto/rand does, since a predictable ID would not be unique
+	}
+
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	return NewBlankNode("u" + hex.EncodeToString(uuid[:]))
+}
+
+// Function transformTriple applies f to every term of triple (including its graph name and the
+// terms of quoted triples). The triple itself is returned if nothing changed.
+func transformTriple(triple *Triple, f func(Term) Term) (result *Triple) {
+	s := transformTerm(triple.Subject, f)
+	p := transformTerm(triple.Predicate, f)
+	o := transformTerm(triple.Object, f)
+
+	g := triple.Graph
+	if g != nil {
+		g = transformTerm(g, f)
+	}
+
+	if s == triple.Subject && p == triple.Predicate && o == triple.Object && g == triple.Graph {
+		return triple
+	}
+
+	return NewQuad(s, p, o, g)
+}
+
+// Function transformTerm applies f to term, or to the terms of term if it is a quoted triple.
+func transformTerm(term Term, f func(Term) Term) (result Term) {
+	quoted, ok := term.(*QuotedTriple)
+	if !ok {
+		return f(term)
+	}
+
+	s, p, o := transformTerm(quoted.Subject, f), transformTerm(quoted.Predicate, f), transformTerm(quoted.Object, f)
+	if s == quoted.Subject && p == quoted.Predicate && o == quoted.Object {
+		return term
+	}
+
+	return NewQuotedTriple(s, p, o)
+}
+
+// GenIDPath is the path under which skolem IRIs are minted, as recommended by section 3.5 of RDF 1.1
+// Concepts.
+const GenIDPath = "/.well-known/genid/"
+
+// A Skolemizer replaces blank nodes with skolem IRIs (globally unique IRIs under GenIDPath of some
+// authority) and back again. The same blank node is always replaced with the same IRI, and
+// deskolemizing an IRI minted by the Skolemizer gives back the original blank node.
+type Skolemizer struct {
+	// The IRI prefix of the skolem IRIs, e.g. "http://example.org/.well-known/genid/".
+	Root string
+
+	// The allocator that provides the IDs of new skolem IRIs, and new blank nodes for skolem IRIs
+	// whose IDs are not valid blank node labels. If nil, DefaultAllocator is used.
+	Allocator BlankNodeAllocator
+
+	mutex  sync.Mutex
+	iris   map[string]Term // Skolem IRIs keyed by blank node ID
+	blanks map[string]Term // Blank nodes keyed by skolem IRI
+}
+
+// Function NewSkolemizer returns a Skolemizer minting IRIs under the GenIDPath of the authority of
+// base, which must be an absolute IRI (such as "http://example.org/").
+func NewSkolemizer(base string) (s *Skolemizer, err error) {
+	b, err := iri.ParseAbsolute(base)
+	if err != nil {
+		return nil, err
+	}
+
+	root, _ := iri.Parse(GenIDPath)
+
+	return &Skolemizer{
+		Root:   b.Resolve(root).String(),
+		iris:   make(map[string]Term),
+		blanks: make(map[string]Term),
+	}, nil
+}
+
+// Method newBlankNode allocates a blank node from the Skolemizer's allocator.
+func (s *Skolemizer) newBlankNode() Term {
+	if s.Allocator != nil {
+		return s.Allocator.NewBlankNode()
+	}
+
+	return DefaultAllocator.NewBlankNode()
+}
+
+// Method newID returns the ID of a blank node from the Skolemizer's allocator. If the allocator
+// returns some other kind of term, the ID of a UUID-based blank node is used instead.
+func (s *Skolemizer) newID() string {
+	if node, ok := s.newBlankNode().(*BlankNode); ok {
+		return node.ID
+	}
+
+	return NewUUIDAllocator().NewBlankNode().(*BlankNode).ID
+}
+
+// Method IsSkolemIRI returns whether term is an IRI under the Skolemizer's root.
+func (s *Skolemizer) IsSkolemIRI(term Term) bool {
+	resource, ok := term.(*Resource)
+	return ok && len(resource.URI) > len(s.Root) && strings.HasPrefix(resource.URI, s.Root)
+}
+
+// Method Skolemize returns the skolem IRI for term if it is a blank node, or term otherwise.
+func (s *Skolemizer) Skolemize(term Term) (result Term) {
+	node, ok := term.(*BlankNode)
+	if !ok {
+		return term
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result, ok = s.iris[node.ID]
+	if !ok {
+		result = NewResource(s.Root + s.newID())
+		s.iris[node.ID] = result
+		s.blanks[result.(*Resource).URI] = term
+	}
+
+	return result
+}
+
+// Method Deskolemize returns the blank node for term if it is a skolem IRI, or term otherwise. IRIs
+// not minted by this Skolemizer are mapped to blank nodes labelled with the last part of the IRI if
+// that is a valid label, or to fresh blank nodes otherwise.
+func (s *Skolemizer) Deskolemize(term Term) (result Term) {
+	if !s.IsSkolemIRI(term) {
+		return term
+	}
+
+	uri := term.(*Resource).URI
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result, ok := s.blanks[uri]
+	if !ok {
+		if id := uri[len(s.Root):]; isBlankNodeLabel(id) {
+			result = NewBlankNode(id)
+		} else {
+			result = s.newBlankNode()
+		}
+
+		s.blanks[uri] = result
+		s.iris[result.(*BlankNode).ID] = term
+	}
+
+	return result
+}
+
+// Method SkolemizeTriple returns triple with all its blank nodes skolemized.
+func (s *Skolemizer) SkolemizeTriple(triple *Triple) (result *Triple) {
+	return transformTriple(triple, s.Skolemize)
+}
+
+// Method DeskolemizeTriple returns triple with all its skolem IRIs replaced by blank nodes.
+func (s *Skolemizer) DeskolemizeTriple(triple *Triple) (result *Triple) {
+	return transformTriple(triple, s.Deskolemize)
+}
+
+// Method Skolemize replaces every blank node in the graph with a skolem IRI minted by s.
+func (graph *Graph) Skolemize(s *Skolemizer) {
+	graph.transform(s.SkolemizeTriple)
+}
+
+// Method Deskolemize replaces every skolem IRI of s in the graph with a blank node.
+func (graph *Graph) Deskolemize(s *Skolemizer) {
+	graph.transform(s.DeskolemizeTriple)
+}
+
+// Method transform replaces every triple of the graph with the result of f, if it differs.
+func (graph *Graph) transform(f func(*Triple) *Triple) {
+	triples := graph.triples()
+
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	for _, triple := range triples {
+		if result := f(triple); result != triple {
+			graph.Store.Remove(triple)
+			graph.Store.Add(result)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCounterAllocator(t *testing.T) {
+	alloc := NewCounterAllocator("b")
+
+	for i, expected := range []string{"b0", "b1", "b2"} {
+		if node := alloc.NewBlankNode().(*BlankNode); node.ID != expected {
+			t.Errorf("Allocation %d: expected %s but got %s", i, expected, node.ID)
+		}
+	}
+}
+
+func TestSeededAllocator(t *testing.T) {
+	a, b := NewSeededAllocator(42), NewSeededAllocator(42)
+
+	for i := 0; i < 3; i++ {
+		if x, y := a.NewBlankNode(), b.NewBlankNode(); !x.Equal(y) {
+			t.Errorf("Allocators with the same seed gave %s and %s", x, y)
+		}
+	}
+}
+
+func TestUUIDAllocator(t *testing.T) {
+	alloc := NewUUIDAllocator()
+	seen := make(map[string]bool)
+
+	for i := 0; i < 100; i++ {
+		id := alloc.NewBlankNode().(*BlankNode).ID
+		if seen[id] || len(id) != 33 || id[13] != '4' || !isBlankNodeLabel(id) {
+			t.Fatalf("Unexpected ID %s", id)
+		}
+
+		seen[id] = true
+	}
+}
+
+func TestReaderAllocator(t *testing.T) {
+	r := NewTurtleReader(strings.NewReader(`<http://example.org/s> <http://example.org/p> [ <http://example.org/q> ( "a" ) ], _:x .`))
+	r.SetAllocator(NewCounterAllocator("n"))
+
+	var lines []string
+	for triple, err := r.Read(); err == nil; triple, err = r.Read() {
+		lines = append(lines, triple.String())
+	}
+
+	expected := `_:n1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "a" .
+_:n1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+_:n0 <http://example.org/q> _:n1 .
+<http://example.org/s> <http://example.org/p> _:n0 .
+<http://example.org/s> <http://example.org/p> _:x .`
+
+	if got := strings.Join(lines, "\n"); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestSkolemize(t *testing.T) {
+	_, err := NewSkolemizer("example.org")
+	if err == nil {
+		t.Errorf("Expected an error for a relative base")
+	}
+
+	s, err := NewSkolemizer("http://example.org/data/doc")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	s.Allocator = NewCounterAllocator("g")
+
+	if s.Root != "http://example.org/.well-known/genid/" {
+		t.Errorf("Unexpected root %s", s.Root)
+	}
+
+	graph := NewGraph(NewListStore())
+	p := NewResource("http://example.org/p")
+	graph.AddTriple(NewBlankNode("a"), p, NewBlankNode("b"))
+	graph.AddTriple(NewQuotedTriple(NewBlankNode("a"), p, NewLiteral("x")), p, NewResource("http://example.org/.well-known/genid/foreign"))
+
+	graph.Skolemize(s)
+
+	expected := parseTurtleGraph(t, `
+<http://example.org/.well-known/genid/g0> <http://example.org/p> <http://example.org/.well-known/genid/g1> .
+<< <http://example.org/.well-known/genid/g0> <http://example.org/p> "x" >> <http://example.org/p> <http://example.org/.well-known/genid/foreign> .`)
+
+	if c := graph.Compare(expected); !c.Isomorphic() || len(c.Mapping) != 0 {
+		t.Errorf("Unexpected skolemized graph:\n%s", c)
+	}
+
+	graph.Deskolemize(s)
+
+	expected = parseTurtleGraph(t, `
+_:a <http://example.org/p> _:b .
+<< _:a <http://example.org/p> "x" >> <http://example.org/p> _:foreign .`)
+
+	for triple := range expected.IterTriples() {
+		if findTriple(graph.Store, triple) == nil {
+			t.Errorf("Expected %s after deskolemizing", triple)
+		}
+	}
+}
+
+// resourceAllocator is a misbehaving allocator that returns IRIs instead of blank nodes.
+type resourceAllocator struct{}
+
+func (resourceAllocator) NewBlankNode() Term {
+	return NewResource("http://example.org/not-blank")
+}
+
+func TestSkolemizeNonBlankAllocator(t *testing.T) {
+	s, err := NewSkolemizer("http://example.org/")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	s.Allocator = resourceAllocator{}
+
+	a, b := s.Skolemize(NewBlankNode("a")), s.Skolemize(NewBlankNode("b"))
+	if !s.IsSkolemIRI(a) || !s.IsSkolemIRI(b) || a.Equal(b) {
+		t.Errorf("Expected two distinct skolem IRIs but got %s and %s", a, b)
+	}
+}
@@ -0,0 +1,464 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrCanonicalizationLimit is returned by Canonicalize when a dataset needs more work to
+// canonicalize than allowed by MaxCanonicalizationCalls.
+var ErrCanonicalizationLimit = errors.New("canonicalization limit exceeded")
+
+// MaxCanonicalizationCalls limits the work done by Canonicalize for each blank node of the input,
+// as a defence against datasets crafted to make canonicalization take exponential time.
+var MaxCanonicalizationCalls = 64
+
+// An idIssuer issues identifiers for blank nodes, such as c14n0, c14n1, ...
+type idIssuer struct {
+	prefix string
+	issued map[string]string
+	order  []string // Existing identifiers, in the order they were issued
+}
+
+func newIDIssuer(prefix string) *idIssuer {
+	return &idIssuer{
+		prefix: prefix,
+		issued: make(map[string]string),
+	}
+}
+
+// issue returns the identifier issued for id, issuing a new one if there is none.
+func (issuer *idIssuer) issue(id string) string {
+	if issued, ok := issuer.issued[id]; ok {
+		return issued
+	}
+
+	issued := issuer.prefix + strconv.Itoa(len(issuer.order))
+	issuer.issued[id] = issued
+	issuer.order = append(issuer.order, id)
+	return issued
+}
+
+func (issuer *idIssuer) copy() *idIssuer {
+	c := &idIssuer{
+		prefix: issuer.prefix,
+		issued: make(map[string]string, len(issuer.issued)),
+		order:  append([]string(nil), issuer.order...),
+	}
+
+	for id, issued := range issuer.issued {
+		c.issued[id] = issued
+	}
+
+	return c
+}
+
+// A c14nState holds the state of the RDFC-1.0 algorithm.
+type c14nState struct {
+	quads     map[string][]*Triple // Quads mentioning each blank node, keyed by blank node ID
+	canonical *idIssuer
+	calls     int
+	maxCalls  int
+}
+
+// Function Canonicalize relabels the blank nodes of a dataset (triples with their Graph field set to
+// the graph name, or nil for the default graph) following the W3C RDF Dataset Canonicalization
+// algorithm, RDFC-1.0. Isomorphic datasets get the same labels (c14n0, c14n1, ...) regardless of
+// their original labels or the order of the triples. It returns the relabelled triples, in the input
+// order, and a map from original blank node IDs to canonical ones.
+func Canonicalize(triples []*Triple) (canonical []*Triple, labels map[string]string, err error) {
+	state := &c14nState{
+		quads:     make(map[string][]*Triple),
+		canonical: newIDIssuer("c14n"),
+	}
+
+	seen := make(map[string]bool)
+
+	for _, triple := range triples {
+		key := triple.QuadString()
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		for _, term := range []Term{triple.Subject, triple.Object, triple.Graph} {
+			if node, ok := term.(*BlankNode); ok {
+				quads := state.quads[node.ID]
+				if len(quads) == 0 || quads[len(quads)-1] != triple {
+					state.quads[node.ID] = append(quads, triple)
+				}
+			}
+		}
+	}
+
+	state.maxCalls = MaxCanonicalizationCalls * len(state.quads)
+
+	// Blank nodes whose first degree hash is unique get canonical identifiers straight away, in
+	// order of their hashes.
+	hashToIDs := make(map[string][]string)
+	for id := range state.quads {
+		hash := state.hashFirstDegreeQuads(id)
+		hashToIDs[hash] = append(hashToIDs[hash], id)
+	}
+
+	hashes := make([]string, 0, len(hashToIDs))
+	for hash := range hashToIDs {
+		hashes = append(hashes, hash)
+	}
+
+	sort.Strings(hashes)
+
+	for _, hash := range hashes {
+		if ids := hashToIDs[hash]; len(ids) == 1 {
+			state.canonical.issue(ids[0])
+		}
+	}
+
+	// The remaining ones are distinguished by the blank nodes they are related to.
+	for _, hash := range hashes {
+		ids := hashToIDs[hash]
+		if len(ids) == 1 {
+			continue
+		}
+
+		type pathResult struct {
+			hash   string
+			issuer *idIssuer
+		}
+
+		var results []pathResult
+
+		for _, id := range ids {
+			if _, ok := state.canonical.issued[id]; ok {
+				continue
+			}
+
+			issuer := newIDIssuer("b")
+			issuer.issue(id)
+
+			hash, issuer, err := state.hashNDegreeQuads(id, issuer)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			results = append(results, pathResult{hash, issuer})
+		}
+
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].hash < results[j].hash
+		})
+
+		for _, result := range results {
+			for _, id := range result.issuer.order {
+				state.canonical.issue(id)
+			}
+		}
+	}
+
+	relabel := func(term Term) Term {
+		if node, ok := term.(*BlankNode); ok {
+			return NewBlankNode(state.canonical.issued[node.ID])
+		}
+
+		return term
+	}
+
+	canonical = make([]*Triple, len(triples))
+	for i, triple := range triples {
+		canonical[i] = NewQuad(relabel(triple.Subject), triple.Predicate, relabel(triple.Object), relabel(triple.Graph))
+	}
+
+	return canonical, state.canonical.issued, nil
+}
+
+// canonicalQuadString returns the canonical N-Quads form of a triple, without the final newline.
+// Blank nodes are relabelled by the given function.
+func canonicalQuadString(triple *Triple, label func(node *BlankNode) string) string {
+	term := func(term Term) string {
+		switch t := term.(type) {
+		case *BlankNode:
+			return "_:" + label(t)
+
+		case *Literal:
+			if t.Language == "" && t.Datatype != nil && t.Datatype.Equal(XSD.Get("string")) {
+				return NewLiteral(t.Value).String()
+			}
+		}
+
+		return term.String()
+	}
+
+	str := term(triple.Subject) + " " + term(triple.Predicate) + " " + term(triple.Object)
+	if triple.Graph != nil {
+		str += " " + term(triple.Graph)
+	}
+
+	return str + " ."
+}
+
+// hashStrings returns the hexadecimal SHA-256 digest of the concatenation of strs.
+func hashStrings(strs ...string) string {
+	h := sha256.New()
+	for _, s := range strs {
+		io.WriteString(h, s)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashFirstDegreeQuads hashes the quads mentioning a blank node, with that blank node labelled a
+// and all others z.
+func (state *c14nState) hashFirstDegreeQuads(id string) string {
+	lines := make([]string, 0, len(state.quads[id]))
+
+	for _, quad := range state.quads[id] {
+		lines = append(lines, canonicalQuadString(quad, func(node *BlankNode) string {
+			if node.ID == id {
+				return "a"
+			}
+
+			return "z"
+		})+"\n")
+	}
+
+	sort.Strings(lines)
+	return hashStrings(lines...)
+}
+
+// hashRelatedBlankNode hashes a blank node related to another through quad, at the given position
+// ("s", "o" or "g").
+func (state *c14nState) hashRelatedBlankNode(related string, quad *Triple, issuer *idIssuer, position string) string {
+	input := position
+	if position != "g" {
+		input += quad.Predicate.String()
+	}
+
+	if id, ok := state.canonical.issued[related]; ok {
+		input += "_:" + id
+	} else if id, ok := issuer.issued[related]; ok {
+		input += "_:" + id
+	} else {
+		input += state.hashFirstDegreeQuads(related)
+	}
+
+	return hashStrings(input)
+}
+
+// hashNDegreeQuads hashes a blank node by the paths to the blank nodes it is related to, choosing
+// the lexicographically least labelling of them.
+func (state *c14nState) hashNDegreeQuads(id string, issuer *idIssuer) (hash string, result *idIssuer, err error) {
+	state.calls++
+	if state.calls > state.maxCalls {
+		return "", nil, ErrCanonicalizationLimit
+	}
+
+	related := make(map[string][]string)
+
+	for _, quad := range state.quads[id] {
+		positions := []struct {
+			term     Term
+			position string
+		}{{quad.Subject, "s"}, {quad.Object, "o"}, {quad.Graph, "g"}}
+
+		for _, p := range positions {
+			if node, ok := p.term.(*BlankNode); ok && node.ID != id {
+				h := state.hashRelatedBlankNode(node.ID, quad, issuer, p.position)
+				related[h] = append(related[h], node.ID)
+			}
+		}
+	}
+
+	hashes := make([]string, 0, len(related))
+	for h := range related {
+		hashes = append(hashes, h)
+	}
+
+	sort.Strings(hashes)
+
+	var data strings.Builder
+
+	for _, h := range hashes {
+		data.WriteString(h)
+
+		chosenPath := ""
+		var chosenIssuer *idIssuer
+
+		err = permute(related[h], func(permutation []string) (err error) {
+			issuerCopy := issuer.copy()
+			path := ""
+			var recursion []string
+
+			longer := func() bool {
+				return chosenPath != "" && len(path) >= len(chosenPath) && path > chosenPath
+			}
+
+			for _, node := range permutation {
+				if id, ok := state.canonical.issued[node]; ok {
+					path += "_:" + id
+				} else {
+					if _, ok := issuerCopy.issued[node]; !ok {
+						recursion = append(recursion, node)
+					}
+
+					path += "_:" + issuerCopy.issue(node)
+				}
+
+				if longer() {
+					return nil
+				}
+			}
+
+			for _, node := range recursion {
+				hash, result, err := state.hashNDegreeQuads(node, issuerCopy)
+				if err != nil {
+					return err
+				}
+
+				path += "_:" + issuerCopy.issue(node) + "<" + hash + ">"
+				issuerCopy = result
+
+				if longer() {
+					return nil
+				}
+			}
+
+			if chosenPath == "" || path < chosenPath {
+				chosenPath = path
+				chosenIssuer = issuerCopy
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		data.WriteString(chosenPath)
+		issuer = chosenIssuer
+	}
+
+	return hashStrings(data.String()), issuer, nil
+}
+
+// permute calls f with every permutation of items, stopping at the first error.
+func permute(items []string, f func([]string) error) (err error) {
+	items = append([]string(nil), items...)
+	sort.Strings(items)
+
+	var generate func(k int) error
+	generate = func(k int) error {
+		if k == len(items) {
+			return f(items)
+		}
+
+		for i := k; i < len(items); i++ {
+			items[k], items[i] = items[i], items[k]
+
+			err := generate(k + 1)
+
+			items[k], items[i] = items[i], items[k]
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return generate(0)
+}
+
+// serializeCanonical canonicalizes the triples from tripleChan and passes them, sorted by their
+// canonical form, to serializer.
+func serializeCanonical(serializer Serializer, quads bool, w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	var triples []*Triple
+	for triple := range tripleChan {
+		if !quads && triple.Graph != nil {
+			triple = NewTriple(triple.Subject, triple.Predicate, triple.Object)
+		}
+
+		// Literals with the xsd:string datatype are written as simple literals. This is done before
+		// hashing, so that "a" and "a"^^xsd:string give the same blank node labels and line.
+		if lit, ok := triple.Object.(*Literal); ok && lit.Language == "" && lit.Datatype != nil && lit.Datatype.Equal(XSD.Get("string")) {
+			triple = NewQuad(triple.Subject, triple.Predicate, NewLiteral(lit.Value), triple.Graph)
+		}
+
+		triples = append(triples, triple)
+	}
+
+	canonical, _, err := Canonicalize(triples)
+	if err != nil {
+		errChan <- err
+		close(errChan)
+		return
+	}
+
+	lines := make(map[string]*Triple, len(canonical))
+	keys := make([]string, 0, len(canonical))
+
+	for _, triple := range canonical {
+		key := canonicalQuadString(triple, func(node *BlankNode) string { return node.ID })
+		if _, ok := lines[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		lines[key] = triple
+	}
+
+	sort.Strings(keys)
+
+	sorted := make(chan *Triple)
+	go func() {
+		for _, key := range keys {
+			sorted <- lines[key]
+		}
+
+		close(sorted)
+	}()
+
+	serializer(w, sorted, errChan, prefixes)
+
+	// Drain the channel if the serializer stopped early.
+	for range sorted {
+	}
+}
+
+// Function SerializeCanonicalNTriples writes canonical N-Triples to w: blank nodes are relabelled
+// with Canonicalize, and the lines are sorted and free of duplicates, so that isomorphic graphs are
+// written identically. Graph names are ignored. errChan is closed when execution is done.
+func SerializeCanonicalNTriples(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	serializeCanonical(SerializeNTriples, false, w, tripleChan, errChan, prefixes)
+}
+
+// Function SerializeCanonicalNQuads writes canonical N-Quads to w, as SerializeCanonicalNTriples
+// but keeping graph names. errChan is closed when execution is done.
+func SerializeCanonicalNQuads(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	serializeCanonical(SerializeNQuads, true, w, tripleChan, errChan, prefixes)
+}
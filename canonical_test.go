@@ -0,0 +1,162 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// The examples from the RDFC-1.0 specification, mapped to their canonical N-Quads.
+var canonicalTestCases = map[string]string{
+	// Unique first degree hashes.
+	`<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#r> _:e1 .
+_:e0 <http://example.com/#s> <http://example.com/#u> .
+_:e1 <http://example.com/#t> <http://example.com/#u> .
+`: `<http://example.com/#p> <http://example.com/#q> _:c14n0 .
+<http://example.com/#p> <http://example.com/#r> _:c14n1 .
+_:c14n0 <http://example.com/#s> <http://example.com/#u> .
+_:c14n1 <http://example.com/#t> <http://example.com/#u> .
+`,
+
+	// Shared first degree hashes, needing N-degree hashing.
+	`<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#q> _:e1 .
+_:e0 <http://example.com/#p> _:e2 .
+_:e1 <http://example.com/#p> _:e3 .
+_:e2 <http://example.com/#r> _:e3 .
+`: `<http://example.com/#p> <http://example.com/#q> _:c14n2 .
+<http://example.com/#p> <http://example.com/#q> _:c14n3 .
+_:c14n0 <http://example.com/#r> _:c14n1 .
+_:c14n2 <http://example.com/#p> _:c14n1 .
+_:c14n3 <http://example.com/#p> _:c14n0 .
+`,
+}
+
+func canonicalize(t *testing.T, triples []*Triple) string {
+	graph := NewGraph(NewListStore())
+	for _, triple := range triples {
+		graph.Add(triple)
+	}
+
+	var buf bytes.Buffer
+	err := graph.Serialize(SerializeCanonicalNQuads, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	return buf.String()
+}
+
+func readAllNQuads(t *testing.T, doc string) (triples []*Triple) {
+	r := NewNQuadsReader(strings.NewReader(doc))
+	for triple, err := r.Read(); err == nil; triple, err = r.Read() {
+		triples = append(triples, triple)
+	}
+
+	return triples
+}
+
+func TestCanonicalize(t *testing.T) {
+	for input, expected := range canonicalTestCases {
+		got := canonicalize(t, readAllNQuads(t, input))
+		if got != expected {
+			t.Errorf("Canonicalizing:\n%s\nexpected:\n%s\ngot:\n%s", input, expected, got)
+		}
+	}
+}
+
+// Relabelling blank nodes and shuffling the input must not change the canonical form.
+func TestCanonicalizeInvariance(t *testing.T) {
+	input := readAllNQuads(t, `_:a <http://example.org/p> _:b .
+_:b <http://example.org/p> _:c .
+_:c <http://example.org/p> _:a .
+_:d <http://example.org/p> _:e _:g .
+_:e <http://example.org/p> _:d _:g .
+_:g <http://example.org/q> "x"^^<http://www.w3.org/2001/XMLSchema#string> .
+_:g <http://example.org/q> "x" .
+`)
+
+	expected := canonicalize(t, input)
+	if strings.Contains(expected, "XMLSchema#string") || strings.Count(expected, "\n") != 6 {
+		t.Errorf("Expected xsd:string literals to be written as simple literals, once:\n%s", expected)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		prefix := string(rune('a' + i))
+		triples := make([]*Triple, len(input))
+
+		for j, k := range rng.Perm(len(input)) {
+			triple := input[k]
+			relabel := func(term Term) Term {
+				if node, ok := term.(*BlankNode); ok {
+					return NewBlankNode(prefix + node.ID)
+				}
+
+				return term
+			}
+
+			triples[j] = NewQuad(relabel(triple.Subject), triple.Predicate, relabel(triple.Object), relabel(triple.Graph))
+		}
+
+		if got := canonicalize(t, triples); got != expected {
+			t.Fatalf("Expected:\n%s\ngot:\n%s", expected, got)
+		}
+	}
+}
+
+// "d" and "d"^^xsd:string are the same term, so a graph holding both must be labelled as if it
+// held one of them.
+func TestCanonicalizeXSDString(t *testing.T) {
+	simple := canonicalize(t, readAllNQuads(t, `_:x <http://example.org/p> "d" .
+_:y <http://example.org/p> "z" .
+`))
+
+	typed := canonicalize(t, readAllNQuads(t, `_:x <http://example.org/p> "d" .
+_:x <http://example.org/p> "d"^^<http://www.w3.org/2001/XMLSchema#string> .
+_:y <http://example.org/p> "z" .
+`))
+
+	if typed != simple {
+		t.Errorf("Expected:\n%s\ngot:\n%s", simple, typed)
+	}
+}
+
+func TestCanonicalizeLimit(t *testing.T) {
+	// A clique of blank nodes has no distinguishing features, so every permutation is explored.
+	var triples []*Triple
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			if i != j {
+				triples = append(triples, NewTriple(NewBlankNode(string(rune('a'+i))), NewResource("http://example.org/p"), NewBlankNode(string(rune('a'+j)))))
+			}
+		}
+	}
+
+	_, _, err := Canonicalize(triples)
+	if err != ErrCanonicalizationLimit {
+		t.Errorf("Expected %s but got %v", ErrCanonicalizationLimit, err)
+	}
+}
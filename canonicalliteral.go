@@ -0,0 +1,182 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Function NormalizeLanguageTag returns a language tag with the case conventions of BCP 47 (RFC
+// 5646, section 2.1.1) applied: region subtags are upper case, script subtags title case and all
+// others lower case, as in "en-GB" or "zh-Hant-TW". Subtags following a singleton (such as the "x"
+// of a private use sequence) are lower case.
+func NormalizeLanguageTag(tag string) string {
+	subtags := strings.Split(tag, "-")
+	extension := false
+
+	for i, subtag := range subtags {
+		subtag = strings.ToLower(subtag)
+
+		if i > 0 && !extension {
+			switch {
+			case len(subtag) == 1:
+				extension = true
+			case len(subtag) == 2:
+				subtag = strings.ToUpper(subtag)
+			case len(subtag) == 4:
+				subtag = strings.ToUpper(subtag[:1]) + subtag[1:]
+			}
+		}
+
+		subtags[i] = subtag
+	}
+
+	return strings.Join(subtags, "-")
+}
+
+// Method Canonical returns the literal in canonical form: language tags are normalized with
+// NormalizeLanguageTag, xsd:string literals become simple literals, and literals of the datatypes
+// understood by Native are rewritten to the canonical lexical form of their value (for example,
+// "01"^^xsd:integer becomes "1"^^xsd:integer and "+1.50"^^xsd:decimal becomes "1.5"^^xsd:decimal).
+// Date-times with a timezone are converted to UTC. The datatype itself is never changed.
+//
+// A LiteralValueError is returned if the lexical form is invalid for the datatype. Values that Go
+// cannot represent, such as durations with a number of months, are returned unchanged.
+func (term Literal) Canonical() (canonical Term, err error) {
+	if term.Language != "" {
+		return NewLiteralWithLanguage(term.Value, NormalizeLanguageTag(term.Language)), nil
+	}
+
+	xsdType := term.xsdType()
+	if xsdType == "string" {
+		return NewLiteral(term.Value), nil
+	}
+
+	value, err := term.Native()
+	if err != nil {
+		// Durations too large for Go are still valid, but are left as they are.
+		isDuration := xsdType == "duration" || xsdType == "dayTimeDuration" || xsdType == "yearMonthDuration"
+		if le, ok := err.(*LiteralValueError); ok && le.Err == ErrOutOfRange && isDuration {
+			return NewLiteralWithDatatype(term.Value, term.Datatype), nil
+		}
+
+		return nil, err
+	}
+
+	lexical := term.Value
+
+	switch v := value.(type) {
+	case bool:
+		lexical = NewBoolLiteral(v).(*Literal).Value
+	case int64:
+		lexical = NewIntLiteral(v).(*Literal).Value
+	case *big.Int:
+		lexical = NewBigIntLiteral(v).(*Literal).Value
+	case *big.Rat:
+		decimal, _ := NewDecimalLiteral(v)
+		lexical = decimal.(*Literal).Value
+	case float64:
+		if xsdType == "float" {
+			lexical = formatXSDDouble(v, 32)
+		} else {
+			lexical = formatXSDDouble(v, 64)
+		}
+	case time.Time:
+		lexical = canonicalXSDTime(xsdType, strings.TrimSpace(term.Value), v)
+	case time.Duration:
+		lexical = NewDurationLiteral(v).(*Literal).Value
+		if xsdType == "yearMonthDuration" {
+			lexical = "P0M"
+		}
+	case []byte:
+		if xsdType == "hexBinary" {
+			lexical = NewHexLiteral(v).(*Literal).Value
+		} else {
+			lexical = base64.StdEncoding.EncodeToString(v)
+		}
+	}
+
+	return NewLiteralWithDatatype(lexical, term.Datatype), nil
+}
+
+// canonicalXSDTime returns the canonical lexical form of a date or dateTime literal, given its
+// original lexical form and parsed value.
+func canonicalXSDTime(xsdType string, lexical string, t time.Time) string {
+	if xsdType == "date" {
+		if xsdDateRegexp.FindStringSubmatch(lexical)[1] == "" {
+			return t.Format("2006-01-02")
+		}
+
+		return t.Format("2006-01-02Z07:00")
+	}
+
+	if xsdDateTimeRegexp.FindStringSubmatch(lexical)[3] == "" {
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+
+	return t.UTC().Format("2006-01-02T15:04:05.999999999Z")
+}
+
+// Function CanonicalTerm returns the canonical form of a literal (see Literal.Canonical). Other
+// terms, and literals with an invalid lexical form, are returned unchanged.
+func CanonicalTerm(term Term) Term {
+	literal, ok := term.(*Literal)
+	if !ok {
+		return term
+	}
+
+	canonical, err := literal.Canonical()
+	if err != nil {
+		return term
+	}
+
+	return canonical
+}
+
+// Function CanonicalTriple returns the triple with its object in canonical form (see
+// CanonicalTerm). The triple itself is returned if nothing changed.
+func CanonicalTriple(triple *Triple) *Triple {
+	object := CanonicalTerm(triple.Object)
+	if object == triple.Object || object.Equal(triple.Object) {
+		return triple
+	}
+
+	return NewQuad(triple.Subject, triple.Predicate, object, triple.Graph)
+}
+
+// Function ValueEqual returns whether two terms are equal, comparing literals by value rather than
+// by lexical form: two literals are equal if their canonical forms are. Thus "01"^^xsd:integer
+// equals "1"^^xsd:integer and "chat"@en-GB equals "chat"@en-gb, but "1"^^xsd:int does not equal
+// "1"^^xsd:integer, as their datatypes differ.
+func ValueEqual(a Term, b Term) bool {
+	if _, ok := a.(*Literal); !ok {
+		return a.Equal(b)
+	}
+
+	return CanonicalTerm(a).Equal(CanonicalTerm(b))
+}
+
+// valueEqualTriples returns whether two triples are equal, comparing their terms with ValueEqual.
+func valueEqualTriples(a *Triple, b *Triple) bool {
+	return ValueEqual(a.Subject, b.Subject) && ValueEqual(a.Predicate, b.Predicate) && ValueEqual(a.Object, b.Object)
+}
@@ -0,0 +1,112 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"testing"
+)
+
+var languageTagTestCases = map[string]string{
+	"EN":             "en",
+	"en-gb":          "en-GB",
+	"zh-hant-tw":     "zh-Hant-TW",
+	"es-419":         "es-419",
+	"de-CH-1901":     "de-CH-1901",
+	"en-a-BBB-x-Abc": "en-a-bbb-x-abc",
+	"X-Klingon":      "x-klingon",
+}
+
+func TestNormalizeLanguageTag(t *testing.T) {
+	for tag, expected := range languageTagTestCases {
+		if got := NormalizeLanguageTag(tag); got != expected {
+			t.Errorf("%s: expected %s but got %s", tag, expected, got)
+		}
+	}
+}
+
+var canonicalLiteralTestCases = map[Term]string{
+	NewLiteralWithLanguage("chat", "EN-gb"):                                      `"chat"@en-GB`,
+	NewLiteralWithDatatype("x", XSD.Get("string")):                               `"x"`,
+	NewLiteralWithDatatype("1", XSD.Get("boolean")):                              `"true"^^<http://www.w3.org/2001/XMLSchema#boolean>`,
+	NewLiteralWithDatatype(" +007 ", XSD.Get("int")):                             `"7"^^<http://www.w3.org/2001/XMLSchema#int>`,
+	NewLiteralWithDatatype("-0", XSD.Get("integer")):                             `"0"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+	NewLiteralWithDatatype("+01.50", XSD.Get("decimal")):                         `"1.5"^^<http://www.w3.org/2001/XMLSchema#decimal>`,
+	NewLiteralWithDatatype("2", XSD.Get("decimal")):                              `"2.0"^^<http://www.w3.org/2001/XMLSchema#decimal>`,
+	NewLiteralWithDatatype("100", XSD.Get("double")):                             `"1.0E2"^^<http://www.w3.org/2001/XMLSchema#double>`,
+	NewLiteralWithDatatype("0.1", XSD.Get("float")):                              `"1.0E-1"^^<http://www.w3.org/2001/XMLSchema#float>`,
+	NewLiteralWithDatatype("2012-03-04T05:06:07.500+01:00", XSD.Get("dateTime")): `"2012-03-04T04:06:07.5Z"^^<http://www.w3.org/2001/XMLSchema#dateTime>`,
+	NewLiteralWithDatatype("2012-03-04T24:00:00", XSD.Get("dateTime")):           `"2012-03-05T00:00:00"^^<http://www.w3.org/2001/XMLSchema#dateTime>`,
+	NewLiteralWithDatatype("2012-03-04+00:00", XSD.Get("date")):                  `"2012-03-04Z"^^<http://www.w3.org/2001/XMLSchema#date>`,
+	NewLiteralWithDatatype("PT36H", XSD.Get("duration")):                         `"P1DT12H"^^<http://www.w3.org/2001/XMLSchema#duration>`,
+	NewLiteralWithDatatype("P1Y2M", XSD.Get("duration")):                         `"P1Y2M"^^<http://www.w3.org/2001/XMLSchema#duration>`,
+	NewLiteralWithDatatype("0fb7", XSD.Get("hexBinary")):                         `"0FB7"^^<http://www.w3.org/2001/XMLSchema#hexBinary>`,
+	NewLiteralWithDatatype("aGVs bG8=", XSD.Get("base64Binary")):                 `"aGVsbG8="^^<http://www.w3.org/2001/XMLSchema#base64Binary>`,
+	NewLiteralWithDatatype("01", NewResource("http://example.org/dt")):           `"01"^^<http://example.org/dt>`,
+}
+
+func TestLiteralCanonical(t *testing.T) {
+	for literal, expected := range canonicalLiteralTestCases {
+		canonical, err := literal.(*Literal).Canonical()
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", literal, err)
+		} else if canonical.String() != expected {
+			t.Errorf("%s: expected %s but got %s", literal, expected, canonical)
+		}
+	}
+
+	invalid := NewLiteralWithDatatype("one", XSD.Get("integer"))
+	if _, err := invalid.(*Literal).Canonical(); err == nil {
+		t.Errorf("%s: expected an error but got none", invalid)
+	}
+
+	if CanonicalTerm(invalid) != invalid {
+		t.Errorf("%s: expected CanonicalTerm to leave an invalid literal unchanged", invalid)
+	}
+}
+
+func TestValueEqual(t *testing.T) {
+	one := NewLiteralWithDatatype("1", XSD.Get("integer"))
+
+	if !ValueEqual(one, NewLiteralWithDatatype("01", XSD.Get("integer"))) {
+		t.Errorf("Expected \"1\" and \"01\" to be equal integers")
+	}
+
+	if ValueEqual(one, NewLiteralWithDatatype("1", XSD.Get("int"))) {
+		t.Errorf("Expected literals of different datatypes to differ")
+	}
+
+	if !ValueEqual(NewLiteralWithLanguage("x", "en-GB"), NewLiteralWithLanguage("x", "en-gb")) {
+		t.Errorf("Expected language tags to be compared case-insensitively")
+	}
+
+	listStore, indexStore := NewListStore(), NewIndexStore()
+	listStore.ValueEquality = true
+	indexStore.ValueEquality = true
+
+	for _, store := range []Store{listStore, indexStore} {
+		s, p := NewResource("http://example.org/s"), NewResource("http://example.org/p")
+		store.Add(NewTriple(s, p, NewLiteralWithDatatype("01", XSD.Get("integer"))))
+		store.Remove(NewTriple(s, p, one))
+
+		if store.Num() != 0 {
+			t.Errorf("%T: expected the triple to be removed by value", store)
+		}
+	}
+}
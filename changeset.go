@@ -0,0 +1,319 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A ChangeSet is a set of changes to the description of a single resource, as described by the
+// Talis Changeset vocabulary (http://vocab.org/changeset/schema.html). Additions and removals are
+// stated in the changeset graph as reified statements.
+type ChangeSet struct {
+	// The node describing the changeset in its graph, or nil if it has not been encoded yet.
+	Node Term
+
+	// The resource whose description is changed; all additions and removals must have it as their
+	// subject.
+	SubjectOfChange Term
+
+	// Optional metadata: the time of creation, the name of the creator, the reason for the change,
+	// and the changeset that this one follows.
+	CreatedDate        string
+	CreatorName        string
+	ChangeReason       string
+	PrecedingChangeSet Term
+
+	// The triples added and removed by the changeset.
+	Additions []*Triple
+	Removals  []*Triple
+}
+
+// These are the errors that can be returned in ChangeSetError.Err.
+var (
+	ErrCSNoSubjectOfChange        = errors.New("changeset has no cs:subjectOfChange")
+	ErrCSMultipleSubjectsOfChange = errors.New("changeset has more than one cs:subjectOfChange")
+	ErrCSIncompleteStatement      = errors.New("statement lacks a single rdf:subject, rdf:predicate or rdf:object")
+	ErrCSSubjectMismatch          = errors.New("statement subject is not the subject of change")
+	ErrCSMissingRemoval           = errors.New("removed statement is not in the graph")
+)
+
+// A ChangeSetError is returned when a changeset is malformed or does not apply to a graph.
+type ChangeSetError struct {
+	ChangeSet Term    // The node of the changeset
+	Triple    *Triple // The statement concerned, if any
+	Err       error   // The actual error
+}
+
+func (e *ChangeSetError) Error() string {
+	if e.Triple != nil {
+		return fmt.Sprintf("changeset %s: %s: %s", e.ChangeSet, e.Err, e.Triple)
+	}
+
+	return fmt.Sprintf("changeset %s: %s", e.ChangeSet, e.Err)
+}
+
+// Function ParseChangeSets reads all changesets (resources of type cs:ChangeSet) described in graph,
+// in the order their descriptions were added to the graph.
+func ParseChangeSets(graph *Graph) (changesets []*ChangeSet, err error) {
+	idx := newTripleIndex().addAll(graph.IterTriples())
+
+	for _, subject := range idx.subjects {
+		for _, class := range idx.objects(subject, A) {
+			if class.Equal(CS.Get("ChangeSet")) {
+				cs, err := parseChangeSet(idx, subject)
+				if err != nil {
+					return nil, err
+				}
+
+				changesets = append(changesets, cs)
+				break
+			}
+		}
+	}
+
+	return changesets, nil
+}
+
+// parseChangeSet reads the changeset described by node.
+func parseChangeSet(idx *tripleIndex, node Term) (cs *ChangeSet, err error) {
+	cs = &ChangeSet{Node: node}
+
+	subjects := idx.objects(node, CS.Get("subjectOfChange"))
+	if len(subjects) == 0 {
+		return nil, &ChangeSetError{ChangeSet: node, Err: ErrCSNoSubjectOfChange}
+	} else if len(subjects) > 1 {
+		return nil, &ChangeSetError{ChangeSet: node, Err: ErrCSMultipleSubjectsOfChange}
+	}
+
+	cs.SubjectOfChange = subjects[0]
+	cs.CreatedDate = literalValue(idx.objects(node, CS.Get("createdDate")))
+	cs.CreatorName = literalValue(idx.objects(node, CS.Get("creatorName")))
+	cs.ChangeReason = literalValue(idx.objects(node, CS.Get("changeReason")))
+
+	if preceding := idx.objects(node, CS.Get("precedingChangeSet")); len(preceding) > 0 {
+		cs.PrecedingChangeSet = preceding[0]
+	}
+
+	cs.Additions, err = parseStatements(idx, node, CS.Get("addition"))
+	if err != nil {
+		return nil, err
+	}
+
+	cs.Removals, err = parseStatements(idx, node, CS.Get("removal"))
+	if err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// literalValue returns the value of the first literal in terms, or "" if there is none.
+func literalValue(terms []Term) string {
+	for _, term := range terms {
+		if literal, ok := term.(*Literal); ok {
+			return literal.Value
+		}
+	}
+
+	return ""
+}
+
+// parseStatements returns the reified statements linked to the changeset node by predicate.
+func parseStatements(idx *tripleIndex, node Term, predicate Term) (triples []*Triple, err error) {
+	for _, statement := range idx.objects(node, predicate) {
+		subjects := idx.objects(statement, RDF.Get("subject"))
+		predicates := idx.objects(statement, RDF.Get("predicate"))
+		objects := idx.objects(statement, RDF.Get("object"))
+
+		if len(subjects) != 1 || len(predicates) != 1 || len(objects) != 1 {
+			return nil, &ChangeSetError{ChangeSet: node, Err: ErrCSIncompleteStatement}
+		}
+
+		triples = append(triples, NewTriple(subjects[0], predicates[0], objects[0]))
+	}
+
+	return triples, nil
+}
+
+// Method Validate checks that the changeset can be applied to graph: every statement must have the
+// subject of change as its subject, and every removed statement must be present in the graph.
+func (cs *ChangeSet) Validate(graph *Graph) (err error) {
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	return cs.validate(graph.Store)
+}
+
+// Method validate is Validate without the locking, checking against store.
+func (cs *ChangeSet) validate(store Store) (err error) {
+	if cs.SubjectOfChange == nil {
+		return &ChangeSetError{ChangeSet: cs.Node, Err: ErrCSNoSubjectOfChange}
+	}
+
+	for _, triples := range [][]*Triple{cs.Removals, cs.Additions} {
+		for _, triple := range triples {
+			if !triple.Subject.Equal(cs.SubjectOfChange) {
+				return &ChangeSetError{ChangeSet: cs.Node, Triple: triple, Err: ErrCSSubjectMismatch}
+			}
+		}
+	}
+
+	for _, triple := range cs.Removals {
+		if findTriple(store, triple) == nil {
+			return &ChangeSetError{ChangeSet: cs.Node, Triple: triple, Err: ErrCSMissingRemoval}
+		}
+	}
+
+	return nil
+}
+
+// Method Patch returns the changes of the changeset as a patch against graph: the removals followed
+// by those additions that are not already present in the graph (or are removed first).
+func (cs *ChangeSet) Patch(graph *Graph) (patch *Patch) {
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	return cs.patch(graph.Store)
+}
+
+// Method patch is Patch without the locking, computing the patch against store.
+func (cs *ChangeSet) patch(store Store) (patch *Patch) {
+	patch = NewPatch()
+	removed := make(map[string]bool, len(cs.Removals))
+
+	for _, triple := range uniqueTriples(cs.Removals) {
+		patch.Delete(triple)
+		removed[triple.String()] = true
+	}
+
+	for _, triple := range uniqueTriples(cs.Additions) {
+		if removed[triple.String()] || findTriple(store, triple) == nil {
+			patch.Add(triple)
+		}
+	}
+
+	return patch
+}
+
+// Method Apply validates the changeset against graph and applies it, holding the graph's lock
+// throughout so that the graph cannot change in between. The changes are applied as a transaction
+// (see Patch.ApplyToStore), so the graph is left unchanged if an error is returned.
+func (cs *ChangeSet) Apply(graph *Graph) (err error) {
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	err = cs.validate(graph.Store)
+	if err != nil {
+		return err
+	}
+
+	return cs.patch(graph.Store).ApplyToStore(graph.Store)
+}
+
+// Method Encode adds the description of the changeset to graph, using cs.Node (or a new blank node
+// if it is nil) as its node, and returns the node.
+func (cs *ChangeSet) Encode(graph *Graph) (node Term) {
+	if cs.Node == nil {
+		cs.Node = graph.NewBlankNode()
+	}
+
+	node = cs.Node
+	graph.AddTriple(node, A, CS.Get("ChangeSet"))
+	graph.AddTriple(node, CS.Get("subjectOfChange"), cs.SubjectOfChange)
+
+	if cs.CreatedDate != "" {
+		graph.AddTriple(node, CS.Get("createdDate"), NewLiteral(cs.CreatedDate))
+	}
+
+	if cs.CreatorName != "" {
+		graph.AddTriple(node, CS.Get("creatorName"), NewLiteral(cs.CreatorName))
+	}
+
+	if cs.ChangeReason != "" {
+		graph.AddTriple(node, CS.Get("changeReason"), NewLiteral(cs.ChangeReason))
+	}
+
+	if cs.PrecedingChangeSet != nil {
+		graph.AddTriple(node, CS.Get("precedingChangeSet"), cs.PrecedingChangeSet)
+	}
+
+	encode := func(predicate Term, triple *Triple) {
+		statement := graph.NewBlankNode()
+		graph.AddTriple(node, predicate, statement)
+		graph.AddTriple(statement, A, RDF.Get("Statement"))
+		graph.AddTriple(statement, RDF.Get("subject"), triple.Subject)
+		graph.AddTriple(statement, RDF.Get("predicate"), triple.Predicate)
+		graph.AddTriple(statement, RDF.Get("object"), triple.Object)
+	}
+
+	for _, triple := range cs.Removals {
+		encode(CS.Get("removal"), triple)
+	}
+
+	for _, triple := range cs.Additions {
+		encode(CS.Get("addition"), triple)
+	}
+
+	return node
+}
+
+// Function ChangeSetsFromPatch splits the additions and deletions of a patch into changesets, one
+// per subject, ordered by subject (see CompareTerms). Prefix changes and graph names are ignored.
+func ChangeSetsFromPatch(patch *Patch) (changesets []*ChangeSet) {
+	bySubject := make(map[string]*ChangeSet)
+	var subjects []Term
+
+	for _, row := range patch.Changes {
+		if row.Op != PatchAdd && row.Op != PatchDelete {
+			continue
+		}
+
+		key := row.Triple.Subject.String()
+		cs, ok := bySubject[key]
+		if !ok {
+			cs = &ChangeSet{SubjectOfChange: row.Triple.Subject}
+			bySubject[key] = cs
+			subjects = append(subjects, row.Triple.Subject)
+		}
+
+		triple := NewTriple(row.Triple.Subject, row.Triple.Predicate, row.Triple.Object)
+		if row.Op == PatchAdd {
+			cs.Additions = append(cs.Additions, triple)
+		} else {
+			cs.Removals = append(cs.Removals, triple)
+		}
+	}
+
+	SortTerms(subjects)
+
+	for _, subject := range subjects {
+		changesets = append(changesets, bySubject[subject.String()])
+	}
+
+	return changesets
+}
+
+// Method DiffChangeSets computes the changesets that turn the graph into target. See Graph.Diff and
+// ChangeSetsFromPatch.
+func (graph *Graph) DiffChangeSets(target *Graph) (changesets []*ChangeSet) {
+	return ChangeSetsFromPatch(graph.Diff(target))
+}
@@ -0,0 +1,157 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"testing"
+)
+
+const changeSetDoc = `@prefix cs: <http://purl.org/vocab/changeset/schema#> .
+@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+@prefix ex: <http://example.org/> .
+
+<http://example.org/changes/1> a cs:ChangeSet ;
+    cs:subjectOfChange ex:book ;
+    cs:createdDate "2012-01-01T00:00:00Z" ;
+    cs:creatorName "Anne" ;
+    cs:changeReason "Fix the title" ;
+    cs:removal [ a rdf:Statement ; rdf:subject ex:book ; rdf:predicate ex:title ; rdf:object "Teh Book" ] ;
+    cs:addition [ a rdf:Statement ; rdf:subject ex:book ; rdf:predicate ex:title ; rdf:object "The Book" ] ,
+                [ a rdf:Statement ; rdf:subject ex:book ; rdf:predicate ex:pages ; rdf:object 300 ] .`
+
+func TestChangeSetApply(t *testing.T) {
+	changesets, err := ParseChangeSets(parseTurtleGraph(t, changeSetDoc))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if len(changesets) != 1 {
+		t.Fatalf("Expected 1 changeset but got %d", len(changesets))
+	}
+
+	cs := changesets[0]
+	if cs.CreatorName != "Anne" || cs.ChangeReason != "Fix the title" || len(cs.Additions) != 2 || len(cs.Removals) != 1 {
+		t.Errorf("Changeset was not read correctly: %+v", cs)
+	}
+
+	graph := parseTurtleGraph(t, `<http://example.org/book> <http://example.org/title> "Teh Book" .
+<http://example.org/book> <http://example.org/pages> 300 .`)
+
+	err = cs.Apply(graph)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expected := parseTurtleGraph(t, `<http://example.org/book> <http://example.org/title> "The Book" .
+<http://example.org/book> <http://example.org/pages> 300 .`)
+
+	if c := graph.Compare(expected); !c.Isomorphic() {
+		t.Errorf("Unexpected result of applying the changeset:\n%s", c)
+	}
+
+	// The removed statement is no longer present.
+	err = cs.Apply(graph)
+	if ce, ok := err.(*ChangeSetError); !ok || ce.Err != ErrCSMissingRemoval {
+		t.Errorf("Expected ErrCSMissingRemoval but got %v", err)
+	}
+}
+
+func TestChangeSetValidate(t *testing.T) {
+	graph := parseTurtleGraph(t, `<http://example.org/a> <http://example.org/p> "x" .`)
+
+	cs := &ChangeSet{
+		SubjectOfChange: NewResource("http://example.org/a"),
+		Additions:       []*Triple{NewTriple(NewResource("http://example.org/b"), NewResource("http://example.org/p"), NewLiteral("y"))},
+	}
+
+	err := cs.Apply(graph)
+	if ce, ok := err.(*ChangeSetError); !ok || ce.Err != ErrCSSubjectMismatch {
+		t.Errorf("Expected ErrCSSubjectMismatch but got %v", err)
+	}
+
+	_, err = ParseChangeSets(parseTurtleGraph(t, `@prefix cs: <http://purl.org/vocab/changeset/schema#> .
+[] a cs:ChangeSet ; cs:addition [ ] .`))
+	if ce, ok := err.(*ChangeSetError); !ok || ce.Err != ErrCSNoSubjectOfChange {
+		t.Errorf("Expected ErrCSNoSubjectOfChange but got %v", err)
+	}
+
+	_, err = ParseChangeSets(parseTurtleGraph(t, `@prefix cs: <http://purl.org/vocab/changeset/schema#> .
+[] a cs:ChangeSet ; cs:subjectOfChange <http://example.org/a> ; cs:addition [ ] .`))
+	if ce, ok := err.(*ChangeSetError); !ok || ce.Err != ErrCSIncompleteStatement {
+		t.Errorf("Expected ErrCSIncompleteStatement but got %v", err)
+	}
+}
+
+func TestChangeSetsFromDiff(t *testing.T) {
+	from := parseTurtleGraph(t, `@prefix ex: <http://example.org/> .
+ex:a ex:p "1" .
+ex:b ex:p "2" .`)
+
+	to := parseTurtleGraph(t, `@prefix ex: <http://example.org/> .
+ex:a ex:p "one" .
+ex:b ex:p "2" .
+ex:c ex:p "3" .`)
+
+	changesets := from.DiffChangeSets(to)
+	if len(changesets) != 2 {
+		t.Fatalf("Expected 2 changesets but got %d", len(changesets))
+	}
+
+	// Round trip the changesets through a graph.
+	encoded := NewGraph(NewListStore())
+	for _, cs := range changesets {
+		cs.Encode(encoded)
+	}
+
+	parsed, err := ParseChangeSets(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	for _, cs := range parsed {
+		err = cs.Apply(from)
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+	}
+
+	if c := from.Compare(to); !c.Isomorphic() {
+		t.Errorf("Unexpected result of applying the changesets:\n%s", c)
+	}
+}
+
+func TestChangeSetsFromPatchOrder(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+
+	patch := NewPatch()
+	patch.Add(NewTriple(ex.Get("b"), ex.Get("p"), NewLiteral("1")))
+	patch.Add(NewTriple(ex.Get("a"), ex.Get("p"), NewLiteral("2")))
+	patch.Delete(NewTriple(NewBlankNode("x"), ex.Get("p"), NewLiteral("3")))
+
+	var subjects []Term
+	for _, cs := range ChangeSetsFromPatch(patch) {
+		subjects = append(subjects, cs.SubjectOfChange)
+	}
+
+	// Subjects are in the order of CompareTerms, which puts blank nodes first.
+	if len(subjects) != 3 || !subjects[0].Equal(NewBlankNode("x")) || !subjects[1].Equal(ex.Get("a")) || !subjects[2].Equal(ex.Get("b")) {
+		t.Errorf("Expected subjects _:x, ex:a and ex:b but got %v", subjects)
+	}
+}
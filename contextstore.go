@@ -0,0 +1,345 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"context"
+	"iter"
+)
+
+// A TripleIterator steps through a sequence of triples. Unlike a channel, it can be abandoned early
+// by calling Close, which releases whatever is producing the triples, and it reports failures
+// through Err.
+//
+// The usual pattern is:
+//
+//	it := store.Filter(ctx, subject, nil, nil)
+//	defer it.Close()
+//
+//	for it.Next() {
+//	    triple := it.Triple()
+//	    ...
+//	}
+//
+//	if err := it.Err(); err != nil {
+//	    ...
+//	}
+type TripleIterator interface {
+	// Method Next should advance the iterator to the next triple, returning false when there are
+	// no more triples, an error occurred, or the iterator has been closed.
+	Next() bool
+
+	// Method Triple should return the triple the iterator is currently positioned on.
+	Triple() *Triple
+
+	// Method Err should return the error that stopped iteration, if any. It should return nil if
+	// the sequence was exhausted or the iterator was closed before the end.
+	Err() error
+
+	// Method Close should stop iteration and release any resources held by the iterator. It is
+	// safe to call Close more than once, and after Next has returned false.
+	Close() error
+}
+
+// A ContextStore is a container for RDF triples whose operations can fail and be cancelled. It is
+// the error-returning counterpart of Store; use AdaptStore to turn an existing Store into one and
+// AdaptContextStore to go the other way.
+type ContextStore interface {
+	// Method Add should add the given triple to the store.
+	Add(context.Context, *Triple) error
+
+	// Method Remove should remove the given triple from the store.
+	Remove(context.Context, *Triple) error
+
+	// Method Clear should remove all triples from the store.
+	Clear(context.Context) error
+
+	// Method Num should return the number of triples in the store.
+	Num(context.Context) (int, error)
+
+	// Method IterTriples should return an iterator over the triples of the store.
+	IterTriples(context.Context) TripleIterator
+
+	// Method Filter should return an iterator over all matching triples of the store. A nil value
+	// passed means that the check for this term is skipped; else the triples returned must have
+	// the same terms as the corresponding arguments.
+	Filter(context.Context, Term, Term, Term) TripleIterator
+}
+
+// A funcIterator is a TripleIterator that runs a producer function in its own goroutine.
+type funcIterator struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	ch     chan *Triple
+	perr   error // Producer's result; only read once ch has been closed
+	triple *Triple
+	err    error
+	done   bool
+}
+
+// Function NewTripleIterator returns a TripleIterator over the triples produced by produce, which
+// is run in a new goroutine. It should pass each triple to yield, and return as soon as yield
+// returns false (which happens when the iterator is closed or ctx is cancelled). The error it
+// returns is reported by the iterator's Err method.
+func NewTripleIterator(ctx context.Context, produce func(context.Context, func(*Triple) bool) error) (it TripleIterator) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	fi := &funcIterator{
+		ctx:    ctx,
+		cancel: cancel,
+		ch:     make(chan *Triple),
+	}
+
+	go func() {
+		defer close(fi.ch)
+
+		fi.perr = produce(ctx, func(triple *Triple) bool {
+			select {
+			case fi.ch <- triple:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return fi
+}
+
+// Method Next advances the iterator to the next triple.
+func (it *funcIterator) Next() (ok bool) {
+	if it.done {
+		return false
+	}
+
+	select {
+	case triple, ok := <-it.ch:
+		if !ok {
+			it.err = it.perr
+			it.finish()
+			return false
+		}
+
+		it.triple = triple
+		return true
+
+	case <-it.ctx.Done():
+		it.err = it.ctx.Err()
+		it.finish()
+		return false
+	}
+}
+
+// Method Triple returns the current triple.
+func (it *funcIterator) Triple() (triple *Triple) {
+	return it.triple
+}
+
+// Method Err returns the error that stopped iteration, if any.
+func (it *funcIterator) Err() (err error) {
+	return it.err
+}
+
+// Method Close stops the producer.
+func (it *funcIterator) Close() (err error) {
+	it.finish()
+	return nil
+}
+
+// Method finish marks the iterator as finished and signals the producer to stop.
+func (it *funcIterator) finish() {
+	it.done = true
+	it.triple = nil
+	it.cancel()
+}
+
+// A storeAdapter presents a Store as a ContextStore.
+type storeAdapter struct {
+	store Store
+}
+
+// Function AdaptStore returns a ContextStore backed by store. Since a Store cannot fail or be
+// interrupted, the context is only checked before each operation is started. Closing an iterator
+// early stops a SeqStore's iteration straight away; for other stores, the store's producer
+// goroutine is left to run to completion in the background rather than blocking forever.
+func AdaptStore(store Store) (cs ContextStore) {
+	if ca, ok := store.(*contextStoreAdapter); ok {
+		return ca.store
+	}
+
+	return &storeAdapter{store}
+}
+
+// Method Add adds the given triple to the underlying store.
+func (sa *storeAdapter) Add(ctx context.Context, triple *Triple) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	sa.store.Add(triple)
+	return nil
+}
+
+// Method Remove removes the given triple from the underlying store.
+func (sa *storeAdapter) Remove(ctx context.Context, triple *Triple) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	sa.store.Remove(triple)
+	return nil
+}
+
+// Method Clear empties the underlying store.
+func (sa *storeAdapter) Clear(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	sa.store.Clear()
+	return nil
+}
+
+// Method Num returns the number of triples in the underlying store.
+func (sa *storeAdapter) Num(ctx context.Context) (n int, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return sa.store.Num(), nil
+}
+
+// Method IterTriples returns an iterator over the triples of the underlying store.
+func (sa *storeAdapter) IterTriples(ctx context.Context) (it TripleIterator) {
+	return sa.iterate(ctx, func() iter.Seq[*Triple] {
+		return storeAllSeq(sa.store)
+	})
+}
+
+// Method Filter returns an iterator over the matching triples of the underlying store.
+func (sa *storeAdapter) Filter(ctx context.Context, subjSearch, predSearch, objSearch Term) (it TripleIterator) {
+	return sa.iterate(ctx, func() iter.Seq[*Triple] {
+		return storeFilterSeq(sa.store, subjSearch, predSearch, objSearch)
+	})
+}
+
+// Method iterate wraps the sequence returned by open in a TripleIterator. The sequence is opened
+// before this method returns, so that callers holding a lock on the store are protected.
+func (sa *storeAdapter) iterate(ctx context.Context, open func() iter.Seq[*Triple]) (it TripleIterator) {
+	if err := ctx.Err(); err != nil {
+		return NewTripleIterator(ctx, func(context.Context, func(*Triple) bool) error {
+			return err
+		})
+	}
+
+	seq := open()
+
+	return NewTripleIterator(ctx, func(ctx context.Context, yield func(*Triple) bool) error {
+		for triple := range seq {
+			if !yield(triple) {
+				return ctx.Err()
+			}
+		}
+
+		return nil
+	})
+}
+
+// A contextStoreAdapter presents a ContextStore as a Store.
+type contextStoreAdapter struct {
+	store        ContextStore
+	errorHandler func(error)
+}
+
+// Function AdaptContextStore returns a Store backed by cs, so that it can be used with a Graph.
+// Operations are run with context.Background(), and any error they return is passed to
+// errorHandler. If errorHandler is nil, errors cause a panic.
+func AdaptContextStore(cs ContextStore, errorHandler func(error)) (store Store) {
+	if sa, ok := cs.(*storeAdapter); ok {
+		return sa.store
+	}
+
+	return &contextStoreAdapter{cs, errorHandler}
+}
+
+// Method handle reports a non-nil error.
+func (ca *contextStoreAdapter) handle(err error) {
+	if err == nil {
+		return
+	}
+
+	if ca.errorHandler == nil {
+		panic(err)
+	}
+
+	ca.errorHandler(err)
+}
+
+// Method Add adds the given triple to the underlying store.
+func (ca *contextStoreAdapter) Add(triple *Triple) {
+	ca.handle(ca.store.Add(context.Background(), triple))
+}
+
+// Method Remove removes the given triple from the underlying store.
+func (ca *contextStoreAdapter) Remove(triple *Triple) {
+	ca.handle(ca.store.Remove(context.Background(), triple))
+}
+
+// Method Clear empties the underlying store.
+func (ca *contextStoreAdapter) Clear() {
+	ca.handle(ca.store.Clear(context.Background()))
+}
+
+// Method Num returns the number of triples in the underlying store, or 0 if it could not be
+// determined.
+func (ca *contextStoreAdapter) Num() (n int) {
+	n, err := ca.store.Num(context.Background())
+	ca.handle(err)
+	return n
+}
+
+// Method IterTriples returns a channel that will yield the triples of the underlying store.
+func (ca *contextStoreAdapter) IterTriples() (ch chan *Triple) {
+	return ca.channel(ca.store.IterTriples(context.Background()))
+}
+
+// Method Filter returns a channel that will yield the matching triples of the underlying store.
+func (ca *contextStoreAdapter) Filter(subjSearch, predSearch, objSearch Term) (ch chan *Triple) {
+	return ca.channel(ca.store.Filter(context.Background(), subjSearch, predSearch, objSearch))
+}
+
+// Method channel feeds the triples from it into a new channel, which is closed once the iterator
+// is exhausted.
+func (ca *contextStoreAdapter) channel(it TripleIterator) (ch chan *Triple) {
+	ch = make(chan *Triple)
+
+	go func() {
+		defer close(ch)
+		defer it.Close()
+
+		for it.Next() {
+			ch <- it.Triple()
+		}
+
+		ca.handle(it.Err())
+	}()
+
+	return ch
+}
@@ -0,0 +1,201 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTripleIteratorClose(t *testing.T) {
+	stopped := make(chan struct{})
+	triple := NewTriple(NewBlankNode("a"), A, NewResource("http://example.org/Thing"))
+
+	it := NewTripleIterator(context.Background(), func(ctx context.Context, yield func(*Triple) bool) error {
+		defer close(stopped)
+
+		for yield(triple) {
+		}
+
+		return ctx.Err()
+	})
+
+	for i := 0; i < 3; i++ {
+		if !it.Next() {
+			t.Fatalf("Iterator stopped early: %v", it.Err())
+		}
+	}
+
+	it.Close()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("Producer was not stopped by Close")
+	}
+
+	if it.Next() {
+		t.Errorf("Next returned true after Close")
+	}
+
+	if err := it.Err(); err != nil {
+		t.Errorf("Expected no error after Close but got %s", err)
+	}
+}
+
+func TestTripleIteratorErrors(t *testing.T) {
+	failure := errors.New("backend failure")
+
+	it := NewTripleIterator(context.Background(), func(ctx context.Context, yield func(*Triple) bool) error {
+		yield(NewTriple(NewBlankNode("a"), A, NewBlankNode("b")))
+		return failure
+	})
+
+	n := 0
+	for it.Next() {
+		n++
+	}
+
+	if n != 1 || it.Err() != failure {
+		t.Errorf("Expected 1 triple and %q but got %d and %v", failure, n, it.Err())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	it = NewTripleIterator(ctx, func(ctx context.Context, yield func(*Triple) bool) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	cancel()
+
+	if it.Next() || it.Err() != context.Canceled {
+		t.Errorf("Expected cancellation to be reported but got %v", it.Err())
+	}
+}
+
+func TestAdaptStore(t *testing.T) {
+	ctx := context.Background()
+	cs := AdaptStore(NewListStore())
+
+	for i := 0; i < 5; i++ {
+		err := cs.Add(ctx, NewTriple(NewBlankNode("a"), RDF.Get("value"), NewLiteral(string(rune('a'+i)))))
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+	}
+
+	if n, err := cs.Num(ctx); n != 5 || err != nil {
+		t.Errorf("Expected 5 triples but got %d (%v)", n, err)
+	}
+
+	it := cs.Filter(ctx, NewBlankNode("a"), nil, NewLiteral("c"))
+	if !it.Next() || it.Triple().Object.(*Literal).Value != "c" {
+		t.Errorf("Filter did not find the expected triple")
+	}
+	it.Close()
+
+	// Closing early must not block the store's producer forever.
+	it = cs.IterTriples(ctx)
+	it.Next()
+	it.Close()
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if err := cs.Add(cancelled, NewTriple(NewBlankNode("b"), A, NewBlankNode("c"))); err != context.Canceled {
+		t.Errorf("Expected Add to fail with %q but got %v", context.Canceled, err)
+	}
+
+	if it = cs.IterTriples(cancelled); it.Next() || it.Err() != context.Canceled {
+		t.Errorf("Expected IterTriples to fail with %q but got %v", context.Canceled, it.Err())
+	}
+
+	if n, _ := cs.Num(ctx); n != 5 {
+		t.Errorf("Expected 5 triples after cancelled operations but got %d", n)
+	}
+}
+
+// A failingStore is a ContextStore on which every operation fails.
+type failingStore struct {
+	err error
+}
+
+func (fs failingStore) Add(context.Context, *Triple) error    { return fs.err }
+func (fs failingStore) Remove(context.Context, *Triple) error { return fs.err }
+func (fs failingStore) Clear(context.Context) error           { return fs.err }
+func (fs failingStore) Num(context.Context) (int, error)      { return 0, fs.err }
+
+func (fs failingStore) IterTriples(ctx context.Context) TripleIterator {
+	return fs.Filter(ctx, nil, nil, nil)
+}
+
+func (fs failingStore) Filter(ctx context.Context, s, p, o Term) TripleIterator {
+	return NewTripleIterator(ctx, func(context.Context, func(*Triple) bool) error {
+		return fs.err
+	})
+}
+
+func TestAdaptContextStore(t *testing.T) {
+	failure := errors.New("backend failure")
+	var errs []error
+
+	store := AdaptContextStore(failingStore{failure}, func(err error) {
+		errs = append(errs, err)
+	})
+
+	store.Add(NewTriple(NewBlankNode("a"), A, NewBlankNode("b")))
+	store.Num()
+
+	for _ = range store.IterTriples() {
+		t.Errorf("Unexpected triple from failing store")
+	}
+
+	if len(errs) != 3 {
+		t.Errorf("Expected 3 errors to be reported but got %d", len(errs))
+	}
+
+	list := NewListStore()
+	if AdaptContextStore(AdaptStore(list), nil) != Store(list) {
+		t.Errorf("Adapters did not unwrap each other")
+	}
+}
+
+func TestGraphGet(t *testing.T) {
+	graph := NewGraph(NewIndexStore())
+	ex := NewNamespace("http://example.org/")
+
+	graph.AddTriple(ex.Get("a"), ex.Get("p"), NewLiteral("1"))
+	graph.AddTriple(ex.Get("a"), ex.Get("p"), NewLiteral("2"))
+
+	if object := graph.Get(ex.Get("a"), ex.Get("p")); object == nil {
+		t.Errorf("Get did not find an object")
+	}
+
+	if object := graph.Get(ex.Get("a"), ex.Get("q")); object != nil {
+		t.Errorf("Expected nil but got %s", object)
+	}
+
+	if !graph.HasSubject(ex.Get("a")) || graph.HasSubject(ex.Get("b")) {
+		t.Errorf("HasSubject gave the wrong answer")
+	}
+}
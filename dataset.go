@@ -0,0 +1,288 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DefaultGraph can be passed as a graph name to select only the default graph of a Dataset, where
+// nil would mean "any graph" (as in Filter). It is compared by identity, so an equal resource
+// created elsewhere names an ordinary named graph.
+var DefaultGraph Term = NewResource("urn:x-argo:default-graph")
+
+// Function isDefaultGraph returns whether the graph name refers to the default graph.
+func isDefaultGraph(name Term) bool {
+	return name == nil || name == DefaultGraph
+}
+
+// Function withoutGraph returns triple, or a copy of it with no graph name if it has one. The
+// stores of a dataset's graphs hold triples without graph names, so that the triple-level methods
+// of a named graph see the same triples as the dataset's quad-level methods.
+func withoutGraph(triple *Triple) (result *Triple) {
+	if triple.Graph != nil {
+		return NewTriple(triple.Subject, triple.Predicate, triple.Object)
+	}
+
+	return triple
+}
+
+// A Dataset is a collection of graphs: an unnamed default graph and any number of named graphs,
+// each backed by its own Store. Triples are routed to a graph according to their Graph field.
+type Dataset struct {
+	// The default graph.
+	Default *Graph
+
+	// Mutex locking the map of named graphs.
+	Mutex sync.Mutex
+
+	// The prefix map, shared by all graphs of the dataset.
+	Prefixes map[string]string
+
+	named    map[string]*Graph
+	names    map[string]Term
+	newStore func() Store
+}
+
+// Function NewDataset creates and returns a new dataset. newStore is called to create the Store
+// backing each graph, including the default graph.
+func NewDataset(newStore func() Store) (dataset *Dataset) {
+	dataset = &Dataset{
+		Default:  NewGraph(newStore()),
+		named:    make(map[string]*Graph),
+		names:    make(map[string]Term),
+		newStore: newStore,
+	}
+
+	dataset.Prefixes = dataset.Default.Prefixes
+	return dataset
+}
+
+// Method Graph returns the graph with the given name, creating it if it does not exist. If name is
+// nil or DefaultGraph, the default graph is returned.
+func (dataset *Dataset) Graph(name Term) (graph *Graph) {
+	if isDefaultGraph(name) {
+		return dataset.Default
+	}
+
+	dataset.Mutex.Lock()
+	defer dataset.Mutex.Unlock()
+
+	key := name.String()
+	graph, ok := dataset.named[key]
+	if !ok {
+		graph = NewGraph(dataset.newStore())
+		graph.Prefixes = dataset.Prefixes
+		dataset.named[key] = graph
+		dataset.names[key] = name
+	}
+
+	return graph
+}
+
+// Method HasGraph returns whether a named graph with the given name exists in the dataset.
+func (dataset *Dataset) HasGraph(name Term) (result bool) {
+	dataset.Mutex.Lock()
+	defer dataset.Mutex.Unlock()
+
+	_, result = dataset.named[name.String()]
+	return result
+}
+
+// Method RemoveGraph removes the named graph with the given name, and all its triples, from the
+// dataset.
+func (dataset *Dataset) RemoveGraph(name Term) {
+	dataset.Mutex.Lock()
+	defer dataset.Mutex.Unlock()
+
+	key := name.String()
+	delete(dataset.named, key)
+	delete(dataset.names, key)
+}
+
+// Method GraphNames returns the names of all named graphs in the dataset, sorted by their N-Triples
+// representation.
+func (dataset *Dataset) GraphNames() (names []Term) {
+	dataset.Mutex.Lock()
+	defer dataset.Mutex.Unlock()
+
+	keys := make([]string, 0, len(dataset.names))
+	for key := range dataset.names {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	names = make([]Term, len(keys))
+	for i, key := range keys {
+		names[i] = dataset.names[key]
+	}
+
+	return names
+}
+
+// Method Add adds the given triple to the graph named by its Graph field, or to the default graph if
+// it has none.
+func (dataset *Dataset) Add(triple *Triple) {
+	dataset.Graph(triple.Graph).Add(withoutGraph(triple))
+}
+
+// Method AddQuad creates a triple from the arguments and adds it to the dataset.
+func (dataset *Dataset) AddQuad(subject Term, predicate Term, object Term, graph Term) {
+	dataset.Add(NewQuad(subject, predicate, object, graph))
+}
+
+// Method Remove removes the given triple from the graph named by its Graph field, if it exists.
+func (dataset *Dataset) Remove(triple *Triple) {
+	if !isDefaultGraph(triple.Graph) && !dataset.HasGraph(triple.Graph) {
+		return
+	}
+
+	graph := dataset.Graph(triple.Graph)
+	triple = withoutGraph(triple)
+
+	// Stores such as ListStore only remove the very triple they hold, so look it up first.
+	if stored := findTriple(graph, triple); stored != nil {
+		graph.Remove(stored)
+	}
+}
+
+// Method Clear removes all named graphs and clears the default graph.
+func (dataset *Dataset) Clear() {
+	dataset.Mutex.Lock()
+	dataset.named = make(map[string]*Graph)
+	dataset.names = make(map[string]Term)
+	dataset.Mutex.Unlock()
+
+	dataset.Default.Clear()
+}
+
+// Method Num returns the total number of triples in all graphs of the dataset.
+func (dataset *Dataset) Num() (n int) {
+	n = dataset.Default.Num()
+
+	for _, name := range dataset.GraphNames() {
+		n += dataset.Graph(name).Num()
+	}
+
+	return n
+}
+
+// Method IterTriples returns a channel that will yield the triples of every graph in the dataset,
+// those of the default graph first. Triples from named graphs have their Graph field set. The
+// channel will be closed when iteration is completed.
+func (dataset *Dataset) IterTriples() (ch chan *Triple) {
+	return dataset.Filter(nil, nil, nil, nil)
+}
+
+// Method Filter returns a channel that will yield all matching triples of the dataset. A nil value
+// passed for the subject, predicate or object means that the check for this term is skipped. If
+// graphSearch is nil, all graphs (including the default graph) are searched; if it is DefaultGraph,
+// only the default graph is searched; otherwise only the named graph with that name is searched.
+// Triples from named graphs have their Graph field set.
+func (dataset *Dataset) Filter(subjSearch, predSearch, objSearch, graphSearch Term) (ch chan *Triple) {
+	ch = make(chan *Triple)
+
+	var names []Term
+	if graphSearch == nil {
+		names = append([]Term{nil}, dataset.GraphNames()...)
+	} else if graphSearch == DefaultGraph {
+		names = []Term{nil}
+	} else if dataset.HasGraph(graphSearch) {
+		names = []Term{graphSearch}
+	}
+
+	go func() {
+		defer close(ch)
+
+		for _, name := range names {
+			for triple := range dataset.Graph(name).Filter(subjSearch, predSearch, objSearch) {
+				if name != nil {
+					triple = NewQuad(triple.Subject, triple.Predicate, triple.Object, name)
+				}
+
+				ch <- triple
+			}
+		}
+	}()
+
+	return ch
+}
+
+// Method LoadFromChannel receives incoming triples and adds them to the dataset.
+func (dataset *Dataset) LoadFromChannel(ch chan *Triple) {
+	for triple := range ch {
+		dataset.Add(triple)
+	}
+}
+
+// Method Parse uses the specified Parser to parse RDF from an io.Reader. Triples with graph names
+// (such as those produced by ParseNQuads) are added to the corresponding named graphs.
+func (dataset *Dataset) Parse(parser Parser, r io.Reader) (err error) {
+	tripleChan := make(chan *Triple)
+	errChan := make(chan error)
+
+	go parser(r, tripleChan, errChan, dataset.Prefixes)
+
+	done := make(chan bool)
+	go func() {
+		dataset.LoadFromChannel(tripleChan)
+		close(done)
+	}()
+
+	for e := range errChan {
+		if err == nil {
+			err = e
+		}
+	}
+
+	<-done
+	return err
+}
+
+// Method ParseFile uses the specified Parser to parse RDF from a file.
+func (dataset *Dataset) ParseFile(parser Parser, filename string) (err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+	return dataset.Parse(parser, f)
+}
+
+// Method Serialize uses the specified Serializer to serialize the whole dataset to an io.Writer.
+// Only serializers that understand graph names (such as SerializeNQuads) will preserve them.
+func (dataset *Dataset) Serialize(serializer Serializer, w io.Writer) (err error) {
+	errChan := make(chan error)
+
+	go serializer(w, dataset.IterTriples(), errChan, dataset.Prefixes)
+
+	for e := range errChan {
+		if err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
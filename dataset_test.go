@@ -0,0 +1,184 @@
+/*
+   Copyright (c) 2012 Kier Davis
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+   associated documentation files (the "Software"), to deal in the Software without restriction,
+   including without limitation the rights to use, copy, modify, merge, publish, distribute,
+   sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all copies or substantial
+   portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+   NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+   NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+   OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+   CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var nquadsDocument = `<http://example.org/s> <http://example.org/p> <http://example.org/o> .
+<http://example.org/s> <http://example.org/p> "in g1" <http://example.org/g1> .
+_:b <http://example.org/p> "in g2"@en _:g2 .
+<http://example.org/s> <http://example.org/q> <http://example.org/o> <http://example.org/g1> .
+`
+
+func TestNQuadsRead(t *testing.T) {
+	r := NewNQuadsReader(strings.NewReader(nquadsDocument))
+
+	expected := []*Triple{
+		NewTriple(NewResource("http://example.org/s"), NewResource("http://example.org/p"), NewResource("http://example.org/o")),
+		NewQuad(NewResource("http://example.org/s"), NewResource("http://example.org/p"), NewLiteral("in g1"), NewResource("http://example.org/g1")),
+		NewQuad(NewBlankNode("b"), NewResource("http://example.org/p"), NewLiteralWithLanguage("in g2", "en"), NewBlankNode("g2")),
+		NewQuad(NewResource("http://example.org/s"), NewResource("http://example.org/q"), NewResource("http://example.org/o"), NewResource("http://example.org/g1")),
+	}
+
+	for _, want := range expected {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("Expected %s but got error %s", want.QuadString(), err)
+		}
+
+		if !got.EqualQuad(want) {
+			t.Errorf("Expected %s but got %s", want.QuadString(), got.QuadString())
+		}
+	}
+
+	_, err := NewNQuadsReader(strings.NewReader(`<http://example.org/s> <http://example.org/p> <http://example.org/o> "g" .`)).Read()
+	if err == nil {
+		t.Errorf("Expected an error for a literal graph name")
+	}
+
+	_, err = NewNTriplesReader(strings.NewReader(`<http://example.org/s> <http://example.org/p> <http://example.org/o> <http://example.org/g> .`)).Read()
+	if err == nil {
+		t.Errorf("Expected an error for a graph name in N-Triples")
+	}
+}
+
+func TestDatasetRoundTrip(t *testing.T) {
+	dataset := NewDataset(func() Store { return NewListStore() })
+
+	err := dataset.Parse(ParseNQuads, strings.NewReader(nquadsDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if dataset.Num() != 4 {
+		t.Errorf("Expected 4 triples but got %d", dataset.Num())
+	}
+
+	if n := dataset.Default.Num(); n != 1 {
+		t.Errorf("Expected 1 triple in the default graph but got %d", n)
+	}
+
+	if n := dataset.Graph(NewResource("http://example.org/g1")).Num(); n != 2 {
+		t.Errorf("Expected 2 triples in g1 but got %d", n)
+	}
+
+	if n := len(dataset.GraphNames()); n != 2 {
+		t.Errorf("Expected 2 named graphs but got %d", n)
+	}
+
+	n := 0
+	for triple := range dataset.Filter(nil, NewResource("http://example.org/p"), nil, NewResource("http://example.org/g1")) {
+		if !triple.Graph.Equal(NewResource("http://example.org/g1")) {
+			t.Errorf("Expected a triple in g1 but got %s", triple.QuadString())
+		}
+
+		n++
+	}
+
+	if n != 1 {
+		t.Errorf("Expected 1 matching triple but got %d", n)
+	}
+
+	n = 0
+	for triple := range dataset.Filter(nil, nil, nil, DefaultGraph) {
+		if triple.Graph != nil {
+			t.Errorf("Expected a triple in the default graph but got %s", triple.QuadString())
+		}
+
+		n++
+	}
+
+	if n != 1 {
+		t.Errorf("Expected 1 triple in the default graph but got %d", n)
+	}
+
+	var buf bytes.Buffer
+	err = dataset.Serialize(SerializeNQuads, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := strings.Split(strings.TrimSpace(nquadsDocument), "\n")
+	sort.Strings(got)
+	sort.Strings(want)
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestDatasetDefaultGraphAddRemove(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+
+	for _, newStore := range txStores {
+		dataset := NewDataset(newStore)
+
+		dataset.Add(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), DefaultGraph))
+		if n := dataset.Default.Num(); n != 1 {
+			t.Errorf("%T: expected 1 triple in the default graph but got %d", dataset.Default.Store, n)
+		}
+
+		dataset.Remove(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), DefaultGraph))
+		if n := dataset.Num(); n != 0 {
+			t.Errorf("%T: expected the triple to be removed but got %d triples", dataset.Default.Store, n)
+		}
+	}
+}
+
+func TestDatasetNamedGraphTriples(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+
+	for _, newStore := range txStores {
+		dataset := NewDataset(newStore)
+		graph := dataset.Graph(ex.Get("g"))
+
+		// Quads added to the dataset are the same triples as those added to the named graph.
+		dataset.AddQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g"))
+		graph.AddTriple(ex.Get("s"), ex.Get("p"), ex.Get("o"))
+		graph.AddTriple(ex.Get("s"), ex.Get("p"), ex.Get("o2"))
+
+		if ls, ok := graph.Store.(*ListStore); ok {
+			ls.Remove(firstTriple(ls.FilterSeq(nil, nil, ex.Get("o")))) // ListStore does not deduplicate
+		}
+
+		if n := graph.Num(); n != 2 {
+			t.Errorf("%T: expected 2 triples in the named graph but got %d", graph.Store, n)
+		}
+
+		for triple := range dataset.Filter(nil, nil, nil, ex.Get("g")) {
+			if triple.Graph == nil || !triple.Graph.Equal(ex.Get("g")) {
+				t.Errorf("%T: expected a triple in g but got %s", graph.Store, triple.QuadString())
+			}
+		}
+
+		graph.Remove(firstTriple(graph.FilterSeq(ex.Get("s"), ex.Get("p"), ex.Get("o"))))
+		dataset.Remove(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o2"), ex.Get("g")))
+
+		if n := dataset.Num(); n != 0 {
+			t.Errorf("%T: expected the named graph to be empty but got %d triples", graph.Store, n)
+		}
+	}
+}
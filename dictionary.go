@@ -0,0 +1,186 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"sync"
+)
+
+// A TermID identifies a term within a Dictionary. IDs are allocated from 1 upwards; 0 is never a
+// valid ID, and is used as a wildcard by DictionaryStore.FilterIDs.
+type TermID uint32
+
+// An IDTriple is a triple whose terms are given as IDs from a Dictionary.
+type IDTriple struct {
+	Subject   TermID
+	Predicate TermID
+	Object    TermID
+	Graph     TermID // 0 for a triple in the default graph
+}
+
+// A dictKey identifies a term by value, without building its string form where possible.
+type dictKey struct {
+	kind     byte
+	value    string
+	language string
+	datatype string
+}
+
+// Function newDictKey returns the key of a term. Terms that are equal have the same key.
+func newDictKey(term Term) (key dictKey) {
+	switch t := term.(type) {
+	case *Resource:
+		return dictKey{kind: 'r', value: t.URI}
+
+	case *BlankNode:
+		return dictKey{kind: 'b', value: t.ID}
+
+	case *Literal:
+		key = dictKey{kind: 'l', value: t.Value, language: t.Language}
+
+		if dt, ok := t.Datatype.(*Resource); ok {
+			key.datatype = dt.URI
+		} else if t.Datatype != nil {
+			key.datatype = t.Datatype.String()
+		}
+
+		return key
+	}
+
+	return dictKey{kind: 'q', value: term.String()}
+}
+
+// A Dictionary interns terms, assigning each distinct term a TermID and keeping a single copy of
+// it. Terms are never removed, so an ID stays valid for the life of the dictionary. It is safe for
+// concurrent use.
+type Dictionary struct {
+	mutex sync.RWMutex
+	ids   map[dictKey]TermID
+	terms []Term // Terms by ID - 1
+}
+
+// Function NewDictionary creates and returns a new empty Dictionary.
+func NewDictionary() (dict *Dictionary) {
+	return &Dictionary{
+		ids: make(map[dictKey]TermID),
+	}
+}
+
+// Method Intern returns the ID of the given term, adding it to the dictionary if it is not already
+// present.
+func (dict *Dictionary) Intern(term Term) (id TermID) {
+	key := newDictKey(term)
+
+	dict.mutex.RLock()
+	id, ok := dict.ids[key]
+	dict.mutex.RUnlock()
+
+	if ok {
+		return id
+	}
+
+	dict.mutex.Lock()
+	defer dict.mutex.Unlock()
+
+	id, ok = dict.ids[key]
+	if !ok {
+		dict.terms = append(dict.terms, term)
+		id = TermID(len(dict.terms))
+		dict.ids[key] = id
+	}
+
+	return id
+}
+
+// Method Lookup returns the ID of the given term, and whether it is present in the dictionary.
+func (dict *Dictionary) Lookup(term Term) (id TermID, ok bool) {
+	dict.mutex.RLock()
+	defer dict.mutex.RUnlock()
+
+	id, ok = dict.ids[newDictKey(term)]
+	return id, ok
+}
+
+// Method Term returns the term with the given ID, or nil if there is none.
+func (dict *Dictionary) Term(id TermID) (term Term) {
+	dict.mutex.RLock()
+	defer dict.mutex.RUnlock()
+
+	if id == 0 || int(id) > len(dict.terms) {
+		return nil
+	}
+
+	return dict.terms[id-1]
+}
+
+// Method Len returns the number of terms in the dictionary.
+func (dict *Dictionary) Len() (n int) {
+	dict.mutex.RLock()
+	defer dict.mutex.RUnlock()
+
+	return len(dict.terms)
+}
+
+// Method InternTriple interns the terms of the given triple, including its graph name if it has
+// one, and returns their IDs.
+func (dict *Dictionary) InternTriple(triple *Triple) (ids IDTriple) {
+	ids = IDTriple{
+		Subject:   dict.Intern(triple.Subject),
+		Predicate: dict.Intern(triple.Predicate),
+		Object:    dict.Intern(triple.Object),
+	}
+
+	if triple.Graph != nil {
+		ids.Graph = dict.Intern(triple.Graph)
+	}
+
+	return ids
+}
+
+// Method LookupTriple returns the IDs of the terms of the given triple, including its graph name if
+// it has one, and whether they are all present in the dictionary.
+func (dict *Dictionary) LookupTriple(triple *Triple) (ids IDTriple, ok bool) {
+	var ok1, ok2, ok3 bool
+	ok4 := true
+
+	ids.Subject, ok1 = dict.Lookup(triple.Subject)
+	ids.Predicate, ok2 = dict.Lookup(triple.Predicate)
+	ids.Object, ok3 = dict.Lookup(triple.Object)
+
+	if triple.Graph != nil {
+		ids.Graph, ok4 = dict.Lookup(triple.Graph)
+	}
+
+	return ids, ok1 && ok2 && ok3 && ok4
+}
+
+// Method Triple returns the triple made up of the terms with the given IDs, in the named graph if
+// the graph ID is not 0.
+func (dict *Dictionary) Triple(ids IDTriple) (triple *Triple) {
+	dict.mutex.RLock()
+	defer dict.mutex.RUnlock()
+
+	var graph Term
+	if ids.Graph != 0 {
+		graph = dict.terms[ids.Graph-1]
+	}
+
+	return NewQuad(dict.terms[ids.Subject-1], dict.terms[ids.Predicate-1], dict.terms[ids.Object-1], graph)
+}
@@ -0,0 +1,334 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// An idKey is an IDTriple with its terms in the order used by one of the indexes of a
+// DictionaryStore. The graph ID always comes last, so the copies of a triple in different graphs
+// are next to each other.
+type idKey [4]TermID
+
+// Function compareIDKeys compares the first n terms of two keys.
+func compareIDKeys(a idKey, b idKey, n int) (result int) {
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return cmp.Compare(a[i], b[i])
+		}
+	}
+
+	return 0
+}
+
+// Function compareFullIDKeys compares two keys.
+func compareFullIDKeys(a idKey, b idKey) (result int) {
+	return compareIDKeys(a, b, 4)
+}
+
+// An idOrder describes the order of the terms in the keys of an index: order[i] is the position in
+// the triple (0 for subject, 1 for predicate, 2 for object) of the key's ith term. The graph is
+// not reordered.
+type idOrder [3]int
+
+var (
+	orderSPO = idOrder{0, 1, 2}
+	orderPOS = idOrder{1, 2, 0}
+	orderOSP = idOrder{2, 0, 1}
+)
+
+// Method key returns the key of the given triple in this order.
+func (order idOrder) key(ids IDTriple) (key idKey) {
+	spo := [3]TermID{ids.Subject, ids.Predicate, ids.Object}
+	return idKey{spo[order[0]], spo[order[1]], spo[order[2]], ids.Graph}
+}
+
+// Method triple returns the triple given by a key in this order.
+func (order idOrder) triple(key idKey) (ids IDTriple) {
+	var spo [3]TermID
+	for i, pos := range order {
+		spo[pos] = key[i]
+	}
+
+	return IDTriple{spo[0], spo[1], spo[2], key[3]}
+}
+
+// A DictionaryStore is a memory-efficient Store. Terms are interned in a Dictionary, so each
+// distinct term is held only once, and triples are kept as sorted arrays of term IDs in three
+// orders (subject-predicate-object, predicate-object-subject and object-subject-predicate), so
+// that any filter is answered by a binary search. Like a set, it holds each triple at most once per
+// graph; triples that differ only in their graph names (see NewQuad) are kept apart.
+//
+// Added and removed triples are buffered and merged into the arrays in bulk before the next read,
+// which makes loading or deleting many triples cheap. The arrays are never modified in place, so an
+// iteration in progress is not disturbed by changes to the store.
+type DictionaryStore struct {
+	dict    *Dictionary
+	spo     []idKey
+	pos     []idKey
+	osp     []idKey
+	pending []idChange // Changes made since the last flush, in the order they were made
+}
+
+// An idChange is a buffered addition or removal of a triple, given in subject-predicate-object
+// order.
+type idChange struct {
+	key    idKey
+	remove bool
+}
+
+// Function NewDictionaryStore creates and returns a new empty DictionaryStore with its own
+// Dictionary.
+func NewDictionaryStore() (store *DictionaryStore) {
+	return NewDictionaryStoreWithDictionary(NewDictionary())
+}
+
+// Function NewDictionaryStoreWithDictionary creates and returns a new empty DictionaryStore that
+// uses the given Dictionary, which may be shared with other stores.
+func NewDictionaryStoreWithDictionary(dict *Dictionary) (store *DictionaryStore) {
+	return &DictionaryStore{
+		dict: dict,
+	}
+}
+
+// Method Dictionary returns the dictionary used to intern the store's terms.
+func (store *DictionaryStore) Dictionary() (dict *Dictionary) {
+	return store.dict
+}
+
+// Method flush merges the pending changes into the indexes. When a triple was changed more than
+// once, the last change wins.
+func (store *DictionaryStore) flush() {
+	if len(store.pending) == 0 {
+		return
+	}
+
+	changes := store.pending
+	store.pending = nil
+
+	slices.SortStableFunc(changes, func(a idChange, b idChange) int {
+		return compareFullIDKeys(a.key, b.key)
+	})
+
+	var added, removed []IDTriple
+
+	for i, change := range changes {
+		if i+1 < len(changes) && changes[i+1].key == change.key {
+			continue // Superseded by a later change
+		}
+
+		_, found := slices.BinarySearchFunc(store.spo, change.key, compareFullIDKeys)
+
+		if change.remove && found {
+			removed = append(removed, orderSPO.triple(change.key))
+		} else if !change.remove && !found {
+			added = append(added, orderSPO.triple(change.key))
+		}
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	store.spo = mergeIDKeys(store.spo, added, removed, orderSPO)
+	store.pos = mergeIDKeys(store.pos, added, removed, orderPOS)
+	store.osp = mergeIDKeys(store.osp, added, removed, orderOSP)
+}
+
+// Function sortedIDKeys returns the keys in the given order of a list of triples, sorted.
+func sortedIDKeys(triples []IDTriple, order idOrder) (keys []idKey) {
+	keys = make([]idKey, len(triples))
+	for i, ids := range triples {
+		keys[i] = order.key(ids)
+	}
+
+	slices.SortFunc(keys, compareFullIDKeys)
+	return keys
+}
+
+// Function mergeIDKeys returns a new sorted array containing the keys of index, plus the keys in
+// the given order of the triples in added (none of which may already be in index), minus those of
+// the triples in removed (all of which must be in index).
+func mergeIDKeys(index []idKey, added []IDTriple, removed []IDTriple, order idOrder) (merged []idKey) {
+	addKeys := sortedIDKeys(added, order)
+	removeKeys := sortedIDKeys(removed, order)
+
+	merged = make([]idKey, 0, len(index)+len(addKeys)-len(removeKeys))
+
+	for len(index) > 0 {
+		switch {
+		case len(removeKeys) > 0 && index[0] == removeKeys[0]:
+			index = index[1:]
+			removeKeys = removeKeys[1:]
+
+		case len(addKeys) > 0 && compareFullIDKeys(addKeys[0], index[0]) < 0:
+			merged = append(merged, addKeys[0])
+			addKeys = addKeys[1:]
+
+		default:
+			merged = append(merged, index[0])
+			index = index[1:]
+		}
+	}
+
+	return append(merged, addKeys...)
+}
+
+// Method Add adds the given triple to the store, unless it is already present.
+func (store *DictionaryStore) Add(triple *Triple) {
+	store.AddIDs(store.dict.InternTriple(triple))
+}
+
+// Method AddIDs adds the triple made up of the given IDs, which must come from the store's
+// dictionary. A graph ID of 0 adds it to the default graph.
+func (store *DictionaryStore) AddIDs(ids IDTriple) {
+	store.pending = append(store.pending, idChange{key: orderSPO.key(ids)})
+}
+
+// Method Remove removes the given triple from the store.
+func (store *DictionaryStore) Remove(triple *Triple) {
+	ids, ok := store.dict.LookupTriple(triple)
+	if ok {
+		store.RemoveIDs(ids)
+	}
+}
+
+// Method RemoveIDs removes the triple made up of the given IDs.
+func (store *DictionaryStore) RemoveIDs(ids IDTriple) {
+	store.pending = append(store.pending, idChange{key: orderSPO.key(ids), remove: true})
+}
+
+// Method Clear removes all triples from the store. The dictionary is kept, so IDs obtained from it
+// remain valid.
+func (store *DictionaryStore) Clear() {
+	store.spo, store.pos, store.osp, store.pending = nil, nil, nil, nil
+}
+
+// Method Begin starts a transaction on the store. Since the store's arrays are never modified in
+// place, taking the snapshot costs nothing beyond merging in any pending changes.
+func (store *DictionaryStore) Begin() (tx Tx, err error) {
+	store.flush()
+
+	snapshot := &DictionaryStore{
+		dict: store.dict,
+		spo:  store.spo,
+		pos:  store.pos,
+		osp:  store.osp,
+	}
+
+	return NewSnapshotTx(store, snapshot, SnapshotTxOptions{}), nil
+}
+
+// Method Num returns the number of triples in the store.
+func (store *DictionaryStore) Num() (n int) {
+	store.flush()
+	return len(store.spo)
+}
+
+// Method IterTriples returns a channel that will yield the triples of the store.
+func (store *DictionaryStore) IterTriples() (ch chan *Triple) {
+	return seqChannel(store.All())
+}
+
+// Method Filter returns a channel that will yield the matching triples of the store; see the
+// documentation of Store for information on the arguments.
+func (store *DictionaryStore) Filter(subjSearch, predSearch, objSearch Term) (ch chan *Triple) {
+	return seqChannel(store.FilterSeq(subjSearch, predSearch, objSearch))
+}
+
+// Method All returns a sequence of the triples of the store.
+func (store *DictionaryStore) All() (seq iter.Seq[*Triple]) {
+	return store.FilterSeq(nil, nil, nil)
+}
+
+// Method FilterSeq returns a sequence of the matching triples of the store; see the documentation
+// of Store for information on the arguments.
+func (store *DictionaryStore) FilterSeq(subjSearch, predSearch, objSearch Term) (seq iter.Seq[*Triple]) {
+	subject, ok1 := store.lookupSearch(subjSearch)
+	predicate, ok2 := store.lookupSearch(predSearch)
+	object, ok3 := store.lookupSearch(objSearch)
+
+	if !ok1 || !ok2 || !ok3 { // A term that was never interned matches nothing
+		return func(yield func(*Triple) bool) {}
+	}
+
+	idSeq := store.FilterIDs(subject, predicate, object)
+
+	return func(yield func(*Triple) bool) {
+		for ids := range idSeq {
+			if !yield(store.dict.Triple(ids)) {
+				return
+			}
+		}
+	}
+}
+
+// Method lookupSearch returns the ID of a search term, or 0 for a nil term, and whether the term
+// is in the dictionary.
+func (store *DictionaryStore) lookupSearch(term Term) (id TermID, ok bool) {
+	if term == nil {
+		return 0, true
+	}
+
+	return store.dict.Lookup(term)
+}
+
+// Method FilterIDs returns a sequence of the triples matching the given IDs, where an ID of 0
+// matches any term. This allows joins and other processing to work on IDs, only looking terms up
+// in the dictionary when they are needed. Triples in every graph are matched; to restrict the
+// result to one graph, skip those whose Graph field is not that graph's ID (0 for the default
+// graph).
+func (store *DictionaryStore) FilterIDs(subject, predicate, object TermID) (seq iter.Seq[IDTriple]) {
+	store.flush()
+
+	ids := IDTriple{Subject: subject, Predicate: predicate, Object: object}
+
+	index, order := store.spo, orderSPO
+	switch {
+	case subject != 0 && predicate == 0 && object != 0:
+		index, order = store.osp, orderOSP
+	case subject == 0 && predicate != 0:
+		index, order = store.pos, orderPOS
+	case subject == 0 && object != 0:
+		index, order = store.osp, orderOSP
+	}
+
+	// The bound terms come first in the chosen order, so the matches are a contiguous range.
+	prefix := order.key(ids)
+	n := 0
+	for n < 3 && prefix[n] != 0 {
+		n++
+	}
+
+	lo, _ := slices.BinarySearchFunc(index, prefix, func(a idKey, b idKey) int {
+		return compareIDKeys(a, b, n)
+	})
+
+	return func(yield func(IDTriple) bool) {
+		for _, key := range index[lo:] {
+			if compareIDKeys(key, prefix, n) != 0 || !yield(order.triple(key)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDictionary(t *testing.T) {
+	dict := NewDictionary()
+	ex := NewNamespace("http://example.org/")
+
+	terms := []Term{
+		ex.Get("a"),
+		NewBlankNode("a"),
+		NewLiteral("http://example.org/a"),
+		NewLiteralWithLanguage("a", "en"),
+		NewLiteralWithDatatype("a", XSD.Get("string")),
+		NewQuotedTriple(ex.Get("a"), ex.Get("b"), NewLiteral("c")),
+	}
+
+	for i, term := range terms {
+		if id := dict.Intern(term); id != TermID(i+1) {
+			t.Errorf("Expected %s to get ID %d but got %d", term, i+1, id)
+		}
+	}
+
+	// Interning an equal term gives the same ID and keeps the original copy.
+	if id := dict.Intern(NewResource("http://example.org/a")); id != 1 || dict.Term(id) != terms[0] {
+		t.Errorf("Expected equal resource to map to the interned term, got ID %d", id)
+	}
+
+	if _, ok := dict.Lookup(ex.Get("z")); ok || dict.Len() != len(terms) {
+		t.Errorf("Lookup should not add terms")
+	}
+
+	if dict.Term(0) != nil || dict.Term(TermID(len(terms)+1)) != nil {
+		t.Errorf("Expected nil for unknown IDs")
+	}
+}
+
+func TestDictionaryStorePatterns(t *testing.T) {
+	testFilterPatterns(t, NewDictionaryStore())
+}
+
+func TestDictionaryStore(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+	store := NewDictionaryStore()
+
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("1")))
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("1")))
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("2")))
+
+	if store.Num() != 2 {
+		t.Errorf("Expected 2 triples but got %d", store.Num())
+	}
+
+	// Iteration sees a snapshot, so removing triples during it is safe.
+	n := 0
+	for triple := range store.FilterSeq(ex.Get("s"), nil, nil) {
+		store.Remove(triple)
+		store.Add(NewTriple(ex.Get("t"), ex.Get("p"), triple.Object))
+		n++
+	}
+
+	if n != 2 || store.Num() != 2 {
+		t.Errorf("Expected to visit 2 triples and keep 2, got %d and %d", n, store.Num())
+	}
+
+	dict := store.Dictionary()
+	p, _ := dict.Lookup(ex.Get("p"))
+	t1, _ := dict.Lookup(ex.Get("t"))
+
+	for ids := range store.FilterIDs(0, p, 0) {
+		if ids.Subject != t1 {
+			t.Errorf("Unexpected subject %s", dict.Term(ids.Subject))
+		}
+	}
+
+	store.Clear()
+	if store.Num() != 0 || dict.Len() == 0 {
+		t.Errorf("Expected Clear to empty the store but keep the dictionary")
+	}
+
+	// Stores can share a dictionary, and so share IDs.
+	other := NewDictionaryStoreWithDictionary(dict)
+	other.AddIDs(IDTriple{Subject: t1, Predicate: p, Object: t1})
+
+	for triple := range other.All() {
+		if !triple.Equal(NewTriple(ex.Get("t"), ex.Get("p"), ex.Get("t"))) {
+			t.Errorf("Unexpected triple %s", triple)
+		}
+	}
+}
+
+func TestDictionaryStoreQuads(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+	store := NewDictionaryStore()
+
+	store.Add(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g1")))
+	store.Add(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g2")))
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), ex.Get("o")))
+
+	if store.Num() != 3 {
+		t.Fatalf("Expected 3 quads but got %d", store.Num())
+	}
+
+	dict := store.Dictionary()
+	g1, _ := dict.Lookup(ex.Get("g1"))
+
+	var graphs []TermID
+	for ids := range store.FilterIDs(0, 0, 0) {
+		graphs = append(graphs, ids.Graph)
+	}
+
+	if len(graphs) != 3 || graphs[0] != 0 || graphs[1] != g1 {
+		t.Errorf("Expected the triple in the default graph, g1 and g2 but got graph IDs %v", graphs)
+	}
+
+	store.Remove(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g1")))
+	store.Remove(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g3")))
+
+	n := 0
+	for triple := range store.FilterSeq(ex.Get("s"), ex.Get("p"), ex.Get("o")) {
+		if triple.Graph != nil && !triple.Graph.Equal(ex.Get("g2")) {
+			t.Errorf("Unexpected triple %s left in the store", triple.QuadString())
+		}
+
+		n++
+	}
+
+	if n != 2 {
+		t.Errorf("Expected 2 quads left but got %d", n)
+	}
+}
+
+func TestDictionaryStoreBufferedChanges(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+	store := NewDictionaryStore()
+	x := NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("x"))
+	y := NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("y"))
+
+	store.Add(y)
+	store.Num()
+
+	// The last change to a triple wins.
+	store.Add(x)
+	store.Remove(x)
+	store.Add(x)
+	store.Remove(y)
+	store.Add(y)
+	store.Remove(y)
+
+	for triple := range store.All() {
+		if !triple.Equal(x) {
+			t.Errorf("Unexpected triple %s left in the store", triple)
+		}
+	}
+
+	if store.Num() != 1 {
+		t.Errorf("Expected only %s to be left but got %d triples", x, store.Num())
+	}
+
+	// Removals are merged in one pass, so emptying a large store is linear.
+	for i := 0; i < 1000; i++ {
+		store.Add(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral(fmt.Sprint(i))))
+	}
+
+	for triple := range store.All() {
+		store.Remove(triple)
+	}
+
+	if store.Num() != 0 || len(store.pos) != 0 || len(store.osp) != 0 {
+		t.Errorf("Expected an empty store but got %d triples", store.Num())
+	}
+}
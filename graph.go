@@ -20,8 +20,10 @@
 package argo
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"os"
 	"sync"
@@ -37,6 +39,9 @@ type Graph struct {
 
 	// The prefix map.
 	Prefixes map[string]string
+
+	// The allocator used for blank nodes created by the graph. If nil, DefaultAllocator is used.
+	Allocator BlankNodeAllocator
 }
 
 // Function NewGraph creates and returns a new graph.
@@ -110,7 +115,7 @@ func (graph *Graph) EncodeList(subject Term) (ch chan Term) {
 			graph.AddTriple(subject, RDF.Get("first"), term)
 
 			for term = range ch {
-				next := NewAnonNode()
+				next := graph.NewBlankNode()
 				graph.AddTriple(subject, RDF.Get("rest"), next)
 				subject = next
 
@@ -125,6 +130,15 @@ func (graph *Graph) EncodeList(subject Term) (ch chan Term) {
 	return ch
 }
 
+// Method NewBlankNode returns a new blank node from the graph's allocator.
+func (graph *Graph) NewBlankNode() (term Term) {
+	if graph.Allocator != nil {
+		return graph.Allocator.NewBlankNode()
+	}
+
+	return DefaultAllocator.NewBlankNode()
+}
+
 // Method Remove removes the given triple from the graph, if it exists.
 func (graph *Graph) Remove(triple *Triple) {
 	graph.Mutex.Lock()
@@ -173,6 +187,44 @@ func (graph *Graph) Filter(subjSearch, predSearch, objSearch Term) (ch chan *Tri
 	return graph.Store.Filter(subjSearch, predSearch, objSearch)
 }
 
+// Method All returns a sequence of the triples of the graph. Unlike IterTriples, no goroutine is
+// needed if the store is a SeqStore, and breaking out of the loop early does not leak one. As with
+// IterTriples, the mutex is only held while the iteration is set up, so the graph must not be
+// modified by other goroutines while the loop runs.
+func (graph *Graph) All() (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
+		graph.Mutex.Lock()
+		seq := storeAllSeq(graph.Store)
+		graph.Mutex.Unlock()
+
+		seq(yield)
+	}
+}
+
+// Method FilterSeq returns a sequence of the matching triples of the graph; see Filter for the
+// meaning of the arguments. Unlike Filter, no goroutine is needed if the store is a SeqStore, and
+// breaking out of the loop early does not leak one. As with Filter, the mutex is only held while
+// the iteration is set up, so the graph must not be modified by other goroutines while the loop
+// runs.
+func (graph *Graph) FilterSeq(subjSearch, predSearch, objSearch Term) (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
+		graph.Mutex.Lock()
+		seq := storeFilterSeq(graph.Store, subjSearch, predSearch, objSearch)
+		graph.Mutex.Unlock()
+
+		seq(yield)
+	}
+}
+
+// Method FilterContext is like Filter, but returns a TripleIterator, which stops when ctx is
+// cancelled and can be closed without leaking the goroutine producing the triples.
+func (graph *Graph) FilterContext(ctx context.Context, subjSearch, predSearch, objSearch Term) (it TripleIterator) {
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	return AdaptStore(graph.Store).Filter(ctx, subjSearch, predSearch, objSearch)
+}
+
 // Method FilterSubset adds the triples returned by Filter(subjSearch, predSearch, objSearch) to the
 // specified graph.
 func (graph *Graph) FilterSubset(subGraph *Graph, subjSearch, predSearch, objSearch Term) {
@@ -183,13 +235,11 @@ func (graph *Graph) FilterSubset(subGraph *Graph, subjSearch, predSearch, objSea
 
 // Method HasSubject returns where the specified term is present as a subject in the graph.
 func (graph *Graph) HasSubject(subject Term) (result bool) {
-	ch := graph.Filter(subject, nil, nil)
-	_, result = <-ch
-
-	for _ = range ch {
+	for _ = range graph.FilterSeq(subject, nil, nil) {
+		return true
 	}
 
-	return result
+	return false
 }
 
 // Method GetAll returns all objects with the given subject and predicate.
@@ -208,10 +258,12 @@ func (graph *Graph) GetAll(subject Term, predicate Term) (objects []Term) {
 // Method Get returns the first object with the given subject and predicate, or nil if it was not
 // found.
 func (graph *Graph) Get(subject Term, predicate Term) (object Term) {
-	for triple := range graph.IterTriples() {
-		if triple.Subject.Equal(subject) && triple.Predicate.Equal(predicate) {
-			return triple.Object
-		}
+	if subject == nil || predicate == nil {
+		return nil
+	}
+
+	for triple := range graph.FilterSeq(subject, predicate, nil) {
+		return triple.Object
 	}
 
 	return nil
@@ -229,63 +281,76 @@ func (graph *Graph) MustGet(subject Term, predicate Term) (object Term) {
 }
 
 // Method IterContainer returns a channel that yields successive items of an RDF container (Seq, Bag
-// or Alt).
+// or Alt), starting from rdf:_1.
 func (graph *Graph) IterContainer(root Term) (ch chan Term) {
-	ch = make(chan Term)
-
-	go func() {
-		i := 0
+	return termChannel(graph.Container(root))
+}
 
-		for {
-			item := graph.Get(root, RDF.Get(fmt.Sprintf("_%d", i)))
-			if item == nil {
-				close(ch)
+// Method Container returns a sequence of the items of an RDF container (Seq, Bag or Alt), each
+// paired with its position (counting from 0). Membership properties are numbered from rdf:_1, as
+// RDF Schema specifies, so rdf:_1 is at position 0; an rdf:_0 property is not a member and is
+// ignored. It stops at the first missing membership property.
+func (graph *Graph) Container(root Term) (seq iter.Seq2[int, Term]) {
+	return func(yield func(int, Term) bool) {
+		for i := 0; ; i++ {
+			item := graph.Get(root, RDF.Get(fmt.Sprintf("_%d", i+1)))
+			if item == nil || !yield(i, item) {
 				return
 			}
-
-			ch <- item
-			i++
 		}
-	}()
-
-	return ch
+	}
 }
 
 // Method IterList returns a channel that yields successive items of an RDF List.
 func (graph *Graph) IterList(root Term) (ch chan Term) {
-	ch = make(chan Term)
+	return termChannel(graph.List(root))
+}
 
-	go func() {
-		for {
-			ch <- graph.Get(root, First)
-			root = graph.Get(root, Rest)
+// Method List returns a sequence of the items of an RDF List, each paired with its position
+// (counting from 0). An empty list (rdf:nil) yields nothing.
+func (graph *Graph) List(root Term) (seq iter.Seq2[int, Term]) {
+	return func(yield func(int, Term) bool) {
+		node := root
 
-			if root == Nil {
-				close(ch)
+		for i := 0; node != nil && !node.Equal(Nil); i++ {
+			if !yield(i, graph.Get(node, First)) {
 				return
 			}
+
+			node = graph.Get(node, Rest)
+		}
+	}
+}
+
+// Function termChannel returns a channel that yields the terms of seq, and is closed after the
+// last one.
+func termChannel(seq iter.Seq2[int, Term]) (ch chan Term) {
+	ch = make(chan Term)
+
+	go func() {
+		defer close(ch)
+
+		for _, term := range seq {
+			ch <- term
 		}
 	}()
 
 	return ch
 }
 
-// Method LoadFromChannel receives incoming triples and adds them to the graph.
+// Method LoadFromChannel receives incoming triples and adds them to the graph. Blank node labels
+// are kept as they are; see MergeFromChannel.
 func (graph *Graph) LoadFromChannel(ch chan *Triple) {
 	for triple := range ch {
 		graph.Add(triple)
 	}
 }
 
-// Method Parse uses the specified Parser to parse RDF from an io.Reader.
+// Method Parse uses the specified Parser to parse RDF from an io.Reader. Blank node labels are kept
+// as they are, so parsing several documents into the same graph may fuse their blank nodes; use
+// ParseInScope to keep them apart.
 func (graph *Graph) Parse(parser Parser, r io.Reader) (err error) {
-	tripleChan := make(chan *Triple)
-	errChan := make(chan error)
-
-	go parser(r, tripleChan, errChan, graph.Prefixes)
-	graph.LoadFromChannel(tripleChan)
-
-	return <-errChan
+	return graph.ParseInScope(parser, r, nil)
 }
 
 // Method ParseFile uses the specified Parser to parse RDF from a file.
@@ -338,7 +403,13 @@ func (graph *Graph) ParseHTTP(parser Parser, url string, acceptMIMEType string)
 func (graph *Graph) Serialize(serializer Serializer, w io.Writer) (err error) {
 	errChan := make(chan error)
 
-	serializer(w, graph.IterTriples(), errChan, graph.Prefixes)
+	go serializer(w, graph.IterTriples(), errChan, graph.Prefixes)
+
+	for e := range errChan {
+		if err == nil {
+			err = e
+		}
+	}
 
-	return <-errChan
+	return err
 }
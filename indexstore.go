@@ -19,240 +19,410 @@
 
 package argo
 
-import ()
+import (
+	"iter"
+)
+
+// A graphSet is the set of keys of the graphs a triple belongs to. The default graph has the key
+// "", which no term produces.
+type graphSet map[string]struct{}
+
+// A permutationIndex maps the keys of the terms in one position of a triple to the keys of the
+// terms in a second position, those to the keys in the remaining position, and those to the keys
+// of the graphs holding the triple.
+type permutationIndex map[string]map[string]map[string]graphSet
+
+// Method add records the key quad (a, b, c, g), returning false if it was already present.
+func (idx permutationIndex) add(a, b, c, g string) (added bool) {
+	second, ok := idx[a]
+	if !ok {
+		second = make(map[string]map[string]graphSet)
+		idx[a] = second
+	}
+
+	third, ok := second[b]
+	if !ok {
+		third = make(map[string]graphSet)
+		second[b] = third
+	}
+
+	graphs, ok := third[c]
+	if !ok {
+		graphs = make(graphSet)
+		third[c] = graphs
+	}
+
+	if _, ok := graphs[g]; ok {
+		return false
+	}
+
+	graphs[g] = struct{}{}
+	return true
+}
+
+// Method remove deletes the key quad (a, b, c, g), pruning any maps left empty, and returns false
+// if it was not present.
+func (idx permutationIndex) remove(a, b, c, g string) (removed bool) {
+	graphs, ok := idx[a][b][c]
+	if !ok {
+		return false
+	}
 
-type toplevelIndex map[string]subjectIndex
-type subjectIndex map[string][]Term
+	if _, ok := graphs[g]; !ok {
+		return false
+	}
+
+	delete(graphs, g)
+
+	if len(graphs) == 0 {
+		delete(idx[a][b], c)
+
+		if len(idx[a][b]) == 0 {
+			delete(idx[a], b)
+
+			if len(idx[a]) == 0 {
+				delete(idx, a)
+			}
+		}
+	}
 
-// An IndexStore stores triples as a hierarchal mapping structure, to speed up searching.
+	return true
+}
+
+// Method copy returns a copy of the index that can be modified independently.
+func (idx permutationIndex) copy() (result permutationIndex) {
+	result = make(permutationIndex, len(idx))
+
+	for a, second := range idx {
+		secondCopy := make(map[string]map[string]graphSet, len(second))
+
+		for b, third := range second {
+			thirdCopy := make(map[string]graphSet, len(third))
+
+			for c, graphs := range third {
+				graphsCopy := make(graphSet, len(graphs))
+
+				for g := range graphs {
+					graphsCopy[g] = struct{}{}
+				}
+
+				thirdCopy[c] = graphsCopy
+			}
+
+			secondCopy[b] = thirdCopy
+		}
+
+		result[a] = secondCopy
+	}
+
+	return result
+}
+
+// An indexedTerm is an entry in the term table of an IndexStore.
+type indexedTerm struct {
+	term Term
+	refs int // Number of positions in stored triples that use the term
+}
+
+// An IndexStore stores triples in three permutation indexes (subject-predicate-object,
+// predicate-object-subject and object-subject-predicate), so that a filter with any combination
+// of bound and unbound terms is answered from an index. Like a set, it holds each triple at most
+// once per graph; triples that differ only in their graph names (see NewQuad) are kept apart.
 type IndexStore struct {
-	index toplevelIndex
+	// If set, literals are compared by value (see ValueEqual) when adding, filtering and removing
+	// triples. It should be set before any triples are added.
+	ValueEquality bool
+
+	terms map[string]*indexedTerm // Terms by key
+	spo   permutationIndex
+	pos   permutationIndex
+	osp   permutationIndex
+	num   int
+
+	shared bool // Whether the indexes are shared with a snapshot
 }
 
 // Function NewIndexStore creates and returns a new Indexstore.
 func NewIndexStore() (store *IndexStore) {
-	return &IndexStore{
-		index: make(toplevelIndex),
-	}
+	store = &IndexStore{}
+	store.Clear()
+	return store
 }
 
-// Method encodeKey converts a term object into a string.
-func (store *IndexStore) encodeKey(term Term) (uri string) {
-	res, isRes := term.(*Resource)
-	if isRes {
-		return res.URI
+// Method key returns the string used to index a term. Terms that are equal (or equal by value, if
+// ValueEquality is set) have the same key.
+func (store *IndexStore) key(term Term) (key string) {
+	if store.ValueEquality {
+		term = CanonicalTerm(term)
 	}
 
-	return "_:" + term.(*BlankNode).ID
+	key = term.String()
+
+	// String omits the datatype of a literal that also has a language, but Equal does not.
+	if lit, ok := term.(*Literal); ok && lit.Language != "" && lit.Datatype != nil {
+		key += "^^" + lit.Datatype.String()
+	}
+
+	return key
 }
 
-// Method decodeKey converts a string as returned by encodeKey back into the term it represents.
-func (store *IndexStore) decodeKey(uri string) (term Term) {
-	if len(uri) >= 2 && uri[0] == '_' && uri[1] == ':' {
-		return NewBlankNode(uri[2:])
+// Method graphKey returns the key used to index a graph name; the default graph (nil) has the key "".
+func (store *IndexStore) graphKey(graph Term) (key string) {
+	if graph == nil {
+		return ""
 	}
 
-	return NewResource(uri)
+	return store.key(graph)
 }
 
-// Method lookupSubject takes a subject term and returns the subjectIndex (a map of predicates to
-// object lists) associated with it.
-func (store *IndexStore) lookupSubject(subject Term) (subjIdx subjectIndex) {
-	key := store.encodeKey(subject)
-	subjIdx, ok := store.index[key]
+// Method ref records a use of term and returns its key.
+func (store *IndexStore) ref(term Term) (key string) {
+	key = store.key(term)
+
+	entry, ok := store.terms[key]
 	if !ok {
-		subjIdx = make(subjectIndex)
-		store.index[key] = subjIdx
+		entry = &indexedTerm{term: term}
+		store.terms[key] = entry
 	}
 
-	return subjIdx
+	entry.refs++
+	return key
 }
 
-// Method lookupPredicate takes a subject index and a predicate term and returns the object list
-// associated with it.
-func (store *IndexStore) lookupPredicate(subjIdx subjectIndex, predicate Term) (objList []Term) {
-	key := store.encodeKey(predicate)
-	objList, ok := subjIdx[key]
-	if !ok {
-		objList = make([]Term, 0, 1)
-		subjIdx[key] = objList
+// Method unref removes a use of the term with the given key, forgetting the term once it is no
+// longer used.
+func (store *IndexStore) unref(key string) {
+	if key == "" {
+		return
+	}
+
+	entry := store.terms[key]
+	entry.refs--
+
+	if entry.refs == 0 {
+		delete(store.terms, key)
+	}
+}
+
+// Method triple builds a triple from the terms with the given keys.
+func (store *IndexStore) triple(s, p, o, g string) (triple *Triple) {
+	var graph Term
+	if g != "" {
+		graph = store.terms[g].term
+	}
+
+	return NewQuad(store.terms[s].term, store.terms[p].term, store.terms[o].term, graph)
+}
+
+// Method triplePOS builds a triple from keys given in predicate-object-subject order.
+func (store *IndexStore) triplePOS(p, o, s, g string) (triple *Triple) {
+	return store.triple(s, p, o, g)
+}
+
+// Method tripleOSP builds a triple from keys given in object-subject-predicate order.
+func (store *IndexStore) tripleOSP(o, s, p, g string) (triple *Triple) {
+	return store.triple(s, p, o, g)
+}
+
+// Method unshare gives the store its own copy of its indexes if they are shared with a snapshot,
+// so that they can be modified.
+func (store *IndexStore) unshare() {
+	if !store.shared {
+		return
 	}
 
-	return objList
+	terms := make(map[string]*indexedTerm, len(store.terms))
+	for key, entry := range store.terms {
+		terms[key] = &indexedTerm{entry.term, entry.refs}
+	}
+
+	store.terms = terms
+	store.spo = store.spo.copy()
+	store.pos = store.pos.copy()
+	store.osp = store.osp.copy()
+	store.shared = false
 }
 
-// Method storePredicate stores the given object list into the subject index, under the key given
-// by the supplied predicate.
-func (store *IndexStore) storePredicate(subjIdx subjectIndex, predicate Term, objList []Term) {
-	key := store.encodeKey(predicate)
-	subjIdx[key] = objList
+// Method Begin starts a transaction on the store. Taking the snapshot is cheap, but the next change
+// made to the store afterwards copies its indexes.
+func (store *IndexStore) Begin() (tx Tx, err error) {
+	snapshot := *store
+	store.shared = true
+	return NewSnapshotTx(store, &snapshot, SnapshotTxOptions{ValueEquality: store.ValueEquality}), nil
 }
 
-// Method Add adds the given triple to the store.
+// Method Add adds the given triple to the store, unless it is already present in the same graph.
 func (store *IndexStore) Add(triple *Triple) {
-	subjIdx := store.lookupSubject(triple.Subject)
-	objList := store.lookupPredicate(subjIdx, triple.Predicate)
+	s, p, o := store.key(triple.Subject), store.key(triple.Predicate), store.key(triple.Object)
+	g := store.graphKey(triple.Graph)
+	if _, ok := store.spo[s][p][o][g]; ok {
+		return
+	}
+
+	store.unshare()
+	store.spo.add(s, p, o, g)
+	store.pos.add(p, o, s, g)
+	store.osp.add(o, s, p, g)
 
-	store.storePredicate(subjIdx, triple.Predicate, append(objList, triple.Object))
+	store.ref(triple.Subject)
+	store.ref(triple.Predicate)
+	store.ref(triple.Object)
+	if triple.Graph != nil {
+		store.ref(triple.Graph)
+	}
+
+	store.num++
 }
 
-// Method Remove removes the given triple from the store.
+// Method Remove removes the given triple from the store. Only the copy in the triple's own graph is
+// removed.
 func (store *IndexStore) Remove(triple *Triple) {
-	subjIdx := store.lookupSubject(triple.Subject)
-	objList := store.lookupPredicate(subjIdx, triple.Predicate)
-
-	for i, obj := range objList {
-		if obj == triple.Object {
-			store.storePredicate(subjIdx, triple.Predicate, append(objList[:i], objList[i+1:]...))
-			break
-		}
+	s, p, o := store.key(triple.Subject), store.key(triple.Predicate), store.key(triple.Object)
+	g := store.graphKey(triple.Graph)
+	if _, ok := store.spo[s][p][o][g]; !ok {
+		return
 	}
+
+	store.unshare()
+	store.spo.remove(s, p, o, g)
+	store.pos.remove(p, o, s, g)
+	store.osp.remove(o, s, p, g)
+
+	store.unref(s)
+	store.unref(p)
+	store.unref(o)
+	store.unref(g)
+	store.num--
 }
 
 // Method Clear empties the store.
 func (store *IndexStore) Clear() {
-	store.index = make(toplevelIndex)
+	store.terms = make(map[string]*indexedTerm)
+	store.spo = make(permutationIndex)
+	store.pos = make(permutationIndex)
+	store.osp = make(permutationIndex)
+	store.num = 0
+	store.shared = false
 }
 
 // Method Num returns the number of triples in the store.
 func (store *IndexStore) Num() (n int) {
-	for _, subjIdx := range store.index {
-		for _, objList := range subjIdx {
-			n += len(objList)
-		}
-	}
-
-	return n
+	return store.num
 }
 
 // Method IterTriples returns a channel that yields successive triples in the graph.
 func (store *IndexStore) IterTriples() (ch chan *Triple) {
-	ch = make(chan *Triple)
-
-	go func() {
-		defer close(ch)
-
-		for subjKey, subjIdx := range store.index {
-			for predKey, objList := range subjIdx {
-				for _, object := range objList {
-					ch <- NewTriple(store.decodeKey(subjKey), store.decodeKey(predKey), object)
-				}
-			}
-		}
-	}()
-
-	return ch
+	return seqChannel(store.All())
 }
 
 // Method Filter performs a basic filter; see the documentation of Store for information on the
 // arguments.
 func (store *IndexStore) Filter(subjSearch, predSearch, objSearch Term) (ch chan *Triple) {
-	if subjSearch == nil && predSearch == nil && objSearch == nil {
-		return store.IterTriples()
-	}
+	return seqChannel(store.FilterSeq(subjSearch, predSearch, objSearch))
+}
 
-	if subjSearch != nil {
-		if predSearch != nil {
-			if objSearch != nil {
-				return store.filterSPO(subjSearch, predSearch, objSearch)
-			}
+// Method All returns a sequence of the triples in the store.
+func (store *IndexStore) All() (seq iter.Seq[*Triple]) {
+	return store.FilterSeq(nil, nil, nil)
+}
 
-			return store.filterSP(subjSearch, predSearch)
-		}
+// Method FilterSeq returns a sequence of the matching triples in the store; see the documentation
+// of Store for information on the arguments. Every combination of bound and unbound terms is
+// served by one of the indexes.
+func (store *IndexStore) FilterSeq(subjSearch, predSearch, objSearch Term) (seq iter.Seq[*Triple]) {
+	var s, p, o string
 
-		return store.filterS(subjSearch)
+	if subjSearch != nil {
+		s = store.key(subjSearch)
 	}
 
-	return store.filterDefault(subjSearch, predSearch, objSearch)
-}
-
-// Method filterSPO performs a filter when the subject, predicate and object are non-nil.
-func (store *IndexStore) filterSPO(subjSearch, predSearch, objSearch Term) (ch chan *Triple) {
-	/*
-		ch = make(chan *Triple)
-
-		subjIdx := store.lookupSubject(subjSearch)
-		objList := store.lookupPredicate(subjIdx, predSearch)
+	if predSearch != nil {
+		p = store.key(predSearch)
+	}
 
-		go func() {
-			defer close(ch)
+	if objSearch != nil {
+		o = store.key(objSearch)
+	}
 
-			for _, obj := range objList {
-				if obj == objSearch {
-					ch <- NewTriple(subjSearch, predSearch, objSearch)
-				}
-			}
-		}()
+	switch {
+	case subjSearch != nil && predSearch != nil && objSearch != nil:
+		return scan3(store.spo, s, p, o, store.triple)
 
-		return ch
-	*/
+	case subjSearch != nil && predSearch != nil:
+		return scan2(store.spo, s, p, store.triple)
 
-	ch = make(chan *Triple, 1)
-	ch <- NewTriple(subjSearch, predSearch, objSearch)
-	close(ch)
-	return ch
-}
+	case subjSearch != nil && objSearch != nil:
+		return scan2(store.osp, o, s, store.tripleOSP)
 
-// Method filterSP performs a filter when the subject and predicate are non-nil.
-func (store *IndexStore) filterSP(subjSearch, predSearch Term) (ch chan *Triple) {
-	ch = make(chan *Triple)
+	case predSearch != nil && objSearch != nil:
+		return scan2(store.pos, p, o, store.triplePOS)
 
-	subjIdx := store.lookupSubject(subjSearch)
-	objList := store.lookupPredicate(subjIdx, predSearch)
+	case subjSearch != nil:
+		return scan1(store.spo, s, store.triple)
 
-	go func() {
-		defer close(ch)
+	case predSearch != nil:
+		return scan1(store.pos, p, store.triplePOS)
 
-		for _, object := range objList {
-			ch <- NewTriple(subjSearch, predSearch, object)
-		}
-	}()
+	case objSearch != nil:
+		return scan1(store.osp, o, store.tripleOSP)
+	}
 
-	return ch
+	return scan0(store.spo, store.triple)
 }
 
-// Method filterS performs a filter when the subject is non-nil.
-func (store *IndexStore) filterS(subjSearch Term) (ch chan *Triple) {
-	ch = make(chan *Triple)
-
-	subjIdx := store.lookupSubject(subjSearch)
-
-	go func() {
-		defer close(ch)
-
-		for predKey, objList := range subjIdx {
-			for _, object := range objList {
-				ch <- NewTriple(subjSearch, store.decodeKey(predKey), object)
+// Function scan3 returns a sequence yielding the key triple (a, b, c) of idx, once for each graph
+// holding it.
+func scan3(idx permutationIndex, a, b, c string, build func(a, b, c, g string) *Triple) (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
+		for g := range idx[a][b][c] {
+			if !yield(build(a, b, c, g)) {
+				return
 			}
 		}
-	}()
-
-	return ch
+	}
 }
 
-// Method filterDefault performs a standard iteration filter.
-func (store *IndexStore) filterDefault(subjSearch, predSearch, objSearch Term) (ch chan *Triple) {
-	ch = make(chan *Triple)
-
-	go func() {
-		defer close(ch)
-
-		for triple := range store.IterTriples() {
-			if subjSearch != nil && subjSearch != triple.Subject {
-				continue
+// Function scan2 returns a sequence of the key triples of idx beginning with a and b.
+func scan2(idx permutationIndex, a, b string, build func(a, b, c, g string) *Triple) (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
+		for c, graphs := range idx[a][b] {
+			for g := range graphs {
+				if !yield(build(a, b, c, g)) {
+					return
+				}
 			}
+		}
+	}
+}
 
-			if predSearch != nil && predSearch != triple.Predicate {
-				continue
+// Function scan1 returns a sequence of the key triples of idx beginning with a.
+func scan1(idx permutationIndex, a string, build func(a, b, c, g string) *Triple) (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
+		for b, third := range idx[a] {
+			for c, graphs := range third {
+				for g := range graphs {
+					if !yield(build(a, b, c, g)) {
+						return
+					}
+				}
 			}
+		}
+	}
+}
 
-			if objSearch != nil && objSearch != triple.Object {
-				continue
+// Function scan0 returns a sequence of all the key triples of idx.
+func scan0(idx permutationIndex, build func(a, b, c, g string) *Triple) (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
+		for a, second := range idx {
+			for b, third := range second {
+				for c, graphs := range third {
+					for g := range graphs {
+						if !yield(build(a, b, c, g)) {
+							return
+						}
+					}
+				}
 			}
-
-			ch <- triple
 		}
-	}()
-
-	return ch
+	}
 }
@@ -0,0 +1,175 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"testing"
+)
+
+func TestIndexStorePatterns(t *testing.T) {
+	testFilterPatterns(t, NewIndexStore())
+}
+
+// testFilterPatterns checks that store's FilterSeq gives the right answer for every combination of
+// bound and unbound terms.
+func testFilterPatterns(t *testing.T, store SeqStore) {
+	ex := NewNamespace("http://example.org/")
+	terms := []Term{ex.Get("a"), ex.Get("b"), NewBlankNode("c"), NewLiteral("d")}
+
+	var triples []*Triple
+
+	for i, s := range terms {
+		for j, p := range terms[:2] {
+			for k, o := range terms {
+				if (i+j+k)%2 == 0 {
+					triples = append(triples, NewTriple(s, p, o))
+					store.Add(NewTriple(s, p, o))
+				}
+			}
+		}
+	}
+
+	if store.Num() != len(triples) {
+		t.Fatalf("%T: expected %d triples but got %d", store, len(triples), store.Num())
+	}
+
+	// Try every combination of bound and unbound terms, with fresh copies of the terms so that
+	// matching is done by value.
+	candidates := []Term{nil, NewResource(ex.Get("a").(*Resource).URI), NewLiteral("d"), ex.Get("z")}
+
+	for _, s := range candidates {
+		for _, p := range candidates {
+			for _, o := range candidates {
+				expected := 0
+				for _, triple := range triples {
+					if (s == nil || s.Equal(triple.Subject)) && (p == nil || p.Equal(triple.Predicate)) && (o == nil || o.Equal(triple.Object)) {
+						expected++
+					}
+				}
+
+				n := 0
+				for triple := range store.FilterSeq(s, p, o) {
+					if (s != nil && !s.Equal(triple.Subject)) || (p != nil && !p.Equal(triple.Predicate)) || (o != nil && !o.Equal(triple.Object)) {
+						t.Errorf("%T: Filter(%v, %v, %v) yielded non-matching triple %s", store, s, p, o, triple)
+					}
+
+					n++
+				}
+
+				if n != expected {
+					t.Errorf("%T: Filter(%v, %v, %v): expected %d triples but got %d", store, s, p, o, expected, n)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexStoreSetSemantics(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+	store := NewIndexStore()
+
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("o")))
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("o")))
+
+	if store.Num() != 1 {
+		t.Errorf("Expected duplicate triple to be ignored but got %d triples", store.Num())
+	}
+
+	store.Remove(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("other")))
+	store.Remove(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteral("o")))
+
+	if store.Num() != 0 || len(store.terms) != 0 {
+		t.Errorf("Expected an empty store but got %d triples and %d terms", store.Num(), len(store.terms))
+	}
+
+	for _ = range store.FilterSeq(ex.Get("s"), nil, nil) {
+		t.Errorf("Unexpected triple in empty store")
+	}
+
+	// Literal subjects are allowed in generalized RDF.
+	store.Add(NewTriple(NewLiteral("x"), ex.Get("p"), ex.Get("o")))
+
+	for triple := range store.FilterSeq(NewLiteral("x"), nil, nil) {
+		if !triple.Object.Equal(ex.Get("o")) {
+			t.Errorf("Unexpected triple %s", triple)
+		}
+	}
+
+	store.ValueEquality = true
+	store.Clear()
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteralWithDatatype("01", XSD.Get("integer"))))
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), NewLiteralWithDatatype("1", XSD.Get("integer"))))
+
+	if store.Num() != 1 {
+		t.Errorf("Expected equal-valued literals to be stored once but got %d triples", store.Num())
+	}
+
+	n := 0
+	for _ = range store.FilterSeq(nil, nil, NewLiteralWithDatatype("+1", XSD.Get("integer"))) {
+		n++
+	}
+
+	if n != 1 {
+		t.Errorf("Expected 1 triple matching by value but got %d", n)
+	}
+}
+
+func TestIndexStoreQuads(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+	store := NewIndexStore()
+
+	store.Add(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g1")))
+	store.Add(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g2")))
+	store.Add(NewTriple(ex.Get("s"), ex.Get("p"), ex.Get("o")))
+	store.Add(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g1")))
+
+	if store.Num() != 3 {
+		t.Fatalf("Expected 3 quads but got %d", store.Num())
+	}
+
+	graphs := make(map[string]bool)
+	for triple := range store.FilterSeq(nil, nil, ex.Get("o")) {
+		name := ""
+		if triple.Graph != nil {
+			name = triple.Graph.String()
+		}
+
+		graphs[name] = true
+	}
+
+	if len(graphs) != 3 || !graphs["<http://example.org/g1>"] || !graphs["<http://example.org/g2>"] || !graphs[""] {
+		t.Errorf("Expected the triple in g1, g2 and the default graph but got %v", graphs)
+	}
+
+	store.Remove(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g1")))
+	store.Remove(NewTriple(ex.Get("s"), ex.Get("p"), ex.Get("o")))
+
+	for triple := range store.All() {
+		if triple.Graph == nil || !triple.Graph.Equal(ex.Get("g2")) {
+			t.Errorf("Unexpected triple %s left in the store", triple.QuadString())
+		}
+	}
+
+	store.Remove(NewQuad(ex.Get("s"), ex.Get("p"), ex.Get("o"), ex.Get("g2")))
+
+	if store.Num() != 0 || len(store.terms) != 0 {
+		t.Errorf("Expected an empty store but got %d triples and %d terms", store.Num(), len(store.terms))
+	}
+}
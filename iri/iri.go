@@ -0,0 +1,627 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package iri implements parsing and validation of Internationalized Resource Identifiers as
+// described in RFC 3987, along with reference resolution and normalization as described in RFC 3986.
+package iri
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"unicode/utf8"
+)
+
+// An Error is returned when a string is not a valid IRI reference.
+type Error struct {
+	IRI    string // The string being parsed
+	Offset int    // Byte offset of the offending character
+	Err    error  // The actual error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid IRI %q: %s at offset %d", e.IRI, e.Err, e.Offset)
+}
+
+// These are the errors that can be returned in Error.Err.
+var (
+	ErrInvalidScheme          = errors.New("invalid scheme")
+	ErrInvalidCharacter       = errors.New("invalid character")
+	ErrInvalidPercentEncoding = errors.New("invalid percent-encoding")
+	ErrInvalidHost            = errors.New("invalid host")
+	ErrInvalidPort            = errors.New("invalid port")
+	ErrNotAbsolute            = errors.New("IRI is relative")
+)
+
+// An IRI is an IRI reference split into its five components. The Has* fields distinguish an empty
+// component from an absent one (for example "http://example.org/?" from "http://example.org/").
+type IRI struct {
+	Scheme       string
+	Authority    string
+	HasAuthority bool
+	Path         string
+	Query        string
+	HasQuery     bool
+	Fragment     string
+	HasFragment  bool
+}
+
+// Function Parse parses and validates an IRI reference, which may be relative.
+func Parse(s string) (iri *IRI, err error) {
+	iri = split(s)
+
+	err = iri.validate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return iri, nil
+}
+
+// Function ParseAbsolute parses and validates an IRI, returning an error if it has no scheme.
+func ParseAbsolute(s string) (iri *IRI, err error) {
+	iri, err = Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if !iri.IsAbsolute() {
+		return nil, &Error{IRI: s, Offset: 0, Err: ErrNotAbsolute}
+	}
+
+	return iri, nil
+}
+
+// Function Resolve parses the reference ref and resolves it against the IRI base. If base is empty
+// ref is returned as-is (after validation).
+func Resolve(base string, ref string) (s string, err error) {
+	r, err := Parse(ref)
+	if err != nil {
+		return "", err
+	}
+
+	if base == "" {
+		return ref, nil
+	}
+
+	b, err := Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	return b.Resolve(r).String(), nil
+}
+
+// Function Normalize parses the IRI reference s and returns its normalized form.
+func Normalize(s string) (normalized string, err error) {
+	iri, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	return iri.Normalize().String(), nil
+}
+
+// Method IsAbsolute returns whether the IRI has a scheme, i.e. is not a relative reference.
+func (iri *IRI) IsAbsolute() bool {
+	return iri.Scheme != ""
+}
+
+// Method String recomposes the components of the IRI.
+func (iri *IRI) String() string {
+	var b strings.Builder
+
+	if iri.Scheme != "" {
+		b.WriteString(iri.Scheme)
+		b.WriteByte(':')
+	}
+
+	if iri.HasAuthority {
+		b.WriteString("//")
+		b.WriteString(iri.Authority)
+	}
+
+	b.WriteString(iri.Path)
+
+	if iri.HasQuery {
+		b.WriteByte('?')
+		b.WriteString(iri.Query)
+	}
+
+	if iri.HasFragment {
+		b.WriteByte('#')
+		b.WriteString(iri.Fragment)
+	}
+
+	return b.String()
+}
+
+// Method Resolve resolves the reference ref against this IRI, following the algorithm in section
+// 5.2 of RFC 3986. The receiver should be absolute; its fragment is ignored.
+func (iri *IRI) Resolve(ref *IRI) (target *IRI) {
+	t := *ref
+
+	if ref.Scheme != "" {
+		t.Path = RemoveDotSegments(ref.Path)
+		return &t
+	}
+
+	t.Scheme = iri.Scheme
+
+	if ref.HasAuthority {
+		t.Path = RemoveDotSegments(ref.Path)
+		return &t
+	}
+
+	t.Authority, t.HasAuthority = iri.Authority, iri.HasAuthority
+
+	if ref.Path == "" {
+		t.Path = iri.Path
+		if !ref.HasQuery {
+			t.Query, t.HasQuery = iri.Query, iri.HasQuery
+		}
+
+		return &t
+	}
+
+	if ref.Path[0] == '/' {
+		t.Path = RemoveDotSegments(ref.Path)
+	} else if iri.HasAuthority && iri.Path == "" {
+		t.Path = RemoveDotSegments("/" + ref.Path)
+	} else {
+		t.Path = RemoveDotSegments(iri.Path[:strings.LastIndexByte(iri.Path, '/')+1] + ref.Path)
+	}
+
+	return &t
+}
+
+// Function RemoveDotSegments removes "." and ".." segments from a path as described in section
+// 5.2.4 of RFC 3986.
+func RemoveDotSegments(in string) (out string) {
+	var output []string
+
+	for in != "" {
+		switch {
+		case strings.HasPrefix(in, "../"):
+			in = in[3:]
+		case strings.HasPrefix(in, "./"):
+			in = in[2:]
+		case strings.HasPrefix(in, "/./"):
+			in = in[2:]
+		case in == "/.":
+			in = "/"
+		case strings.HasPrefix(in, "/../"):
+			in = in[3:]
+			if len(output) > 0 {
+				output = output[:len(output)-1]
+			}
+		case in == "/..":
+			in = "/"
+			if len(output) > 0 {
+				output = output[:len(output)-1]
+			}
+		case in == "." || in == "..":
+			in = ""
+		default:
+			i := strings.IndexByte(in[1:], '/')
+			if i < 0 {
+				output = append(output, in)
+				in = ""
+			} else {
+				output = append(output, in[:i+1])
+				in = in[i+1:]
+			}
+		}
+	}
+
+	return strings.Join(output, "")
+}
+
+// defaultPorts maps schemes to the port that is implied when none is given.
+var defaultPorts = map[string]string{
+	"ftp":   "21",
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// Method Normalize returns a normalized copy of the IRI, applying the syntax-based normalizations of
+// section 6.2.2 of RFC 3986 and section 5.3.2 of RFC 3987: the scheme and host are lower-cased,
+// percent-encodings are upper-cased, percent-encoded unreserved characters are decoded and dot
+// segments are removed. Empty paths and default ports of the http(s), ws(s) and ftp schemes are
+// also normalized. Unicode normalization (NFC) is not applied.
+func (iri *IRI) Normalize() (normalized *IRI) {
+	n := *iri
+	n.Scheme = strings.ToLower(iri.Scheme)
+
+	if n.HasAuthority {
+		userinfo, host, port, hasPort := splitAuthority(iri.Authority)
+		host = strings.ToLower(normalizePercentEncoding(host))
+
+		if hasPort && (port == "" || port == defaultPorts[n.Scheme]) {
+			hasPort = false
+		}
+
+		n.Authority = host
+		if userinfo != "" || strings.Contains(iri.Authority, "@") {
+			n.Authority = normalizePercentEncoding(userinfo) + "@" + n.Authority
+		}
+
+		if hasPort {
+			n.Authority += ":" + port
+		}
+
+		if n.Path == "" && defaultPorts[n.Scheme] != "" {
+			n.Path = "/"
+		}
+	}
+
+	n.Path = normalizePercentEncoding(n.Path)
+	if n.Scheme != "" || n.HasAuthority {
+		n.Path = RemoveDotSegments(n.Path)
+	}
+
+	n.Query = normalizePercentEncoding(n.Query)
+	n.Fragment = normalizePercentEncoding(n.Fragment)
+	return &n
+}
+
+// Method URI returns the URI that the IRI maps to, as described in section 3.1 of RFC 3987: every
+// non-ASCII character is percent-encoded as UTF-8.
+func (iri *IRI) URI() string {
+	s := iri.String()
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			fmt.Fprintf(&b, "%%%02X", s[i])
+		} else {
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// Function split splits an IRI reference into its five components, without validating them.
+func split(s string) (iri *IRI) {
+	iri = new(IRI)
+
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		iri.Fragment, iri.HasFragment = s[i+1:], true
+		s = s[:i]
+	}
+
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		iri.Query, iri.HasQuery = s[i+1:], true
+		s = s[:i]
+	}
+
+	if i := strings.IndexByte(s, ':'); i > 0 && !strings.ContainsRune(s[:i], '/') && isScheme(s[:i]) {
+		iri.Scheme = s[:i]
+		s = s[i+1:]
+	}
+
+	if strings.HasPrefix(s, "//") {
+		s = s[2:]
+		iri.HasAuthority = true
+
+		if i := strings.IndexByte(s, '/'); i >= 0 {
+			iri.Authority, s = s[:i], s[i:]
+		} else {
+			iri.Authority, s = s, ""
+		}
+	}
+
+	iri.Path = s
+	return iri
+}
+
+// Function splitAuthority splits an authority into its userinfo, host and port.
+func splitAuthority(authority string) (userinfo, host, port string, hasPort bool) {
+	if i := strings.IndexByte(authority, '@'); i >= 0 {
+		userinfo, authority = authority[:i], authority[i+1:]
+	}
+
+	end := 0
+	if strings.HasPrefix(authority, "[") {
+		end = strings.IndexByte(authority, ']') + 1
+	}
+
+	if i := strings.LastIndexByte(authority, ':'); i >= end {
+		return userinfo, authority[:i], authority[i+1:], true
+	}
+
+	return userinfo, authority, "", false
+}
+
+// Function isScheme returns whether s is a syntactically valid scheme.
+func isScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isAlpha(c) && (i == 0 || !(isDigit(c) || c == '+' || c == '-' || c == '.')) {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+// Method validate checks each component of the IRI against the grammar of RFC 3987. s is the
+// original string, used to compute offsets for errors.
+func (iri *IRI) validate(s string) (err error) {
+	fail := func(offset int, err error) error {
+		return &Error{IRI: s, Offset: offset, Err: err}
+	}
+
+	offset := 0
+	if iri.Scheme != "" {
+		offset += len(iri.Scheme) + 1
+	}
+
+	if iri.HasAuthority {
+		offset += 2
+		userinfo, host, port, hasPort := splitAuthority(iri.Authority)
+
+		if i, err := check(userinfo, isUserInfoChar); err != nil {
+			return fail(offset+i, err)
+		}
+
+		hostOffset := offset + len(iri.Authority) - len(host) - len(port)
+		if hasPort {
+			hostOffset--
+		}
+
+		if strings.HasPrefix(host, "[") {
+			if !isIPLiteral(host) {
+				return fail(hostOffset, ErrInvalidHost)
+			}
+
+		} else if i, err := check(host, isRegNameChar); err != nil {
+			return fail(hostOffset+i, err)
+		}
+
+		for i := 0; i < len(port); i++ {
+			if !isDigit(port[i]) {
+				return fail(hostOffset+len(host)+1+i, ErrInvalidPort)
+			}
+		}
+
+		offset += len(iri.Authority)
+
+	} else if iri.Scheme == "" {
+		// A relative-path reference may not contain a colon in its first segment, as it would be
+		// mistaken for a scheme.
+		segment := iri.Path
+		if i := strings.IndexByte(segment, '/'); i >= 0 {
+			segment = segment[:i]
+		}
+
+		if i := strings.IndexByte(segment, ':'); i >= 0 {
+			return fail(offset+i, ErrInvalidScheme)
+		}
+	}
+
+	if i, err := check(iri.Path, isPathChar); err != nil {
+		return fail(offset+i, err)
+	}
+
+	offset += len(iri.Path)
+
+	if iri.HasQuery {
+		offset++
+
+		if i, err := check(iri.Query, isQueryChar); err != nil {
+			return fail(offset+i, err)
+		}
+
+		offset += len(iri.Query)
+	}
+
+	if iri.HasFragment {
+		offset++
+
+		if i, err := check(iri.Fragment, isFragmentChar); err != nil {
+			return fail(offset+i, err)
+		}
+	}
+
+	return nil
+}
+
+// Function check returns the offset and error of the first character in s that is neither a valid
+// percent-encoding nor accepted by allowed.
+func check(s string, allowed func(rune) bool) (offset int, err error) {
+	for i := 0; i < len(s); {
+		if s[i] == '%' {
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return i, ErrInvalidPercentEncoding
+			}
+
+			i += 3
+			continue
+		}
+
+		r1, size := utf8.DecodeRuneInString(s[i:])
+		if (r1 == utf8.RuneError && size == 1) || !allowed(r1) {
+			return i, ErrInvalidCharacter
+		}
+
+		i += size
+	}
+
+	return 0, nil
+}
+
+// Function isIPLiteral returns whether s is a bracketed IPv6 address or IPvFuture literal.
+func isIPLiteral(s string) bool {
+	if len(s) < 3 || s[len(s)-1] != ']' {
+		return false
+	}
+
+	s = s[1 : len(s)-1]
+
+	if s[0] == 'v' || s[0] == 'V' {
+		dot := strings.IndexByte(s, '.')
+		if dot < 2 || dot == len(s)-1 {
+			return false
+		}
+
+		for i := 1; i < dot; i++ {
+			if !isHex(s[i]) {
+				return false
+			}
+		}
+
+		for _, r1 := range s[dot+1:] {
+			if r1 >= 0x80 || !(isUnreserved(r1) || isSubDelim(r1) || r1 == ':') {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is6() && addr.Zone() == ""
+}
+
+// Function normalizePercentEncoding upper-cases percent-encodings in s, and decodes those that
+// encode unreserved characters.
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		if s[i] != '%' || i+2 >= len(s) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		// Collect the run of percent-encoded octets that make up a single UTF-8 sequence.
+		var octets []byte
+		j := i
+		for j+2 < len(s) && s[j] == '%' && isHex(s[j+1]) && isHex(s[j+2]) {
+			octets = append(octets, unhex(s[j+1])<<4|unhex(s[j+2]))
+			j += 3
+
+			if utf8.FullRune(octets) {
+				break
+			}
+		}
+
+		if len(octets) == 0 {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		r1, size := utf8.DecodeRune(octets)
+		if size == len(octets) && r1 != utf8.RuneError && isUnreserved(r1) {
+			b.WriteRune(r1)
+		} else {
+			b.WriteString(strings.ToUpper(s[i:j]))
+		}
+
+		i = j
+	}
+
+	return b.String()
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case isDigit(c):
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	}
+
+	return c - 'A' + 10
+}
+
+// Function isUnreserved returns whether r1 matches the iunreserved production.
+func isUnreserved(r1 rune) bool {
+	if r1 < 0x80 {
+		c := byte(r1)
+		return isAlpha(c) || isDigit(c) || c == '-' || c == '.' || c == '_' || c == '~'
+	}
+
+	return isUCSChar(r1)
+}
+
+// Function isUCSChar returns whether r1 matches the ucschar production.
+func isUCSChar(r1 rune) bool {
+	switch {
+	case r1 >= 0xA0 && r1 <= 0xD7FF, r1 >= 0xF900 && r1 <= 0xFDCF, r1 >= 0xFDF0 && r1 <= 0xFFEF:
+		return true
+	case r1 >= 0x10000 && r1 <= 0xEFFFD:
+		// Every plane from 1 to 14, except its last two code points.
+		return r1&0xFFFF <= 0xFFFD && (r1 < 0xE0000 || r1 >= 0xE1000)
+	}
+
+	return false
+}
+
+// Function isPrivate returns whether r1 matches the iprivate production.
+func isPrivate(r1 rune) bool {
+	return (r1 >= 0xE000 && r1 <= 0xF8FF) || (r1 >= 0xF0000 && r1 <= 0xFFFFD) || (r1 >= 0x100000 && r1 <= 0x10FFFD)
+}
+
+func isSubDelim(r1 rune) bool {
+	return strings.ContainsRune("!$&'()*+,;=", r1)
+}
+
+func isUserInfoChar(r1 rune) bool {
+	return isUnreserved(r1) || isSubDelim(r1) || r1 == ':'
+}
+
+func isRegNameChar(r1 rune) bool {
+	return isUnreserved(r1) || isSubDelim(r1)
+}
+
+func isPathChar(r1 rune) bool {
+	return isUnreserved(r1) || isSubDelim(r1) || r1 == ':' || r1 == '@' || r1 == '/'
+}
+
+func isQueryChar(r1 rune) bool {
+	return isPathChar(r1) || r1 == '?' || isPrivate(r1)
+}
+
+func isFragmentChar(r1 rune) bool {
+	return isPathChar(r1) || r1 == '?'
+}
@@ -0,0 +1,434 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"sort"
+	"strings"
+)
+
+// A GraphComparison is the result of comparing two graphs while allowing for the renaming of blank
+// nodes.
+type GraphComparison struct {
+	// Mapping from the IDs of blank nodes in the first graph to the IDs of their counterparts in the
+	// second graph. Blank nodes with no counterpart are not included.
+	Mapping map[string]string
+
+	// Triples of the first graph that have no counterpart in the second graph, and vice versa. The
+	// triples are as they appear in their own graph, and sorted by their N-Triples representation.
+	OnlyInFirst  []*Triple
+	OnlyInSecond []*Triple
+}
+
+// Method Isomorphic returns whether the two graphs were found to be equal up to the renaming of
+// blank nodes.
+func (c *GraphComparison) Isomorphic() bool {
+	return len(c.OnlyInFirst) == 0 && len(c.OnlyInSecond) == 0
+}
+
+// Method String returns a report of the differences between the graphs, in the style of a unified
+// diff: triples only in the first graph are prefixed with "-", and those only in the second with
+// "+".
+func (c *GraphComparison) String() string {
+	var b strings.Builder
+
+	for _, triple := range c.OnlyInFirst {
+		b.WriteString("- " + triple.String() + "\n")
+	}
+
+	for _, triple := range c.OnlyInSecond {
+		b.WriteString("+ " + triple.String() + "\n")
+	}
+
+	return b.String()
+}
+
+// uniqueTriples returns the triples without duplicates (ignoring graph names), in their original
+// order.
+func uniqueTriples(triples []*Triple) (unique []*Triple) {
+	seen := make(map[string]bool, len(triples))
+
+	for _, triple := range triples {
+		key := triple.String()
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, NewTriple(triple.Subject, triple.Predicate, triple.Object))
+		}
+	}
+
+	return unique
+}
+
+// Function CompareTriples compares two sets of triples, allowing for the renaming of blank nodes.
+// Graph names and duplicate triples are ignored, so callers that care about duplicates must check
+// for them separately.
+//
+// If the sets are isomorphic, the comparison holds a mapping between all their blank nodes and no
+// differences. Otherwise, blank nodes are paired up heuristically (by the structure around them) to
+// keep the reported differences small; the differences are then accurate for that pairing, though
+// not necessarily the smallest possible.
+func CompareTriples(a []*Triple, b []*Triple) (c *GraphComparison) {
+	a, b = uniqueTriples(a), uniqueTriples(b)
+
+	if mapping, ok := findIsomorphism(a, b); ok {
+		return &GraphComparison{Mapping: mapping}
+	}
+
+	mapping := matchBlankNodes(a, b)
+	c = &GraphComparison{Mapping: mapping}
+
+	inB := make(map[string]bool, len(b))
+	for _, triple := range b {
+		inB[triple.String()] = true
+	}
+
+	matched := make(map[string]bool, len(a))
+	for _, triple := range a {
+		key, ok := mapTriple(triple, mapping)
+		if ok && inB[key] {
+			matched[key] = true
+		} else {
+			c.OnlyInFirst = append(c.OnlyInFirst, triple)
+		}
+	}
+
+	for _, triple := range b {
+		if !matched[triple.String()] {
+			c.OnlyInSecond = append(c.OnlyInSecond, triple)
+		}
+	}
+
+	sort.Sort(triplesByString(c.OnlyInFirst))
+	sort.Sort(triplesByString(c.OnlyInSecond))
+	return c
+}
+
+// Method Compare compares the graph with another, allowing for the renaming of blank nodes. See
+// CompareTriples.
+func (graph *Graph) Compare(other *Graph) (c *GraphComparison) {
+	return CompareTriples(graph.triples(), other.triples())
+}
+
+// Method IsIsomorphic returns whether the graph is equal to another up to the renaming of blank
+// nodes.
+func (graph *Graph) IsIsomorphic(other *Graph) bool {
+	a, b := uniqueTriples(graph.triples()), uniqueTriples(other.triples())
+	_, ok := findIsomorphism(a, b)
+	return ok
+}
+
+// triples returns all triples of the graph as a slice.
+func (graph *Graph) triples() (triples []*Triple) {
+	for triple := range graph.IterTriples() {
+		triples = append(triples, triple)
+	}
+
+	return triples
+}
+
+// triplesByString sorts triples by their N-Triples representation.
+type triplesByString []*Triple
+
+func (t triplesByString) Len() int           { return len(t) }
+func (t triplesByString) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t triplesByString) Less(i, j int) bool { return t[i].String() < t[j].String() }
+
+// mapTriple returns the N-Triples representation of a triple with its blank nodes renamed by
+// mapping, or false if one of them has no mapping.
+func mapTriple(triple *Triple, mapping map[string]string) (str string, ok bool) {
+	ok = true
+
+	term := func(term Term) Term {
+		if node, isBlank := term.(*BlankNode); isBlank {
+			id, found := mapping[node.ID]
+			ok = ok && found
+			return NewBlankNode(id)
+		}
+
+		return term
+	}
+
+	str = NewTriple(term(triple.Subject), term(triple.Predicate), term(triple.Object)).String()
+	return str, ok
+}
+
+// findIsomorphism returns a mapping between the blank nodes of two sets of unique triples, if they
+// are isomorphic. Both sets are canonicalized; if that is too expensive, a backtracking search is
+// used instead.
+func findIsomorphism(a []*Triple, b []*Triple) (mapping map[string]string, ok bool) {
+	if len(a) != len(b) {
+		return nil, false
+	}
+
+	ca, labelsA, errA := Canonicalize(a)
+	cb, labelsB, errB := Canonicalize(b)
+
+	if errA != nil || errB != nil {
+		return searchIsomorphism(a, b)
+	}
+
+	lines := make(map[string]bool, len(cb))
+	for _, triple := range cb {
+		lines[triple.String()] = true
+	}
+
+	for _, triple := range ca {
+		if !lines[triple.String()] {
+			return nil, false
+		}
+	}
+
+	canonicalToB := make(map[string]string, len(labelsB))
+	for id, label := range labelsB {
+		canonicalToB[label] = id
+	}
+
+	mapping = make(map[string]string, len(labelsA))
+	for id, label := range labelsA {
+		mapping[id] = canonicalToB[label]
+	}
+
+	return mapping, true
+}
+
+// searchIsomorphism finds a mapping between the blank nodes of two sets of unique triples with a
+// backtracking search, matching each triple of a with one of b in turn.
+func searchIsomorphism(a []*Triple, b []*Triple) (mapping map[string]string, ok bool) {
+	mapping = make(map[string]string)
+	used := make(map[string]bool)
+	matched := make([]bool, len(b))
+
+	matchTerm := func(x Term, y Term, bound *[]string) bool {
+		xb, xIsBlank := x.(*BlankNode)
+		yb, yIsBlank := y.(*BlankNode)
+
+		if !xIsBlank || !yIsBlank {
+			return x.Equal(y)
+		}
+
+		if m, ok := mapping[xb.ID]; ok {
+			return m == yb.ID
+		}
+
+		if used[yb.ID] {
+			return false
+		}
+
+		mapping[xb.ID] = yb.ID
+		used[yb.ID] = true
+		*bound = append(*bound, xb.ID)
+		return true
+	}
+
+	var search func(i int) bool
+	search = func(i int) bool {
+		if i == len(a) {
+			return true
+		}
+
+		for j, t := range b {
+			if matched[j] {
+				continue
+			}
+
+			var bound []string
+			if matchTerm(a[i].Subject, t.Subject, &bound) && matchTerm(a[i].Predicate, t.Predicate, &bound) && matchTerm(a[i].Object, t.Object, &bound) {
+				matched[j] = true
+				if search(i + 1) {
+					return true
+				}
+
+				matched[j] = false
+			}
+
+			for _, id := range bound {
+				delete(used, mapping[id])
+				delete(mapping, id)
+			}
+		}
+
+		return false
+	}
+
+	if !search(0) {
+		return nil, false
+	}
+
+	return mapping, true
+}
+
+// blankNodeSignatures describes each blank node of triples by the structure around it: starting
+// from the triples it appears in (with other blank nodes left anonymous), each round mixes in the
+// signatures of its neighbours, until the signatures distinguish no more nodes.
+func blankNodeSignatures(triples []*Triple) (signatures map[string]string) {
+	state := &c14nState{quads: make(map[string][]*Triple)}
+
+	for _, triple := range triples {
+		for _, term := range []Term{triple.Subject, triple.Object} {
+			if node, ok := term.(*BlankNode); ok {
+				quads := state.quads[node.ID]
+				if len(quads) == 0 || quads[len(quads)-1] != triple {
+					state.quads[node.ID] = append(quads, triple)
+				}
+			}
+		}
+	}
+
+	signatures = make(map[string]string, len(state.quads))
+	for id := range state.quads {
+		signatures[id] = state.hashFirstDegreeQuads(id)
+	}
+
+	distinct := func(signatures map[string]string) int {
+		seen := make(map[string]bool)
+		for _, signature := range signatures {
+			seen[signature] = true
+		}
+
+		return len(seen)
+	}
+
+	for n := distinct(signatures); ; {
+		next := make(map[string]string, len(signatures))
+
+		for id, quads := range state.quads {
+			parts := make([]string, 0, len(quads))
+
+			for _, quad := range quads {
+				s, o := "", ""
+				if node, ok := quad.Subject.(*BlankNode); ok && node.ID != id {
+					s = signatures[node.ID]
+				}
+
+				if node, ok := quad.Object.(*BlankNode); ok && node.ID != id {
+					o = signatures[node.ID]
+				}
+
+				parts = append(parts, s+" "+quad.Predicate.String()+" "+o)
+			}
+
+			sort.Strings(parts)
+			next[id] = hashStrings(signatures[id], strings.Join(parts, "\n"))
+		}
+
+		m := distinct(next)
+		if m <= n {
+			return signatures
+		}
+
+		signatures, n = next, m
+	}
+}
+
+// matchBlankNodes pairs up the blank nodes of two sets of triples. Nodes with equal signatures are
+// paired first; the remaining ones are then paired greedily, choosing for each node of a the node of
+// b under which most of its triples are found in b.
+func matchBlankNodes(a []*Triple, b []*Triple) (mapping map[string]string) {
+	sigA, sigB := blankNodeSignatures(a), blankNodeSignatures(b)
+
+	bySignature := make(map[string][]string)
+	for id, signature := range sigB {
+		bySignature[signature] = append(bySignature[signature], id)
+	}
+
+	for _, ids := range bySignature {
+		sort.Strings(ids)
+	}
+
+	idsA := make([]string, 0, len(sigA))
+	for id := range sigA {
+		idsA = append(idsA, id)
+	}
+
+	sort.Strings(idsA)
+
+	mapping = make(map[string]string)
+	used := make(map[string]bool)
+
+	for _, id := range idsA {
+		if candidates := bySignature[sigA[id]]; len(candidates) > 0 {
+			mapping[id] = candidates[0]
+			used[candidates[0]] = true
+			bySignature[sigA[id]] = candidates[1:]
+		}
+	}
+
+	inB := make(map[string]bool, len(b))
+	for _, triple := range b {
+		inB[triple.String()] = true
+	}
+
+	triplesOf := make(map[string][]*Triple)
+	for _, triple := range a {
+		for _, term := range []Term{triple.Subject, triple.Object} {
+			if node, ok := term.(*BlankNode); ok {
+				triplesOf[node.ID] = append(triplesOf[node.ID], triple)
+			}
+		}
+	}
+
+	var unusedB []string
+	for id := range sigB {
+		if !used[id] {
+			unusedB = append(unusedB, id)
+		}
+	}
+
+	sort.Strings(unusedB)
+
+	for progress := true; progress; {
+		progress = false
+
+		for _, id := range idsA {
+			if _, ok := mapping[id]; ok {
+				continue
+			}
+
+			best, bestScore := "", 0
+			for _, candidate := range unusedB {
+				if used[candidate] {
+					continue
+				}
+
+				mapping[id] = candidate
+				score := 0
+				for _, triple := range triplesOf[id] {
+					if key, ok := mapTriple(triple, mapping); ok && inB[key] {
+						score++
+					}
+				}
+
+				delete(mapping, id)
+
+				if score > bestScore {
+					best, bestScore = candidate, score
+				}
+			}
+
+			if best != "" {
+				mapping[id] = best
+				used[best] = true
+				progress = true
+			}
+		}
+	}
+
+	return mapping
+}
@@ -0,0 +1,138 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func graphFromNTriples(t *testing.T, doc string) (graph *Graph) {
+	graph = NewGraph(NewListStore())
+
+	err := graph.Parse(ParseNTriples, strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	return graph
+}
+
+func TestIsIsomorphic(t *testing.T) {
+	a := graphFromNTriples(t, `_:a <http://example.org/p> _:b .
+_:b <http://example.org/p> _:c .
+_:c <http://example.org/p> _:a .
+_:a <http://example.org/name> "x" .
+`)
+
+	b := graphFromNTriples(t, `_:z <http://example.org/name> "x" .
+_:y <http://example.org/p> _:z .
+_:x <http://example.org/p> _:y .
+_:z <http://example.org/p> _:x .
+_:z <http://example.org/p> _:x .
+`)
+
+	if !a.IsIsomorphic(b) || !b.IsIsomorphic(a) {
+		t.Errorf("Expected graphs to be isomorphic")
+	}
+
+	c := a.Compare(b)
+	if !c.Isomorphic() || c.Mapping["a"] != "z" || c.Mapping["b"] != "x" || c.Mapping["c"] != "y" {
+		t.Errorf("Expected mapping a->z, b->x, c->y but got %v", c.Mapping)
+	}
+
+	// Two triangles and a hexagon have the same degrees everywhere, but are not isomorphic.
+	triangles := graphFromNTriples(t, `_:a <http://example.org/p> _:b .
+_:b <http://example.org/p> _:c .
+_:c <http://example.org/p> _:a .
+_:d <http://example.org/p> _:e .
+_:e <http://example.org/p> _:f .
+_:f <http://example.org/p> _:d .
+`)
+
+	hexagon := graphFromNTriples(t, `_:a <http://example.org/p> _:b .
+_:b <http://example.org/p> _:c .
+_:c <http://example.org/p> _:d .
+_:d <http://example.org/p> _:e .
+_:e <http://example.org/p> _:f .
+_:f <http://example.org/p> _:a .
+`)
+
+	if triangles.IsIsomorphic(hexagon) {
+		t.Errorf("Expected two triangles and a hexagon not to be isomorphic")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := graphFromNTriples(t, `<http://example.org/s> <http://example.org/p> _:a .
+_:a <http://example.org/name> "Alice" .
+_:a <http://example.org/age> "30" .
+<http://example.org/s> <http://example.org/p> _:b .
+_:b <http://example.org/name> "Bob" .
+`)
+
+	b := graphFromNTriples(t, `<http://example.org/s> <http://example.org/p> _:x .
+_:x <http://example.org/name> "Alice" .
+_:x <http://example.org/age> "31" .
+<http://example.org/s> <http://example.org/p> _:y .
+_:y <http://example.org/name> "Bob" .
+`)
+
+	c := a.Compare(b)
+
+	expected := `- _:a <http://example.org/age> "30" .
++ _:x <http://example.org/age> "31" .
+`
+
+	if c.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, c)
+	}
+
+	if c.Mapping["a"] != "x" || c.Mapping["b"] != "y" {
+		t.Errorf("Expected mapping a->x, b->y but got %v", c.Mapping)
+	}
+}
+
+func TestSquirtleRoundTrip(t *testing.T) {
+	graph := graphFromNTriples(t, `<http://example.org/a> <http://example.org/p> _:b .
+_:b <http://example.org/q> "x" .
+_:b <http://example.org/q> "y"@en .
+<http://example.org/a> <http://example.org/r> <http://example.org/c> .
+`)
+
+	graph.Bind("http://example.org/", "ex")
+
+	var buf bytes.Buffer
+	err := graph.Serialize(SerializeSquirtle, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	parsed := NewGraph(NewListStore())
+	err = parsed.Parse(ParseSquirtle, strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Unexpected error %s parsing:\n%s", err, buf.String())
+	}
+
+	if c := graph.Compare(parsed); !c.Isomorphic() {
+		t.Errorf("Round trip changed the graph:\n%s", c)
+	}
+}
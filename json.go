@@ -1,120 +1,224 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
 package argo
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 )
 
-func SerializeJSON(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
-	defer close(errChan)
+// A JSONParseError is returned for RDF/JSON parsing errors. Subject and Predicate identify where in
+// the document the error occurred, and are empty if the error is not specific to a triple.
+type JSONParseError struct {
+	Subject   string // Subject key under which the error occurred
+	Predicate string // Predicate key under which the error occurred
+	Err       error  // The actual error
+}
 
-	// Offload indexing to IndexStore
+func (e *JSONParseError) Error() string {
+	if e.Subject == "" {
+		return e.Err.Error()
+	}
 
-	store := NewIndexStore()
-	for triple := range tripleChan {
-		store.Add(triple)
+	return fmt.Sprintf("subject %q, predicate %q: %s", e.Subject, e.Predicate, e.Err)
+}
+
+// These are the errors that can be returned in JSONParseError.Err
+var (
+	ErrJSONInvalidObjectType = errors.New("invalid object type, expecting 'uri', 'bnode' or 'literal'")
+	ErrJSONInvalidBlankNode  = errors.New("invalid blank node, expecting '_:' prefix")
+	ErrJSONLangAndDatatype   = errors.New("literal has both a language and a datatype")
+	ErrJSONUnexpectedLang    = errors.New("non-literal object has a language or datatype")
+)
+
+// A jsonObject is the representation of an object term in RDF/JSON.
+type jsonObject struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Lang     string `json:"lang,omitempty"`
+	Datatype string `json:"datatype,omitempty"`
+}
+
+// jsonKey returns the RDF/JSON representation of a subject or predicate.
+func jsonKey(term Term) string {
+	switch t := term.(type) {
+	case *Resource:
+		return t.URI
+	case *BlankNode:
+		return "_:" + t.ID
+	}
+
+	return term.String()
+}
+
+// jsonTerm converts a subject key back into a term, checking that resources are valid IRIs.
+func jsonTerm(key string) (term Term, err error) {
+	if len(key) >= 2 && key[0] == '_' && key[1] == ':' {
+		return NewBlankNode(key[2:]), nil
 	}
 
-	_, err := fmt.Fprint(w, "{")
+	return NewCheckedResource(key)
+}
+
+// Function ParseJSON parses RDF/JSON from r and sends the resulting triples on tripleChan and errors
+// on errChan. Subjects and predicates are visited in lexicographical order. Both channels are closed
+// when execution is done.
+func ParseJSON(r io.Reader, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(tripleChan)
+	defer close(errChan)
+
+	var doc map[string]map[string][]jsonObject
+
+	err := json.NewDecoder(r).Decode(&doc)
 	if err != nil {
-		errChan <- err
+		errChan <- &JSONParseError{Err: err}
 		return
 	}
 
-	restSubject := false
+	subjects := make([]string, 0, len(doc))
+	for subject := range doc {
+		subjects = append(subjects, subject)
+	}
 
-	for subject, predicates := range store.index {
-		if restSubject {
-			_, err = fmt.Fprint(w, ",")
-			if err != nil {
-				errChan <- err
-				return
-			}
+	sort.Strings(subjects)
 
-		} else {
-			restSubject = true
+	for _, subject := range subjects {
+		predicates := make([]string, 0, len(doc[subject]))
+		for predicate := range doc[subject] {
+			predicates = append(predicates, predicate)
 		}
 
-		_, err = fmt.Fprintf(w, "'%s':{", subject)
+		sort.Strings(predicates)
+
+		subjectTerm, err := jsonTerm(subject)
 		if err != nil {
-			errChan <- err
+			errChan <- &JSONParseError{Subject: subject, Err: err}
 			return
 		}
 
-		restPredicate := false
+		for _, predicate := range predicates {
+			predicateTerm, err := NewCheckedResource(predicate)
+			if err != nil {
+				errChan <- &JSONParseError{Subject: subject, Predicate: predicate, Err: err}
+				return
+			}
 
-		for predicate, objects := range predicates {
-			if restPredicate {
-				_, err = fmt.Fprint(w, ",")
+			for _, o := range doc[subject][predicate] {
+				object, err := o.term()
 				if err != nil {
-					errChan <- err
+					errChan <- &JSONParseError{Subject: subject, Predicate: predicate, Err: err}
 					return
 				}
 
-			} else {
-				restPredicate = true
+				tripleChan <- NewTriple(subjectTerm, predicateTerm, object)
 			}
+		}
+	}
+}
 
-			_, err = fmt.Fprintf(w, "'%s':[", predicate)
-			if err != nil {
-				errChan <- err
-				return
-			}
+// Method term converts an RDF/JSON object into the term it represents.
+func (o jsonObject) term() (term Term, err error) {
+	if o.Type != "literal" && (o.Lang != "" || o.Datatype != "") {
+		return nil, ErrJSONUnexpectedLang
+	}
 
-			restObject := false
+	switch o.Type {
+	case "uri":
+		return NewCheckedResource(o.Value)
 
-			for _, object := range objects {
-				if restObject {
-					_, err = fmt.Fprint(w, ",")
-					if err != nil {
-						errChan <- err
-						return
-					}
+	case "bnode":
+		if len(o.Value) < 2 || o.Value[:2] != "_:" {
+			return nil, ErrJSONInvalidBlankNode
+		}
 
-				} else {
-					restObject = true
-				}
+		return NewBlankNode(o.Value[2:]), nil
 
-				switch o := object.(type) {
-				case *Resource:
-					_, err = fmt.Fprintf(w, "{'type':'uri','value':'%s'}", o.URI)
-
-				case *BlankNode:
-					_, err = fmt.Fprintf(w, "{'type':'bnode','value':'_:%s'}", o.ID)
-
-				case *Literal:
-					if o.Language != "" {
-						_, err = fmt.Fprintf(w, "{'type':'literal','value':'%s','lang':'%s'}", o.Value, o.Language)
-					} else if o.Datatype != nil {
-						_, err = fmt.Fprintf(w, "{'type':'literal','value':'%s','datatype':'%s'}", o.Value, o.Datatype.(*Resource).URI)
-					} else {
-						_, err = fmt.Fprintf(w, "{'type':'literal','value':'%s'}", o.Value)
-					}
-				}
+	case "literal":
+		if o.Lang != "" && o.Datatype != "" {
+			return nil, ErrJSONLangAndDatatype
+		}
 
-				if err != nil {
-					errChan <- err
-					return
-				}
-			}
+		if o.Lang != "" {
+			return NewLiteralWithLanguage(o.Value, o.Lang), nil
+		}
 
-			_, err = fmt.Fprint(w, "]")
+		if o.Datatype != "" {
+			datatype, err := NewCheckedResource(o.Datatype)
 			if err != nil {
-				errChan <- err
-				return
+				return nil, err
 			}
+
+			return NewLiteralWithDatatype(o.Value, datatype), nil
 		}
 
-		_, err = fmt.Fprint(w, "}")
-		if err != nil {
-			errChan <- err
-			return
+		return NewLiteral(o.Value), nil
+	}
+
+	return nil, ErrJSONInvalidObjectType
+}
+
+// Function SerializeJSON serializes the triples received on tripleChan as RDF/JSON and writes the
+// result to w. Subjects and predicates are written in lexicographical order, and objects in the order
+// they were received. errChan is closed when execution is done.
+func SerializeJSON(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(errChan)
+
+	doc := make(map[string]map[string][]jsonObject)
+
+	for triple := range tripleChan {
+		subject := jsonKey(triple.Subject)
+		predicates, ok := doc[subject]
+		if !ok {
+			predicates = make(map[string][]jsonObject)
+			doc[subject] = predicates
 		}
+
+		predicate := jsonKey(triple.Predicate)
+		var o jsonObject
+
+		switch object := triple.Object.(type) {
+		case *Resource:
+			o = jsonObject{Type: "uri", Value: object.URI}
+
+		case *BlankNode:
+			o = jsonObject{Type: "bnode", Value: "_:" + object.ID}
+
+		case *Literal:
+			o = jsonObject{Type: "literal", Value: object.Value, Lang: object.Language}
+			if object.Language == "" && object.Datatype != nil {
+				o.Datatype = jsonKey(object.Datatype)
+			}
+		}
+
+		predicates[predicate] = append(predicates[predicate], o)
 	}
 
-	_, err = fmt.Fprint(w, "}")
+	// encoding/json writes map keys in sorted order, so the output is deterministic.
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	err := encoder.Encode(doc)
 	if err != nil {
 		errChan <- err
-		return
 	}
 }
@@ -0,0 +1,109 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	s := NewResource("http://example.org/s")
+	p := NewResource("http://example.org/p")
+	b := NewBlankNode("b1")
+
+	triples := []*Triple{
+		NewTriple(s, p, NewResource("http://example.org/o")),
+		NewTriple(s, p, b),
+		NewTriple(s, p, NewLiteral("plain \"quoted\" 'text'")),
+		NewTriple(s, p, NewLiteralWithLanguage("chat", "fr")),
+		NewTriple(b, p, NewLiteralWithDatatype("1", XSD.Get("integer"))),
+	}
+
+	graph := NewGraph(NewListStore())
+	for _, triple := range triples {
+		graph.Add(triple)
+	}
+
+	var buf bytes.Buffer
+	err := graph.Serialize(SerializeJSON, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expected := `{"_:b1":{"http://example.org/p":[{"type":"literal","value":"1","datatype":"http://www.w3.org/2001/XMLSchema#integer"}]},` +
+		`"http://example.org/s":{"http://example.org/p":[{"type":"uri","value":"http://example.org/o"},{"type":"bnode","value":"_:b1"},` +
+		`{"type":"literal","value":"plain \"quoted\" 'text'"},{"type":"literal","value":"chat","lang":"fr"}]}}` + "\n"
+
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	parsed := NewGraph(NewListStore())
+	err = parsed.Parse(ParseJSON, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if parsed.Num() != len(triples) {
+		t.Errorf("Expected %d triples but got %d", len(triples), parsed.Num())
+	}
+
+	var got []*Triple
+	for triple := range parsed.IterTriples() {
+		got = append(got, triple)
+	}
+
+	for _, triple := range triples {
+		found := false
+		for _, g := range got {
+			if g.Equal(triple) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("Expected %s to be parsed", triple)
+		}
+	}
+}
+
+var jsonNegativeCases = map[string]error{
+	`{"http://example.org/s": {"http://example.org/p": [{"type": "thing", "value": "x"}]}}`:                                  ErrJSONInvalidObjectType,
+	`{"http://example.org/s": {"http://example.org/p": [{"type": "bnode", "value": "x"}]}}`:                                  ErrJSONInvalidBlankNode,
+	`{"http://example.org/s": {"http://example.org/p": [{"type": "literal", "value": "x", "lang": "en", "datatype": "d"}]}}`: ErrJSONLangAndDatatype,
+	`{"http://example.org/s": {"http://example.org/p": [{"type": "uri", "value": "x", "lang": "en"}]}}`:                      ErrJSONUnexpectedLang,
+}
+
+func TestJSONParseErrors(t *testing.T) {
+	for doc, expected := range jsonNegativeCases {
+		err := NewGraph(NewListStore()).Parse(ParseJSON, strings.NewReader(doc))
+
+		if pe, ok := err.(*JSONParseError); !ok || pe.Err != expected {
+			t.Errorf("Expected %s for %q but got %v", expected, doc, err)
+		}
+	}
+
+	err := NewGraph(NewListStore()).Parse(ParseJSON, strings.NewReader(`{'single': 'quotes'}`))
+	if err == nil {
+		t.Errorf("Expected an error for invalid JSON")
+	}
+}
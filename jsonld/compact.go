@@ -0,0 +1,701 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package jsonld
+
+import (
+	"sort"
+	"strings"
+)
+
+// compact implements the compaction algorithm. An empty activeProperty stands for null.
+func (p *processor) compact(active *context, activeProperty string, element interface{}, insideReverse bool) (result interface{}, err error) {
+	compactArrays := !p.opts.KeepArrays
+
+	switch e := element.(type) {
+	case []interface{}:
+		items := []interface{}{}
+
+		for _, item := range e {
+			compacted, err := p.compact(active, activeProperty, item, insideReverse)
+			if err != nil {
+				return nil, err
+			}
+
+			if compacted != nil {
+				items = append(items, compacted)
+			}
+		}
+
+		def := active.terms[activeProperty]
+		if len(items) == 1 && compactArrays && activeProperty != "@graph" && activeProperty != "@set" &&
+			!def.hasContainer("@list") && !def.hasContainer("@set") {
+			return items[0], nil
+		}
+
+		return items, nil
+
+	case map[string]interface{}:
+		return p.compactMap(active, activeProperty, e, insideReverse)
+	}
+
+	return element, nil
+}
+
+// compactMap compacts an expanded JSON object.
+func (p *processor) compactMap(active *context, activeProperty string, element map[string]interface{}, insideReverse bool) (interface{}, error) {
+	var err error
+	compactArrays := !p.opts.KeepArrays
+
+	// Contexts that do not propagate are reverted on entering a new node object.
+	if active.previous != nil && !isValueObject(element) && !isNodeReference(element) {
+		active = active.previous
+	}
+
+	def := active.terms[activeProperty]
+	if def != nil && def.hasContext {
+		active, err = p.processContext(active, def.context, def.baseURL, nil, true, true)
+		if err != nil {
+			return nil, err
+		}
+
+		def = active.terms[activeProperty]
+	}
+
+	if isValueObject(element) || isNodeReference(element) {
+		compacted := p.compactValue(active, activeProperty, element)
+		if _, ok := compacted.(map[string]interface{}); !ok || (def != nil && def.typ == "@json") {
+			return compacted, nil
+		}
+	}
+
+	if isListObject(element) && def.hasContainer("@list") {
+		return p.compact(active, activeProperty, element["@list"], false)
+	}
+
+	insideReverse = activeProperty == "@reverse"
+	result := make(map[string]interface{})
+	typeScoped := active
+
+	if types, ok := element["@type"]; ok {
+		var compactedTypes []string
+		for _, t := range asArray(types) {
+			if s, ok := t.(string); ok {
+				compactedTypes = append(compactedTypes, p.compactIRI(typeScoped, s, nil, true, false))
+			}
+		}
+
+		sort.Strings(compactedTypes)
+
+		for _, term := range compactedTypes {
+			if tdef := typeScoped.terms[term]; tdef != nil && tdef.hasContext {
+				active, err = p.processContext(active, tdef.context, tdef.baseURL, nil, false, false)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, expandedProperty := range sortedKeys(element) {
+		expandedValue := element[expandedProperty]
+
+		switch expandedProperty {
+		case "@id":
+			s, _ := expandedValue.(string)
+			result[active.compactKeyword("@id")] = p.compactIRI(active, s, nil, false, false)
+			continue
+
+		case "@type":
+			var compactedTypes []interface{}
+			for _, t := range asArray(expandedValue) {
+				s, _ := t.(string)
+				compactedTypes = append(compactedTypes, p.compactIRI(typeScoped, s, nil, true, false))
+			}
+
+			alias := active.compactKeyword("@type")
+			if len(compactedTypes) == 1 && compactArrays && !active.terms[alias].hasContainer("@set") {
+				result[alias] = compactedTypes[0]
+			} else {
+				result[alias] = compactedTypes
+			}
+
+			continue
+
+		case "@reverse":
+			compacted, err := p.compact(active, "@reverse", expandedValue, true)
+			if err != nil {
+				return nil, err
+			}
+
+			reverseMap, _ := compacted.(map[string]interface{})
+
+			for _, property := range sortedKeys(reverseMap) {
+				if pdef := active.terms[property]; pdef != nil && pdef.reverse {
+					addCompactedValue(result, property, reverseMap[property], pdef.hasContainer("@set") || !compactArrays)
+					delete(reverseMap, property)
+				}
+			}
+
+			if len(reverseMap) > 0 {
+				result[active.compactKeyword("@reverse")] = reverseMap
+			}
+
+			continue
+
+		case "@preserve":
+			continue
+
+		case "@index":
+			if def.hasContainer("@index") {
+				continue
+			}
+
+			result[active.compactKeyword("@index")] = expandedValue
+			continue
+
+		case "@value", "@language", "@direction":
+			result[active.compactKeyword(expandedProperty)] = expandedValue
+			continue
+		}
+
+		items, isArray := expandedValue.([]interface{})
+		if !isArray {
+			items = []interface{}{expandedValue}
+		}
+
+		if len(items) == 0 {
+			itemActiveProperty := p.compactIRI(active, expandedProperty, expandedValue, true, insideReverse)
+			nestResult := p.nestResult(active, result, itemActiveProperty)
+
+			addCompactedValue(nestResult, itemActiveProperty, []interface{}{}, true)
+			continue
+		}
+
+		for _, item := range items {
+			itemActiveProperty := p.compactIRI(active, expandedProperty, item, true, insideReverse)
+			idef := active.terms[itemActiveProperty]
+			nestResult := p.nestResult(active, result, itemActiveProperty)
+
+			asArray := !compactArrays || idef.hasContainer("@set") || idef.hasContainer("@list") ||
+				expandedProperty == "@list" || expandedProperty == "@graph"
+
+			var elementToCompact interface{} = item
+			if isListObject(item) {
+				elementToCompact = item.(map[string]interface{})["@list"]
+			} else if isGraphObject(item) && idef.hasContainer("@graph") {
+				elementToCompact = item.(map[string]interface{})["@graph"]
+			}
+
+			compactedItem, err := p.compact(active, itemActiveProperty, elementToCompact, false)
+			if err != nil {
+				return nil, err
+			}
+
+			itemMap, _ := item.(map[string]interface{})
+
+			switch {
+			case isListObject(item):
+				compactedItem = toArray(compactedItem)
+
+				if !idef.hasContainer("@list") {
+					wrapped := map[string]interface{}{active.compactKeyword("@list"): compactedItem}
+					if index, ok := itemMap["@index"]; ok {
+						wrapped[active.compactKeyword("@index")] = index
+					}
+
+					addCompactedValue(nestResult, itemActiveProperty, wrapped, asArray)
+				} else {
+					nestResult[itemActiveProperty] = compactedItem
+				}
+
+			case isGraphObject(item) && idef.hasContainer("@graph"):
+				if idef.hasContainer("@id") || idef.hasContainer("@index") {
+					key, _ := itemMap["@index"].(string)
+
+					if idef.hasContainer("@id") {
+						id, _ := itemMap["@id"].(string)
+						key = p.compactIRI(active, id, nil, false, false)
+					}
+
+					if key == "" {
+						key = p.compactIRI(active, "@none", nil, true, false)
+					}
+
+					mapObject := p.mapObject(nestResult, itemActiveProperty)
+					addCompactedValue(mapObject, key, compactedItem, asArray)
+
+				} else {
+					if a, ok := compactedItem.([]interface{}); ok && len(a) > 1 {
+						compactedItem = map[string]interface{}{active.compactKeyword("@included"): a}
+					}
+
+					addCompactedValue(nestResult, itemActiveProperty, compactedItem, asArray)
+				}
+
+			case isGraphObject(item):
+				graph, err := p.compact(active, itemActiveProperty, itemMap["@graph"], false)
+				if err != nil {
+					return nil, err
+				}
+
+				wrapped := map[string]interface{}{active.compactKeyword("@graph"): toArray(graph)}
+				if id, ok := itemMap["@id"].(string); ok {
+					wrapped[active.compactKeyword("@id")] = p.compactIRI(active, id, nil, false, false)
+				}
+
+				if index, ok := itemMap["@index"]; ok {
+					wrapped[active.compactKeyword("@index")] = index
+				}
+
+				addCompactedValue(nestResult, itemActiveProperty, wrapped, asArray)
+
+			case idef.hasContainer("@language") || idef.hasContainer("@index") || idef.hasContainer("@id") || idef.hasContainer("@type"):
+				mapObject := p.mapObject(nestResult, itemActiveProperty)
+				var key string
+
+				switch {
+				case idef.hasContainer("@language"):
+					if isValueObject(item) {
+						compactedItem = itemMap["@value"]
+					}
+
+					key, _ = itemMap["@language"].(string)
+
+				case idef.hasContainer("@index"):
+					key, _ = itemMap["@index"].(string)
+
+				case idef.hasContainer("@id"):
+					if cm, ok := compactedItem.(map[string]interface{}); ok {
+						alias := active.compactKeyword("@id")
+						id, _ := cm[alias].(string)
+						delete(cm, alias)
+
+						if id != "" {
+							expanded, err := p.expandIRI(active, id, true, false, nil, nil)
+							if err != nil {
+								return nil, err
+							}
+
+							key = p.compactIRI(active, expanded, nil, false, false)
+						}
+					}
+
+				case idef.hasContainer("@type"):
+					if cm, ok := compactedItem.(map[string]interface{}); ok {
+						alias := active.compactKeyword("@type")
+						types := toArray(cm[alias])
+
+						if len(types) > 0 {
+							key, _ = types[0].(string)
+							types = types[1:]
+						}
+
+						if len(types) == 0 {
+							delete(cm, alias)
+						} else if len(types) == 1 && compactArrays {
+							cm[alias] = types[0]
+						} else {
+							cm[alias] = types
+						}
+
+						if id, ok := itemMap["@id"]; ok && len(cm) == 1 {
+							compactedItem, err = p.compact(active, itemActiveProperty, map[string]interface{}{"@id": id}, false)
+							if err != nil {
+								return nil, err
+							}
+						}
+					}
+				}
+
+				if key == "" {
+					key = p.compactIRI(active, "@none", nil, true, false)
+				}
+
+				addCompactedValue(mapObject, key, compactedItem, asArray)
+
+			default:
+				addCompactedValue(nestResult, itemActiveProperty, compactedItem, asArray)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// nestResult returns the object into which values of the given term are to be placed, taking
+// @nest into account.
+func (p *processor) nestResult(active *context, result map[string]interface{}, term string) map[string]interface{} {
+	def := active.terms[term]
+	if def == nil || def.nest == "" {
+		return result
+	}
+
+	return p.mapObject(result, def.nest)
+}
+
+// mapObject returns the object stored under key in m, creating it if necessary.
+func (p *processor) mapObject(m map[string]interface{}, key string) map[string]interface{} {
+	object, ok := m[key].(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{})
+		m[key] = object
+	}
+
+	return object
+}
+
+// compactValue compacts a value object or node reference, returning a scalar where the term
+// definition allows the value to be recovered on expansion.
+func (p *processor) compactValue(active *context, activeProperty string, value map[string]interface{}) interface{} {
+	def := active.terms[activeProperty]
+
+	_, hasIndex := value["@index"]
+	indexed := hasIndex && def.hasContainer("@index")
+	size := len(value)
+	if indexed {
+		size--
+	}
+
+	if isNodeReference(value) {
+		id, _ := value["@id"].(string)
+
+		if def != nil && def.typ == "@id" && size == 1 {
+			return p.compactIRI(active, id, nil, false, false)
+		}
+
+		if def != nil && def.typ == "@vocab" && size == 1 {
+			return p.compactIRI(active, id, nil, true, false)
+		}
+
+		return value
+	}
+
+	v := value["@value"]
+	typ, hasType := value["@type"].(string)
+	language, hasLanguage := value["@language"].(string)
+	direction, hasDirection := value["@direction"].(string)
+
+	termLanguage, termDirection := active.language, active.direction
+	if def != nil && def.hasLanguage {
+		termLanguage = def.language
+	}
+
+	if def != nil && def.hasDirection {
+		termDirection = def.direction
+	}
+
+	var termType string
+	if def != nil {
+		termType = def.typ
+	}
+
+	if hasType && termType == typ && size == 2 {
+		return v
+	}
+
+	if termType == "@json" && typ == "@json" {
+		return v
+	}
+
+	if !hasType && (termType == "" || termType == "@none") && (!hasIndex || indexed) {
+		_, isString := v.(string)
+
+		switch {
+		case !isString && !hasLanguage && !hasDirection && size == 1:
+			return v
+		case isString && size == 1+btoi(hasLanguage)+btoi(hasDirection) &&
+			strings.EqualFold(language, termLanguage) && direction == termDirection:
+			return v
+		}
+	}
+
+	result := make(map[string]interface{})
+	result[active.compactKeyword("@value")] = v
+
+	if hasType {
+		result[active.compactKeyword("@type")] = p.compactIRI(active, typ, nil, true, false)
+	}
+
+	if hasLanguage {
+		result[active.compactKeyword("@language")] = language
+	}
+
+	if hasDirection {
+		result[active.compactKeyword("@direction")] = direction
+	}
+
+	if hasIndex && !indexed {
+		result[active.compactKeyword("@index")] = value["@index"]
+	}
+
+	return result
+}
+
+// compactIRI compacts an IRI to a term, compact IRI or relative IRI. If vocab is true, terms and the
+// vocabulary mapping may be used; value is the value the IRI is a property of, used to choose
+// between several terms mapping to the same IRI.
+func (p *processor) compactIRI(active *context, iri string, value interface{}, vocab bool, reverse bool) string {
+	if iri == "" {
+		return ""
+	}
+
+	if vocab {
+		if isKeyword(iri) {
+			return active.compactKeyword(iri)
+		}
+
+		best, bestScore := "", -1
+
+		for term, def := range active.terms {
+			if def.id != iri || def.reverse != reverse {
+				continue
+			}
+
+			score := termScore(active, def, value)
+			if score < 0 {
+				continue
+			}
+
+			if score > bestScore || (score == bestScore && (len(term) < len(best) || (len(term) == len(best) && term < best))) {
+				best, bestScore = term, score
+			}
+		}
+
+		if best != "" {
+			return best
+		}
+
+		if active.vocab != "" && strings.HasPrefix(iri, active.vocab) && len(iri) > len(active.vocab) {
+			suffix := iri[len(active.vocab):]
+			if _, ok := active.terms[suffix]; !ok {
+				return suffix
+			}
+		}
+	}
+
+	best := ""
+
+	for term, def := range active.terms {
+		if def.id == "" || !def.prefix || def.id == iri || !strings.HasPrefix(iri, def.id) {
+			continue
+		}
+
+		candidate := term + ":" + iri[len(def.id):]
+
+		if cdef, ok := active.terms[candidate]; ok && !(cdef.id == iri && value == nil) {
+			continue
+		}
+
+		if best == "" || len(candidate) < len(best) || (len(candidate) == len(best) && candidate < best) {
+			best = candidate
+		}
+	}
+
+	if best != "" {
+		return best
+	}
+
+	if !vocab {
+		return relativeIRI(active.base, iri)
+	}
+
+	return iri
+}
+
+// termScore rates how well a term definition fits a value, for choosing among terms mapping to the
+// same IRI. A negative score means the term cannot be used for the value.
+func termScore(active *context, def *termDefinition, value interface{}) (score int) {
+	m, isMap := value.(map[string]interface{})
+
+	if def.hasContainer("@list") != isListObject(value) {
+		if def.hasContainer("@list") || !isMap {
+			return -1
+		}
+
+		score--
+	}
+
+	if def.hasContainer("@graph") && !isGraphObject(value) {
+		return -1
+	}
+
+	if isGraphObject(value) && def.hasContainer("@graph") {
+		score += 2
+	}
+
+	if isListObject(value) {
+		items := toArray(m["@list"])
+		if len(items) == 0 {
+			return score + 1
+		}
+
+		value = items[0]
+		m, isMap = value.(map[string]interface{})
+	}
+
+	if !isMap {
+		if def.typ == "" && !def.hasLanguage && len(def.container) == 0 {
+			return score + 1
+		}
+
+		return score
+	}
+
+	if _, ok := m["@index"]; ok && def.hasContainer("@index") {
+		score++
+	}
+
+	if isValueObject(m) {
+		typ, hasType := m["@type"].(string)
+		language, hasLanguage := m["@language"].(string)
+		_, isString := m["@value"].(string)
+
+		switch {
+		case hasType:
+			if def.typ == typ {
+				return score + 3
+			}
+
+			if def.typ != "" || def.hasContainer("@language") || (def.hasLanguage && def.language != "") {
+				return -1
+			}
+
+		case hasLanguage:
+			if def.typ != "" {
+				return -1
+			}
+
+			if def.hasContainer("@language") {
+				return score + 2
+			}
+
+			if def.hasLanguage {
+				if !strings.EqualFold(def.language, language) {
+					return -1
+				}
+
+				return score + 3
+			}
+
+		case isString:
+			if def.typ != "" {
+				return -1
+			}
+
+			if def.hasLanguage && def.language == "" {
+				return score + 3
+			}
+
+			if def.hasLanguage || (def.hasContainer("@language") && active.language != "") {
+				return -1
+			}
+
+		default:
+			if def.typ != "" || def.hasLanguage {
+				return -1
+			}
+		}
+
+		if def.hasContainer("@id") || def.hasContainer("@type") {
+			return -1
+		}
+
+		return score + 1
+	}
+
+	if def.hasContainer("@language") || (def.typ != "" && def.typ != "@id" && def.typ != "@vocab") {
+		return -1
+	}
+
+	if def.typ == "@id" || def.typ == "@vocab" {
+		if isNodeReference(m) {
+			return score + 3
+		}
+
+		return score
+	}
+
+	if _, ok := m["@id"]; ok && def.hasContainer("@id") {
+		score += 2
+	}
+
+	if _, ok := m["@type"]; ok && def.hasContainer("@type") {
+		score += 2
+	}
+
+	return score + 1
+}
+
+// isNodeReference returns whether v is a node object containing only @id (and possibly @index).
+func isNodeReference(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	if _, ok := m["@id"]; !ok {
+		return false
+	}
+
+	_, hasIndex := m["@index"]
+	return len(m) == 1 || (len(m) == 2 && hasIndex)
+}
+
+// addCompactedValue adds value under key in m. If asArray is true or the key already has a value,
+// the values are collected into an array.
+func addCompactedValue(m map[string]interface{}, key string, value interface{}, asArray bool) {
+	existing, exists := m[key]
+
+	if !exists && !asArray {
+		m[key] = value
+		return
+	}
+
+	values := []interface{}{}
+	if exists {
+		values = append(values, toArray(existing)...)
+	}
+
+	if a, ok := value.([]interface{}); ok {
+		values = append(values, a...)
+	} else {
+		values = append(values, value)
+	}
+
+	m[key] = values
+}
+
+// toArray wraps v in an array if it is not already one; nil becomes an empty array.
+func toArray(v interface{}) []interface{} {
+	if v == nil {
+		return []interface{}{}
+	}
+
+	return asArray(v)
+}
+
+// btoi converts a bool to 0 or 1.
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
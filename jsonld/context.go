@@ -0,0 +1,724 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package jsonld
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// The maximum depth of remote context inclusion.
+const maxRemoteContexts = 32
+
+// A context is an active context: the term definitions and defaults in effect at some point of a
+// document.
+type context struct {
+	base      string
+	vocab     string
+	language  string
+	direction string
+	terms     map[string]*termDefinition
+	previous  *context
+}
+
+// A termDefinition maps a term to an IRI and records how its values are to be interpreted.
+type termDefinition struct {
+	id           string
+	reverse      bool
+	typ          string
+	language     string
+	hasLanguage  bool
+	direction    string
+	hasDirection bool
+	container    map[string]bool
+	context      interface{}
+	hasContext   bool
+	baseURL      string
+	prefix       bool
+	protected    bool
+	index        string
+	nest         string
+}
+
+// newContext returns an empty active context with the given base IRI.
+func newContext(base string) *context {
+	return &context{
+		base:  base,
+		terms: make(map[string]*termDefinition),
+	}
+}
+
+// clone returns a copy of the context that may be modified independently.
+func (c *context) clone() *context {
+	n := *c
+	n.terms = make(map[string]*termDefinition, len(c.terms))
+
+	for term, def := range c.terms {
+		n.terms[term] = def
+	}
+
+	return &n
+}
+
+// hasContainer returns whether the term's container mapping includes the given keyword.
+func (def *termDefinition) hasContainer(container string) bool {
+	return def != nil && def.container[container]
+}
+
+// equal returns whether two term definitions are the same, ignoring protection.
+func (def *termDefinition) equal(other *termDefinition) bool {
+	if def.id != other.id || def.reverse != other.reverse || def.typ != other.typ ||
+		def.language != other.language || def.hasLanguage != other.hasLanguage ||
+		def.direction != other.direction || def.prefix != other.prefix ||
+		def.index != other.index || def.nest != other.nest || len(def.container) != len(other.container) ||
+		def.hasContext != other.hasContext || (def.hasContext && !deepEqual(def.context, other.context)) {
+		return false
+	}
+
+	for c := range def.container {
+		if !other.container[c] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// A processor holds the options shared by the algorithms while processing a document.
+type processor struct {
+	opts *Options
+}
+
+// processContext applies a local context to the active context and returns the result.
+func (p *processor) processContext(active *context, local interface{}, baseURL string, remoteContexts []string, overrideProtected bool, propagate bool) (result *context, err error) {
+	result = active.clone()
+
+	if m, ok := local.(map[string]interface{}); ok {
+		if prop, ok := m["@propagate"]; ok {
+			b, ok := prop.(bool)
+			if !ok {
+				return nil, newError("invalid @propagate value", "%v", prop)
+			}
+
+			propagate = b
+		}
+	}
+
+	if !propagate && result.previous == nil {
+		result.previous = active
+	}
+
+	for _, item := range asArray(local) {
+		switch ctx := item.(type) {
+		case nil:
+			if !overrideProtected {
+				for term, def := range result.terms {
+					if def.protected {
+						return nil, newError("invalid context nullification", "term %s is protected", term)
+					}
+				}
+			}
+
+			previous := result.previous
+			result = newContext(p.opts.Base)
+			if !propagate {
+				result.previous = previous
+			}
+
+		case string:
+			ctxURL := resolveIRI(baseURL, ctx)
+
+			for _, remote := range remoteContexts {
+				if remote == ctxURL {
+					return nil, newError("recursive context inclusion", "%s", ctxURL)
+				}
+			}
+
+			if len(remoteContexts) >= maxRemoteContexts {
+				return nil, newError("context overflow", "%s", ctxURL)
+			}
+
+			doc, err := p.opts.documentLoader().LoadDocument(ctxURL)
+			if err != nil {
+				return nil, newError("loading remote context failed", "%s", err)
+			}
+
+			m, ok := doc.Document.(map[string]interface{})
+			if !ok {
+				return nil, newError("invalid remote context", "%s", ctxURL)
+			}
+
+			inner, ok := m["@context"]
+			if !ok {
+				return nil, newError("invalid remote context", "%s has no @context", ctxURL)
+			}
+
+			result, err = p.processContext(result, inner, doc.DocumentURL, append(remoteContexts, ctxURL), overrideProtected, true)
+			if err != nil {
+				return nil, err
+			}
+
+		case map[string]interface{}:
+			result, err = p.processLocalContext(result, ctx, baseURL, remoteContexts, overrideProtected)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, newError("invalid local context", "%v", item)
+		}
+	}
+
+	return result, nil
+}
+
+// processLocalContext applies a single context definition object to result.
+func (p *processor) processLocalContext(result *context, ctx map[string]interface{}, baseURL string, remoteContexts []string, overrideProtected bool) (*context, error) {
+	if version, ok := ctx["@version"]; ok {
+		if n, ok := version.(json.Number); !ok || n.String() != "1.1" {
+			if f, ok := version.(float64); !ok || f != 1.1 {
+				return nil, newError("invalid @version value", "%v", version)
+			}
+		}
+	}
+
+	if imp, ok := ctx["@import"]; ok {
+		s, ok := imp.(string)
+		if !ok {
+			return nil, newError("invalid @import value", "%v", imp)
+		}
+
+		doc, err := p.opts.documentLoader().LoadDocument(resolveIRI(baseURL, s))
+		if err != nil {
+			return nil, newError("loading remote context failed", "%s", err)
+		}
+
+		m, _ := doc.Document.(map[string]interface{})
+		imported, ok := m["@context"].(map[string]interface{})
+		if !ok {
+			return nil, newError("invalid remote context", "%s", s)
+		}
+
+		if _, ok := imported["@import"]; ok {
+			return nil, newError("invalid context entry", "@import in imported context")
+		}
+
+		merged := make(map[string]interface{}, len(imported)+len(ctx))
+		for k, v := range imported {
+			merged[k] = v
+		}
+
+		for k, v := range ctx {
+			if k != "@import" {
+				merged[k] = v
+			}
+		}
+
+		ctx = merged
+	}
+
+	if base, ok := ctx["@base"]; ok && len(remoteContexts) == 0 {
+		switch b := base.(type) {
+		case nil:
+			result.base = ""
+		case string:
+			if isAbsoluteIRI(b) {
+				result.base = b
+			} else if result.base != "" {
+				result.base = resolveIRI(result.base, b)
+			} else {
+				return nil, newError("invalid base IRI", "%s", b)
+			}
+		default:
+			return nil, newError("invalid base IRI", "%v", base)
+		}
+	}
+
+	if vocab, ok := ctx["@vocab"]; ok {
+		switch v := vocab.(type) {
+		case nil:
+			result.vocab = ""
+		case string:
+			if v == "" || isBlankNodeID(v) || isAbsoluteIRI(v) || result.base != "" {
+				expanded, err := p.expandIRI(result, v, true, true, nil, nil)
+				if err != nil {
+					return nil, err
+				}
+
+				result.vocab = expanded
+			} else {
+				return nil, newError("invalid vocab mapping", "%s", v)
+			}
+		default:
+			return nil, newError("invalid vocab mapping", "%v", vocab)
+		}
+	}
+
+	if language, ok := ctx["@language"]; ok {
+		switch l := language.(type) {
+		case nil:
+			result.language = ""
+		case string:
+			result.language = l
+		default:
+			return nil, newError("invalid default language", "%v", language)
+		}
+	}
+
+	if direction, ok := ctx["@direction"]; ok {
+		switch d := direction.(type) {
+		case nil:
+			result.direction = ""
+		case string:
+			if d != "ltr" && d != "rtl" {
+				return nil, newError("invalid base direction", "%s", d)
+			}
+
+			result.direction = d
+		default:
+			return nil, newError("invalid base direction", "%v", direction)
+		}
+	}
+
+	protected := false
+	if prot, ok := ctx["@protected"]; ok {
+		b, ok := prot.(bool)
+		if !ok {
+			return nil, newError("invalid @protected value", "%v", prot)
+		}
+
+		protected = b
+	}
+
+	defined := make(map[string]bool)
+
+	for _, term := range sortedKeys(ctx) {
+		switch term {
+		case "@base", "@direction", "@import", "@language", "@propagate", "@protected", "@version", "@vocab":
+			continue
+		}
+
+		err := p.createTermDefinition(result, ctx, term, defined, baseURL, protected, overrideProtected, remoteContexts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// createTermDefinition creates the definition of term in active from the local context.
+func (p *processor) createTermDefinition(active *context, local map[string]interface{}, term string, defined map[string]bool, baseURL string, protected bool, overrideProtected bool, remoteContexts []string) (err error) {
+	if done, ok := defined[term]; ok {
+		if done {
+			return nil
+		}
+
+		return newError("cyclic IRI mapping", "%s", term)
+	}
+
+	if term == "" {
+		return newError("invalid term definition", "empty term")
+	}
+
+	defined[term] = false
+	value := local[term]
+
+	if term == "@type" {
+		// Only @container: @set and @protected may be specified for @type.
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return newError("keyword redefinition", "%s", term)
+		}
+
+		for key, v := range m {
+			if !(key == "@container" && v == "@set") && key != "@protected" {
+				return newError("keyword redefinition", "%s", term)
+			}
+		}
+
+		defined[term] = true
+		return nil
+	}
+
+	if isKeyword(term) {
+		return newError("keyword redefinition", "%s", term)
+	}
+
+	if looksLikeKeyword(term) {
+		defined[term] = true
+		return nil
+	}
+
+	previous := active.terms[term]
+	delete(active.terms, term)
+
+	simpleTerm := false
+	var m map[string]interface{}
+
+	switch v := value.(type) {
+	case nil:
+		m = map[string]interface{}{"@id": nil}
+	case string:
+		m = map[string]interface{}{"@id": v}
+		simpleTerm = true
+	case map[string]interface{}:
+		m = v
+	default:
+		return newError("invalid term definition", "%s", term)
+	}
+
+	def := &termDefinition{protected: protected, container: make(map[string]bool)}
+
+	if prot, ok := m["@protected"]; ok {
+		b, ok := prot.(bool)
+		if !ok {
+			return newError("invalid @protected value", "%v", prot)
+		}
+
+		def.protected = b
+	}
+
+	if t, ok := m["@type"]; ok {
+		s, ok := t.(string)
+		if !ok {
+			return newError("invalid type mapping", "%v", t)
+		}
+
+		expanded, err := p.expandIRI(active, s, false, true, local, defined)
+		if err != nil {
+			return err
+		}
+
+		if expanded != "@id" && expanded != "@json" && expanded != "@none" && expanded != "@vocab" && !isAbsoluteIRI(expanded) {
+			return newError("invalid type mapping", "%s", s)
+		}
+
+		def.typ = expanded
+	}
+
+	if r, ok := m["@reverse"]; ok {
+		if _, ok := m["@id"]; ok {
+			return newError("invalid reverse property", "%s", term)
+		}
+
+		s, ok := r.(string)
+		if !ok {
+			return newError("invalid IRI mapping", "%v", r)
+		}
+
+		if looksLikeKeyword(s) {
+			defined[term] = true
+			return nil
+		}
+
+		id, err := p.expandIRI(active, s, false, true, local, defined)
+		if err != nil {
+			return err
+		}
+
+		if !strings.Contains(id, ":") {
+			return newError("invalid IRI mapping", "%s", s)
+		}
+
+		def.id = id
+		def.reverse = true
+
+		if c, ok := m["@container"]; ok {
+			if c != nil && c != "@set" && c != "@index" {
+				return newError("invalid reverse property", "%s", term)
+			}
+
+			if s, ok := c.(string); ok {
+				def.container[s] = true
+			}
+		}
+
+		active.terms[term] = def
+		defined[term] = true
+		return nil
+	}
+
+	if idValue, ok := m["@id"]; ok && idValue != term {
+		switch id := idValue.(type) {
+		case nil:
+			// The term is explicitly decoupled from any IRI, and so its values are ignored.
+
+		case string:
+			if !isKeyword(id) && looksLikeKeyword(id) {
+				defined[term] = true
+				return nil
+			}
+
+			expanded, err := p.expandIRI(active, id, false, true, local, defined)
+			if err != nil {
+				return err
+			}
+
+			if !isKeyword(expanded) && !strings.Contains(expanded, ":") {
+				return newError("invalid IRI mapping", "%s", id)
+			}
+
+			if expanded == "@context" {
+				return newError("invalid keyword alias", "%s", term)
+			}
+
+			def.id = expanded
+
+			if strings.Contains(strings.TrimRight(term[1:], ":"), ":") || strings.Contains(term, "/") {
+				defined[term] = true
+
+				termExpanded, err := p.expandIRI(active, term, false, true, local, defined)
+				if err != nil {
+					return err
+				}
+
+				if termExpanded != expanded {
+					return newError("invalid IRI mapping", "%s does not expand to %s", term, expanded)
+				}
+			}
+
+			if !strings.Contains(term, ":") && !strings.Contains(term, "/") && simpleTerm &&
+				(endsWithGenDelim(expanded) || isBlankNodeID(expanded)) {
+				def.prefix = true
+			}
+
+		default:
+			return newError("invalid IRI mapping", "%v", idValue)
+		}
+
+	} else if i := strings.Index(term, ":"); i > 0 {
+		prefix, suffix := term[:i], term[i+1:]
+
+		if _, ok := local[prefix]; ok {
+			err = p.createTermDefinition(active, local, prefix, defined, baseURL, protected, overrideProtected, remoteContexts)
+			if err != nil {
+				return err
+			}
+		}
+
+		if prefixDef, ok := active.terms[prefix]; ok && prefixDef.id != "" && !strings.HasPrefix(suffix, "//") {
+			def.id = prefixDef.id + suffix
+		} else {
+			def.id = term
+		}
+
+	} else if strings.Contains(term, "/") {
+		expanded, err := p.expandIRI(active, term, false, true, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		if !isAbsoluteIRI(expanded) {
+			return newError("invalid IRI mapping", "%s", term)
+		}
+
+		def.id = expanded
+
+	} else if active.vocab != "" {
+		def.id = active.vocab + term
+
+	} else {
+		return newError("invalid IRI mapping", "%s has no IRI and there is no vocabulary mapping", term)
+	}
+
+	if c, ok := m["@container"]; ok {
+		for _, item := range asArray(c) {
+			s, ok := item.(string)
+			if !ok {
+				return newError("invalid container mapping", "%v", c)
+			}
+
+			switch s {
+			case "@graph", "@id", "@index", "@language", "@list", "@set", "@type":
+				def.container[s] = true
+			default:
+				return newError("invalid container mapping", "%s", s)
+			}
+		}
+
+		if def.container["@list"] && len(def.container) > 1 {
+			return newError("invalid container mapping", "@list cannot be combined with other containers")
+		}
+	}
+
+	if index, ok := m["@index"]; ok {
+		s, ok := index.(string)
+		if !ok || !def.container["@index"] || isKeyword(s) {
+			return newError("invalid term definition", "invalid @index for %s", term)
+		}
+
+		def.index = s
+	}
+
+	if ctx, ok := m["@context"]; ok {
+		_, err = p.processContext(active, ctx, baseURL, remoteContexts, true, true)
+		if err != nil {
+			return newError("invalid scoped context", "%s", err)
+		}
+
+		def.context = ctx
+		def.hasContext = true
+		def.baseURL = baseURL
+	}
+
+	if language, ok := m["@language"]; ok {
+		if _, hasType := m["@type"]; !hasType {
+			switch l := language.(type) {
+			case nil:
+			case string:
+				def.language = l
+			default:
+				return newError("invalid language mapping", "%v", language)
+			}
+
+			def.hasLanguage = true
+		}
+	}
+
+	if direction, ok := m["@direction"]; ok {
+		if _, hasType := m["@type"]; !hasType {
+			switch d := direction.(type) {
+			case nil:
+			case string:
+				if d != "ltr" && d != "rtl" {
+					return newError("invalid base direction", "%s", d)
+				}
+
+				def.direction = d
+			default:
+				return newError("invalid base direction", "%v", direction)
+			}
+
+			def.hasDirection = true
+		}
+	}
+
+	if nest, ok := m["@nest"]; ok {
+		s, ok := nest.(string)
+		if !ok || (isKeyword(s) && s != "@nest") {
+			return newError("invalid @nest value", "%v", nest)
+		}
+
+		def.nest = s
+	}
+
+	if prefix, ok := m["@prefix"]; ok {
+		b, ok := prefix.(bool)
+		if !ok || strings.Contains(term, ":") || strings.Contains(term, "/") {
+			return newError("invalid term definition", "invalid @prefix for %s", term)
+		}
+
+		def.prefix = b
+	}
+
+	if previous != nil && previous.protected && !overrideProtected {
+		if !def.equal(previous) {
+			return newError("protected term redefinition", "%s", term)
+		}
+
+		def = previous
+	}
+
+	active.terms[term] = def
+	defined[term] = true
+	return nil
+}
+
+// expandIRI expands a string that may be a keyword, term, compact IRI, relative IRI or absolute IRI.
+// It returns the empty string if the value maps to null.
+func (p *processor) expandIRI(active *context, value string, documentRelative bool, vocab bool, local map[string]interface{}, defined map[string]bool) (iri string, err error) {
+	if isKeyword(value) {
+		return value, nil
+	}
+
+	if looksLikeKeyword(value) {
+		return "", nil
+	}
+
+	if local != nil {
+		if _, ok := local[value]; ok && !defined[value] {
+			err = p.createTermDefinition(active, local, value, defined, "", false, false, nil)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if def, ok := active.terms[value]; ok {
+		if isKeyword(def.id) {
+			return def.id, nil
+		}
+
+		if vocab {
+			return def.id, nil
+		}
+	}
+
+	if i := strings.Index(value, ":"); i > 0 {
+		prefix, suffix := value[:i], value[i+1:]
+
+		if prefix == "_" || strings.HasPrefix(suffix, "//") {
+			return value, nil
+		}
+
+		if local != nil {
+			if _, ok := local[prefix]; ok && !defined[prefix] {
+				err = p.createTermDefinition(active, local, prefix, defined, "", false, false, nil)
+				if err != nil {
+					return "", err
+				}
+			}
+		}
+
+		if def, ok := active.terms[prefix]; ok && def.id != "" && def.prefix {
+			return def.id + suffix, nil
+		}
+
+		if isAbsoluteIRI(value) {
+			return value, nil
+		}
+	}
+
+	if vocab && active.vocab != "" {
+		return active.vocab + value, nil
+	}
+
+	if documentRelative {
+		return resolveIRI(active.base, value), nil
+	}
+
+	return value, nil
+}
+
+// compactKeyword returns the shortest term aliasing the given keyword, or the keyword itself.
+func (c *context) compactKeyword(keyword string) string {
+	best := keyword
+
+	for term, def := range c.terms {
+		if def.id == keyword && !def.reverse {
+			if best == keyword || len(term) < len(best) || (len(term) == len(best) && term < best) {
+				best = term
+			}
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,658 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package jsonld
+
+import (
+	"sort"
+)
+
+// expand implements the expansion algorithm. An empty activeProperty stands for null.
+func (p *processor) expand(active *context, activeProperty string, element interface{}, baseURL string, fromMap bool) (result interface{}, err error) {
+	switch e := element.(type) {
+	case nil:
+		return nil, nil
+
+	case []interface{}:
+		def := active.terms[activeProperty]
+		items := []interface{}{}
+
+		for _, item := range e {
+			expanded, err := p.expand(active, activeProperty, item, baseURL, fromMap)
+			if err != nil {
+				return nil, err
+			}
+
+			if a, ok := expanded.([]interface{}); ok && def.hasContainer("@list") {
+				expanded = map[string]interface{}{"@list": a}
+			}
+
+			if a, ok := expanded.([]interface{}); ok {
+				items = append(items, a...)
+			} else if expanded != nil {
+				items = append(items, expanded)
+			}
+		}
+
+		return items, nil
+
+	case map[string]interface{}:
+		return p.expandMap(active, activeProperty, e, baseURL, fromMap)
+	}
+
+	// A free-floating scalar is dropped.
+	if activeProperty == "" || activeProperty == "@graph" {
+		return nil, nil
+	}
+
+	if def := active.terms[activeProperty]; def != nil && def.hasContext {
+		active, err = p.processContext(active, def.context, def.baseURL, nil, true, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p.expandValue(active, activeProperty, element)
+}
+
+// expandMap expands a JSON object.
+func (p *processor) expandMap(active *context, activeProperty string, element map[string]interface{}, baseURL string, fromMap bool) (interface{}, error) {
+	var err error
+	def := active.terms[activeProperty]
+
+	// Contexts that do not propagate are reverted on entering a new node object.
+	if active.previous != nil && !fromMap {
+		revert := true
+
+		for key := range element {
+			expanded, err := p.expandIRI(active, key, false, true, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			if expanded == "@value" || (expanded == "@id" && len(element) == 1) {
+				revert = false
+				break
+			}
+		}
+
+		if revert {
+			active = active.previous
+		}
+	}
+
+	if def != nil && def.hasContext {
+		active, err = p.processContext(active, def.context, def.baseURL, nil, true, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ctx, ok := element["@context"]; ok {
+		active, err = p.processContext(active, ctx, baseURL, nil, false, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	typeScoped := active
+
+	for _, key := range sortedKeys(element) {
+		expanded, err := p.expandIRI(active, key, false, true, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if expanded != "@type" {
+			continue
+		}
+
+		var types []string
+		for _, t := range asArray(element[key]) {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+
+		sort.Strings(types)
+
+		for _, t := range types {
+			if tdef := typeScoped.terms[t]; tdef != nil && tdef.hasContext {
+				active, err = p.processContext(active, tdef.context, tdef.baseURL, nil, false, false)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	result := make(map[string]interface{})
+
+	err = p.expandObject(active, typeScoped, activeProperty, element, result, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := result["@value"]; ok {
+		for key := range result {
+			switch key {
+			case "@value", "@language", "@type", "@index", "@direction":
+			default:
+				return nil, newError("invalid value object", "unexpected %s", key)
+			}
+		}
+
+		_, hasLanguage := result["@language"]
+		_, hasDirection := result["@direction"]
+		typ, hasType := result["@type"]
+
+		if hasType && (hasLanguage || hasDirection) {
+			return nil, newError("invalid value object", "both @type and @language")
+		}
+
+		if typ == "@json" {
+			return result, nil
+		}
+
+		if value == nil {
+			return nil, nil
+		}
+
+		if _, ok := value.(string); !ok && hasLanguage {
+			return nil, newError("invalid language-tagged value", "%v", value)
+		}
+
+		if !isScalar(value) {
+			return nil, newError("invalid value object value", "%v", value)
+		}
+
+		if hasType {
+			s, ok := typ.(string)
+			if !ok || !isAbsoluteIRI(s) {
+				return nil, newError("invalid typed value", "%v", typ)
+			}
+		}
+
+	} else if typ, ok := result["@type"]; ok {
+		result["@type"] = asArray(typ)
+
+	} else if _, hasSet := result["@set"]; hasSet || isListObject(result) {
+		for key := range result {
+			if key != "@set" && key != "@list" && key != "@index" {
+				return nil, newError("invalid set or list object", "unexpected %s", key)
+			}
+		}
+
+		if hasSet {
+			return result["@set"], nil
+		}
+	}
+
+	if _, ok := result["@language"]; ok && len(result) == 1 {
+		return nil, nil
+	}
+
+	if activeProperty == "" || activeProperty == "@graph" {
+		_, hasID := result["@id"]
+
+		if len(result) == 0 || isValueObject(result) || isListObject(result) || (hasID && len(result) == 1) {
+			return nil, nil
+		}
+	}
+
+	return result, nil
+}
+
+// expandObject expands the entries of element into result.
+func (p *processor) expandObject(active *context, typeScoped *context, activeProperty string, element map[string]interface{}, result map[string]interface{}, baseURL string) (err error) {
+	var nests []string
+
+	for _, key := range sortedKeys(element) {
+		value := element[key]
+
+		if key == "@context" {
+			continue
+		}
+
+		expandedProperty, err := p.expandIRI(active, key, false, true, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		if expandedProperty == "" || (!isKeyword(expandedProperty) && !isAbsoluteIRI(expandedProperty) && !isBlankNodeID(expandedProperty)) {
+			continue
+		}
+
+		if isKeyword(expandedProperty) {
+			if activeProperty == "@reverse" {
+				return newError("invalid reverse property map", "%s", key)
+			}
+
+			if _, exists := result[expandedProperty]; exists && expandedProperty != "@included" && expandedProperty != "@type" {
+				return newError("colliding keywords", "%s", expandedProperty)
+			}
+
+			var expandedValue interface{}
+
+			switch expandedProperty {
+			case "@id":
+				s, ok := value.(string)
+				if !ok {
+					return newError("invalid @id value", "%v", value)
+				}
+
+				expandedValue, err = p.expandIRI(active, s, true, false, nil, nil)
+				if err != nil {
+					return err
+				}
+
+			case "@type":
+				var types []interface{}
+
+				for _, t := range asArray(value) {
+					s, ok := t.(string)
+					if !ok {
+						return newError("invalid type value", "%v", value)
+					}
+
+					expanded, err := p.expandIRI(typeScoped, s, true, true, nil, nil)
+					if err != nil {
+						return err
+					}
+
+					types = append(types, expanded)
+				}
+
+				if existing, ok := result["@type"]; ok {
+					expandedValue = append(asArray(existing), types...)
+				} else if _, isArray := value.([]interface{}); isArray {
+					expandedValue = types
+				} else {
+					expandedValue = types[0]
+				}
+
+			case "@graph":
+				expanded, err := p.expand(active, "@graph", value, baseURL, false)
+				if err != nil {
+					return err
+				}
+
+				if expanded == nil {
+					expanded = []interface{}{}
+				}
+
+				expandedValue = asArray(expanded)
+
+			case "@included":
+				expanded, err := p.expand(active, "", value, baseURL, false)
+				if err != nil {
+					return err
+				}
+
+				items := []interface{}{}
+				if expanded != nil {
+					items = asArray(expanded)
+				}
+
+				for _, item := range items {
+					if !isNodeObject(item) {
+						return newError("invalid @included value", "%v", item)
+					}
+				}
+
+				if existing, ok := result["@included"]; ok {
+					items = append(asArray(existing), items...)
+				}
+
+				expandedValue = items
+
+			case "@value":
+				if value == nil {
+					result["@value"] = nil
+					continue
+				}
+
+				if !isScalar(value) {
+					return newError("invalid value object value", "%v", value)
+				}
+
+				expandedValue = value
+
+			case "@language":
+				s, ok := value.(string)
+				if !ok {
+					return newError("invalid language-tagged string", "%v", value)
+				}
+
+				expandedValue = s
+
+			case "@direction":
+				if value != "ltr" && value != "rtl" {
+					return newError("invalid base direction", "%v", value)
+				}
+
+				expandedValue = value
+
+			case "@index":
+				s, ok := value.(string)
+				if !ok {
+					return newError("invalid @index value", "%v", value)
+				}
+
+				expandedValue = s
+
+			case "@list":
+				if activeProperty == "" || activeProperty == "@graph" {
+					continue
+				}
+
+				expanded, err := p.expand(active, activeProperty, value, baseURL, false)
+				if err != nil {
+					return err
+				}
+
+				if expanded == nil {
+					expanded = []interface{}{}
+				}
+
+				expandedValue = asArray(expanded)
+
+			case "@set":
+				expandedValue, err = p.expand(active, activeProperty, value, baseURL, false)
+				if err != nil {
+					return err
+				}
+
+			case "@reverse":
+				m, ok := value.(map[string]interface{})
+				if !ok {
+					return newError("invalid @reverse value", "%v", value)
+				}
+
+				expanded, err := p.expand(active, "@reverse", m, baseURL, false)
+				if err != nil {
+					return err
+				}
+
+				em, _ := expanded.(map[string]interface{})
+
+				if inner, ok := em["@reverse"].(map[string]interface{}); ok {
+					for _, property := range sortedKeys(inner) {
+						addValue(result, property, inner[property], false)
+					}
+				}
+
+				for _, property := range sortedKeys(em) {
+					if property == "@reverse" {
+						continue
+					}
+
+					reverseMap, ok := result["@reverse"].(map[string]interface{})
+					if !ok {
+						reverseMap = make(map[string]interface{})
+						result["@reverse"] = reverseMap
+					}
+
+					for _, item := range asArray(em[property]) {
+						if isValueObject(item) || isListObject(item) {
+							return newError("invalid reverse property value", "%v", item)
+						}
+
+						addValue(reverseMap, property, item, false)
+					}
+				}
+
+				continue
+
+			case "@nest":
+				nests = append(nests, key)
+				continue
+
+			default:
+				continue
+			}
+
+			if expandedValue != nil {
+				result[expandedProperty] = expandedValue
+			}
+
+			continue
+		}
+
+		def := active.terms[key]
+		var expandedValue interface{}
+		valueMap, isMap := value.(map[string]interface{})
+
+		if def != nil && def.typ == "@json" {
+			expandedValue = map[string]interface{}{"@value": value, "@type": "@json"}
+
+		} else if def.hasContainer("@language") && isMap {
+			items := []interface{}{}
+
+			for _, language := range sortedKeys(valueMap) {
+				expandedLanguage, err := p.expandIRI(active, language, false, true, nil, nil)
+				if err != nil {
+					return err
+				}
+
+				for _, item := range asArray(valueMap[language]) {
+					if item == nil {
+						continue
+					}
+
+					s, ok := item.(string)
+					if !ok {
+						return newError("invalid language map value", "%v", item)
+					}
+
+					v := map[string]interface{}{"@value": s}
+					if language != "@none" && expandedLanguage != "@none" {
+						v["@language"] = language
+					}
+
+					items = append(items, v)
+				}
+			}
+
+			expandedValue = items
+
+		} else if (def.hasContainer("@index") || def.hasContainer("@type") || def.hasContainer("@id")) && isMap {
+			items := []interface{}{}
+			indexKey := "@index"
+			if def.index != "" {
+				indexKey = def.index
+			}
+
+			for _, index := range sortedKeys(valueMap) {
+				mapContext := active
+				if def.hasContainer("@type") {
+					if active.previous != nil {
+						mapContext = active.previous
+					}
+
+					if tdef := mapContext.terms[index]; tdef != nil && tdef.hasContext {
+						mapContext, err = p.processContext(mapContext, tdef.context, tdef.baseURL, nil, false, true)
+						if err != nil {
+							return err
+						}
+					}
+				}
+
+				expandedIndex, err := p.expandIRI(active, index, false, true, nil, nil)
+				if err != nil {
+					return err
+				}
+
+				expanded, err := p.expand(mapContext, key, asArray(valueMap[index]), baseURL, true)
+				if err != nil {
+					return err
+				}
+
+				for _, item := range asArray(expanded) {
+					if def.hasContainer("@graph") && !isGraphObject(item) {
+						item = map[string]interface{}{"@graph": asArray(item)}
+					}
+
+					m, ok := item.(map[string]interface{})
+					if !ok || expandedIndex == "@none" {
+						items = append(items, item)
+						continue
+					}
+
+					if def.hasContainer("@index") && indexKey != "@index" {
+						reExpanded, err := p.expandValue(active, indexKey, index)
+						if err != nil {
+							return err
+						}
+
+						property, err := p.expandIRI(active, indexKey, false, true, nil, nil)
+						if err != nil {
+							return err
+						}
+
+						m[property] = append([]interface{}{reExpanded}, asArray(m[property])...)
+
+					} else if _, hasIndex := m["@index"]; def.hasContainer("@index") && !hasIndex {
+						m["@index"] = index
+
+					} else if _, hasID := m["@id"]; def.hasContainer("@id") && !hasID {
+						m["@id"], err = p.expandIRI(active, index, true, false, nil, nil)
+						if err != nil {
+							return err
+						}
+
+					} else if def.hasContainer("@type") {
+						types := []interface{}{expandedIndex}
+						if existing, ok := m["@type"]; ok {
+							types = append(types, asArray(existing)...)
+						}
+
+						m["@type"] = types
+					}
+
+					items = append(items, m)
+				}
+			}
+
+			expandedValue = items
+
+		} else {
+			expandedValue, err = p.expand(active, key, value, baseURL, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		if expandedValue == nil {
+			continue
+		}
+
+		if def.hasContainer("@list") && !isListObject(expandedValue) {
+			expandedValue = map[string]interface{}{"@list": asArray(expandedValue)}
+		}
+
+		if def.hasContainer("@graph") && !def.hasContainer("@id") && !def.hasContainer("@index") {
+			items := []interface{}{}
+			for _, item := range asArray(expandedValue) {
+				items = append(items, map[string]interface{}{"@graph": asArray(item)})
+			}
+
+			expandedValue = items
+		}
+
+		if def != nil && def.reverse {
+			reverseMap, ok := result["@reverse"].(map[string]interface{})
+			if !ok {
+				reverseMap = make(map[string]interface{})
+				result["@reverse"] = reverseMap
+			}
+
+			for _, item := range asArray(expandedValue) {
+				if isValueObject(item) || isListObject(item) {
+					return newError("invalid reverse property value", "%v", item)
+				}
+
+				addValue(reverseMap, expandedProperty, item, false)
+			}
+
+		} else {
+			addValue(result, expandedProperty, expandedValue, false)
+		}
+	}
+
+	for _, nestKey := range nests {
+		for _, nested := range asArray(element[nestKey]) {
+			m, ok := nested.(map[string]interface{})
+			if !ok || isValueObject(m) {
+				return newError("invalid @nest value", "%v", nested)
+			}
+
+			err = p.expandObject(active, typeScoped, activeProperty, m, result, baseURL)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// expandValue expands a scalar value of the given property into a value object or node reference.
+func (p *processor) expandValue(active *context, activeProperty string, value interface{}) (result interface{}, err error) {
+	def := active.terms[activeProperty]
+
+	if s, ok := value.(string); ok && def != nil {
+		if def.typ == "@id" {
+			id, err := p.expandIRI(active, s, true, false, nil, nil)
+			return map[string]interface{}{"@id": id}, err
+		}
+
+		if def.typ == "@vocab" {
+			id, err := p.expandIRI(active, s, true, true, nil, nil)
+			return map[string]interface{}{"@id": id}, err
+		}
+	}
+
+	m := map[string]interface{}{"@value": value}
+
+	if def != nil && def.typ != "" && def.typ != "@id" && def.typ != "@vocab" && def.typ != "@none" {
+		m["@type"] = def.typ
+
+	} else if _, ok := value.(string); ok {
+		language := active.language
+		if def != nil && def.hasLanguage {
+			language = def.language
+		}
+
+		if language != "" {
+			m["@language"] = language
+		}
+
+		direction := active.direction
+		if def != nil && def.hasDirection {
+			direction = def.direction
+		}
+
+		if direction != "" {
+			m["@direction"] = direction
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,239 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package jsonld
+
+import (
+	"fmt"
+)
+
+// A nodeMap collects the node objects of an expanded document, keyed by graph name and then by
+// node identifier. Blank node identifiers are relabelled _:b0, _:b1, ... as they are encountered.
+type nodeMap struct {
+	graphs  map[string]map[string]map[string]interface{}
+	labels  map[string]string
+	counter int
+}
+
+// newNodeMap returns an empty node map containing only the default graph.
+func newNodeMap() *nodeMap {
+	return &nodeMap{
+		graphs: map[string]map[string]map[string]interface{}{
+			"@default": make(map[string]map[string]interface{}),
+		},
+		labels: make(map[string]string),
+	}
+}
+
+// blankNodeID returns the new identifier for the blank node identifier old, or a fresh identifier
+// if old is empty.
+func (nm *nodeMap) blankNodeID(old string) string {
+	if old != "" {
+		if id, ok := nm.labels[old]; ok {
+			return id
+		}
+	}
+
+	id := fmt.Sprintf("_:b%d", nm.counter)
+	nm.counter++
+
+	if old != "" {
+		nm.labels[old] = id
+	}
+
+	return id
+}
+
+// generate adds the nodes of element to the node map. activeSubject is either the identifier of the
+// node owning element, or a node reference when element is the value of a reverse property. If list
+// is non-nil, values are appended to its @list instead of being added to the subject.
+func (nm *nodeMap) generate(element interface{}, activeGraph string, activeSubject interface{}, activeProperty string, list map[string]interface{}) error {
+	if items, ok := element.([]interface{}); ok {
+		for _, item := range items {
+			err := nm.generate(item, activeGraph, activeSubject, activeProperty, list)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	elem, ok := element.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	graph, ok := nm.graphs[activeGraph]
+	if !ok {
+		graph = make(map[string]map[string]interface{})
+		nm.graphs[activeGraph] = graph
+	}
+
+	var subjectNode map[string]interface{}
+	if s, ok := activeSubject.(string); ok {
+		subjectNode = graph[s]
+	}
+
+	if isValueObject(elem) {
+		if list == nil {
+			addValue(subjectNode, activeProperty, elem, true)
+		} else {
+			list["@list"] = append(toArray(list["@list"]), elem)
+		}
+
+		return nil
+	}
+
+	// Value objects were handled above: their @type is a datatype IRI, not a node type, and must
+	// stay a string.
+	if types, ok := elem["@type"]; ok {
+		var relabelled []interface{}
+		for _, t := range asArray(types) {
+			if s, ok := t.(string); ok && isBlankNodeID(s) {
+				t = nm.blankNodeID(s)
+			}
+
+			relabelled = append(relabelled, t)
+		}
+
+		elem["@type"] = relabelled
+	}
+
+	if isListObject(elem) {
+		result := map[string]interface{}{"@list": []interface{}{}}
+
+		err := nm.generate(elem["@list"], activeGraph, activeSubject, activeProperty, result)
+		if err != nil {
+			return err
+		}
+
+		if list == nil {
+			addValue(subjectNode, activeProperty, result, false)
+		} else {
+			list["@list"] = append(toArray(list["@list"]), result)
+		}
+
+		return nil
+	}
+
+	id, _ := elem["@id"].(string)
+	if id == "" || isBlankNodeID(id) {
+		id = nm.blankNodeID(id)
+	}
+
+	node, ok := graph[id]
+	if !ok {
+		node = map[string]interface{}{"@id": id}
+		graph[id] = node
+	}
+
+	if reference, ok := activeSubject.(map[string]interface{}); ok {
+		addValue(node, activeProperty, reference, true)
+	} else if activeProperty != "" {
+		reference := map[string]interface{}{"@id": id}
+
+		if list == nil {
+			addValue(subjectNode, activeProperty, reference, true)
+		} else {
+			list["@list"] = append(toArray(list["@list"]), reference)
+		}
+	}
+
+	if types, ok := elem["@type"]; ok {
+		addValue(node, "@type", types, true)
+	}
+
+	if index, ok := elem["@index"]; ok {
+		if existing, ok := node["@index"]; ok && !deepEqual(existing, index) {
+			return newError("conflicting indexes", "%s", id)
+		}
+
+		node["@index"] = index
+	}
+
+	if reverseMap, ok := elem["@reverse"].(map[string]interface{}); ok {
+		referenced := map[string]interface{}{"@id": id}
+
+		for _, property := range sortedKeys(reverseMap) {
+			for _, value := range asArray(reverseMap[property]) {
+				err := nm.generate(value, activeGraph, referenced, property, nil)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if g, ok := elem["@graph"]; ok {
+		err := nm.generate(g, id, nil, "", nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if included, ok := elem["@included"]; ok {
+		err := nm.generate(included, activeGraph, nil, "", nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, property := range sortedKeys(elem) {
+		switch property {
+		case "@id", "@type", "@index", "@reverse", "@graph", "@included":
+			continue
+		}
+
+		value := elem[property]
+
+		if isBlankNodeID(property) {
+			property = nm.blankNodeID(property)
+		}
+
+		if _, ok := node[property]; !ok {
+			node[property] = []interface{}{}
+		}
+
+		err := nm.generate(value, activeGraph, id, property, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flattenGraph returns the nodes of the named graph ordered by identifier, omitting nodes that
+// consist only of an @id.
+func (nm *nodeMap) flattenGraph(name string) []interface{} {
+	graph := nm.graphs[name]
+	result := []interface{}{}
+
+	for _, id := range sortedKeys(graph) {
+		node := graph[id]
+		if len(node) == 1 {
+			continue
+		}
+
+		result = append(result, node)
+	}
+
+	return result
+}
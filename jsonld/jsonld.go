@@ -0,0 +1,729 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package jsonld implements the JSON-LD 1.1 processing algorithms (expansion, compaction,
+// flattening and conversion to and from RDF) and registers a JSON-LD parser and serializer in
+// argo.Formats.
+package jsonld
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/kierdavis/argo"
+	"github.com/kierdavis/argo/iri"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+func init() {
+	argo.Formats["jsonld"] = &argo.Format{
+		ID:                 "jsonld",
+		Name:               "JSON-LD",
+		PreferredMIMEType:  "application/ld+json",
+		PreferredExtension: ".jsonld",
+		OtherMIMETypes:     []string{},
+		OtherExtensions:    []string{},
+		Parser:             Parse,
+		Serializer:         Serialize,
+	}
+}
+
+// An Error is returned when a JSON-LD document cannot be processed. Code is one of the error codes
+// defined by the JSON-LD 1.1 API specification, such as "invalid local context".
+type Error struct {
+	Code   string
+	Detail string
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return "jsonld: " + e.Code
+	}
+
+	return fmt.Sprintf("jsonld: %s: %s", e.Code, e.Detail)
+}
+
+// newError creates a new Error with the given code and a formatted detail message.
+func newError(code string, format string, args ...interface{}) error {
+	return &Error{Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+// A RemoteDocument is a document retrieved by a DocumentLoader.
+type RemoteDocument struct {
+	// The final URL of the document, after any redirects.
+	DocumentURL string
+
+	// The parsed JSON content of the document.
+	Document interface{}
+}
+
+// A DocumentLoader retrieves remote documents, such as contexts referenced by URL.
+type DocumentLoader interface {
+	LoadDocument(url string) (*RemoteDocument, error)
+}
+
+// An HTTPDocumentLoader is a DocumentLoader that fetches documents over HTTP.
+type HTTPDocumentLoader struct {
+	// The client used to make requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Method LoadDocument fetches and parses the document at the given URL.
+func (loader *HTTPDocumentLoader) LoadDocument(u string) (doc *RemoteDocument, err error) {
+	client := loader.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, newError("loading document failed", "%s", err)
+	}
+
+	req.Header.Add("Accept", "application/ld+json, application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, newError("loading document failed", "%s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, newError("loading document failed", "HTTP request returned status %d", resp.StatusCode)
+	}
+
+	document, err := decode(resp.Body)
+	if err != nil {
+		return nil, newError("loading document failed", "%s", err)
+	}
+
+	return &RemoteDocument{DocumentURL: resp.Request.URL.String(), Document: document}, nil
+}
+
+// A MapDocumentLoader is a DocumentLoader that serves documents from a map of URLs to JSON text.
+// It is useful for tests and for applications that ship the contexts they use.
+type MapDocumentLoader map[string]string
+
+// Method LoadDocument returns the document stored under the given URL.
+func (loader MapDocumentLoader) LoadDocument(u string) (doc *RemoteDocument, err error) {
+	text, ok := loader[u]
+	if !ok {
+		return nil, newError("loading document failed", "no document for %s", u)
+	}
+
+	document, err := decode(strings.NewReader(text))
+	if err != nil {
+		return nil, newError("loading document failed", "%s", err)
+	}
+
+	return &RemoteDocument{DocumentURL: u, Document: document}, nil
+}
+
+// Options control the behaviour of the processing algorithms. A nil *Options is equivalent to the
+// zero value.
+type Options struct {
+	// The base IRI of the document being processed.
+	Base string
+
+	// A context used to initialize the active context when expanding.
+	ExpandContext interface{}
+
+	// The loader used to retrieve remote contexts and documents. If nil, an HTTPDocumentLoader is
+	// used.
+	DocumentLoader DocumentLoader
+
+	// If false (the default), arrays containing a single element are replaced by that element when
+	// compacting, unless the term's container mapping requires an array. Note that the sense of this
+	// option is inverted relative to the specification's compactArrays flag, so that the zero value
+	// produces idiomatic output.
+	KeepArrays bool
+
+	// If true, triples whose predicate is a blank node are produced by ToRDF.
+	ProduceGeneralizedRDF bool
+
+	// If true, FromRDF converts xsd:boolean, xsd:integer and xsd:double literals to native JSON
+	// values.
+	UseNativeTypes bool
+
+	// If true, FromRDF represents rdf:type as an ordinary property instead of using @type.
+	UseRDFType bool
+}
+
+// documentLoader returns the loader to use.
+func (opts *Options) documentLoader() DocumentLoader {
+	if opts.DocumentLoader == nil {
+		return &HTTPDocumentLoader{}
+	}
+
+	return opts.DocumentLoader
+}
+
+// decode parses JSON, preserving the lexical form of numbers.
+func decode(r io.Reader) (v interface{}, err error) {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+
+	err = d.Decode(&v)
+	return v, err
+}
+
+// loadInput resolves a document given as a URL string or a RemoteDocument to its parsed content,
+// updating the base IRI in opts if necessary.
+func loadInput(input interface{}, opts *Options) (document interface{}, err error) {
+	switch in := input.(type) {
+	case string:
+		remote, err := opts.documentLoader().LoadDocument(in)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.Base == "" {
+			opts.Base = remote.DocumentURL
+		}
+
+		return remote.Document, nil
+
+	case *RemoteDocument:
+		if opts.Base == "" {
+			opts.Base = in.DocumentURL
+		}
+
+		return in.Document, nil
+	}
+
+	return input, nil
+}
+
+// copyOptions returns a copy of opts, or of the zero value if opts is nil.
+func copyOptions(opts *Options) *Options {
+	if opts == nil {
+		return &Options{}
+	}
+
+	o := *opts
+	return &o
+}
+
+// Function Expand expands a JSON-LD document, removing its context so that all IRIs, types and
+// values are fully expanded. input may be parsed JSON, a URL string or a *RemoteDocument.
+func Expand(input interface{}, opts *Options) (expanded []interface{}, err error) {
+	opts = copyOptions(opts)
+
+	document, err := loadInput(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &processor{opts: opts}
+	activeCtx := newContext(opts.Base)
+
+	if opts.ExpandContext != nil {
+		ctx := opts.ExpandContext
+		if m, ok := ctx.(map[string]interface{}); ok {
+			if inner, ok := m["@context"]; ok {
+				ctx = inner
+			}
+		}
+
+		activeCtx, err = p.processContext(activeCtx, ctx, opts.Base, nil, false, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := p.expand(activeCtx, "", document, opts.Base, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := result.(map[string]interface{}); ok && len(m) == 1 {
+		if graph, ok := m["@graph"]; ok {
+			result = graph
+		}
+	}
+
+	if result == nil {
+		return []interface{}{}, nil
+	}
+
+	return asArray(result), nil
+}
+
+// Function Compact expands input and then compacts it against the given context, producing a
+// document that uses the context's terms and compact IRIs. context may be a context value or a
+// document with a @context member; the returned document includes it unless it is empty.
+func Compact(input interface{}, context interface{}, opts *Options) (compacted map[string]interface{}, err error) {
+	opts = copyOptions(opts)
+
+	expanded, err := Expand(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return compactExpanded(expanded, context, opts)
+}
+
+// compactExpanded compacts an already-expanded document.
+func compactExpanded(expanded interface{}, context interface{}, opts *Options) (compacted map[string]interface{}, err error) {
+	if m, ok := context.(map[string]interface{}); ok {
+		if inner, ok := m["@context"]; ok {
+			context = inner
+		}
+	}
+
+	p := &processor{opts: opts}
+	activeCtx, err := p.processContext(newContext(opts.Base), context, opts.Base, nil, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.compact(activeCtx, "", expanded, false)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r := result.(type) {
+	case nil:
+		compacted = map[string]interface{}{}
+	case []interface{}:
+		compacted = map[string]interface{}{}
+		if len(r) > 0 {
+			compacted[activeCtx.compactKeyword("@graph")] = r
+		}
+	case map[string]interface{}:
+		compacted = r
+	}
+
+	if context != nil && !isEmptyContext(context) {
+		compacted["@context"] = context
+	}
+
+	return compacted, nil
+}
+
+// isEmptyContext returns whether the context value is an empty object or array.
+func isEmptyContext(context interface{}) bool {
+	switch c := context.(type) {
+	case map[string]interface{}:
+		return len(c) == 0
+	case []interface{}:
+		return len(c) == 0
+	}
+
+	return false
+}
+
+// Function Flatten expands input and collects all node objects into a single flat array, with
+// nested nodes replaced by references. If context is non-nil, the result is compacted against it.
+func Flatten(input interface{}, context interface{}, opts *Options) (flattened interface{}, err error) {
+	opts = copyOptions(opts)
+
+	expanded, err := Expand(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeMap := newNodeMap()
+	err = nodeMap.generate(expanded, "@default", nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultGraph := nodeMap.graphs["@default"]
+
+	for _, graphName := range sortedKeys(nodeMap.graphs) {
+		if graphName == "@default" {
+			continue
+		}
+
+		entry, ok := defaultGraph[graphName]
+		if !ok {
+			entry = map[string]interface{}{"@id": graphName}
+			defaultGraph[graphName] = entry
+		}
+
+		entry["@graph"] = nodeMap.flattenGraph(graphName)
+	}
+
+	result := nodeMap.flattenGraph("@default")
+
+	if context == nil {
+		return result, nil
+	}
+
+	return compactExpanded(result, context, opts)
+}
+
+// Function ToRDF converts a JSON-LD document into RDF. Triples belonging to named graphs have their
+// Graph field set.
+func ToRDF(input interface{}, opts *Options) (triples []*argo.Triple, err error) {
+	opts = copyOptions(opts)
+
+	expanded, err := Expand(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeMap := newNodeMap()
+	err = nodeMap.generate(expanded, "@default", nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodeMap.toRDF(opts), nil
+}
+
+// Function FromRDF converts RDF triples (with graph names, if any) into an expanded JSON-LD
+// document.
+func FromRDF(triples []*argo.Triple, opts *Options) (expanded []interface{}, err error) {
+	opts = copyOptions(opts)
+	return fromRDF(triples, opts), nil
+}
+
+// Function Parse parses JSON-LD from r and sends the resulting triples on tripleChan and errors on
+// errChan. Prefix-like terms defined by the top-level context are added to prefixes. Both channels
+// are closed when execution is done.
+func Parse(r io.Reader, tripleChan chan *argo.Triple, errChan chan error, prefixes map[string]string) {
+	defer close(tripleChan)
+	defer close(errChan)
+
+	document, err := decode(r)
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	triples, err := ToRDF(document, nil)
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	if m, ok := document.(map[string]interface{}); ok && prefixes != nil {
+		collectPrefixes(m["@context"], prefixes)
+	}
+
+	for _, triple := range triples {
+		tripleChan <- triple
+	}
+}
+
+// collectPrefixes adds terms of a context that look like namespace prefixes to prefixes.
+func collectPrefixes(context interface{}, prefixes map[string]string) {
+	switch c := context.(type) {
+	case []interface{}:
+		for _, item := range c {
+			collectPrefixes(item, prefixes)
+		}
+
+	case map[string]interface{}:
+		for term, def := range c {
+			iri, ok := def.(string)
+			if ok && !strings.HasPrefix(term, "@") && !strings.Contains(term, ":") && endsWithGenDelim(iri) {
+				prefixes[iri] = term
+			}
+		}
+	}
+}
+
+// Function Serialize writes the triples received on tripleChan to w as a JSON-LD document,
+// compacted using the given prefixes. errChan is closed when execution is done.
+func Serialize(w io.Writer, tripleChan chan *argo.Triple, errChan chan error, prefixes map[string]string) {
+	defer close(errChan)
+
+	var triples []*argo.Triple
+	for triple := range tripleChan {
+		triples = append(triples, triple)
+	}
+
+	context := make(map[string]interface{})
+	for uri, prefix := range prefixes {
+		if prefix != "" {
+			context[prefix] = uri
+		}
+	}
+
+	opts := &Options{}
+	compacted, err := compactExpanded(fromRDF(triples, opts), context, opts)
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	data, err := json.MarshalIndent(compacted, "", "  ")
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	buf.WriteString("\n")
+
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		errChan <- err
+	}
+}
+
+// ---- Utilities ----------------------------------------------------------------------------------
+
+var keywords = map[string]bool{
+	"@base": true, "@container": true, "@context": true, "@direction": true, "@graph": true,
+	"@id": true, "@import": true, "@included": true, "@index": true, "@json": true,
+	"@language": true, "@list": true, "@nest": true, "@none": true, "@prefix": true,
+	"@propagate": true, "@protected": true, "@reverse": true, "@set": true, "@type": true,
+	"@value": true, "@version": true, "@vocab": true,
+}
+
+// isKeyword returns whether s is a JSON-LD keyword.
+func isKeyword(s string) bool {
+	return keywords[s]
+}
+
+var keywordLike = regexp.MustCompile(`^@[a-zA-Z]+$`)
+
+// looksLikeKeyword returns whether s has the form of a keyword, and so must be ignored if it is not
+// one.
+func looksLikeKeyword(s string) bool {
+	return keywordLike.MatchString(s)
+}
+
+var schemeRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.\-]*:`)
+
+// isAbsoluteIRI returns whether s begins with a scheme.
+func isAbsoluteIRI(s string) bool {
+	return schemeRegexp.MatchString(s)
+}
+
+// isBlankNodeID returns whether s is a blank node identifier.
+func isBlankNodeID(s string) bool {
+	return strings.HasPrefix(s, "_:")
+}
+
+// endsWithGenDelim returns whether s ends with one of the URI gen-delims characters.
+func endsWithGenDelim(s string) bool {
+	return s != "" && strings.ContainsRune(":/?#[]@", rune(s[len(s)-1]))
+}
+
+// resolveIRI resolves ref against base.
+func resolveIRI(base string, ref string) string {
+	if base == "" {
+		return ref
+	}
+
+	s, err := iri.Resolve(base, ref)
+	if err != nil {
+		return ref
+	}
+
+	return s
+}
+
+// relativeIRI returns iri expressed relative to base, if it can be.
+func relativeIRI(base string, iri string) string {
+	if base == "" {
+		return iri
+	}
+
+	if iri == base {
+		return ""
+	}
+
+	if strings.HasPrefix(iri, base+"#") {
+		return iri[len(base):]
+	}
+
+	dir := base[:strings.LastIndex(base, "/")+1]
+	if dir != "" && strings.HasPrefix(iri, dir) && strings.Contains(dir, "//") {
+		rel := iri[len(dir):]
+		if rel != "" && !strings.Contains(strings.SplitN(rel, "/", 2)[0], ":") {
+			return rel
+		}
+	}
+
+	return iri
+}
+
+// asArray wraps v in an array if it is not already one.
+func asArray(v interface{}) []interface{} {
+	if a, ok := v.([]interface{}); ok {
+		return a
+	}
+
+	return []interface{}{v}
+}
+
+// sortedKeys returns the keys of a map in lexicographical order.
+func sortedKeys(m interface{}) (keys []string) {
+	switch mm := m.(type) {
+	case map[string]interface{}:
+		for k := range mm {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]map[string]interface{}:
+		for k := range mm {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]interface{}:
+		for k := range mm {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// isValueObject returns whether v is a value object.
+func isValueObject(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	_, ok = m["@value"]
+	return ok
+}
+
+// isListObject returns whether v is a list object.
+func isListObject(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	_, ok = m["@list"]
+	return ok
+}
+
+// isGraphObject returns whether v is a graph object.
+func isGraphObject(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	if _, ok = m["@graph"]; !ok {
+		return false
+	}
+
+	for key := range m {
+		if key != "@graph" && key != "@id" && key != "@index" && key != "@context" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isNodeObject returns whether v is a node object.
+func isNodeObject(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, key := range []string{"@value", "@list", "@set"} {
+		if _, ok := m[key]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isScalar returns whether v is a string, number or boolean.
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, bool, json.Number, float64, float32, int, int64, int32:
+		return true
+	}
+
+	return false
+}
+
+// addValue adds value to the array stored under key in m, optionally skipping duplicates.
+func addValue(m map[string]interface{}, key string, value interface{}, unique bool) {
+	existing, _ := m[key].([]interface{})
+
+	for _, v := range asArray(value) {
+		if unique {
+			dup := false
+			for _, e := range existing {
+				if deepEqual(e, v) {
+					dup = true
+					break
+				}
+			}
+
+			if dup {
+				continue
+			}
+		}
+
+		existing = append(existing, v)
+	}
+
+	if existing == nil {
+		existing = []interface{}{}
+	}
+
+	m[key] = existing
+}
+
+// deepEqual compares two JSON values.
+func deepEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+
+		for k, v := range av {
+			if !deepEqual(v, bv[k]) {
+				return false
+			}
+		}
+
+		return true
+
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+
+		for i := range av {
+			if !deepEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return fmt.Sprint(a) == fmt.Sprint(b) && fmt.Sprintf("%T", a) == fmt.Sprintf("%T", b)
+}
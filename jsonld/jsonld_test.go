@@ -0,0 +1,288 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package jsonld
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kierdavis/argo"
+)
+
+func mustDecode(t *testing.T, s string) interface{} {
+	v, err := decode(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Decoding %q: %s", s, err)
+	}
+
+	return v
+}
+
+func assertJSON(t *testing.T, name string, got interface{}, expected string) {
+	want, _ := json.Marshal(mustDecode(t, expected))
+	have, _ := json.Marshal(got)
+
+	if !bytes.Equal(want, have) {
+		t.Errorf("%s:\nexpected: %s\ngot:      %s", name, want, have)
+	}
+}
+
+var expandTestCases = map[string]string{
+	`{"@context": {"name": "http://xmlns.com/foaf/0.1/name"}, "@id": "http://example.org/a", "name": "A"}`: `[{"@id": "http://example.org/a", "http://xmlns.com/foaf/0.1/name": [{"@value": "A"}]}]`,
+
+	`{"@context": {"@vocab": "http://example.org/", "@language": "en"}, "@type": "Thing", "label": "x", "n": 5}`: `[{"@type": ["http://example.org/Thing"], "http://example.org/label": [{"@value": "x", "@language": "en"}], "http://example.org/n": [{"@value": 5}]}]`,
+
+	`{"@context": {"ex": "http://example.org/", "ex:list": {"@container": "@list"}, "ex:ref": {"@type": "@id"}}, "ex:list": [1, 2], "ex:ref": "b", "@id": "ex:a"}`: `[{"@id": "http://example.org/a", "http://example.org/list": [{"@list": [{"@value": 1}, {"@value": 2}]}], "http://example.org/ref": [{"@id": "b"}]}]`,
+
+	`{"@context": {"@vocab": "http://example.org/", "Person": {"@context": {"name": "http://xmlns.com/foaf/0.1/name"}}}, "@type": "Person", "name": "A", "knows": {"name": "B"}}`: `[{"@type": ["http://example.org/Person"], "http://xmlns.com/foaf/0.1/name": [{"@value": "A"}], "http://example.org/knows": [{"http://example.org/name": [{"@value": "B"}]}]}]`,
+
+	`{"@context": {"@vocab": "http://example.org/", "isKnownBy": {"@reverse": "knows"}}, "@id": "http://example.org/a", "isKnownBy": {"@id": "http://example.org/b"}}`: `[{"@id": "http://example.org/a", "@reverse": {"http://example.org/knows": [{"@id": "http://example.org/b"}]}}]`,
+
+	`{"@context": {"@vocab": "http://example.org/"}, "@id": "http://example.org/g", "@graph": [{"@id": "http://example.org/a", "p": "v"}]}`: `[{"@id": "http://example.org/g", "@graph": [{"@id": "http://example.org/a", "http://example.org/p": [{"@value": "v"}]}]}]`,
+}
+
+func TestExpand(t *testing.T) {
+	for doc, expected := range expandTestCases {
+		expanded, err := Expand(mustDecode(t, doc), nil)
+		if err != nil {
+			t.Errorf("Expanding %s: unexpected error %s", doc, err)
+			continue
+		}
+
+		assertJSON(t, "Expanding "+doc, expanded, expected)
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	docs := map[string]string{
+		`{"@context": {"@vocab": "http://example.org/"}, "@id": 5}`:                                              "invalid @id value",
+		`{"@context": {"@vocab": "http://example.org/"}, "p": {"@value": "x", "@type": "t", "@language": "en"}}`: "invalid value object",
+		`{"@context": {"term": {"@id": "http://example.org/", "@container": "@bogus"}}}`:                         "invalid container mapping",
+		`{"@context": "http://example.org/recursive"}`:                                                           "recursive context inclusion",
+	}
+
+	opts := &Options{DocumentLoader: MapDocumentLoader{
+		"http://example.org/recursive": `{"@context": "http://example.org/recursive"}`,
+	}}
+
+	for doc, code := range docs {
+		_, err := Expand(mustDecode(t, doc), opts)
+
+		if e, ok := err.(*Error); !ok || e.Code != code {
+			t.Errorf("Expanding %s: expected error %q but got %v", doc, code, err)
+		}
+	}
+}
+
+func TestRemoteContext(t *testing.T) {
+	opts := &Options{DocumentLoader: MapDocumentLoader{
+		"http://example.org/context.jsonld": `{"@context": {"name": "http://xmlns.com/foaf/0.1/name"}}`,
+	}}
+
+	doc := mustDecode(t, `{"@context": "http://example.org/context.jsonld", "name": "A"}`)
+
+	expanded, err := Expand(doc, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	assertJSON(t, "Expanding with remote context", expanded, `[{"http://xmlns.com/foaf/0.1/name": [{"@value": "A"}]}]`)
+}
+
+func TestCompact(t *testing.T) {
+	input := mustDecode(t, `[{
+		"@id": "http://example.org/a",
+		"@type": ["http://example.org/Person"],
+		"http://xmlns.com/foaf/0.1/name": [{"@value": "A"}],
+		"http://xmlns.com/foaf/0.1/knows": [{"@id": "http://example.org/b"}],
+		"http://example.org/tags": [{"@list": [{"@value": "x"}, {"@value": "y"}]}],
+		"http://example.org/label": [{"@value": "Hi", "@language": "en"}, {"@value": "Salut", "@language": "fr"}],
+		"http://example.org/other": [{"@value": "1", "@type": "http://www.w3.org/2001/XMLSchema#integer"}]
+	}]`)
+
+	context := mustDecode(t, `{
+		"ex": "http://example.org/",
+		"name": "http://xmlns.com/foaf/0.1/name",
+		"knows": {"@id": "http://xmlns.com/foaf/0.1/knows", "@type": "@id"},
+		"tags": {"@id": "ex:tags", "@container": "@list"},
+		"label": {"@id": "ex:label", "@container": "@language"},
+		"id": "@id"
+	}`)
+
+	compacted, err := Compact(input, context, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	delete(compacted, "@context")
+
+	assertJSON(t, "Compacting", compacted, `{
+		"id": "ex:a",
+		"@type": "ex:Person",
+		"name": "A",
+		"knows": "ex:b",
+		"tags": ["x", "y"],
+		"label": {"en": "Hi", "fr": "Salut"},
+		"ex:other": {"@value": "1", "@type": "http://www.w3.org/2001/XMLSchema#integer"}
+	}`)
+}
+
+func TestFlatten(t *testing.T) {
+	doc := mustDecode(t, `{
+		"@context": {"@vocab": "http://example.org/"},
+		"@id": "http://example.org/a",
+		"knows": {"@id": "_:x", "name": "B", "knows": {"name": "C"}}
+	}`)
+
+	flattened, err := Flatten(doc, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	assertJSON(t, "Flattening", flattened, `[
+		{"@id": "_:b0", "http://example.org/knows": [{"@id": "_:b1"}], "http://example.org/name": [{"@value": "B"}]},
+		{"@id": "_:b1", "http://example.org/name": [{"@value": "C"}]},
+		{"@id": "http://example.org/a", "http://example.org/knows": [{"@id": "_:b0"}]}
+	]`)
+}
+
+func TestToRDF(t *testing.T) {
+	doc := mustDecode(t, `{
+		"@context": {"@vocab": "http://example.org/", "xsd": "http://www.w3.org/2001/XMLSchema#", "list": {"@container": "@list"}},
+		"@id": "http://example.org/a",
+		"n": [1, 1.5, true, {"@value": "x", "@language": "en"}, {"@value": "30", "@type": "xsd:integer"}],
+		"d": {"@value": "2012-05-01", "@type": "xsd:date"},
+		"list": ["a"],
+		"@graph": [{"@id": "http://example.org/b", "p": "v"}]
+	}`)
+
+	triples, err := ToRDF(doc, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	var lines []string
+	for _, triple := range triples {
+		lines = append(lines, triple.QuadString())
+	}
+
+	expected := strings.Join([]string{
+		`<http://example.org/a> <http://example.org/d> "2012-05-01"^^<http://www.w3.org/2001/XMLSchema#date> .`,
+		`_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "a" .`,
+		`_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .`,
+		`<http://example.org/a> <http://example.org/list> _:b0 .`,
+		`<http://example.org/a> <http://example.org/n> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+		`<http://example.org/a> <http://example.org/n> "1.5E0"^^<http://www.w3.org/2001/XMLSchema#double> .`,
+		`<http://example.org/a> <http://example.org/n> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .`,
+		`<http://example.org/a> <http://example.org/n> "x"@en .`,
+		`<http://example.org/a> <http://example.org/n> "30"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+		`<http://example.org/b> <http://example.org/p> "v" <http://example.org/a> .`,
+	}, "\n")
+
+	if got := strings.Join(lines, "\n"); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ex := argo.NewNamespace("http://example.org/")
+	graph := argo.NewGraph(argo.NewListStore())
+
+	graph.AddTriple(ex.Get("a"), argo.A, ex.Get("Thing"))
+	graph.AddTriple(ex.Get("a"), ex.Get("born"), argo.NewLiteralWithDatatype("2012-05-01", argo.XSD.Get("date")))
+	graph.AddTriple(ex.Get("a"), ex.Get("age"), argo.NewLiteralWithDatatype("30", argo.XSD.Get("integer")))
+	graph.AddTriple(ex.Get("a"), ex.Get("knows"), argo.NewBlankNode("b"))
+	graph.AddTriple(argo.NewBlankNode("b"), argo.A, argo.NewBlankNode("c"))
+	graph.AddTriple(argo.NewBlankNode("b"), ex.Get("name"), argo.NewLiteralWithLanguage("B", "en"))
+
+	var buf bytes.Buffer
+	err := graph.Serialize(Serialize, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	parsed := argo.NewGraph(argo.NewListStore())
+	err = parsed.Parse(Parse, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if c := graph.Compare(parsed); !c.Isomorphic() {
+		t.Errorf("Graph changed in round trip:\n%s", c)
+	}
+}
+
+func TestFromRDF(t *testing.T) {
+	a := argo.NewResource("http://example.org/a")
+	p := argo.NewResource("http://example.org/p")
+	l0, l1 := argo.NewBlankNode("l0"), argo.NewBlankNode("l1")
+
+	triples := []*argo.Triple{
+		argo.NewTriple(a, p, l0),
+		argo.NewTriple(l0, argo.First, argo.NewLiteral("x")),
+		argo.NewTriple(l0, argo.Rest, l1),
+		argo.NewTriple(l1, argo.First, argo.NewLiteralWithDatatype("2", argo.XSD.Get("integer"))),
+		argo.NewTriple(l1, argo.Rest, argo.Nil),
+		argo.NewTriple(a, argo.A, argo.NewResource("http://example.org/T")),
+		argo.NewQuad(a, p, argo.NewLiteralWithLanguage("y", "en"), argo.NewResource("http://example.org/g")),
+	}
+
+	expanded, err := FromRDF(triples, &Options{UseNativeTypes: true})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	assertJSON(t, "Converting from RDF", expanded, `[
+		{"@id": "http://example.org/a", "@type": ["http://example.org/T"], "http://example.org/p": [{"@list": [{"@value": "x"}, {"@value": 2}]}]},
+		{"@id": "http://example.org/g", "@graph": [{"@id": "http://example.org/a", "http://example.org/p": [{"@value": "y", "@language": "en"}]}]}
+	]`)
+}
+
+func TestFormat(t *testing.T) {
+	format := argo.FormatFromMIMEType("application/ld+json; profile=\"http://www.w3.org/ns/json-ld#expanded\"")
+	if format == nil || format.ID != "jsonld" {
+		t.Fatalf("Expected the jsonld format but got %v", format)
+	}
+
+	graph := argo.NewGraph(argo.NewListStore())
+	doc := `{"@context": {"foaf": "http://xmlns.com/foaf/0.1/"}, "@id": "http://example.org/a", "foaf:name": "A"}`
+
+	err := graph.Parse(format.Parser, strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if graph.Num() != 1 || graph.Prefixes["http://xmlns.com/foaf/0.1/"] != "foaf" {
+		t.Fatalf("Expected 1 triple and the foaf prefix, got %d triples and %v", graph.Num(), graph.Prefixes)
+	}
+
+	var buf bytes.Buffer
+	err = graph.Serialize(format.Serializer, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	serialized, _ := mustDecode(t, buf.String()).(map[string]interface{})
+	if serialized["foaf:name"] != "A" || serialized["@id"] != "http://example.org/a" {
+		t.Errorf("Expected the foaf prefix to be used, got %s", buf.String())
+	}
+}
@@ -0,0 +1,501 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package jsonld
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kierdavis/argo"
+)
+
+const (
+	rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xsdNS = "http://www.w3.org/2001/XMLSchema#"
+
+	rdfType    = rdfNS + "type"
+	rdfFirst   = rdfNS + "first"
+	rdfRest    = rdfNS + "rest"
+	rdfNil     = rdfNS + "nil"
+	rdfList    = rdfNS + "List"
+	rdfJSON    = rdfNS + "JSON"
+	xsdString  = xsdNS + "string"
+	xsdBoolean = xsdNS + "boolean"
+	xsdInteger = xsdNS + "integer"
+	xsdDouble  = xsdNS + "double"
+)
+
+// toRDF converts the node map into triples. Graphs, subjects and properties are visited in
+// lexicographical order so that the output is deterministic.
+func (nm *nodeMap) toRDF(opts *Options) (triples []*argo.Triple) {
+	for _, graphName := range sortedKeys(nm.graphs) {
+		var graphTerm argo.Term
+
+		if graphName != "@default" {
+			graphTerm = nodeTerm(graphName)
+			if graphTerm == nil {
+				continue
+			}
+		}
+
+		graph := nm.graphs[graphName]
+
+		for _, subject := range sortedKeys(graph) {
+			subjectTerm := nodeTerm(subject)
+			if subjectTerm == nil {
+				continue
+			}
+
+			node := graph[subject]
+
+			for _, property := range sortedKeys(node) {
+				if property == "@type" {
+					for _, t := range asArray(node[property]) {
+						s, _ := t.(string)
+						if object := nodeTerm(s); object != nil {
+							triples = append(triples, argo.NewQuad(subjectTerm, argo.NewResource(rdfType), object, graphTerm))
+						}
+					}
+
+					continue
+				}
+
+				if isKeyword(property) {
+					continue
+				}
+
+				if isBlankNodeID(property) && !opts.ProduceGeneralizedRDF {
+					continue
+				}
+
+				predicate := nodeTerm(property)
+				if predicate == nil {
+					continue
+				}
+
+				for _, item := range asArray(node[property]) {
+					var listTriples []*argo.Triple
+					object := nm.objectToRDF(item, &listTriples)
+
+					for _, t := range listTriples {
+						t.Graph = graphTerm
+					}
+
+					triples = append(triples, listTriples...)
+
+					if object != nil {
+						triples = append(triples, argo.NewQuad(subjectTerm, predicate, object, graphTerm))
+					}
+				}
+			}
+		}
+	}
+
+	return triples
+}
+
+// nodeTerm returns the RDF term for a node identifier, or nil if it is not a valid absolute IRI.
+func nodeTerm(id string) argo.Term {
+	if isBlankNodeID(id) {
+		return argo.NewBlankNode(id[2:])
+	}
+
+	term, err := argo.NewCheckedResource(id)
+	if err != nil {
+		return nil
+	}
+
+	return term
+}
+
+// objectToRDF converts a node reference, value object or list object to an RDF term. Triples
+// describing list structure are appended to listTriples.
+func (nm *nodeMap) objectToRDF(item interface{}, listTriples *[]*argo.Triple) argo.Term {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if list, ok := m["@list"]; ok {
+		return nm.listToRDF(toArray(list), listTriples)
+	}
+
+	if !isValueObject(m) {
+		id, _ := m["@id"].(string)
+		return nodeTerm(id)
+	}
+
+	value := m["@value"]
+	datatype, _ := m["@type"].(string)
+
+	if datatype != "" && datatype != "@json" && !isAbsoluteIRI(datatype) {
+		return nil
+	}
+
+	if datatype == "@json" {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+
+		return argo.NewLiteralWithDatatype(string(data), argo.NewResource(rdfJSON))
+	}
+
+	var lexical string
+
+	switch v := value.(type) {
+	case bool:
+		lexical = strconv.FormatBool(v)
+		if datatype == "" {
+			datatype = xsdBoolean
+		}
+
+	case json.Number:
+		s := string(v)
+		if !strings.ContainsAny(s, ".eE") && datatype != xsdDouble {
+			lexical = s
+			if datatype == "" {
+				datatype = xsdInteger
+			}
+		} else {
+			f, err := v.Float64()
+			if err != nil {
+				return nil
+			}
+
+			lexical, datatype = formatNumber(f, datatype)
+		}
+
+	case float64:
+		lexical, datatype = formatNumber(v, datatype)
+
+	case string:
+		lexical = v
+
+	default:
+		return nil
+	}
+
+	if language, ok := m["@language"].(string); ok {
+		return argo.NewLiteralWithLanguage(lexical, language)
+	}
+
+	if datatype == "" || datatype == xsdString {
+		return argo.NewLiteral(lexical)
+	}
+
+	return argo.NewLiteralWithDatatype(lexical, argo.NewResource(datatype))
+}
+
+// formatNumber returns the lexical form and datatype for a JSON number: integral numbers become
+// xsd:integer, all others xsd:double in canonical form.
+func formatNumber(f float64, datatype string) (string, string) {
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 && datatype != xsdDouble {
+		if datatype == "" {
+			datatype = xsdInteger
+		}
+
+		return strconv.FormatFloat(f, 'f', 0, 64), datatype
+	}
+
+	if datatype == "" {
+		datatype = xsdDouble
+	}
+
+	return canonicalDouble(f), datatype
+}
+
+// canonicalDouble formats f in the canonical lexical form of xsd:double, such as 1.1E0.
+func canonicalDouble(f float64) string {
+	s := strconv.FormatFloat(f, 'E', -1, 64)
+
+	mantissa, exponent := s, "0"
+	if i := strings.Index(s, "E"); i >= 0 {
+		mantissa = s[:i]
+
+		e, _ := strconv.Atoi(s[i+1:])
+		exponent = strconv.Itoa(e)
+	}
+
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+
+	return mantissa + "E" + exponent
+}
+
+// listToRDF converts the items of a list into a chain of rdf:first/rdf:rest triples and returns the
+// head of the list.
+func (nm *nodeMap) listToRDF(items []interface{}, listTriples *[]*argo.Triple) argo.Term {
+	if len(items) == 0 {
+		return argo.NewResource(rdfNil)
+	}
+
+	nodes := make([]argo.Term, len(items))
+	for i := range items {
+		nodes[i] = nodeTerm(nm.blankNodeID(""))
+	}
+
+	for i, item := range items {
+		object := nm.objectToRDF(item, listTriples)
+		if object != nil {
+			*listTriples = append(*listTriples, argo.NewTriple(nodes[i], argo.NewResource(rdfFirst), object))
+		}
+
+		var rest argo.Term = argo.NewResource(rdfNil)
+		if i+1 < len(nodes) {
+			rest = nodes[i+1]
+		}
+
+		*listTriples = append(*listTriples, argo.NewTriple(nodes[i], argo.NewResource(rdfRest), rest))
+	}
+
+	return nodes[0]
+}
+
+// A usage records a reference to a node: the node holding the reference, the property and the
+// reference object itself.
+type usage struct {
+	node     map[string]interface{}
+	property string
+	value    map[string]interface{}
+}
+
+// termID returns the JSON-LD identifier for a resource or blank node.
+func termID(term argo.Term) string {
+	switch t := term.(type) {
+	case *argo.Resource:
+		return t.URI
+	case *argo.BlankNode:
+		return "_:" + t.ID
+	}
+
+	return term.String()
+}
+
+// fromRDF converts RDF triples into an expanded JSON-LD document.
+func fromRDF(triples []*argo.Triple, opts *Options) []interface{} {
+	graphMap := map[string]map[string]map[string]interface{}{
+		"@default": make(map[string]map[string]interface{}),
+	}
+
+	defaultGraph := graphMap["@default"]
+	nilUsages := make(map[string][]usage)
+	referencedOnce := make(map[string]*usage)
+	referencedMany := make(map[string]bool)
+
+	for _, triple := range triples {
+		name := "@default"
+		if triple.Graph != nil {
+			name = termID(triple.Graph)
+		}
+
+		graph, ok := graphMap[name]
+		if !ok {
+			graph = make(map[string]map[string]interface{})
+			graphMap[name] = graph
+		}
+
+		if name != "@default" {
+			if _, ok := defaultGraph[name]; !ok {
+				defaultGraph[name] = map[string]interface{}{"@id": name}
+			}
+		}
+
+		subject := termID(triple.Subject)
+		node, ok := graph[subject]
+		if !ok {
+			node = map[string]interface{}{"@id": subject}
+			graph[subject] = node
+		}
+
+		predicate := termID(triple.Predicate)
+		_, isLiteral := triple.Object.(*argo.Literal)
+
+		var objectID string
+		if !isLiteral {
+			objectID = termID(triple.Object)
+			if _, ok := graph[objectID]; !ok {
+				graph[objectID] = map[string]interface{}{"@id": objectID}
+			}
+		}
+
+		if predicate == rdfType && !opts.UseRDFType && !isLiteral {
+			addValue(node, "@type", objectID, true)
+			continue
+		}
+
+		value := objectToJSON(triple.Object, opts)
+		addValue(node, predicate, value, true)
+
+		if objectID == rdfNil {
+			nilUsages[name] = append(nilUsages[name], usage{node, predicate, value})
+		} else if isBlankNodeID(objectID) {
+			key := name + " " + objectID
+
+			if referencedOnce[key] != nil || referencedMany[key] {
+				referencedOnce[key] = nil
+				referencedMany[key] = true
+			} else {
+				referencedOnce[key] = &usage{node, predicate, value}
+			}
+		}
+	}
+
+	for name, graph := range graphMap {
+		for _, u := range nilUsages[name] {
+			node, property, head := u.node, u.property, u.value
+			var list []interface{}
+			var listNodes []string
+
+			for property == rdfRest && isWellFormedListNode(node, referencedOnce[name+" "+node["@id"].(string)] != nil) {
+				list = append(list, node[rdfFirst].([]interface{})[0])
+				id := node["@id"].(string)
+				listNodes = append(listNodes, id)
+
+				next := referencedOnce[name+" "+id]
+				node, property, head = next.node, next.property, next.value
+
+				if !isBlankNodeID(node["@id"].(string)) {
+					break
+				}
+			}
+
+			delete(head, "@id")
+
+			for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+				list[i], list[j] = list[j], list[i]
+			}
+
+			head["@list"] = list
+
+			for _, id := range listNodes {
+				delete(graph, id)
+			}
+		}
+	}
+
+	result := []interface{}{}
+
+	for _, subject := range sortedKeys(defaultGraph) {
+		node := defaultGraph[subject]
+
+		if graph, ok := graphMap[subject]; ok && subject != "@default" {
+			nodes := []interface{}{}
+			for _, id := range sortedKeys(graph) {
+				if n := graph[id]; len(n) > 1 {
+					nodes = append(nodes, n)
+				}
+			}
+
+			node["@graph"] = nodes
+		}
+
+		if len(node) > 1 {
+			result = append(result, node)
+		}
+	}
+
+	return result
+}
+
+// isWellFormedListNode returns whether node is a blank node that is referenced once and consists
+// only of a single rdf:first, a single rdf:rest and optionally a type of rdf:List.
+func isWellFormedListNode(node map[string]interface{}, referencedOnce bool) bool {
+	id, _ := node["@id"].(string)
+	if !isBlankNodeID(id) || !referencedOnce {
+		return false
+	}
+
+	first, _ := node[rdfFirst].([]interface{})
+	rest, _ := node[rdfRest].([]interface{})
+	if len(first) != 1 || len(rest) != 1 {
+		return false
+	}
+
+	for key, value := range node {
+		switch key {
+		case "@id", rdfFirst, rdfRest:
+		case "@type":
+			types, _ := value.([]interface{})
+			if len(types) != 1 || types[0] != rdfList {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// objectToJSON converts an RDF term into a node reference or value object.
+func objectToJSON(term argo.Term, opts *Options) map[string]interface{} {
+	literal, ok := term.(*argo.Literal)
+	if !ok {
+		return map[string]interface{}{"@id": termID(term)}
+	}
+
+	if literal.Language != "" {
+		return map[string]interface{}{"@value": literal.Value, "@language": literal.Language}
+	}
+
+	datatype := ""
+	if literal.Datatype != nil {
+		datatype = termID(literal.Datatype)
+	}
+
+	result := map[string]interface{}{"@value": literal.Value}
+
+	switch datatype {
+	case "", xsdString:
+		return result
+
+	case rdfJSON:
+		var v interface{}
+		if err := json.Unmarshal([]byte(literal.Value), &v); err == nil {
+			return map[string]interface{}{"@value": v, "@type": "@json"}
+		}
+
+	case xsdBoolean:
+		if opts.UseNativeTypes && (literal.Value == "true" || literal.Value == "false") {
+			result["@value"] = literal.Value == "true"
+			return result
+		}
+
+	case xsdInteger:
+		if n, err := strconv.ParseInt(literal.Value, 10, 64); opts.UseNativeTypes && err == nil {
+			result["@value"] = json.Number(strconv.FormatInt(n, 10))
+			return result
+		}
+
+	case xsdDouble:
+		if f, err := strconv.ParseFloat(literal.Value, 64); opts.UseNativeTypes && err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			result["@value"] = json.Number(fmt.Sprint(f))
+			return result
+		}
+	}
+
+	result["@type"] = datatype
+	return result
+}
@@ -19,11 +19,17 @@
 
 package argo
 
-import ()
+import (
+	"iter"
+)
 
 // A ListStore is a Store that stores triples in a slice stored in memory.
 type ListStore struct {
+	// If set, literals are compared by value (see ValueEqual) when filtering and removing triples.
+	ValueEquality bool
+
 	triples []*Triple
+	shared  bool // Whether the backing array of triples is shared with a snapshot
 }
 
 // Function NewListStore create and returns a new empty ListStore.
@@ -41,8 +47,14 @@ func (store *ListStore) Add(triple *Triple) {
 // Method Remove removes the given triple from the store.
 func (store *ListStore) Remove(triple *Triple) {
 	for i, t := range store.triples {
-		if t == triple {
-			store.triples = append(store.triples[:i], store.triples[i+1:]...)
+		if t == triple || (store.ValueEquality && valueEqualTriples(t, triple)) {
+			if store.shared {
+				store.triples = append(store.triples[:i:i], store.triples[i+1:]...)
+				store.shared = false
+			} else {
+				store.triples = append(store.triples[:i], store.triples[i+1:]...)
+			}
+
 			return
 		}
 	}
@@ -50,7 +62,27 @@ func (store *ListStore) Remove(triple *Triple) {
 
 // Method Clear removes all triples from the store.
 func (store *ListStore) Clear() {
-	store.triples = store.triples[:0]
+	if store.shared {
+		store.triples = make([]*Triple, 0)
+		store.shared = false
+	} else {
+		store.triples = store.triples[:0]
+	}
+}
+
+// Method Begin starts a transaction on the store. Taking the snapshot is cheap: the store only
+// copies its triples if it is changed in a way that would disturb the snapshot.
+// Like the store, the transaction may hold duplicate triples: removing a triple within it removes
+// a single copy.
+func (store *ListStore) Begin() (tx Tx, err error) {
+	snapshot := &ListStore{
+		ValueEquality: store.ValueEquality,
+		triples:       store.triples[:len(store.triples):len(store.triples)],
+	}
+
+	// Appending does not affect the snapshot, since it only sees the first len(triples) elements.
+	store.shared = true
+	return NewSnapshotTx(store, snapshot, SnapshotTxOptions{ValueEquality: store.ValueEquality, Bag: true}), nil
 }
 
 // Method Num returns the number of triples in the store.
@@ -61,26 +93,31 @@ func (store *ListStore) Num() (n int) {
 // Method IterTriples returns a channel that will yield the triples of the store. The channel will
 // be closed when iteration is completed.
 func (store *ListStore) IterTriples() (ch chan *Triple) {
-	ch = make(chan *Triple)
-
-	go func() {
-		for _, triple := range store.triples {
-			ch <- triple
-		}
-
-		close(ch)
-	}()
-
-	return ch
+	return seqChannel(store.All())
 }
 
 // Method Filter returns a channel that will yield all matching triples of the graph. A nil value
 // passed means that the check for this term is skipped; else the triples returned must have the
 // same terms as the corresponding arguments.
 func (store *ListStore) Filter(subject Term, predicate Term, object Term) (ch chan *Triple) {
-	ch = make(chan *Triple)
+	return seqChannel(store.FilterSeq(subject, predicate, object))
+}
+
+// Method All returns a sequence of the triples of the store.
+func (store *ListStore) All() (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
+		for _, triple := range store.triples {
+			if !yield(triple) {
+				return
+			}
+		}
+	}
+}
 
-	go func() {
+// Method FilterSeq returns a sequence of the matching triples of the store; see Filter for the
+// meaning of the arguments.
+func (store *ListStore) FilterSeq(subject Term, predicate Term, object Term) (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
 		for _, triple := range store.triples {
 			if subject != nil && !subject.Equal(triple.Subject) {
 				continue
@@ -90,15 +127,22 @@ func (store *ListStore) Filter(subject Term, predicate Term, object Term) (ch ch
 				continue
 			}
 
-			if object != nil && !object.Equal(triple.Object) {
+			if object != nil && !store.equal(object, triple.Object) {
 				continue
 			}
 
-			ch <- triple
+			if !yield(triple) {
+				return
+			}
 		}
+	}
+}
 
-		close(ch)
-	}()
+// Method equal compares two terms, by value if the store's ValueEquality flag is set.
+func (store *ListStore) equal(a Term, b Term) bool {
+	if store.ValueEquality {
+		return ValueEqual(a, b)
+	}
 
-	return ch
+	return a.Equal(b)
 }
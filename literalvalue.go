@@ -0,0 +1,661 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// These are the errors that can be returned in LiteralValueError.Err.
+var (
+	ErrInvalidLexicalForm = errors.New("invalid lexical form for datatype")
+	ErrOutOfRange         = errors.New("value out of range for datatype")
+	ErrWrongDatatype      = errors.New("literal does not have the requested type")
+	ErrUnsupportedValue   = errors.New("no datatype for Go value")
+)
+
+// A LiteralValueError is returned when a literal cannot be converted to or from a Go value.
+type LiteralValueError struct {
+	Value    string // The lexical form (or, for ErrUnsupportedValue, the Go type)
+	Datatype Term   // The datatype, or nil if there is none
+	Err      error  // The actual error
+}
+
+func (e *LiteralValueError) Error() string {
+	if e.Datatype == nil {
+		return fmt.Sprintf("%q: %s", e.Value, e.Err)
+	}
+
+	return fmt.Sprintf("%q^^%s: %s", e.Value, e.Datatype, e.Err)
+}
+
+var (
+	xsdIntegerRegexp  = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	xsdDecimalRegexp  = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+	xsdDoubleRegexp   = regexp.MustCompile(`^([+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?|[+-]?INF|NaN)$`)
+	xsdDateTimeRegexp = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}T([0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?)(Z|[+-][0-9]{2}:[0-9]{2})?$`)
+	xsdDateRegexp     = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}(Z|[+-][0-9]{2}:[0-9]{2})?$`)
+	xsdDurationRegexp = regexp.MustCompile(`^(-)?P(?:([0-9]+)Y)?(?:([0-9]+)M)?(?:([0-9]+)D)?(?:T(?:([0-9]+)H)?(?:([0-9]+)M)?(?:([0-9]+)(?:\.([0-9]+))?S)?)?$`)
+)
+
+// The bounds of the integer datatypes derived from xsd:integer; nil means unbounded.
+var xsdIntegerRanges = map[string][2]*big.Int{
+	"integer":            {nil, nil},
+	"nonPositiveInteger": {nil, big.NewInt(0)},
+	"negativeInteger":    {nil, big.NewInt(-1)},
+	"nonNegativeInteger": {big.NewInt(0), nil},
+	"positiveInteger":    {big.NewInt(1), nil},
+	"long":               {big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)},
+	"int":                {big.NewInt(math.MinInt32), big.NewInt(math.MaxInt32)},
+	"short":              {big.NewInt(math.MinInt16), big.NewInt(math.MaxInt16)},
+	"byte":               {big.NewInt(math.MinInt8), big.NewInt(math.MaxInt8)},
+	"unsignedLong":       {big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)},
+	"unsignedInt":        {big.NewInt(0), big.NewInt(math.MaxUint32)},
+	"unsignedShort":      {big.NewInt(0), big.NewInt(math.MaxUint16)},
+	"unsignedByte":       {big.NewInt(0), big.NewInt(math.MaxUint8)},
+}
+
+// Method xsdType returns the local name of the literal's datatype if it is in the XSD namespace, or
+// "" otherwise.
+func (term Literal) xsdType() string {
+	datatype, ok := term.Datatype.(*Resource)
+	if !ok || !strings.HasPrefix(datatype.URI, string(XSD)) {
+		return ""
+	}
+
+	return datatype.URI[len(XSD):]
+}
+
+// Method error creates a new LiteralValueError for the literal based on err.
+func (term Literal) error(err error) error {
+	return &LiteralValueError{Value: term.Value, Datatype: term.Datatype, Err: err}
+}
+
+// Method Native returns the value of the literal as a Go value, according to its datatype:
+//
+//	xsd:boolean                           bool
+//	xsd:integer and its derived types     int64, or *big.Int if it does not fit
+//	xsd:decimal                           *big.Rat
+//	xsd:float, xsd:double                 float64
+//	xsd:dateTime, xsd:dateTimeStamp,
+//	xsd:date                              time.Time (UTC if no timezone is given)
+//	xsd:duration, xsd:dayTimeDuration     time.Duration
+//	xsd:base64Binary, xsd:hexBinary       []byte
+//	xsd:anyURI                            *url.URL
+//
+// Literals with any other datatype, or none, are returned as their lexical form (a string). A
+// LiteralValueError is returned if the lexical form is not valid for the datatype, or if the value
+// cannot be represented by the Go type (such as a duration with a number of months).
+func (term Literal) Native() (value interface{}, err error) {
+	xsdType := term.xsdType()
+	lexical := strings.TrimSpace(term.Value)
+
+	if bounds, ok := xsdIntegerRanges[xsdType]; ok {
+		if !xsdIntegerRegexp.MatchString(lexical) {
+			return nil, term.error(ErrInvalidLexicalForm)
+		}
+
+		n, _ := new(big.Int).SetString(lexical, 10)
+		if (bounds[0] != nil && n.Cmp(bounds[0]) < 0) || (bounds[1] != nil && n.Cmp(bounds[1]) > 0) {
+			return nil, term.error(ErrOutOfRange)
+		}
+
+		if n.IsInt64() {
+			return n.Int64(), nil
+		}
+
+		return n, nil
+	}
+
+	switch xsdType {
+	case "boolean":
+		switch lexical {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+
+	case "decimal":
+		if xsdDecimalRegexp.MatchString(lexical) {
+			r, _ := new(big.Rat).SetString(lexical)
+			return r, nil
+		}
+
+	case "float", "double":
+		if xsdDoubleRegexp.MatchString(lexical) {
+			return parseXSDDouble(lexical, xsdType == "float"), nil
+		}
+
+	case "dateTime", "dateTimeStamp":
+		if m := xsdDateTimeRegexp.FindStringSubmatch(lexical); m != nil && (xsdType == "dateTime" || m[3] != "") {
+			if t, err := parseXSDTime(lexical, "2006-01-02T15:04:05.999999999", m[3] != "", m[1] == "24:00:00"); err == nil {
+				return t, nil
+			}
+		}
+
+	case "date":
+		if m := xsdDateRegexp.FindStringSubmatch(lexical); m != nil {
+			if t, err := parseXSDTime(lexical, "2006-01-02", m[1] != "", false); err == nil {
+				return t, nil
+			}
+		}
+
+	case "duration", "dayTimeDuration", "yearMonthDuration":
+		if d, err := parseXSDDuration(lexical); err != errInvalid {
+			if err != nil {
+				return nil, term.error(err)
+			}
+
+			return d, nil
+		}
+
+	case "base64Binary":
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(lexical), ""))
+		if err == nil {
+			return b, nil
+		}
+
+	case "hexBinary":
+		b, err := hex.DecodeString(lexical)
+		if err == nil {
+			return b, nil
+		}
+
+	case "anyURI":
+		u, err := url.Parse(lexical)
+		if err == nil {
+			return u, nil
+		}
+
+	default:
+		return term.Value, nil
+	}
+
+	return nil, term.error(ErrInvalidLexicalForm)
+}
+
+// errInvalid is used internally to signal an invalid lexical form.
+var errInvalid = errors.New("invalid")
+
+// parseXSDDouble parses a lexical form already checked against xsdDoubleRegexp. Values too large
+// for the type become infinities, as XSD specifies.
+func parseXSDDouble(lexical string, single bool) float64 {
+	switch lexical {
+	case "INF", "+INF":
+		return math.Inf(1)
+	case "-INF":
+		return math.Inf(-1)
+	case "NaN":
+		return math.NaN()
+	}
+
+	bitSize := 64
+	if single {
+		bitSize = 32
+	}
+
+	f, _ := strconv.ParseFloat(lexical, bitSize)
+	return f
+}
+
+// parseXSDTime parses a date or dateTime lexical form already checked against the corresponding
+// regexp, returning an error for out of range fields such as a 13th month. A time of 24:00:00 is
+// the first instant of the following day.
+func parseXSDTime(lexical string, layout string, zoned bool, endOfDay bool) (t time.Time, err error) {
+	if zoned {
+		layout += "Z07:00"
+	}
+
+	if endOfDay {
+		lexical = strings.Replace(lexical, "T24:", "T00:", 1)
+	}
+
+	t, err = time.Parse(layout, lexical)
+	if err != nil {
+		return t, err
+	}
+
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+// parseXSDDuration parses a duration lexical form. It returns errInvalid if the form is invalid, and
+// ErrOutOfRange if it has years or months or does not fit in a time.Duration.
+func parseXSDDuration(lexical string) (d time.Duration, err error) {
+	m := xsdDurationRegexp.FindStringSubmatch(lexical)
+	if m == nil || lexical == "P" || lexical == "-P" || strings.HasSuffix(lexical, "T") {
+		return 0, errInvalid
+	}
+
+	if (m[2] != "" && strings.Trim(m[2], "0") != "") || (m[3] != "" && strings.Trim(m[3], "0") != "") {
+		return 0, ErrOutOfRange
+	}
+
+	total := new(big.Int)
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[4+i] != "" {
+			n, _ := new(big.Int).SetString(m[4+i], 10)
+			total.Add(total, n.Mul(n, big.NewInt(int64(unit))))
+		}
+	}
+
+	if m[8] != "" {
+		nanos := (m[8] + "000000000")[:9]
+		n, _ := new(big.Int).SetString(nanos, 10)
+		total.Add(total, n)
+	}
+
+	if m[1] == "-" {
+		total.Neg(total)
+	}
+
+	if !total.IsInt64() {
+		return 0, ErrOutOfRange
+	}
+
+	return time.Duration(total.Int64()), nil
+}
+
+// Method Bool returns the value of an xsd:boolean literal.
+func (term Literal) Bool() (b bool, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		return false, term.error(ErrWrongDatatype)
+	}
+
+	return b, nil
+}
+
+// Method BigInt returns the value of a literal of type xsd:integer or one of its derived types.
+func (term Literal) BigInt() (n *big.Int, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return big.NewInt(v), nil
+	case *big.Int:
+		return v, nil
+	}
+
+	return nil, term.error(ErrWrongDatatype)
+}
+
+// Method Int returns the value of a literal of type xsd:integer or one of its derived types. An
+// error is returned if the value does not fit in an int64.
+func (term Literal) Int() (n int64, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case *big.Int:
+		return 0, term.error(ErrOutOfRange)
+	}
+
+	return 0, term.error(ErrWrongDatatype)
+}
+
+// Method Float returns the value of a numeric literal (xsd:float, xsd:double, xsd:decimal, or
+// xsd:integer and its derived types) as a float64, rounding it if necessary.
+func (term Literal) Float() (f float64, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case *big.Int:
+		f, _ = new(big.Float).SetInt(v).Float64()
+		return f, nil
+	case *big.Rat:
+		f, _ = v.Float64()
+		return f, nil
+	}
+
+	return 0, term.error(ErrWrongDatatype)
+}
+
+// Method Rat returns the exact value of an xsd:decimal literal, or of a literal of type xsd:integer
+// or one of its derived types. xsd:float and xsd:double literals are also accepted, unless they
+// are infinite or NaN.
+func (term Literal) Rat() (r *big.Rat, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case *big.Rat:
+		return v, nil
+	case int64:
+		return new(big.Rat).SetInt64(v), nil
+	case *big.Int:
+		return new(big.Rat).SetInt(v), nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, term.error(ErrOutOfRange)
+		}
+
+		return new(big.Rat).SetFloat64(v), nil
+	}
+
+	return nil, term.error(ErrWrongDatatype)
+}
+
+// Method Time returns the value of an xsd:dateTime, xsd:dateTimeStamp or xsd:date literal.
+func (term Literal) Time() (t time.Time, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return t, err
+	}
+
+	t, ok := value.(time.Time)
+	if !ok {
+		return t, term.error(ErrWrongDatatype)
+	}
+
+	return t, nil
+}
+
+// Method Duration returns the value of an xsd:duration or xsd:dayTimeDuration literal.
+func (term Literal) Duration() (d time.Duration, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return 0, err
+	}
+
+	d, ok := value.(time.Duration)
+	if !ok {
+		return 0, term.error(ErrWrongDatatype)
+	}
+
+	return d, nil
+}
+
+// Method Bytes returns the value of an xsd:base64Binary or xsd:hexBinary literal.
+func (term Literal) Bytes() (b []byte, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := value.([]byte)
+	if !ok {
+		return nil, term.error(ErrWrongDatatype)
+	}
+
+	return b, nil
+}
+
+// Method URL returns the value of an xsd:anyURI literal.
+func (term Literal) URL() (u *url.URL, err error) {
+	value, err := term.Native()
+	if err != nil {
+		return nil, err
+	}
+
+	u, ok := value.(*url.URL)
+	if !ok {
+		return nil, term.error(ErrWrongDatatype)
+	}
+
+	return u, nil
+}
+
+// Function NewCheckedLiteral returns a new literal with the given value and datatype, or a
+// LiteralValueError if the value is not a valid lexical form of a datatype known to Native.
+func NewCheckedLiteral(value string, datatype Term) (term Term, err error) {
+	term = NewLiteralWithDatatype(value, datatype)
+
+	_, err = term.(*Literal).Native()
+	if err != nil {
+		return nil, err
+	}
+
+	return term, nil
+}
+
+// Function NewBoolLiteral returns a new xsd:boolean literal.
+func NewBoolLiteral(b bool) (term Term) {
+	return NewLiteralWithDatatype(strconv.FormatBool(b), XSD.Get("boolean"))
+}
+
+// Function NewIntLiteral returns a new xsd:integer literal.
+func NewIntLiteral(n int64) (term Term) {
+	return NewLiteralWithDatatype(strconv.FormatInt(n, 10), XSD.Get("integer"))
+}
+
+// Function NewBigIntLiteral returns a new xsd:integer literal.
+func NewBigIntLiteral(n *big.Int) (term Term) {
+	return NewLiteralWithDatatype(n.String(), XSD.Get("integer"))
+}
+
+// Function NewDecimalLiteral returns a new xsd:decimal literal, or a LiteralValueError if r has no
+// finite decimal representation (such as 1/3).
+func NewDecimalLiteral(r *big.Rat) (term Term, err error) {
+	// A fraction has a finite decimal representation if its denominator has no prime factors other
+	// than 2 and 5; the number of digits needed is the larger of their multiplicities.
+	denom := new(big.Int).Set(r.Denom())
+	digits := 0
+	for _, p := range []int64{2, 5} {
+		n := 0
+		q, m := new(big.Int), new(big.Int)
+		for {
+			q.QuoRem(denom, big.NewInt(p), m)
+			if m.Sign() != 0 {
+				break
+			}
+
+			denom.Set(q)
+			n++
+		}
+
+		if n > digits {
+			digits = n
+		}
+	}
+
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return nil, &LiteralValueError{Value: r.String(), Datatype: XSD.Get("decimal"), Err: ErrOutOfRange}
+	}
+
+	if digits == 0 {
+		digits = 1
+	}
+
+	return NewLiteralWithDatatype(r.FloatString(digits), XSD.Get("decimal")), nil
+}
+
+// Function NewFloatLiteral returns a new xsd:double literal, in canonical form (such as "1.5E2").
+func NewFloatLiteral(f float64) (term Term) {
+	return NewLiteralWithDatatype(formatXSDDouble(f, 64), XSD.Get("double"))
+}
+
+// formatXSDDouble returns the canonical lexical form of a float or double.
+func formatXSDDouble(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+
+	str := strconv.FormatFloat(f, 'E', -1, bitSize)
+	mantissa, exponent := str[:strings.Index(str, "E")], str[strings.Index(str, "E")+1:]
+
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+
+	exp, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(exp)
+}
+
+// Function NewDateTimeLiteral returns a new xsd:dateTime literal, including the time's timezone.
+func NewDateTimeLiteral(t time.Time) (term Term) {
+	return NewLiteralWithDatatype(t.Format(time.RFC3339Nano), XSD.Get("dateTime"))
+}
+
+// Function NewDateLiteral returns a new xsd:date literal, without a timezone.
+func NewDateLiteral(t time.Time) (term Term) {
+	return NewLiteralWithDatatype(t.Format("2006-01-02"), XSD.Get("date"))
+}
+
+// Function NewDurationLiteral returns a new xsd:dayTimeDuration literal (such as "PT1H30M").
+func NewDurationLiteral(d time.Duration) (term Term) {
+	var b strings.Builder
+
+	// Work with the magnitude as an unsigned number, so that the most negative duration is handled.
+	n := uint64(d)
+	if d < 0 {
+		b.WriteString("-")
+		n = -n
+	}
+
+	b.WriteString("P")
+
+	days, n := n/uint64(24*time.Hour), n%uint64(24*time.Hour)
+	hours, n := n/uint64(time.Hour), n%uint64(time.Hour)
+	minutes, n := n/uint64(time.Minute), n%uint64(time.Minute)
+	seconds, nanos := n/uint64(time.Second), n%uint64(time.Second)
+
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+
+	if hours > 0 || minutes > 0 || seconds > 0 || nanos > 0 || days == 0 {
+		b.WriteString("T")
+
+		if hours > 0 {
+			fmt.Fprintf(&b, "%dH", hours)
+		}
+
+		if minutes > 0 {
+			fmt.Fprintf(&b, "%dM", minutes)
+		}
+
+		if seconds > 0 || nanos > 0 || (hours == 0 && minutes == 0) {
+			fmt.Fprintf(&b, "%d", seconds)
+			if nanos > 0 {
+				b.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", nanos), "0"))
+			}
+
+			b.WriteString("S")
+		}
+	}
+
+	return NewLiteralWithDatatype(b.String(), XSD.Get("dayTimeDuration"))
+}
+
+// Function NewBase64Literal returns a new xsd:base64Binary literal.
+func NewBase64Literal(b []byte) (term Term) {
+	return NewLiteralWithDatatype(base64.StdEncoding.EncodeToString(b), XSD.Get("base64Binary"))
+}
+
+// Function NewHexLiteral returns a new xsd:hexBinary literal, using upper-case digits.
+func NewHexLiteral(b []byte) (term Term) {
+	return NewLiteralWithDatatype(strings.ToUpper(hex.EncodeToString(b)), XSD.Get("hexBinary"))
+}
+
+// Function NewURLLiteral returns a new xsd:anyURI literal.
+func NewURLLiteral(u *url.URL) (term Term) {
+	return NewLiteralWithDatatype(u.String(), XSD.Get("anyURI"))
+}
+
+// Function NewLiteralFromValue returns a new literal representing a Go value, choosing the datatype
+// as Native does (float32 values become xsd:float literals, and strings plain literals). A
+// LiteralValueError is returned for values of other types.
+func NewLiteralFromValue(value interface{}) (term Term, err error) {
+	switch v := value.(type) {
+	case bool:
+		return NewBoolLiteral(v), nil
+	case int:
+		return NewIntLiteral(int64(v)), nil
+	case int8:
+		return NewIntLiteral(int64(v)), nil
+	case int16:
+		return NewIntLiteral(int64(v)), nil
+	case int32:
+		return NewIntLiteral(int64(v)), nil
+	case int64:
+		return NewIntLiteral(v), nil
+	case uint:
+		return NewBigIntLiteral(new(big.Int).SetUint64(uint64(v))), nil
+	case uint8:
+		return NewIntLiteral(int64(v)), nil
+	case uint16:
+		return NewIntLiteral(int64(v)), nil
+	case uint32:
+		return NewIntLiteral(int64(v)), nil
+	case uint64:
+		return NewBigIntLiteral(new(big.Int).SetUint64(v)), nil
+	case *big.Int:
+		return NewBigIntLiteral(v), nil
+	case *big.Rat:
+		return NewDecimalLiteral(v)
+	case float32:
+		return NewLiteralWithDatatype(formatXSDDouble(float64(v), 32), XSD.Get("float")), nil
+	case float64:
+		return NewFloatLiteral(v), nil
+	case time.Time:
+		return NewDateTimeLiteral(v), nil
+	case time.Duration:
+		return NewDurationLiteral(v), nil
+	case []byte:
+		return NewBase64Literal(v), nil
+	case *url.URL:
+		return NewURLLiteral(v), nil
+	case string:
+		return NewLiteral(v), nil
+	}
+
+	return nil, &LiteralValueError{Value: fmt.Sprintf("%T", value), Err: ErrUnsupportedValue}
+}
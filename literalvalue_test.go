@@ -0,0 +1,179 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"math"
+	"math/big"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var nativeTestCases = []struct {
+	literal  Term
+	expected interface{}
+}{
+	{NewLiteral("plain"), "plain"},
+	{NewLiteralWithLanguage("chat", "fr"), "chat"},
+	{NewLiteralWithDatatype("x", NewResource("http://example.org/dt")), "x"},
+	{NewLiteralWithDatatype("true", XSD.Get("boolean")), true},
+	{NewLiteralWithDatatype(" 0 ", XSD.Get("boolean")), false},
+	{NewLiteralWithDatatype("+42", XSD.Get("integer")), int64(42)},
+	{NewLiteralWithDatatype("-128", XSD.Get("byte")), int64(-128)},
+	{NewLiteralWithDatatype("123456789012345678901234567890", XSD.Get("integer")), func() *big.Int {
+		n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+		return n
+	}()},
+	{NewLiteralWithDatatype("-1.50", XSD.Get("decimal")), big.NewRat(-3, 2)},
+	{NewLiteralWithDatatype("1.5E2", XSD.Get("double")), 150.0},
+	{NewLiteralWithDatatype("-INF", XSD.Get("float")), math.Inf(-1)},
+	{NewLiteralWithDatatype("2012-03-04T05:06:07.5+01:00", XSD.Get("dateTime")), time.Date(2012, 3, 4, 4, 6, 7, 500000000, time.UTC)},
+	{NewLiteralWithDatatype("2012-03-04T24:00:00", XSD.Get("dateTime")), time.Date(2012, 3, 5, 0, 0, 0, 0, time.UTC)},
+	{NewLiteralWithDatatype("2012-03-04", XSD.Get("date")), time.Date(2012, 3, 4, 0, 0, 0, 0, time.UTC)},
+	{NewLiteralWithDatatype("-P1DT2H3M4.5S", XSD.Get("duration")), -(26*time.Hour + 3*time.Minute + 4500*time.Millisecond)},
+	{NewLiteralWithDatatype("P0Y0M", XSD.Get("yearMonthDuration")), time.Duration(0)},
+	{NewLiteralWithDatatype("aGVs bG8=", XSD.Get("base64Binary")), []byte("hello")},
+	{NewLiteralWithDatatype("0FB7", XSD.Get("hexBinary")), []byte{0x0f, 0xb7}},
+	{NewLiteralWithDatatype("http://example.org/a?b", XSD.Get("anyURI")), &url.URL{Scheme: "http", Host: "example.org", Path: "/a", RawQuery: "b"}},
+}
+
+var nativeNegativeCases = map[Term]error{
+	NewLiteralWithDatatype("yes", XSD.Get("boolean")):                       ErrInvalidLexicalForm,
+	NewLiteralWithDatatype("1.0", XSD.Get("integer")):                       ErrInvalidLexicalForm,
+	NewLiteralWithDatatype("128", XSD.Get("byte")):                          ErrOutOfRange,
+	NewLiteralWithDatatype("0", XSD.Get("positiveInteger")):                 ErrOutOfRange,
+	NewLiteralWithDatatype("1/2", XSD.Get("decimal")):                       ErrInvalidLexicalForm,
+	NewLiteralWithDatatype("inf", XSD.Get("double")):                        ErrInvalidLexicalForm,
+	NewLiteralWithDatatype("2012-13-01T00:00:00", XSD.Get("dateTime")):      ErrInvalidLexicalForm,
+	NewLiteralWithDatatype("2012-01-01T00:00:00", XSD.Get("dateTimeStamp")): ErrInvalidLexicalForm,
+	NewLiteralWithDatatype("P1Y", XSD.Get("duration")):                      ErrOutOfRange,
+	NewLiteralWithDatatype("PT", XSD.Get("duration")):                       ErrInvalidLexicalForm,
+	NewLiteralWithDatatype("0FB", XSD.Get("hexBinary")):                     ErrInvalidLexicalForm,
+}
+
+func TestLiteralNative(t *testing.T) {
+	for _, tc := range nativeTestCases {
+		value, err := tc.literal.(*Literal).Native()
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tc.literal, err)
+			continue
+		}
+
+		equal := reflect.DeepEqual(value, tc.expected)
+		switch v := value.(type) {
+		case *big.Int:
+			equal = v.Cmp(tc.expected.(*big.Int)) == 0
+		case *big.Rat:
+			equal = v.Cmp(tc.expected.(*big.Rat)) == 0
+		case time.Time:
+			equal = v.Equal(tc.expected.(time.Time))
+		}
+
+		if !equal {
+			t.Errorf("%s: expected %#v but got %#v", tc.literal, tc.expected, value)
+		}
+	}
+
+	for literal, expected := range nativeNegativeCases {
+		_, err := literal.(*Literal).Native()
+		if le, ok := err.(*LiteralValueError); !ok || le.Err != expected {
+			t.Errorf("%s: expected %s but got %v", literal, expected, err)
+		}
+	}
+}
+
+func TestLiteralAccessors(t *testing.T) {
+	n := NewLiteralWithDatatype("7", XSD.Get("int")).(*Literal)
+
+	if i, err := n.Int(); err != nil || i != 7 {
+		t.Errorf("Int: expected 7 but got %d, %v", i, err)
+	}
+
+	if f, err := n.Float(); err != nil || f != 7 {
+		t.Errorf("Float: expected 7 but got %f, %v", f, err)
+	}
+
+	if r, err := n.Rat(); err != nil || r.Cmp(big.NewRat(7, 1)) != 0 {
+		t.Errorf("Rat: expected 7 but got %s, %v", r, err)
+	}
+
+	if _, err := n.Bool(); err == nil || err.(*LiteralValueError).Err != ErrWrongDatatype {
+		t.Errorf("Bool: expected ErrWrongDatatype but got %v", err)
+	}
+
+	huge := NewLiteralWithDatatype("18446744073709551615", XSD.Get("unsignedLong")).(*Literal)
+	if _, err := huge.Int(); err == nil || err.(*LiteralValueError).Err != ErrOutOfRange {
+		t.Errorf("Int: expected ErrOutOfRange but got %v", err)
+	}
+}
+
+var literalFromValueTestCases = []struct {
+	value    interface{}
+	expected string
+}{
+	{true, `"true"^^<http://www.w3.org/2001/XMLSchema#boolean>`},
+	{-5, `"-5"^^<http://www.w3.org/2001/XMLSchema#integer>`},
+	{uint64(math.MaxUint64), `"18446744073709551615"^^<http://www.w3.org/2001/XMLSchema#integer>`},
+	{big.NewRat(-5, 4), `"-1.25"^^<http://www.w3.org/2001/XMLSchema#decimal>`},
+	{big.NewRat(3, 1), `"3.0"^^<http://www.w3.org/2001/XMLSchema#decimal>`},
+	{150.0, `"1.5E2"^^<http://www.w3.org/2001/XMLSchema#double>`},
+	{0.001, `"1.0E-3"^^<http://www.w3.org/2001/XMLSchema#double>`},
+	{float32(0.5), `"5.0E-1"^^<http://www.w3.org/2001/XMLSchema#float>`},
+	{math.Inf(1), `"INF"^^<http://www.w3.org/2001/XMLSchema#double>`},
+	{time.Date(2012, 3, 4, 5, 6, 7, 0, time.UTC), `"2012-03-04T05:06:07Z"^^<http://www.w3.org/2001/XMLSchema#dateTime>`},
+	{26*time.Hour + 1500*time.Millisecond, `"P1DT2H1.5S"^^<http://www.w3.org/2001/XMLSchema#dayTimeDuration>`},
+	{-time.Minute, `"-PT1M"^^<http://www.w3.org/2001/XMLSchema#dayTimeDuration>`},
+	{time.Duration(0), `"PT0S"^^<http://www.w3.org/2001/XMLSchema#dayTimeDuration>`},
+	{[]byte("hello"), `"aGVsbG8="^^<http://www.w3.org/2001/XMLSchema#base64Binary>`},
+	{"text", `"text"`},
+}
+
+func TestNewLiteralFromValue(t *testing.T) {
+	for _, tc := range literalFromValueTestCases {
+		term, err := NewLiteralFromValue(tc.value)
+		if err != nil {
+			t.Errorf("%#v: unexpected error %s", tc.value, err)
+			continue
+		}
+
+		if term.String() != tc.expected {
+			t.Errorf("%#v: expected %s but got %s", tc.value, tc.expected, term)
+		}
+
+		// The value must survive a round trip, apart from the widening of integers.
+		if _, err := term.(*Literal).Native(); err != nil {
+			t.Errorf("%s: unexpected error %s", term, err)
+		}
+	}
+
+	if _, err := NewDecimalLiteral(big.NewRat(1, 3)); err == nil {
+		t.Errorf("Expected an error for 1/3 but got none")
+	}
+
+	if _, err := NewLiteralFromValue(struct{}{}); err == nil || err.(*LiteralValueError).Err != ErrUnsupportedValue {
+		t.Errorf("Expected ErrUnsupportedValue but got %v", err)
+	}
+
+	if _, err := NewCheckedLiteral("12a", XSD.Get("integer")); err == nil {
+		t.Errorf("Expected an error for an invalid integer but got none")
+	}
+}
@@ -20,11 +20,10 @@
 package loop
 
 import (
-	"encoding/base64"
-	"encoding/hex"
 	"fmt"
 	"github.com/kierdavis/argo"
-	"strconv"
+	"math/big"
+	"net/url"
 	"strings"
 )
 
@@ -70,35 +69,7 @@ func (graph *Interpreter) Evaluate(term argo.Term, ctx map[string]interface{}) (
 
 	lit, isLit := term.(*argo.Literal)
 	if isLit {
-		switch lit.Datatype {
-		case XSD.Get("boolean"):
-			switch lit.Value {
-			case "true", "1":
-				return true, nil
-
-			case "false", "0":
-				return false, nil
-
-			default:
-				return nil, fmt.Errorf("Invalid boolean value: %s", lit.Value)
-			}
-
-		case XSD.Get("base64Binary"):
-			return base64.StdEncoding.DecodeString(lit.Value)
-
-		case XSD.Get("hexBinary"):
-			return hex.DecodeString(lit.Value)
-
-		case XSD.Get("float"), XSD.Get("decimal"), XSD.Get("double"):
-			return strconv.ParseFloat(lit.Value, 64)
-
-		case XSD.Get("integer"), XSD.Get("nonPositiveInteger"), XSD.Get("negativeInteger"), XSD.Get("long"), XSD.Get("int"), XSD.Get("short"), XSD.Get("byte"), XSD.Get("nonNegativeInteger"), XSD.Get("unsignedLong"), XSD.Get("unsignedInt"), XSD.Get("unsignedShort"), XSD.Get("unsignedByte"), XSD.Get("positiveInteger"):
-			return strconv.ParseInt(lit.Value, 10, 64)
-
-		case XSD.Get("anyURI"):
-			return Resource(argo.NewResource(lit.Value)), nil
-
-		case XSD.Get("QName"):
+		if lit.Datatype != nil && lit.Datatype.Equal(XSD.Get("QName")) {
 			colonPos := strings.Index(lit.Value, ":")
 			if colonPos < 0 {
 				return nil, fmt.Errorf("No colon found in QName value: %s", lit.Value)
@@ -112,10 +83,26 @@ func (graph *Interpreter) Evaluate(term argo.Term, ctx map[string]interface{}) (
 			}
 
 			return Resource(argo.NewResource(a + b)), nil
+		}
+
+		value, err := lit.Native()
+		if err != nil {
+			return nil, err
+		}
 
-		default:
-			return lit.Value, nil
+		// Builtins work with int64 and float64 numbers, and refer to resources by term.
+		switch v := value.(type) {
+		case *big.Int:
+			return lit.Int()
+
+		case *big.Rat:
+			return lit.Float()
+
+		case *url.URL:
+			return Resource(argo.NewResource(v.String())), nil
 		}
+
+		return value, nil
 	}
 
 	graph.FetchIfNeeded(term)
@@ -252,11 +252,7 @@ func (expr BooleanConstant) ToLOOP() (code string) {
 }
 
 func (expr BooleanConstant) ToRDF(graph *argo.Graph) (term argo.Term) {
-	if expr {
-		return argo.NewLiteralWithDatatype("true", argo.XSD.Get("boolean"))
-	}
-
-	return argo.NewLiteralWithDatatype("false", argo.XSD.Get("boolean"))
+	return argo.NewBoolLiteral(bool(expr))
 }
 
 type DataConstant []byte
@@ -266,7 +262,7 @@ func (expr DataConstant) ToLOOP() (code string) {
 }
 
 func (expr DataConstant) ToRDF(graph *argo.Graph) (term argo.Term) {
-	return argo.NewLiteralWithDatatype(base64.StdEncoding.EncodeToString([]byte(expr)), argo.XSD.Get("base64Binary"))
+	return argo.NewBase64Literal([]byte(expr))
 }
 
 type FloatConstant float64
@@ -276,7 +272,7 @@ func (expr FloatConstant) ToLOOP() (code string) {
 }
 
 func (expr FloatConstant) ToRDF(graph *argo.Graph) (term argo.Term) {
-	return argo.NewLiteralWithDatatype(fmt.Sprintf("%f", float64(expr)), argo.XSD.Get("double"))
+	return argo.NewFloatLiteral(float64(expr))
 }
 
 type IntegerConstant int64
@@ -286,7 +282,7 @@ func (expr IntegerConstant) ToLOOP() (code string) {
 }
 
 func (expr IntegerConstant) ToRDF(graph *argo.Graph) (term argo.Term) {
-	return argo.NewLiteralWithDatatype(fmt.Sprintf("%d", float64(expr)), argo.XSD.Get("integer"))
+	return argo.NewIntLiteral(int64(expr))
 }
 
 type ResourceConstant string
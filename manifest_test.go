@@ -0,0 +1,117 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	mfNs   = "http://www.w3.org/2001/sw/DataAccess/tests/test-manifest#"
+	rdftNs = "http://www.w3.org/ns/rdftest#"
+)
+
+// A manifestEntry is a test from a W3C test suite manifest.
+type manifestEntry struct {
+	Name   string // The test's mf:name
+	Type   string // Local name of the test's type, such as "TestNTriplesPositiveSyntax"
+	Action string // Path of the input file
+	Result string // Path of the expected output file, or "" if there is none
+}
+
+// loadManifest reads the entries of the manifest.ttl in dir, in the order they are listed.
+func loadManifest(t *testing.T, dir string) (entries []manifestEntry) {
+	path, err := filepath.Abs(filepath.Join(dir, "manifest.ttl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	base := "file://" + filepath.ToSlash(path)
+	r := NewTurtleReader(f)
+	r.SetBase(base)
+
+	objects := make(map[string][]Term)
+	for {
+		triple, err := r.Read()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("%s: %s", path, err)
+			}
+
+			break
+		}
+
+		key := triple.Subject.String() + " " + triple.Predicate.String()
+		objects[key] = append(objects[key], triple.Object)
+	}
+
+	get := func(subject Term, predicate string) Term {
+		if values := objects[subject.String()+" <"+predicate+">"]; len(values) > 0 {
+			return values[0]
+		}
+
+		return nil
+	}
+
+	file := func(subject Term, predicate string) string {
+		if resource, ok := get(subject, predicate).(*Resource); ok {
+			return filepath.FromSlash(strings.TrimPrefix(resource.URI, "file://"))
+		}
+
+		return ""
+	}
+
+	node := get(NewResource(base), mfNs+"entries")
+	for node != nil && !node.Equal(Nil) {
+		entry := get(node, First.(*Resource).URI)
+
+		var e manifestEntry
+		if name, ok := get(entry, mfNs+"name").(*Literal); ok {
+			e.Name = name.Value
+		}
+
+		if typ, ok := get(entry, A.(*Resource).URI).(*Resource); ok {
+			e.Type = strings.TrimPrefix(typ.URI, rdftNs)
+		}
+
+		e.Action = file(entry, mfNs+"action")
+		e.Result = file(entry, mfNs+"result")
+
+		entries = append(entries, e)
+		node = get(node, Rest.(*Resource).URI)
+	}
+
+	if len(entries) == 0 {
+		t.Fatalf("%s: no entries found", path)
+	}
+
+	return entries
+}
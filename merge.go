@@ -0,0 +1,138 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"io"
+	"sync"
+)
+
+// A BlankNodeScope renames the blank nodes of one or more sources to fresh blank nodes, so that
+// sources read in different scopes cannot share blank nodes by accident, while every occurrence of
+// a label within the same scope still denotes the same node. This is the "standardizing apart"
+// required when merging RDF graphs.
+type BlankNodeScope struct {
+	// The allocator providing the fresh blank nodes. If nil, DefaultAllocator is used.
+	Allocator BlankNodeAllocator
+
+	mutex  sync.Mutex
+	labels map[string]Term
+}
+
+// Function NewBlankNodeScope returns a new, empty scope drawing blank nodes from alloc (or from
+// DefaultAllocator if alloc is nil).
+func NewBlankNodeScope(alloc BlankNodeAllocator) (scope *BlankNodeScope) {
+	return &BlankNodeScope{
+		Allocator: alloc,
+		labels:    make(map[string]Term),
+	}
+}
+
+// Method Rename returns the blank node standing for term within the scope if term is a blank node,
+// or term otherwise.
+func (scope *BlankNodeScope) Rename(term Term) (result Term) {
+	node, ok := term.(*BlankNode)
+	if !ok {
+		return term
+	}
+
+	scope.mutex.Lock()
+	defer scope.mutex.Unlock()
+
+	result, ok = scope.labels[node.ID]
+	if !ok {
+		if scope.Allocator != nil {
+			result = scope.Allocator.NewBlankNode()
+		} else {
+			result = DefaultAllocator.NewBlankNode()
+		}
+
+		scope.labels[node.ID] = result
+	}
+
+	return result
+}
+
+// Method RenameTriple returns triple with all its blank nodes (including the graph name and those
+// in quoted triples) renamed.
+func (scope *BlankNodeScope) RenameTriple(triple *Triple) (result *Triple) {
+	return transformTriple(triple, scope.Rename)
+}
+
+// Method NewBlankNodeScope returns a new scope drawing blank nodes from the graph's allocator.
+func (graph *Graph) NewBlankNodeScope() (scope *BlankNodeScope) {
+	return NewBlankNodeScope(graph.Allocator)
+}
+
+// Method MergeFromChannel receives incoming triples and adds them to the graph, renaming their
+// blank nodes through scope. If scope is nil, blank node labels are kept as they are, as with
+// LoadFromChannel.
+func (graph *Graph) MergeFromChannel(ch chan *Triple, scope *BlankNodeScope) {
+	if scope == nil {
+		graph.LoadFromChannel(ch)
+		return
+	}
+
+	for triple := range ch {
+		graph.Add(scope.RenameTriple(triple))
+	}
+}
+
+// Method Merge adds all triples of other to the graph, with the blank nodes of other standardized
+// apart from those already in the graph. The prefixes of other are added to the graph's prefix map.
+func (graph *Graph) Merge(other *Graph) {
+	scope := graph.NewBlankNodeScope()
+
+	for _, triple := range other.triples() {
+		graph.Add(scope.RenameTriple(triple))
+	}
+
+	for uri, prefix := range other.Prefixes {
+		if _, ok := graph.Prefixes[uri]; !ok {
+			graph.Prefixes[uri] = prefix
+		}
+	}
+}
+
+// Method ParseInScope is like Parse, but renames the blank nodes of the document through scope.
+// Documents parsed in the same scope share their blank node labels, while documents parsed in
+// different scopes never share blank nodes. If scope is nil, labels are kept as they are.
+func (graph *Graph) ParseInScope(parser Parser, r io.Reader, scope *BlankNodeScope) (err error) {
+	tripleChan := make(chan *Triple)
+	errChan := make(chan error)
+
+	go parser(r, tripleChan, errChan, graph.Prefixes)
+
+	// Load concurrently, as a parser may report an error before it has closed tripleChan.
+	done := make(chan bool)
+	go func() {
+		graph.MergeFromChannel(tripleChan, scope)
+		close(done)
+	}()
+
+	for e := range errChan {
+		if err == nil {
+			err = e
+		}
+	}
+
+	<-done
+	return err
+}
@@ -0,0 +1,89 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"strings"
+	"testing"
+)
+
+const mergeDocA = `@prefix ex: <http://example.org/> .
+_:b0 ex:name "a" ; ex:knows _:b1 .
+<< _:b0 ex:knows _:b1 >> ex:since 2001 .`
+
+const mergeDocB = `@prefix ex: <http://example.org/> .
+_:b0 ex:name "b" .`
+
+func TestParseInScope(t *testing.T) {
+	graph := NewGraph(NewListStore())
+	graph.Allocator = NewCounterAllocator("m")
+
+	for _, doc := range []string{mergeDocA, mergeDocB} {
+		err := graph.ParseInScope(ParseTurtle, strings.NewReader(doc), graph.NewBlankNodeScope())
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+	}
+
+	expected := parseTurtleGraph(t, `@prefix ex: <http://example.org/> .
+_:m0 ex:name "a" ; ex:knows _:m1 .
+<< _:m0 ex:knows _:m1 >> ex:since 2001 .
+_:m2 ex:name "b" .`)
+
+	if c := graph.Compare(expected); !c.Isomorphic() {
+		t.Errorf("Sources were not kept apart:\n%s", c)
+	}
+
+	// Documents parsed in the same scope share their labels.
+	shared := NewGraph(NewListStore())
+	scope := shared.NewBlankNodeScope()
+
+	for _, doc := range []string{mergeDocA, mergeDocB} {
+		err := shared.ParseInScope(ParseTurtle, strings.NewReader(doc), scope)
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+	}
+
+	n := 0
+	for _ = range shared.Filter(scope.Rename(NewBlankNode("b0")), NewResource("http://example.org/name"), nil) {
+		n++
+	}
+
+	if n != 2 {
+		t.Errorf("Expected 2 names for the shared node but got %d", n)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := parseTurtleGraph(t, mergeDocB)
+	b := parseTurtleGraph(t, mergeDocB)
+	b.Bind("http://example.org/other#", "other")
+
+	a.Merge(b)
+
+	if a.Num() != 2 {
+		t.Errorf("Expected 2 triples after merging but got %d", a.Num())
+	}
+
+	if a.Prefixes["http://example.org/other#"] != "other" {
+		t.Errorf("Expected the prefixes of the merged graph to be added but got %v", a.Prefixes)
+	}
+}
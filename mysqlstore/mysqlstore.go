@@ -20,6 +20,7 @@
 package mysqlstore
 
 import (
+	"context"
 	"fmt"
 	"github.com/kierdavis/argo"
 	"github.com/yanatan16/GoMySQL"
@@ -36,8 +37,9 @@ type cachedLiteral struct {
 type mysqlRequest struct {
 	Query      string
 	Params     []interface{}
+	Context    context.Context
 	ResultChan chan []interface{}
-	FailChan   chan bool
+	ErrChan    chan error
 }
 
 func term2node(term argo.Term) (node string) {
@@ -62,7 +64,10 @@ func node2term(node string) (term argo.Term) {
 }
 
 type MySQLStore struct {
-	Debug        bool
+	Debug bool
+
+	// Called with the errors encountered by the methods that cannot return them (Add, Remove,
+	// Filter etc.). The ...Context variants of those methods return errors instead.
 	ErrorHandler func(error)
 
 	requests         chan *mysqlRequest
@@ -154,7 +159,15 @@ func (store *MySQLStore) handleMySQLRequest(client *mysql.Client, request *mysql
 				break
 			}
 
-			request.ResultChan <- results
+			// results is overwritten by the next Fetch, so send a copy
+			row := make([]interface{}, len(results))
+			copy(row, results)
+
+			select {
+			case request.ResultChan <- row:
+			case <-request.Context.Done():
+				return request.Context.Err()
+			}
 		}
 	}
 
@@ -167,72 +180,91 @@ func (store *MySQLStore) handleMySQLRequests(client *mysql.Client) {
 		err := store.handleMySQLRequest(client, request)
 		close(request.ResultChan)
 
-		request.FailChan <- err != nil
-		if err != nil {
-			store.ErrorHandler(err)
-		}
-
-		close(request.FailChan)
+		request.ErrChan <- err
+		close(request.ErrChan)
 	}
 }
 
-func (store *MySQLStore) execute(query string, params ...interface{}) (resultChan chan []interface{}, failChan chan bool) {
-	resultChan = make(chan []interface{})
-	failChan = make(chan bool)
-	store.requests <- &mysqlRequest{query, params, resultChan, failChan}
+func (store *MySQLStore) execute(ctx context.Context, query string, params ...interface{}) (request *mysqlRequest, err error) {
+	request = &mysqlRequest{
+		Query:      query,
+		Params:     params,
+		Context:    ctx,
+		ResultChan: make(chan []interface{}),
+		ErrChan:    make(chan error, 1),
+	}
 
-	return resultChan, failChan
+	select {
+	case store.requests <- request:
+		return request, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (store *MySQLStore) selectOne(query string, params ...interface{}) (row []interface{}, ok bool) {
+func (store *MySQLStore) selectOne(ctx context.Context, query string, params ...interface{}) (row []interface{}, err error) {
 	// Either returns:
-	//   (row, true) - success, returns the first valid row
-	//   (nil, true) - success but no results
-	//   (nil, false) - error, caller should return immediately
-
-	resultChan, failChan := store.execute(query, params...)
-	row = <-resultChan
+	//   (row, nil) - success, returns the first valid row
+	//   (nil, nil) - success but no results
+	//   (nil, err) - error, caller should return it immediately
 
-	// Clean out extra results
-	for _ = range resultChan {
+	request, err := store.execute(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
 
+	for r := range request.ResultChan {
+		if row == nil {
+			row = r
+		}
 	}
 
-	if row == nil { // Channel has been closed, so either an error occured or no results were returned
-		return nil, !<-failChan
+	err = <-request.ErrChan
+	if err != nil {
+		return nil, err
 	}
 
-	return row, true
+	return row, nil
 }
 
-func (store *MySQLStore) execVoid(query string, params ...interface{}) (ok bool) {
-	resultChan, failChan := store.execute(query, params...)
+func (store *MySQLStore) execVoid(ctx context.Context, query string, params ...interface{}) (err error) {
+	request, err := store.execute(ctx, query, params...)
+	if err != nil {
+		return err
+	}
 
 	// Clean out results
-	for _ = range resultChan {
+	for _ = range request.ResultChan {
 
 	}
 
-	return !<-failChan
+	return <-request.ErrChan
+}
+
+func (store *MySQLStore) handle(err error) {
+	if err != nil {
+		store.ErrorHandler(err)
+	}
 }
 
-func (store *MySQLStore) cacheLookup(uri string, cache map[string]uint64, table string) (id uint64) {
+func (store *MySQLStore) cacheLookup(ctx context.Context, uri string, cache map[string]uint64, table string) (id uint64, err error) {
 	// Try the cache
 	id, ok := cache[uri]
 	if !ok { // Cache miss, try the database
-		row, ok := store.selectOne("SELECT id FROM %s_"+table+" WHERE uri = ? LIMIT 1", uri)
-		if !ok {
-			return 0
+		row, err := store.selectOne(ctx, "SELECT id FROM %s_"+table+" WHERE uri = ? LIMIT 1", uri)
+		if err != nil {
+			return 0, err
 		}
 
 		if row == nil {
-			if !store.execVoid("INSERT INTO %s_"+table+" SET uri = ?", uri) {
-				return 0
+			err = store.execVoid(ctx, "INSERT INTO %s_"+table+" SET uri = ?", uri)
+			if err != nil {
+				return 0, err
 			}
 
-			row, ok = store.selectOne("SELECT id FROM %s_"+table+" WHERE uri = ? LIMIT 1", uri)
-			if !ok {
-				return 0
+			row, err = store.selectOne(ctx, "SELECT id FROM %s_"+table+" WHERE uri = ? LIMIT 1", uri)
+			if err != nil {
+				return 0, err
 			}
 		}
 
@@ -243,18 +275,18 @@ func (store *MySQLStore) cacheLookup(uri string, cache map[string]uint64, table
 	}
 
 	fmt.Printf("cache lookup %q -> %d\n", uri, id)
-	return id
+	return id, nil
 }
 
-func (store *MySQLStore) node2id(uri string) (id uint64) {
-	return store.cacheLookup(uri, store.nodeLookup, "nodes")
+func (store *MySQLStore) node2id(ctx context.Context, uri string) (id uint64, err error) {
+	return store.cacheLookup(ctx, uri, store.nodeLookup, "nodes")
 }
 
-func (store *MySQLStore) prefix2id(uri string) (id uint64) {
-	return store.cacheLookup(uri, store.prefixLookup, "prefixes")
+func (store *MySQLStore) prefix2id(ctx context.Context, uri string) (id uint64, err error) {
+	return store.cacheLookup(ctx, uri, store.prefixLookup, "prefixes")
 }
 
-func (store *MySQLStore) literal2id(lit *argo.Literal) (id uint64) {
+func (store *MySQLStore) literal2id(ctx context.Context, lit *argo.Literal) (id uint64, err error) {
 	datatypeURI := ""
 	if lit.Datatype != nil {
 		datatypeURI = lit.Datatype.(*argo.Resource).URI
@@ -267,22 +299,26 @@ func (store *MySQLStore) literal2id(lit *argo.Literal) (id uint64) {
 	if !ok { // Cache miss, try the database
 		var datatypeID uint64
 		if lit.Datatype != nil {
-			datatypeID = store.node2id(datatypeURI)
+			datatypeID, err = store.node2id(ctx, datatypeURI)
+			if err != nil {
+				return 0, err
+			}
 		}
 
-		row, ok := store.selectOne("SELECT id FROM %s_literals WHERE value = ? AND language = ? AND datatype = ? LIMIT 1", lit.Value, lit.Language, datatypeID)
-		if !ok {
-			return 0
+		row, err := store.selectOne(ctx, "SELECT id FROM %s_literals WHERE value = ? AND language = ? AND datatype = ? LIMIT 1", lit.Value, lit.Language, datatypeID)
+		if err != nil {
+			return 0, err
 		}
 
 		if row == nil {
-			if !store.execVoid("INSERT INTO %s_literals SET value = ? AND language = ? AND datatype = ?", lit.Value, lit.Value, datatypeID) {
-				return 0
+			err = store.execVoid(ctx, "INSERT INTO %s_literals SET value = ? AND language = ? AND datatype = ?", lit.Value, lit.Value, datatypeID)
+			if err != nil {
+				return 0, err
 			}
 
-			row, ok = store.selectOne("SELECT id FROM %s_literals WHERE value = ? AND language = ? AND datatype = ? LIMIT 1", lit.Value, lit.Language, datatypeID)
-			if !ok {
-				return 0
+			row, err = store.selectOne(ctx, "SELECT id FROM %s_literals WHERE value = ? AND language = ? AND datatype = ? LIMIT 1", lit.Value, lit.Language, datatypeID)
+			if err != nil {
+				return 0, err
 			}
 		}
 
@@ -292,38 +328,38 @@ func (store *MySQLStore) literal2id(lit *argo.Literal) (id uint64) {
 		store.literalLookup[hash] = id
 	}
 
-	return id
+	return id, nil
 }
 
-func (store *MySQLStore) cacheRevLookup(id uint64, cache map[uint64]string, table string) (uri string) {
+func (store *MySQLStore) cacheRevLookup(ctx context.Context, id uint64, cache map[uint64]string, table string) (uri string, err error) {
 	uri, ok := cache[id]
 	if !ok {
-		row, ok := store.selectOne("SELECT uri FROM %s_"+table+" WHERE id = ?", id)
-		if !ok || row == nil {
-			return ""
+		row, err := store.selectOne(ctx, "SELECT uri FROM %s_"+table+" WHERE id = ?", id)
+		if err != nil || row == nil {
+			return "", err
 		}
 
 		uri = row[0].(string)
 		cache[id] = uri
 	}
 
-	return uri
+	return uri, nil
 }
 
-func (store *MySQLStore) id2node(id uint64) (uri string) {
-	return store.cacheRevLookup(id, store.nodeRevLookup, "nodes")
+func (store *MySQLStore) id2node(ctx context.Context, id uint64) (uri string, err error) {
+	return store.cacheRevLookup(ctx, id, store.nodeRevLookup, "nodes")
 }
 
-func (store *MySQLStore) id2prefix(id uint64) (uri string) {
-	return store.cacheRevLookup(id, store.prefixRevLookup, "prefixes")
+func (store *MySQLStore) id2prefix(ctx context.Context, id uint64) (uri string, err error) {
+	return store.cacheRevLookup(ctx, id, store.prefixRevLookup, "prefixes")
 }
 
-func (store *MySQLStore) id2literal(id uint64) (term argo.Term) {
+func (store *MySQLStore) id2literal(ctx context.Context, id uint64) (term argo.Term, err error) {
 	cachedLit, ok := store.literalRevLookup[id]
 	if !ok {
-		row, ok := store.selectOne("SELECT value, language, datatype FROM %s_literals WHERE id = ?", id)
-		if !ok || row == nil {
-			return nil
+		row, err := store.selectOne(ctx, "SELECT value, language, datatype FROM %s_literals WHERE id = ?", id)
+		if err != nil || row == nil {
+			return nil, err
 		}
 
 		cachedLit = &cachedLiteral{
@@ -338,215 +374,353 @@ func (store *MySQLStore) id2literal(id uint64) (term argo.Term) {
 	var datatype argo.Term = nil
 
 	if cachedLit.DatatypeID != 0 {
-		datatypeURI := store.id2node(cachedLit.DatatypeID)
+		datatypeURI, err := store.id2node(ctx, cachedLit.DatatypeID)
+		if err != nil {
+			return nil, err
+		}
+
 		datatype = argo.NewResource(datatypeURI)
 	}
 
-	return argo.NewLiteralWithLanguageAndDatatype(cachedLit.Value, cachedLit.Language, datatype)
+	return argo.NewLiteralWithLanguageAndDatatype(cachedLit.Value, cachedLit.Language, datatype), nil
 }
 
 func (store *MySQLStore) CreateTables() {
-	if !store.execVoid("CREATE TABLE IF NOT EXISTS %s_triples ( id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, subject BIGINT UNSIGNED NOT NULL, predicatePrefix BIGINT UNSIGNED NOT NULL, predicateLocal VARCHAR(64) NOT NULL, objectIsLiteral TINYINT UNSIGNED NOT NULL, object BIGINT UNSIGNED NOT NULL )") {
-		return
-	}
+	store.handle(store.CreateTablesContext(context.Background()))
+}
 
-	if !store.execVoid("CREATE TABLE IF NOT EXISTS %s_nodes ( id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, uri VARCHAR(512) NOT NULL )") {
-		return
+func (store *MySQLStore) CreateTablesContext(ctx context.Context) (err error) {
+	queries := []string{
+		"CREATE TABLE IF NOT EXISTS %s_triples ( id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, subject BIGINT UNSIGNED NOT NULL, predicatePrefix BIGINT UNSIGNED NOT NULL, predicateLocal VARCHAR(64) NOT NULL, objectIsLiteral TINYINT UNSIGNED NOT NULL, object BIGINT UNSIGNED NOT NULL )",
+		"CREATE TABLE IF NOT EXISTS %s_nodes ( id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, uri VARCHAR(512) NOT NULL )",
+		"CREATE TABLE IF NOT EXISTS %s_literals ( id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, value TEXT NOT NULL, language VARCHAR(32) NULL, datatype BIGINT UNSIGNED NULL )",
+		"CREATE TABLE IF NOT EXISTS %s_prefixes ( id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, uri VARCHAR(512) NOT NULL )",
 	}
 
-	if !store.execVoid("CREATE TABLE IF NOT EXISTS %s_literals ( id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, value TEXT NOT NULL, language VARCHAR(32) NULL, datatype BIGINT UNSIGNED NULL )") {
-		return
+	for _, query := range queries {
+		err = store.execVoid(ctx, query)
+		if err != nil {
+			return err
+		}
 	}
 
-	if !store.execVoid("CREATE TABLE IF NOT EXISTS %s_prefixes ( id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, uri VARCHAR(512) NOT NULL )") {
-		return
-	}
+	return nil
 }
 
 func (store *MySQLStore) DropTables() {
-	if !store.execVoid("DROP TABLE IF EXISTS %s_triples") {
-		return
-	}
-
-	if !store.execVoid("DROP TABLE IF EXISTS %s_nodes") {
-		return
-	}
+	store.handle(store.DropTablesContext(context.Background()))
+}
 
-	if !store.execVoid("DROP TABLE IF EXISTS %s_literals") {
-		return
+func (store *MySQLStore) DropTablesContext(ctx context.Context) (err error) {
+	for _, table := range []string{"triples", "nodes", "literals", "prefixes"} {
+		err = store.execVoid(ctx, "DROP TABLE IF EXISTS %s_"+table)
+		if err != nil {
+			return err
+		}
 	}
 
-	if !store.execVoid("DROP TABLE IF EXISTS %s_prefixes") {
-		return
-	}
+	return nil
 }
 
-func (store *MySQLStore) encodeSubject(term argo.Term) (subjectID uint64) {
-	return store.node2id(term2node(term))
+func (store *MySQLStore) encodeSubject(ctx context.Context, term argo.Term) (subjectID uint64, err error) {
+	return store.node2id(ctx, term2node(term))
 }
 
-func (store *MySQLStore) encodePredicate(term argo.Term) (predicatePrefixID uint64, predicateLocal string) {
+func (store *MySQLStore) encodePredicate(ctx context.Context, term argo.Term) (predicatePrefixID uint64, predicateLocal string, err error) {
 	predicatePrefix, predicateLocal := argo.SplitPrefix(term.(*argo.Resource).URI)
-	return store.prefix2id(predicatePrefix), predicateLocal
+	predicatePrefixID, err = store.prefix2id(ctx, predicatePrefix)
+	return predicatePrefixID, predicateLocal, err
 }
 
-func (store *MySQLStore) encodeObject(term argo.Term) (objectIsLiteral uint8, objectID uint64) {
+func (store *MySQLStore) encodeObject(ctx context.Context, term argo.Term) (objectIsLiteral uint8, objectID uint64, err error) {
 	lit, isLit := term.(*argo.Literal)
 	if isLit {
 		objectIsLiteral = 1
-		objectID = store.literal2id(lit)
+		objectID, err = store.literal2id(ctx, lit)
 
 	} else {
 		objectIsLiteral = 0
-		objectID = store.node2id(term2node(term))
+		objectID, err = store.node2id(ctx, term2node(term))
 	}
 
-	return objectIsLiteral, objectID
+	return objectIsLiteral, objectID, err
 }
 
-func (store *MySQLStore) encodeTriple(triple *argo.Triple) (subjectID uint64, predicatePrefixID uint64, predicateLocal string, objectIsLiteral uint8, objectID uint64) {
-	subjectID = store.encodeSubject(triple.Subject)
-	predicatePrefixID, predicateLocal = store.encodePredicate(triple.Predicate)
-	objectIsLiteral, objectID = store.encodeObject(triple.Object)
-	return subjectID, predicatePrefixID, predicateLocal, objectIsLiteral, objectID
+func (store *MySQLStore) encodeTriple(ctx context.Context, triple *argo.Triple) (params []interface{}, err error) {
+	subjectID, err := store.encodeSubject(ctx, triple.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	predicatePrefixID, predicateLocal, err := store.encodePredicate(ctx, triple.Predicate)
+	if err != nil {
+		return nil, err
+	}
+
+	objectIsLiteral, objectID, err := store.encodeObject(ctx, triple.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{subjectID, predicatePrefixID, predicateLocal, objectIsLiteral, objectID}, nil
 }
 
-func (store *MySQLStore) decodeSubject(subjectID uint64) (subject argo.Term) {
-	return node2term(store.id2node(subjectID))
+func (store *MySQLStore) decodeSubject(ctx context.Context, subjectID uint64) (subject argo.Term, err error) {
+	node, err := store.id2node(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return node2term(node), nil
 }
 
-func (store *MySQLStore) decodePredicate(predicatePrefixID uint64, predicateLocal string) (predicate argo.Term) {
-	predicatePrefix := store.id2prefix(predicatePrefixID)
-	return argo.NewResource(predicatePrefix + predicateLocal)
+func (store *MySQLStore) decodePredicate(ctx context.Context, predicatePrefixID uint64, predicateLocal string) (predicate argo.Term, err error) {
+	predicatePrefix, err := store.id2prefix(ctx, predicatePrefixID)
+	if err != nil {
+		return nil, err
+	}
+
+	return argo.NewResource(predicatePrefix + predicateLocal), nil
 }
 
-func (store *MySQLStore) decodeObject(objectIsLiteral uint8, objectID uint64) (object argo.Term) {
+func (store *MySQLStore) decodeObject(ctx context.Context, objectIsLiteral uint8, objectID uint64) (object argo.Term, err error) {
 	if objectIsLiteral != 0 {
-		object = store.id2literal(objectID)
-	} else {
-		object = node2term(store.id2node(objectID))
+		return store.id2literal(ctx, objectID)
 	}
 
-	return object
-}
+	node, err := store.id2node(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
 
-func (store *MySQLStore) decodeTriple(subjectID uint64, predicatePrefixID uint64, predicateLocal string, objectIsLiteral uint8, objectID uint64) (triple *argo.Triple) {
-	return argo.NewTriple(store.decodeSubject(subjectID), store.decodePredicate(predicatePrefixID, predicateLocal), store.decodeObject(objectIsLiteral, objectID))
+	return node2term(node), nil
 }
 
 func (store *MySQLStore) Add(triple *argo.Triple) (index int) {
-	subjectID, predicatePrefixID, predicateLocal, objectIsLiteral, objectID := store.encodeTriple(triple)
+	index, err := store.AddContext(context.Background(), triple)
+	store.handle(err)
+	return index
+}
 
-	if !store.execVoid("INSERT INTO %s_triples SET subject = ? AND predicatePrefix = ? AND predicateLocal = ? AND objectIsLiteral = ? AND object = ?", subjectID, predicatePrefixID, predicateLocal, objectIsLiteral, objectID) {
-		return 0
+func (store *MySQLStore) AddContext(ctx context.Context, triple *argo.Triple) (index int, err error) {
+	params, err := store.encodeTriple(ctx, triple)
+	if err != nil {
+		return 0, err
+	}
+
+	err = store.execVoid(ctx, "INSERT INTO %s_triples SET subject = ? AND predicatePrefix = ? AND predicateLocal = ? AND objectIsLiteral = ? AND object = ?", params...)
+	if err != nil {
+		return 0, err
 	}
 
-	row, ok := store.selectOne("SELECT id FROM %s_triples WHERE subject = ? AND predicatePrefix = ? AND predicateLocal = ? AND objectIsLiteral = ? AND object = ?", subjectID, predicatePrefixID, predicateLocal, objectIsLiteral, objectID)
-	if !ok || row == nil {
-		return 0
+	row, err := store.selectOne(ctx, "SELECT id FROM %s_triples WHERE subject = ? AND predicatePrefix = ? AND predicateLocal = ? AND objectIsLiteral = ? AND object = ?", params...)
+	if err != nil || row == nil {
+		return 0, err
 	}
 
-	return int(row[0].(uint64))
+	return int(row[0].(uint64)), nil
 }
 
 func (store *MySQLStore) Remove(triple *argo.Triple) {
-	subjectID, predicatePrefixID, predicateLocal, objectIsLiteral, objectID := store.encodeTriple(triple)
+	store.handle(store.RemoveContext(context.Background(), triple))
+}
 
-	if !store.execVoid("DELETE FROM %s_triples WHERE subject = ? AND predicatePrefix = ? AND predicateLocal = ? AND objectIsLiteral = ? AND object = ?", subjectID, predicatePrefixID, predicateLocal, objectIsLiteral, objectID) {
-		return
+func (store *MySQLStore) RemoveContext(ctx context.Context, triple *argo.Triple) (err error) {
+	params, err := store.encodeTriple(ctx, triple)
+	if err != nil {
+		return err
 	}
+
+	return store.execVoid(ctx, "DELETE FROM %s_triples WHERE subject = ? AND predicatePrefix = ? AND predicateLocal = ? AND objectIsLiteral = ? AND object = ?", params...)
 }
 
 func (store *MySQLStore) RemoveIndex(index int) {
-	if !store.execVoid("DELETE FROM %s_triples WHERE id = ?", index) {
-		return
-	}
+	store.handle(store.RemoveIndexContext(context.Background(), index))
+}
+
+func (store *MySQLStore) RemoveIndexContext(ctx context.Context, index int) (err error) {
+	return store.execVoid(ctx, "DELETE FROM %s_triples WHERE id = ?", index)
 }
 
 func (store *MySQLStore) Clear() {
-	store.DropTables()
-	store.CreateTables()
+	store.handle(store.ClearContext(context.Background()))
+}
+
+func (store *MySQLStore) ClearContext(ctx context.Context) (err error) {
+	err = store.DropTablesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return store.CreateTablesContext(ctx)
 }
 
 func (store *MySQLStore) Num() (n int) {
-	row, ok := store.selectOne("SELECT COUNT(*) FROM %s_triples")
-	if !ok || row == nil {
-		return 0
+	n, err := store.NumContext(context.Background())
+	store.handle(err)
+	return n
+}
+
+func (store *MySQLStore) NumContext(ctx context.Context) (n int, err error) {
+	row, err := store.selectOne(ctx, "SELECT COUNT(*) FROM %s_triples")
+	if err != nil || row == nil {
+		return 0, err
 	}
 
-	return int(row[0].(uint64))
+	return int(row[0].(uint64)), nil
 }
 
 func (store *MySQLStore) IterTriples() (ch chan *argo.Triple) {
 	return store.Filter(nil, nil, nil)
 }
 
+func (store *MySQLStore) IterTriplesContext(ctx context.Context) (it argo.TripleIterator) {
+	return store.FilterContext(ctx, nil, nil, nil)
+}
+
 func (store *MySQLStore) Filter(subjSearch, predSearch, objSearch argo.Term) (ch chan *argo.Triple) {
 	ch = make(chan *argo.Triple)
+	it := store.FilterContext(context.Background(), subjSearch, predSearch, objSearch)
 
-	queryClauses := make([]string, 0)
-	queryValues := make([]interface{}, 0)
+	go func() {
+		defer close(ch)
+		defer it.Close()
 
-	if subjSearch != nil {
-		subjectID := store.encodeSubject(subjSearch)
+		for it.Next() {
+			ch <- it.Triple()
+		}
 
-		queryClauses = append(queryClauses, "subject = ?")
-		queryValues = append(queryValues, subjectID)
-	}
+		store.handle(it.Err())
+	}()
 
-	if predSearch != nil {
-		predicatePrefixID, predicateLocal := store.encodePredicate(predSearch)
+	return ch
+}
 
-		queryClauses = append(queryClauses, "predicatePrefix = ?")
-		queryClauses = append(queryClauses, "predicateLocal = ?")
-		queryValues = append(queryValues, predicatePrefixID)
-		queryValues = append(queryValues, predicateLocal)
-	}
+func (store *MySQLStore) FilterContext(ctx context.Context, subjSearch, predSearch, objSearch argo.Term) (it argo.TripleIterator) {
+	return argo.NewTripleIterator(ctx, func(ctx context.Context, yield func(*argo.Triple) bool) (err error) {
+		queryClauses := make([]string, 0)
+		queryValues := make([]interface{}, 0)
 
-	if objSearch != nil {
-		objectIsLiteral, objectID := store.encodePredicate(objSearch)
+		if subjSearch != nil {
+			subjectID, err := store.encodeSubject(ctx, subjSearch)
+			if err != nil {
+				return err
+			}
 
-		queryClauses = append(queryClauses, "objectIsLiteral = ?")
-		queryClauses = append(queryClauses, "object = ?")
-		queryValues = append(queryValues, objectIsLiteral)
-		queryValues = append(queryValues, objectID)
-	}
+			queryClauses = append(queryClauses, "subject = ?")
+			queryValues = append(queryValues, subjectID)
+		}
 
-	whereStr := ""
-	if len(queryClauses) > 0 {
-		whereStr = " WHERE " + strings.Join(queryClauses, " AND ")
-	}
+		if predSearch != nil {
+			predicatePrefixID, predicateLocal, err := store.encodePredicate(ctx, predSearch)
+			if err != nil {
+				return err
+			}
 
-	resultChan, failChan := store.execute("SELECT subject, predicatePrefix, predicateLocal, objectIsLiteral, object FROM %s_triples"+whereStr, queryValues...)
+			queryClauses = append(queryClauses, "predicatePrefix = ?")
+			queryClauses = append(queryClauses, "predicateLocal = ?")
+			queryValues = append(queryValues, predicatePrefixID)
+			queryValues = append(queryValues, predicateLocal)
+		}
 
-	go func() {
-		for row := range resultChan {
-			var subject, predicate, object argo.Term
+		if objSearch != nil {
+			objectIsLiteral, objectID, err := store.encodeObject(ctx, objSearch)
+			if err != nil {
+				return err
+			}
+
+			queryClauses = append(queryClauses, "objectIsLiteral = ?")
+			queryClauses = append(queryClauses, "object = ?")
+			queryValues = append(queryValues, objectIsLiteral)
+			queryValues = append(queryValues, objectID)
+		}
+
+		whereStr := ""
+		if len(queryClauses) > 0 {
+			whereStr = " WHERE " + strings.Join(queryClauses, " AND ")
+		}
+
+		request, err := store.execute(ctx, "SELECT subject, predicatePrefix, predicateLocal, objectIsLiteral, object FROM %s_triples"+whereStr, queryValues...)
+		if err != nil {
+			return err
+		}
+
+		// Decoding a row can require further queries, which are not run until this one has
+		// finished, so collect all the rows first.
+		rows := make([][]interface{}, 0)
+		for row := range request.ResultChan {
+			rows = append(rows, row)
+		}
+
+		err = <-request.ErrChan
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			subject, predicate, object := subjSearch, predSearch, objSearch
 
 			if subjSearch == nil {
-				subject = store.decodeSubject(row[0].(uint64))
-			} else {
-				subject = subjSearch
+				subject, err = store.decodeSubject(ctx, row[0].(uint64))
+				if err != nil {
+					return err
+				}
 			}
 
 			if predSearch == nil {
-				predicate = store.decodePredicate(row[1].(uint64), row[2].(string))
-			} else {
-				predicate = predSearch
+				predicate, err = store.decodePredicate(ctx, row[1].(uint64), row[2].(string))
+				if err != nil {
+					return err
+				}
 			}
 
 			if objSearch == nil {
-				object = store.decodeObject(row[3].(uint8), row[4].(uint64))
-			} else {
-				object = objSearch
+				object, err = store.decodeObject(ctx, row[3].(uint8), row[4].(uint64))
+				if err != nil {
+					return err
+				}
 			}
 
-			ch <- argo.NewTriple(subject, predicate, object)
+			if !yield(argo.NewTriple(subject, predicate, object)) {
+				return nil
+			}
 		}
 
-		<-failChan
-		close(ch)
-	}()
+		return nil
+	})
+}
 
-	return ch
+// Method ContextStore returns a view of the store that implements argo.ContextStore, reporting
+// errors to the caller rather than to ErrorHandler.
+func (store *MySQLStore) ContextStore() (cs argo.ContextStore) {
+	return contextStore{store}
+}
+
+type contextStore struct {
+	store *MySQLStore
+}
+
+func (cs contextStore) Add(ctx context.Context, triple *argo.Triple) (err error) {
+	_, err = cs.store.AddContext(ctx, triple)
+	return err
+}
+
+func (cs contextStore) Remove(ctx context.Context, triple *argo.Triple) (err error) {
+	return cs.store.RemoveContext(ctx, triple)
+}
+
+func (cs contextStore) Clear(ctx context.Context) (err error) {
+	return cs.store.ClearContext(ctx)
+}
+
+func (cs contextStore) Num(ctx context.Context) (n int, err error) {
+	return cs.store.NumContext(ctx)
+}
+
+func (cs contextStore) IterTriples(ctx context.Context) (it argo.TripleIterator) {
+	return cs.store.IterTriplesContext(ctx)
+}
+
+func (cs contextStore) Filter(ctx context.Context, subjSearch, predSearch, objSearch argo.Term) (it argo.TripleIterator) {
+	return cs.store.FilterContext(ctx, subjSearch, predSearch, objSearch)
 }
@@ -25,7 +25,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"unicode"
+
+	"github.com/kierdavis/argo/iri"
 )
 
 // A NTriplesParseError is returned for parsing errors.
@@ -42,38 +45,48 @@ func (e *NTriplesParseError) Error() string {
 
 // These are the errors that can be returned in NTriplesParseError.Error
 var (
-	ErrNTUnexpectedCharacter = errors.New("unexpected character")
-	ErrNTUnexpectedEOF       = errors.New("unexpected end of file")
-	ErrNTTermCount           = errors.New("wrong number of terms in line")
-	ErrNTUnterminatedIri     = errors.New("unterminated IRI, expecting '>'")
-	ErrNTUnterminatedLiteral = errors.New("unterminated literal, expecting '\"'")
-	ErrNTUnterminatedTriple  = errors.New("unterminated triple, expecting '.'")
+	ErrNTUnexpectedCharacter      = errors.New("unexpected character")
+	ErrNTUnexpectedEOF            = errors.New("unexpected end of file")
+	ErrNTTermCount                = errors.New("wrong number of terms in line")
+	ErrNTUnterminatedIri          = errors.New("unterminated IRI, expecting '>'")
+	ErrNTUnterminatedLiteral      = errors.New("unterminated literal, expecting '\"'")
+	ErrNTUnterminatedTriple       = errors.New("unterminated triple, expecting '.'")
+	ErrNTInvalidEscape            = errors.New("invalid escape sequence")
+	ErrNTRelativeIRI              = errors.New("relative IRI")
+	ErrNTInvalidIRI               = errors.New("invalid IRI")
+	ErrNTLangString               = errors.New("rdf:langString literal without a language tag")
+	ErrNTUnterminatedQuotedTriple = errors.New("unterminated quoted triple, expecting '>>'")
 )
 
+// A NTriplesReader parses N-Triples (or N-Quads) documents, as specified by RDF 1.1. RDF-star
+// quoted triples (N-Triples-star) are accepted in the subject and object positions.
 type NTriplesReader struct {
-	line   int
-	column int
-	r      *bufio.Reader
-	buf    bytes.Buffer
+	line    int
+	column  int
+	r       *bufio.Reader
+	ahead   []rune
+	newline bool
+	buf     bytes.Buffer
+	quads   bool
 }
 
-/*
-// Constants for types of RdfTerm
-const (
-	RdfUnknown = iota
-	RdfIri
-	RdfBlank
-	RdfLiteral
-)
-*/
-
 // NewNTriplesReader returns a new NTriplesReader that reads from r.
 func NewNTriplesReader(r io.Reader) *NTriplesReader {
 	return &NTriplesReader{
-		r: bufio.NewReader(r),
+		r:       bufio.NewReader(r),
+		newline: true,
 	}
 }
 
+// NewNQuadsReader returns a new NTriplesReader that reads N-Quads from r. Each line may contain a
+// fourth term naming the graph the triple belongs to, which is stored in the Graph field of the
+// returned triples.
+func NewNQuadsReader(r io.Reader) *NTriplesReader {
+	ntr := NewNTriplesReader(r)
+	ntr.quads = true
+	return ntr
+}
+
 // error creates a new NTriplesParseError based on err.
 func (r *NTriplesReader) error(err error) error {
 	return &NTriplesParseError{
@@ -83,394 +96,511 @@ func (r *NTriplesReader) error(err error) error {
 	}
 }
 
-// Read reads the next triple
-func (r *NTriplesReader) Read() (t *Triple, e error) {
-	var s, p Term
-
-	r.line++
-	r.column = -1
-
-	r1, err := r.skipWhitespace()
-	if err != nil {
-		return nil, err
-	}
+// Read reads the next triple. It returns io.EOF when the end of the document has been reached.
+func (r *NTriplesReader) Read() (t *Triple, err error) {
+	// Skip empty lines and comments.
+	for {
+		r1, err := r.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
 
-	for r1 == '#' {
-		for {
-			r1, err = r.readRune()
+		if r1 == '#' {
+			err = r.skipComment()
 			if err != nil {
 				return nil, err
 			}
-			if r1 == '\n' {
-				break
-			}
-		}
-		r1, err = r.skipWhitespace()
-		if err != nil {
-			return nil, err
+
+		} else if r1 != '\n' && r1 != '\r' {
+			break
 		}
 
+		r.readRune()
 	}
 
-	r.r.UnreadRune()
+	var terms []Term
 
-	termCount := 0
 	for {
-		haveTerm, term, err := r.parseTerm()
-		if haveTerm {
-			termCount++
-			switch termCount {
-			case 1:
-				s = term
-				err = r.expectWhitespace()
-				if err != nil {
-					return nil, err
-				}
-			case 2:
-				p = term
-				err = r.expectWhitespace()
-				if err != nil {
-					return nil, err
-				}
-			case 3:
-
-				err = r.readEndTriple()
-				if err != nil {
-					return nil, err
-				}
-
-				return NewTriple(s, p, term), nil
-			default:
-				// TODO: error, too many terms
-				return nil, r.error(ErrNTTermCount)
-			}
+		r1, err := r.skipWhitespace()
+		if err == io.EOF {
+			return nil, r.error(ErrNTUnterminatedTriple)
+		} else if err != nil {
+			return nil, err
+		}
+
+		if r1 == '.' && len(terms) > 0 {
+			r.readRune()
+			break
+		}
 
+		if len(terms) == 4 || (len(terms) == 3 && !r.quads) {
+			r.readRune()
+			return nil, r.error(ErrNTUnexpectedCharacter)
 		}
+
+		term, err := r.parseTerm(len(terms))
 		if err != nil {
 			return nil, err
 		}
+
+		terms = append(terms, term)
 	}
 
-	return nil, nil
+	if len(terms) < 3 {
+		return nil, r.error(ErrNTTermCount)
+	}
+
+	err = r.readEndLine()
+	if err != nil {
+		return nil, err
+	}
 
+	if len(terms) == 4 {
+		return NewQuad(terms[0], terms[1], terms[2], terms[3]), nil
+	}
+
+	return NewTriple(terms[0], terms[1], terms[2]), nil
 }
 
-// readRune reads one rune from r, folding \r\n to \n and keeping track
-// of how far into the line we have read.  r.column will point to the start
-// of this rune, not the end of this rune.
-func (r *NTriplesReader) readRune() (rune, error) {
-	r1, _, err := r.r.ReadRune()
-
-	// Handle \r\n here.  We make the simplifying assumption that
-	// anytime \r is followed by \n that it can be folded to \n.
-	// We will not detect files which contain both \r\n and bare \n.
-	if r1 == '\r' {
-		r1, _, err = r.r.ReadRune()
-		if err == nil {
-			if r1 != '\n' {
-				r.r.UnreadRune()
-				r1 = '\r'
-			}
+// peekRune returns the rune i positions ahead of the current position without consuming it.
+func (r *NTriplesReader) peekRune(i int) (rune, error) {
+	for len(r.ahead) <= i {
+		r1, _, err := r.r.ReadRune()
+		if err != nil {
+			return 0, err
 		}
+
+		r.ahead = append(r.ahead, r1)
+	}
+
+	return r.ahead[i], nil
+}
+
+// readRune reads one rune, keeping track of the position of the rune within the document. r.column
+// will point to the start of this rune, not the end of this rune.
+func (r *NTriplesReader) readRune() (r1 rune, err error) {
+	r1, err = r.peekRune(0)
+	if err != nil {
+		return 0, err
 	}
-	r.column++
+
+	r.ahead = r.ahead[1:]
+
+	if r.newline {
+		r.line++
+		r.column = 0
+		r.newline = false
+	} else {
+		r.column++
+	}
+
+	// A "\r\n" pair counts as a single line break.
+	if r1 == '\n' || (r1 == '\r' && !r.nextIs('\n')) {
+		r.newline = true
+	}
+
+	return r1, nil
+}
+
+// nextIs returns whether the next rune is r1.
+func (r *NTriplesReader) nextIs(r1 rune) bool {
+	r2, err := r.peekRune(0)
+	return err == nil && r2 == r1
+}
+
+// mustReadRune reads one rune, treating the end of the input as an error.
+func (r *NTriplesReader) mustReadRune() (r1 rune, err error) {
+	r1, err = r.readRune()
+	if err == io.EOF {
+		return 0, r.error(ErrNTUnexpectedEOF)
+	}
+
 	return r1, err
 }
 
-// unreadRune puts the last rune read from r back.
-func (r *NTriplesReader) unreadRune() {
-	r.r.UnreadRune()
-	r.column--
+// parseTerm parses the term at the given position (0 for the subject, 3 for the graph name).
+func (r *NTriplesReader) parseTerm(position int) (term Term, err error) {
+	r1, _ := r.peekRune(0)
+	r2, _ := r.peekRune(1)
+
+	switch {
+	case r1 == '<' && r2 == '<' && (position == 0 || position == 2):
+		return r.parseQuotedTriple()
+
+	case r1 == '<':
+		iri, err := r.parseIRI()
+		if err != nil {
+			return nil, err
+		}
+
+		return NewResource(iri), nil
+
+	case r1 == '_' && position != 1:
+		return r.parseBlankNode()
+
+	case r1 == '"' && position == 2:
+		return r.parseLiteral()
+	}
+
+	r.readRune()
+	return nil, r.error(ErrNTUnexpectedCharacter)
 }
 
-func (r *NTriplesReader) parseTerm() (haveField bool, term Term, err error) {
-	r.buf.Reset()
+// parseQuotedTriple parses an RDF-star quoted triple enclosed in '<<' and '>>'.
+func (r *NTriplesReader) parseQuotedTriple() (term Term, err error) {
+	r.readRune()
+	r.readRune()
 
-	r1, err := r.skipWhitespace()
+	var terms []Term
 
-	switch r1 {
-	case '<':
-		// Read an IRI
-		for {
-			r1, err = r.readRune()
-			if err != nil {
-				if err == io.EOF {
-					return false, term, r.error(ErrNTUnexpectedEOF)
-				}
-				return false, term, err
-			}
-			if r1 == '>' {
-				if r.buf.Len() == 0 {
-					return false, term, r.error(ErrNTUnexpectedCharacter)
-				}
-				return true, NewResource(r.buf.String()), nil
-			} else if r1 < 0x20 || r1 > 0x7E || r1 == ' ' || r1 == '<' || r1 == '"' {
-				return false, term, r.error(ErrNTUnexpectedCharacter)
-			}
-			r.buf.WriteRune(r1)
+	for {
+		r1, err := r.skipWhitespace()
+		if err == io.EOF {
+			return nil, r.error(ErrNTUnexpectedEOF)
+		} else if err != nil {
+			return nil, err
 		}
-	case '_':
-		// Read a blank node
-		r1, err = r.readRune()
-		if err != nil {
-			if err == io.EOF {
-				return false, term, r.error(ErrNTUnexpectedEOF)
+
+		if len(terms) == 3 {
+			r2, _ := r.peekRune(1)
+			r.readRune()
+
+			if r1 != '>' || r2 != '>' {
+				return nil, r.error(ErrNTUnterminatedQuotedTriple)
 			}
-			return false, term, err
+
+			r.readRune()
+			return NewQuotedTriple(terms[0], terms[1], terms[2]), nil
 		}
 
-		if r1 != ':' {
-			return false, term, r.error(ErrNTUnexpectedCharacter)
+		if r1 == '>' {
+			r.readRune()
+			return nil, r.error(ErrNTTermCount)
 		}
 
-		r1, err = r.readRune()
+		term, err := r.parseTerm(len(terms))
 		if err != nil {
-			if err == io.EOF {
-				return false, term, r.error(ErrNTUnexpectedEOF)
-			}
-			return false, term, err
+			return nil, err
 		}
-		if !((r1 >= 'a' && r1 <= 'z') || (r1 >= 'A' && r1 <= 'Z')) {
-			return false, term, r.error(ErrNTUnexpectedCharacter)
+
+		terms = append(terms, term)
+	}
+}
+
+// parseIRI parses an absolute IRI enclosed in angle brackets.
+func (r *NTriplesReader) parseIRI() (uri string, err error) {
+	r.readRune()
+	r.buf.Reset()
+
+	for {
+		r1, err := r.readRune()
+		if err == io.EOF {
+			return "", r.error(ErrNTUnexpectedEOF)
+		} else if err != nil {
+			return "", err
 		}
-		r.buf.WriteRune(r1)
 
-		for {
-			r1, err = r.readRune()
-			if err != nil {
-				if err == io.EOF {
-					return false, term, r.error(ErrNTUnexpectedEOF)
-				}
-				return false, term, err
-			}
-			if !((r1 >= 'a' && r1 <= 'z') || (r1 >= 'A' && r1 <= 'Z') || (r1 >= '0' && r1 <= '9')) {
-				if r1 == '.' || unicode.IsSpace(r1) {
-					r.unreadRune()
-					return true, NewBlankNode(r.buf.String()), nil
-				}
-				return false, term, r.error(ErrNTUnexpectedCharacter)
-			}
-			r.buf.WriteRune(r1)
+		if r1 == '>' {
+			break
 		}
 
-	case '"':
-		// Read a literal
-		for {
-			r1, err = r.readRune()
+		if r1 == '\\' {
+			r1, err = r.readEscape(false)
 			if err != nil {
-				if err == io.EOF {
-					return false, term, r.error(ErrNTUnexpectedEOF)
-				}
-				return false, term, err
+				return "", err
 			}
-			switch r1 {
-			case '"':
-				r1, err = r.readRune()
-				if err != nil {
-					if err == io.EOF {
-						return false, term, r.error(ErrNTUnexpectedEOF)
-					}
-					return false, term, err
-				}
-
-				switch r1 {
-
-				case '.', ' ', '\t':
-					r.unreadRune()
-					return true, NewLiteral(r.buf.String()), nil
-				case '@':
-					lexicalForm := r.buf.String()
-					r.buf.Reset()
-
-					for {
-						r1, err = r.readRune()
-						if err != nil {
-							if err == io.EOF {
-								return false, term, r.error(ErrNTUnexpectedEOF)
-							}
-							return false, term, err
-						}
-						if r1 == '.' || r1 == ' ' || r1 == '\t' {
-							if r.buf.Len() == 0 {
-								return false, term, r.error(ErrNTUnexpectedCharacter)
-							}
-							return true, NewLiteralWithLanguage(lexicalForm, r.buf.String()), nil
-						}
-						if r1 == '-' || (r1 >= 'a' && r1 <= 'z') || (r1 >= '0' && r1 <= '9') {
-							r.buf.WriteRune(r1)
-						} else {
-							return false, term, r.error(ErrNTUnexpectedCharacter)
-						}
-					}
-				case '^':
-					lexicalForm := r.buf.String()
-					r.buf.Reset()
-
-					r1, err = r.readRune()
-					if err != nil {
-						if err == io.EOF {
-							return false, term, r.error(ErrNTUnexpectedEOF)
-						}
-						return false, term, err
-					}
-					if r1 != '^' {
-						return false, term, r.error(ErrNTUnexpectedCharacter)
-					}
-
-					r1, err = r.readRune()
-					if err != nil {
-						if err == io.EOF {
-							return false, term, r.error(ErrNTUnexpectedEOF)
-						}
-						return false, term, err
-					}
-					if r1 != '<' {
-						return false, term, r.error(ErrNTUnexpectedCharacter)
-					}
-
-					// Read an IRI
-					for {
-						r1, err = r.readRune()
-						if err != nil {
-							if err == io.EOF {
-								return false, term, r.error(ErrNTUnexpectedEOF)
-							}
-							return false, term, err
-						}
-						if r1 == '>' {
-							if r.buf.Len() == 0 {
-								return false, term, r.error(ErrNTUnexpectedCharacter)
-							}
-							return true, NewLiteralWithDatatype(lexicalForm, NewResource(r.buf.String())), nil
-						} else if r1 < 0x20 || r1 > 0x7E || r1 == ' ' || r1 == '<' || r1 == '"' {
-							return false, term, r.error(ErrNTUnexpectedCharacter)
-						}
-						r.buf.WriteRune(r1)
-					}
-
-				}
-				return false, term, r.error(ErrNTUnexpectedCharacter)
-
-			case '\\':
-				r1, err = r.readRune()
-				if err != nil {
-					if err == io.EOF {
-						return false, term, r.error(ErrNTUnexpectedEOF)
-					}
-					return false, term, err
-				}
-				switch r1 {
-				case '\\', '"':
-				case 't':
-					r1 = '\t'
-				case 'r':
-					r1 = '\r'
-				case 'n':
-					r1 = '\n'
-				case 'u', 'U':
-
-					codepoint := rune(0)
-
-					for i := 3; i >= 0; i-- {
-						r1, err = r.readRune()
-
-						if err != nil {
-							if err == io.EOF {
-								return false, term, r.error(ErrNTUnexpectedEOF)
-							}
-							return false, term, err
-						}
-
-						if r1 >= '0' && r1 <= '9' {
-							codepoint += (1 << uint32(4*i)) * (r1 - '0')
-						} else if r1 >= 'a' && r1 <= 'f' {
-							codepoint += (1 << uint32(4*i)) * (r1 - 'a' + 10)
-						} else if r1 >= 'A' && r1 <= 'F' {
-							codepoint += (1 << uint32(4*i)) * (r1 - 'A' + 10)
-						} else {
-							return false, term, r.error(ErrNTUnexpectedCharacter)
-						}
-
-					}
-					r1 = codepoint
-
-				default:
-					return false, term, r.error(ErrNTUnexpectedCharacter)
-				}
-			}
-			r.buf.WriteRune(r1)
+
+		} else if r1 <= 0x20 || strings.ContainsRune("<\"{}|^`", r1) {
+			return "", r.error(ErrNTUnexpectedCharacter)
 		}
-	default:
-		// TODO: raise error, unexpected character
-		return false, term, r.error(ErrNTUnexpectedCharacter)
 
+		r.buf.WriteRune(r1)
 	}
 
-	panic("unreachable")
+	uri = r.buf.String()
+	if uri == "" {
+		return "", r.error(ErrNTUnexpectedCharacter)
+	}
 
+	_, err = iri.ParseAbsolute(uri)
+	if e, ok := err.(*iri.Error); ok && e.Err == iri.ErrNotAbsolute {
+		return "", r.error(ErrNTRelativeIRI)
+	} else if err != nil {
+		return "", r.error(ErrNTInvalidIRI)
+	}
+
+	return uri, nil
 }
 
-func (r *NTriplesReader) readEndTriple() (err error) {
-	r1, err := r.skipWhitespace()
+// parseBlankNode parses a blank node label. Labels may contain but not end with dots.
+func (r *NTriplesReader) parseBlankNode() (term Term, err error) {
+	r.readRune()
+	r.buf.Reset()
+
+	r1, err := r.mustReadRune()
 	if err != nil {
-		if err == io.EOF {
-			return r.error(ErrNTUnterminatedTriple)
-		}
-		return err
+		return nil, err
 	}
 
-	if r1 != '.' {
-		return r.error(ErrNTUnexpectedCharacter)
+	if r1 != ':' {
+		return nil, r.error(ErrNTUnexpectedCharacter)
 	}
 
-	r1, err = r.skipWhitespace()
+	r1, err = r.mustReadRune()
 	if err != nil {
+		return nil, err
+	}
+
+	if !isPNCharsU(r1) && !isDigit(r1) {
+		return nil, r.error(ErrNTUnexpectedCharacter)
+	}
+
+	r.buf.WriteRune(r1)
+
+	for {
+		r1, err = r.peekRune(0)
+		if err != nil {
+			break
+		}
+
+		if isPNChars(r1) {
+			r.readRune()
+			r.buf.WriteRune(r1)
+
+		} else if r1 == '.' {
+			n := 1
+			r2, err := r.peekRune(n)
+			for err == nil && r2 == '.' {
+				n++
+				r2, err = r.peekRune(n)
+			}
+
+			if err != nil || !isPNChars(r2) {
+				break
+			}
+
+			for ; n > 0; n-- {
+				r.readRune()
+				r.buf.WriteRune('.')
+			}
+
+		} else {
+			break
+		}
+	}
+
+	return NewBlankNode(r.buf.String()), nil
+}
+
+// parseLiteral parses a quoted string, followed by an optional language tag or datatype.
+func (r *NTriplesReader) parseLiteral() (term Term, err error) {
+	r.readRune()
+	r.buf.Reset()
+
+	for {
+		r1, err := r.readRune()
 		if err == io.EOF {
-			return nil
+			return nil, r.error(ErrNTUnterminatedLiteral)
+		} else if err != nil {
+			return nil, err
 		}
-		return err
+
+		if r1 == '"' {
+			break
+		}
+
+		switch r1 {
+		case '\\':
+			r1, err = r.readEscape(true)
+			if err != nil {
+				return nil, err
+			}
+
+		case '\n', '\r':
+			return nil, r.error(ErrNTUnterminatedLiteral)
+		}
+
+		r.buf.WriteRune(r1)
 	}
 
-	if r1 != '\n' {
-		return r.error(ErrNTUnexpectedCharacter)
+	value := r.buf.String()
+
+	switch {
+	case r.nextIs('@'):
+		r.readRune()
+		language, err := r.parseLanguage()
+		if err != nil {
+			return nil, err
+		}
+
+		return NewLiteralWithLanguage(value, language), nil
+
+	case r.nextIs('^'):
+		r.readRune()
+		r1, err := r.mustReadRune()
+		if err != nil {
+			return nil, err
+		}
+
+		if r1 != '^' || !r.nextIs('<') {
+			return nil, r.error(ErrNTUnexpectedCharacter)
+		}
+
+		datatype, err := r.parseIRI()
+		if err != nil {
+			return nil, err
+		}
+
+		if datatype == rdfNs+"langString" {
+			return nil, r.error(ErrNTLangString)
+		}
+
+		return NewLiteralWithDatatype(value, NewResource(datatype)), nil
 	}
 
-	return nil
+	return NewLiteral(value), nil
+}
 
+// parseLanguage parses a language tag (the '@' having already been consumed).
+func (r *NTriplesReader) parseLanguage() (language string, err error) {
+	r.buf.Reset()
+	first, subtag := true, 0
+
+	for {
+		r1, err := r.peekRune(0)
+		if err != nil {
+			break
+		}
+
+		isLetter := (r1 >= 'a' && r1 <= 'z') || (r1 >= 'A' && r1 <= 'Z')
+
+		if isLetter || (!first && isDigit(r1)) {
+			subtag++
+		} else if r1 == '-' && subtag > 0 {
+			first, subtag = false, 0
+		} else {
+			break
+		}
+
+		r.readRune()
+		r.buf.WriteRune(r1)
+	}
+
+	if subtag == 0 {
+		r.readRune()
+		return "", r.error(ErrNTUnexpectedCharacter)
+	}
+
+	return r.buf.String(), nil
 }
 
-func (r *NTriplesReader) skipWhitespace() (r1 rune, err error) {
-	r1, err = r.readRune()
+// readEscape reads an escape sequence (the backslash having already been consumed) and returns the
+// character it denotes. Only numeric escapes are allowed in IRIs.
+func (r *NTriplesReader) readEscape(literal bool) (r1 rune, err error) {
+	r1, err = r.mustReadRune()
 	if err != nil {
-		return r1, err
+		return 0, err
 	}
 
-	for r1 == ' ' || r1 == '\t' {
-		r1, err = r.readRune()
+	switch r1 {
+	case 'u':
+		return r.readUChar(4)
+	case 'U':
+		return r.readUChar(8)
+	}
+
+	if literal {
+		switch r1 {
+		case 't':
+			return '\t', nil
+		case 'b':
+			return '\b', nil
+		case 'n':
+			return '\n', nil
+		case 'r':
+			return '\r', nil
+		case 'f':
+			return '\f', nil
+		case '"', '\'', '\\':
+			return r1, nil
+		}
+	}
+
+	return 0, r.error(ErrNTInvalidEscape)
+}
+
+// readUChar reads n hexadecimal digits and returns the code point they denote.
+func (r *NTriplesReader) readUChar(n int) (codepoint rune, err error) {
+	for i := 0; i < n; i++ {
+		r1, err := r.mustReadRune()
 		if err != nil {
-			return r1, err
+			return 0, err
+		}
+
+		if !isHexDigit(r1) {
+			return 0, r.error(ErrNTInvalidEscape)
 		}
+
+		codepoint = codepoint<<4 | hexValue(r1)
 	}
 
-	return r1, nil
+	if codepoint > unicode.MaxRune {
+		return 0, r.error(ErrNTInvalidEscape)
+	}
 
+	return codepoint, nil
 }
 
-func (r *NTriplesReader) expectWhitespace() (err error) {
-	r1, err := r.readRune()
-	if err != nil {
-		if err == io.EOF {
-			return r.error(ErrNTUnexpectedEOF)
-		}
+// readEndLine reads the rest of the line after the terminating '.', which may only contain
+// whitespace and a comment.
+func (r *NTriplesReader) readEndLine() (err error) {
+	r1, err := r.skipWhitespace()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
 		return err
 	}
-	if r1 != ' ' && r1 != '\t' {
+
+	if r1 == '#' {
+		return r.skipComment()
+	}
+
+	if r1 != '\n' && r1 != '\r' {
+		r.readRune()
 		return r.error(ErrNTUnexpectedCharacter)
 	}
 
 	return nil
 }
 
+// skipWhitespace skips spaces and tabs, and returns the next rune without consuming it.
+func (r *NTriplesReader) skipWhitespace() (r1 rune, err error) {
+	for {
+		r1, err = r.peekRune(0)
+		if err != nil || (r1 != ' ' && r1 != '\t') {
+			return r1, err
+		}
+
+		r.readRune()
+	}
+}
+
+// skipComment skips the rest of the line, up to but not including the line break.
+func (r *NTriplesReader) skipComment() (err error) {
+	for {
+		r1, err := r.peekRune(0)
+		if err == io.EOF {
+			return nil
+		} else if err != nil || r1 == '\n' || r1 == '\r' {
+			return err
+		}
+
+		r.readRune()
+	}
+}
+
+// Function ParseNTriples parses N-Triples from r and sends parsed triples on tripleChan and errors
+// on errChan. Both channels are closed when execution is done.
 func ParseNTriples(r io.Reader, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
 	defer close(tripleChan)
 	defer close(errChan)
@@ -491,6 +621,30 @@ func ParseNTriples(r io.Reader, tripleChan chan *Triple, errChan chan error, pre
 	}
 }
 
+// Function ParseNQuads parses N-Quads from r and sends parsed triples, with their graph names, on
+// tripleChan and errors on errChan. Both channels are closed when execution is done.
+func ParseNQuads(r io.Reader, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(tripleChan)
+	defer close(errChan)
+
+	nqr := NewNQuadsReader(r)
+
+	for {
+		triple, err := nqr.Read()
+		if err != nil {
+			if err != io.EOF {
+				errChan <- err
+			}
+
+			break
+		}
+
+		tripleChan <- triple
+	}
+}
+
+// Function SerializeNTriples writes N-Triples to w, sourcing triples from tripleChan and sending
+// errors to errChan. errChan is closed when execution is done.
 func SerializeNTriples(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
 	defer close(errChan)
 
@@ -503,3 +657,19 @@ func SerializeNTriples(w io.Writer, tripleChan chan *Triple, errChan chan error,
 		}
 	}
 }
+
+// Function SerializeNQuads writes N-Quads to w, sourcing triples from tripleChan and sending errors to
+// errChan. Triples with a graph name are written with it as the fourth term. errChan is closed when
+// execution is done.
+func SerializeNQuads(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(errChan)
+
+	for triple := range tripleChan {
+		_, err := fmt.Fprintln(w, triple.QuadString())
+
+		if err != nil {
+			errChan <- err
+			return
+		}
+	}
+}
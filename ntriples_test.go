@@ -21,6 +21,9 @@ package argo
 
 import (
 	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -31,13 +34,13 @@ var testCases = map[string]*Triple{
 		NewResource("http://example.org/property"),
 		NewResource("http://example.org/resource2")),
 
-	"_:anon <http://example.org/property> <http://example.org/resource2> .": NewTriple(NewNode("anon"),
+	"_:anon <http://example.org/property> <http://example.org/resource2> .": NewTriple(NewBlankNode("anon"),
 		NewResource("http://example.org/property"),
 		NewResource("http://example.org/resource2")),
 
 	"<http://example.org/resource1> <http://example.org/property> _:anon .": NewTriple(NewResource("http://example.org/resource1"),
 		NewResource("http://example.org/property"),
-		NewNode("anon")),
+		NewBlankNode("anon")),
 
 	" 	 <http://example.org/resource3> 	 <http://example.org/property>	 <http://example.org/resource2> 	.": NewTriple(NewResource("http://example.org/resource3"),
 		NewResource("http://example.org/property"),
@@ -90,37 +93,66 @@ var testCases = map[string]*Triple{
 	"<http://example.org/resource7> <http://example.org/property> \"typed literal\"^^<http://example.org/datatype1> .": NewTriple(NewResource("http://example.org/resource7"),
 		NewResource("http://example.org/property"),
 		NewLiteralWithDatatype("typed literal", NewResource("http://example.org/datatype1"))),
+
+	// N-Triples 1.1 allows whitespace between terms to be omitted, blank node labels to start with
+	// digits and contain dashes and dots, and numeric escapes in IRIs.
+	"<http://example.org/resource1><http://example.org/property>\"x\".": NewTriple(NewResource("http://example.org/resource1"),
+		NewResource("http://example.org/property"),
+		NewLiteral("x")),
+
+	"_:0a-b.c<http://example.org/property>_:abc. # comment": NewTriple(NewBlankNode("0a-b.c"),
+		NewResource("http://example.org/property"),
+		NewBlankNode("abc")),
+
+	`<http://example.org/\u0053> <http://example.org/property> "\U0001F600\b\f"@en-GB-1996 .`: NewTriple(NewResource("http://example.org/S"),
+		NewResource("http://example.org/property"),
+		NewLiteralWithLanguage("\U0001F600\b\f", "en-GB-1996")),
+
+	`<< <http://example.org/a> <http://example.org/b> << _:c <http://example.org/d> "e" >> >> <http://example.org/property> <<<http://example.org/f><http://example.org/g>_:h>> .`: NewTriple(
+		NewQuotedTriple(NewResource("http://example.org/a"), NewResource("http://example.org/b"),
+			NewQuotedTriple(NewBlankNode("c"), NewResource("http://example.org/d"), NewLiteral("e"))),
+		NewResource("http://example.org/property"),
+		NewQuotedTriple(NewResource("http://example.org/f"), NewResource("http://example.org/g"), NewBlankNode("h"))),
 }
 
 var negativeCases = map[string]error{
-	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ":   ErrUnterminatedTriple,
-	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ,":  ErrUnexpectedCharacter,
-	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ..": ErrUnexpectedCharacter,
-	"http://example.org/resource1> <http://example.org/property> <http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1 <http://example.org/property> <http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1><http://example.org/property> <http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1> <http://example.org/property><http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1> http://example.org/property> <http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1> <http://example.org/property <http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1> <http://example.org/property> http://example.org/resource2>.":    ErrUnexpectedCharacter,
-	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2.":    ErrUnexpectedEOF,
-	"<http://example.org/resource1> \n<http://example.org/property> <http://example.org/resource2>.": ErrUnexpectedCharacter,
-	"_:foo\n <http://example.org/property> <http://example.org/resource2>.":                          ErrUnexpectedCharacter,
-	"_:0abc <http://example.org/property> <http://example.org/resource2>.":                           ErrUnexpectedCharacter,
-	"_abc <http://example.org/property> <http://example.org/resource2>.":                             ErrUnexpectedCharacter,
-	"_:a-bc <http://example.org/property> <http://example.org/resource2>.":                           ErrUnexpectedCharacter,
-	"_:abc<http://example.org/property> <http://example.org/resource2>.":                             ErrUnexpectedCharacter,
-	"_:abc <http://example.org/property> \"foo\"@ .":                                                 ErrUnexpectedCharacter,
-	"_:abc <http://example.org/property> \"foo\"^ .":                                                 ErrUnexpectedCharacter,
-	"_:abc <http://example.org/property> \"foo\"^^< .":                                               ErrUnexpectedCharacter,
-	"_:abc <http://example.org/property> \"foo\"^^<> .":                                              ErrUnexpectedCharacter,
-	"_:abc <> _:abc .":                                                                               ErrUnexpectedCharacter,
-	"_:abc < > _:abc .":                                                                              ErrUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ":   ErrNTUnterminatedTriple,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ,":  ErrNTUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2> ..": ErrNTUnexpectedCharacter,
+	"http://example.org/resource1> <http://example.org/property> <http://example.org/resource2>.":    ErrNTUnexpectedCharacter,
+	"<http://example.org/resource1 <http://example.org/property> <http://example.org/resource2>.":    ErrNTUnexpectedCharacter,
+	"<http://example.org/resource1> http://example.org/property> <http://example.org/resource2>.":    ErrNTUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property <http://example.org/resource2>.":    ErrNTUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> http://example.org/resource2>.":    ErrNTUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> <http://example.org/resource2.":    ErrNTUnexpectedEOF,
+	"<http://example.org/resource1> \n<http://example.org/property> <http://example.org/resource2>.": ErrNTUnexpectedCharacter,
+	"_:foo\n <http://example.org/property> <http://example.org/resource2>.":                          ErrNTUnexpectedCharacter,
+	"_abc <http://example.org/property> <http://example.org/resource2>.":                             ErrNTUnexpectedCharacter,
+	"_:abc <http://example.org/property> \"foo\"@ .":                                                 ErrNTUnexpectedCharacter,
+	"_:abc <http://example.org/property> \"foo\"^ .":                                                 ErrNTUnexpectedCharacter,
+	"_:abc <http://example.org/property> \"foo\"^^< .":                                               ErrNTUnexpectedCharacter,
+	"_:abc <http://example.org/property> \"foo\"^^<> .":                                              ErrNTUnexpectedCharacter,
+	"_:abc <> _:abc .":  ErrNTUnexpectedCharacter,
+	"_:abc < > _:abc .": ErrNTUnexpectedCharacter,
+
+	"<s> <http://example.org/property> <http://example.org/resource2> .":                                                            ErrNTRelativeIRI,
+	"<http://example.org/a%zz> <http://example.org/property> <http://example.org/resource2> .":                                      ErrNTInvalidIRI,
+	"<http://example.org/resource1> <http://example.org/property> <http://[example.org]/> .":                                        ErrNTInvalidIRI,
+	"<http://example.org/resource1> <http://example.org/property> \"a\\qb\" .":                                                      ErrNTInvalidEscape,
+	"<http://example.org/resource1> <http://example.org/property> \"a\\u00ZZ\" .":                                                   ErrNTInvalidEscape,
+	"<http://example.org/resource1> <http://example.org/property> \"a\"@1 .":                                                        ErrNTUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> \"a\"@en- .":                                                      ErrNTUnexpectedCharacter,
+	"<http://example.org/resource1> <http://example.org/property> \"a\nb\" .":                                                       ErrNTUnterminatedLiteral,
+	"<http://example.org/resource1> <http://example.org/property> \"a\"^^<http://www.w3.org/1999/02/22-rdf-syntax-ns#langString> .": ErrNTLangString,
+	"<< <http://example.org/a> <http://example.org/b> <http://example.org/c> > <http://example.org/property> _:x .":                 ErrNTUnterminatedQuotedTriple,
+	"<< <http://example.org/a> <http://example.org/b> >> <http://example.org/property> _:x .":                                       ErrNTTermCount,
+	"<http://example.org/a> << <http://example.org/a> <http://example.org/b> <http://example.org/c> >> _:x .":                       ErrNTUnexpectedCharacter,
+	"<< \"a\" <http://example.org/b> <http://example.org/c> >> <http://example.org/property> \"x\" .":                               ErrNTUnexpectedCharacter,
 }
 
 func TestRead(t *testing.T) {
 	for ntriple, expected := range testCases {
-		r := NewReader(strings.NewReader(ntriple))
+		r := NewNTriplesReader(strings.NewReader(ntriple))
 		triple, err := r.Read()
 		if err != nil {
 			t.Errorf("Expected %s but got error %s", *expected, err)
@@ -147,7 +179,7 @@ func TestReadMultiple(t *testing.T) {
 	}
 
 	count := 0
-	r := NewReader(strings.NewReader(ntriples.String()))
+	r := NewNTriplesReader(strings.NewReader(ntriples.String()))
 	triple, err := r.Read()
 	for err == nil {
 		if !triple.Equal(triples[count]) {
@@ -169,13 +201,13 @@ func TestReadMultiple(t *testing.T) {
 func TestReadErrors(t *testing.T) {
 
 	for ntriple, expected := range negativeCases {
-		r := NewReader(strings.NewReader(ntriple))
+		r := NewNTriplesReader(strings.NewReader(ntriple))
 		_, err := r.Read()
 
 		if err == nil {
 			t.Errorf("Expected %s for %s but no error reported", expected, ntriple)
-		} else if err.(*ParseError).Err != expected {
-			t.Errorf("Expected %s for %s but got error %s", expected, ntriple, err.(*ParseError).Err)
+		} else if err.(*NTriplesParseError).Err != expected {
+			t.Errorf("Expected %s for %s but got error %s", expected, ntriple, err.(*NTriplesParseError).Err)
 		}
 	}
 }
@@ -192,7 +224,7 @@ func BenchmarkRead(b *testing.B) {
 	}
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		r := NewReader(strings.NewReader(ntriples.String()))
+		r := NewNTriplesReader(strings.NewReader(ntriples.String()))
 
 		count := 0
 		for triple, err := r.Read(); err == nil; triple, err = r.Read() {
@@ -203,3 +235,47 @@ func BenchmarkRead(b *testing.B) {
 
 	}
 }
+
+func TestNTriplesManifest(t *testing.T) {
+	for _, entry := range loadManifest(t, filepath.Join("testdata", "ntriples")) {
+		f, err := os.Open(entry.Action)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var triples []*Triple
+		r := NewNTriplesReader(f)
+		for err == nil {
+			var triple *Triple
+			triple, err = r.Read()
+			if err == nil {
+				triples = append(triples, triple)
+			}
+		}
+
+		f.Close()
+
+		switch entry.Type {
+		case "TestNTriplesPositiveSyntax":
+			if err != io.EOF {
+				t.Errorf("%s: unexpected error %s", entry.Name, err)
+			}
+
+			// The serialized form of each triple must parse back to the same triple.
+			for _, triple := range triples {
+				reparsed, err := NewNTriplesReader(strings.NewReader(triple.String())).Read()
+				if err != nil || !reparsed.Equal(triple) {
+					t.Errorf("%s: %s did not round trip (%v)", entry.Name, triple, err)
+				}
+			}
+
+		case "TestNTriplesNegativeSyntax":
+			if _, ok := err.(*NTriplesParseError); !ok {
+				t.Errorf("%s: expected a parse error but got %v", entry.Name, err)
+			}
+
+		default:
+			t.Errorf("%s: unknown test type %s", entry.Name, entry.Type)
+		}
+	}
+}
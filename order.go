@@ -0,0 +1,362 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ranks of the kinds of term, in sort order.
+const (
+	rankUnbound = iota
+	rankBlankNode
+	rankResource
+	rankLiteral
+	rankQuotedTriple
+	rankOther
+)
+
+// Ranks of the groups of literals, in sort order.
+const (
+	literalNumeric = iota
+	literalBoolean
+	literalDateTime
+	literalDate
+	literalString
+	literalLangString
+	literalOther
+)
+
+// Function termRank returns the rank of the kind of term.
+func termRank(term Term) int {
+	switch term.(type) {
+	case nil:
+		return rankUnbound
+	case *BlankNode:
+		return rankBlankNode
+	case *Resource:
+		return rankResource
+	case *Literal:
+		return rankLiteral
+	case *QuotedTriple:
+		return rankQuotedTriple
+	}
+
+	return rankOther
+}
+
+// Function CompareTerms compares two terms, returning a negative number if a sorts before b, zero if
+// they are equal and a positive number if a sorts after b. The order extends the ORDER BY rules of
+// section 15.1 of SPARQL 1.1 to a total order:
+//
+//  1. nil (an unbound value)
+//  2. blank nodes, by ID
+//  3. IRIs, by code point
+//  4. literals, as described below
+//  5. quoted triples, by subject, predicate and then object
+//
+// Literals are grouped as follows: numeric literals (xsd:integer and its derived types, xsd:decimal,
+// xsd:float and xsd:double) by value, xsd:boolean by value, xsd:dateTime by value, xsd:date by value,
+// simple literals and xsd:string by code point, language-tagged strings by code point and then
+// language, and finally all other literals, including those with an invalid lexical form, by
+// datatype IRI and then lexical form. Literals with equal values are ordered by lexical form and
+// then datatype IRI, so that only equal terms compare as equal.
+func CompareTerms(a Term, b Term) int {
+	ra, rb := termRank(a), termRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+
+	switch a := a.(type) {
+	case nil:
+		return 0
+
+	case *BlankNode:
+		return strings.Compare(a.ID, b.(*BlankNode).ID)
+
+	case *Resource:
+		return strings.Compare(a.URI, b.(*Resource).URI)
+
+	case *Literal:
+		return compareLiterals(a, b.(*Literal))
+
+	case *QuotedTriple:
+		qb := b.(*QuotedTriple)
+		if c := CompareTerms(a.Subject, qb.Subject); c != 0 {
+			return c
+		}
+
+		if c := CompareTerms(a.Predicate, qb.Predicate); c != 0 {
+			return c
+		}
+
+		return CompareTerms(a.Object, qb.Object)
+	}
+
+	return strings.Compare(a.String(), b.String())
+}
+
+// Function compareLiterals compares two literals as described for CompareTerms.
+func compareLiterals(a *Literal, b *Literal) int {
+	ga, va := literalGroup(a)
+	gb, vb := literalGroup(b)
+	if ga != gb {
+		return ga - gb
+	}
+
+	c := 0
+	switch ga {
+	case literalNumeric:
+		c = va.(numericKey).compare(vb.(numericKey))
+
+	case literalBoolean:
+		if va != vb {
+			c = -1
+			if va.(bool) {
+				c = 1
+			}
+		}
+
+	case literalDateTime, literalDate:
+		c = va.(time.Time).Compare(vb.(time.Time))
+
+	case literalLangString:
+		if c = strings.Compare(a.Value, b.Value); c == 0 {
+			c = strings.Compare(a.Language, b.Language)
+		}
+
+	case literalOther:
+		c = strings.Compare(datatypeURI(a), datatypeURI(b))
+	}
+
+	if c != 0 {
+		return c
+	}
+
+	if c = strings.Compare(a.Value, b.Value); c != 0 {
+		return c
+	}
+
+	if c = strings.Compare(datatypeURI(a), datatypeURI(b)); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.Language, b.Language)
+}
+
+// Function datatypeURI returns the IRI of the datatype of lit, or the empty string if it has none.
+func datatypeURI(lit *Literal) string {
+	if datatype, ok := lit.Datatype.(*Resource); ok {
+		return datatype.URI
+	} else if lit.Datatype != nil {
+		return lit.Datatype.String()
+	}
+
+	return ""
+}
+
+// Function literalGroup returns the group a literal sorts in, and the value it is compared by
+// within the group, if any.
+func literalGroup(lit *Literal) (group int, value interface{}) {
+	if lit.Language != "" {
+		return literalLangString, nil
+	}
+
+	if lit.Datatype == nil {
+		return literalString, nil
+	}
+
+	xsdType := lit.xsdType()
+	_, isInteger := xsdIntegerRanges[xsdType]
+
+	switch {
+	case xsdType == "string":
+		return literalString, nil
+
+	case xsdType == "boolean", xsdType == "dateTime", xsdType == "dateTimeStamp", xsdType == "date":
+		v, err := lit.Native()
+		if err != nil {
+			return literalOther, nil
+		}
+
+		switch xsdType {
+		case "boolean":
+			return literalBoolean, v
+		case "date":
+			return literalDate, v
+		}
+
+		return literalDateTime, v
+
+	case xsdType == "decimal", xsdType == "float", xsdType == "double", isInteger:
+		v, err := lit.Native()
+		if err != nil {
+			return literalOther, nil
+		}
+
+		return literalNumeric, newNumericKey(v)
+	}
+
+	return literalOther, nil
+}
+
+// A numericKey is a numeric value in a form that can be compared across types. NaN sorts before
+// every other number.
+type numericKey struct {
+	special int      // -2 for NaN, -1 for negative infinity, 1 for positive infinity, else 0
+	rat     *big.Rat // The value, if it is finite
+}
+
+// Function newNumericKey converts a value returned by Literal.Native for a numeric literal into a
+// numericKey.
+func newNumericKey(v interface{}) (key numericKey) {
+	switch v := v.(type) {
+	case int64:
+		return numericKey{rat: new(big.Rat).SetInt64(v)}
+
+	case *big.Int:
+		return numericKey{rat: new(big.Rat).SetInt(v)}
+
+	case *big.Rat:
+		return numericKey{rat: v}
+
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return numericKey{special: -2}
+		case math.IsInf(v, -1):
+			return numericKey{special: -1}
+		case math.IsInf(v, 1):
+			return numericKey{special: 1}
+		}
+
+		return numericKey{rat: new(big.Rat).SetFloat64(v)}
+	}
+
+	return numericKey{special: -2}
+}
+
+// Method compare compares two numeric keys.
+func (key numericKey) compare(other numericKey) int {
+	if key.special != other.special {
+		return key.special - other.special
+	}
+
+	if key.rat == nil || other.rat == nil {
+		return 0
+	}
+
+	return key.rat.Cmp(other.rat)
+}
+
+// Method Compare compares two triples by subject, predicate, object and then graph name, using
+// CompareTerms.
+func (triple Triple) Compare(other *Triple) int {
+	if c := CompareTerms(triple.Subject, other.Subject); c != 0 {
+		return c
+	}
+
+	if c := CompareTerms(triple.Predicate, other.Predicate); c != 0 {
+		return c
+	}
+
+	if c := CompareTerms(triple.Object, other.Object); c != 0 {
+		return c
+	}
+
+	return CompareTerms(triple.Graph, other.Graph)
+}
+
+// termsByOrder sorts terms according to CompareTerms.
+type termsByOrder []Term
+
+func (t termsByOrder) Len() int           { return len(t) }
+func (t termsByOrder) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t termsByOrder) Less(i, j int) bool { return CompareTerms(t[i], t[j]) < 0 }
+
+// triplesByOrder sorts triples according to Triple.Compare.
+type triplesByOrder []*Triple
+
+func (t triplesByOrder) Len() int           { return len(t) }
+func (t triplesByOrder) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t triplesByOrder) Less(i, j int) bool { return t[i].Compare(t[j]) < 0 }
+
+// Function SortTerms sorts terms in place according to CompareTerms.
+func SortTerms(terms []Term) {
+	sort.Sort(termsByOrder(terms))
+}
+
+// Function SortTriples sorts triples in place according to Triple.Compare.
+func SortTriples(triples []*Triple) {
+	sort.Sort(triplesByOrder(triples))
+}
+
+// Function sendSorted returns a channel that will yield triples in sorted order. The channel will
+// be closed when iteration is completed.
+func sendSorted(triples []*Triple) (ch chan *Triple) {
+	SortTriples(triples)
+	ch = make(chan *Triple)
+
+	go func() {
+		defer close(ch)
+
+		for _, triple := range triples {
+			ch <- triple
+		}
+	}()
+
+	return ch
+}
+
+// Method IterSorted returns a channel that will yield the triples of the graph in the order given by
+// Triple.Compare. The channel will be closed when iteration is completed.
+func (graph *Graph) IterSorted() (ch chan *Triple) {
+	return sendSorted(graph.triples())
+}
+
+// Method FilterSorted is like Filter, but yields the matching triples in the order given by
+// Triple.Compare.
+func (graph *Graph) FilterSorted(subjSearch, predSearch, objSearch Term) (ch chan *Triple) {
+	var triples []*Triple
+	for triple := range graph.Filter(subjSearch, predSearch, objSearch) {
+		triples = append(triples, triple)
+	}
+
+	return sendSorted(triples)
+}
+
+// Function SortedSerializer wraps serializer so that it receives the triples in the order given by
+// Triple.Compare, making its output deterministic (given deterministic blank node IDs). All triples
+// are buffered in memory before serialization starts.
+func SortedSerializer(serializer Serializer) Serializer {
+	return func(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+		var triples []*Triple
+		for triple := range tripleChan {
+			triples = append(triples, triple)
+		}
+
+		serializer(w, sendSorted(triples), errChan, prefixes)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Terms in ascending order.
+var orderedTerms = []Term{
+	nil,
+	NewBlankNode("a"),
+	NewBlankNode("b"),
+	NewResource("http://example.org/a"),
+	NewResource("http://example.org/b"),
+	NewLiteralWithDatatype("NaN", XSD.Get("double")),
+	NewLiteralWithDatatype("-INF", XSD.Get("double")),
+	NewLiteralWithDatatype("-5", XSD.Get("integer")),
+	NewLiteralWithDatatype("1.5", XSD.Get("decimal")),
+	NewLiteralWithDatatype("02", XSD.Get("int")),
+	NewLiteralWithDatatype("2", XSD.Get("integer")),
+	NewLiteralWithDatatype("2.0", XSD.Get("decimal")),
+	NewLiteralWithDatatype("1E1", XSD.Get("double")),
+	NewLiteralWithDatatype("99999999999999999999", XSD.Get("integer")),
+	NewLiteralWithDatatype("INF", XSD.Get("float")),
+	NewLiteralWithDatatype("false", XSD.Get("boolean")),
+	NewLiteralWithDatatype("true", XSD.Get("boolean")),
+	NewLiteralWithDatatype("2001-01-01T12:00:00+02:00", XSD.Get("dateTime")),
+	NewLiteralWithDatatype("2001-01-01T11:00:00Z", XSD.Get("dateTime")),
+	NewLiteralWithDatatype("2000-12-31", XSD.Get("date")),
+	NewLiteral("10"),
+	NewLiteralWithDatatype("10", XSD.Get("string")),
+	NewLiteral("9"),
+	NewLiteralWithLanguage("chat", "en"),
+	NewLiteralWithLanguage("chat", "fr"),
+	NewLiteralWithDatatype("x", NewResource("http://example.org/dt")),
+	NewLiteralWithDatatype("abc", XSD.Get("integer")),
+	NewQuotedTriple(NewBlankNode("a"), NewResource("http://example.org/p"), NewLiteral("o")),
+	NewQuotedTriple(NewResource("http://example.org/a"), NewResource("http://example.org/p"), NewLiteral("o")),
+}
+
+func TestCompareTerms(t *testing.T) {
+	for i, a := range orderedTerms {
+		for j, b := range orderedTerms {
+			c := CompareTerms(a, b)
+
+			if (i < j && c >= 0) || (i == j && c != 0) || (i > j && c <= 0) {
+				t.Errorf("CompareTerms(%v, %v) = %d", a, b, c)
+			}
+		}
+	}
+}
+
+func TestSortTerms(t *testing.T) {
+	terms := append([]Term(nil), orderedTerms...)
+	rand.New(rand.NewSource(1)).Shuffle(len(terms), func(i, j int) { terms[i], terms[j] = terms[j], terms[i] })
+
+	SortTerms(terms)
+
+	for i := range terms {
+		if CompareTerms(terms[i], orderedTerms[i]) != 0 {
+			t.Errorf("Position %d: expected %v but got %v", i, orderedTerms[i], terms[i])
+		}
+	}
+}
+
+func TestIterSorted(t *testing.T) {
+	graph := parseTurtleGraph(t, `@prefix ex: <http://example.org/> .
+ex:b ex:p 10, 9, "a" .
+ex:a ex:q ex:c ; ex:p _:x .`)
+
+	var lines []string
+	for triple := range graph.IterSorted() {
+		lines = append(lines, triple.String())
+	}
+
+	expected := `<http://example.org/a> <http://example.org/p> _:x .
+<http://example.org/a> <http://example.org/q> <http://example.org/c> .
+<http://example.org/b> <http://example.org/p> "9"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/b> <http://example.org/p> "10"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/b> <http://example.org/p> "a" .`
+
+	if got := strings.Join(lines, "\n"); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	n := 0
+	for _ = range graph.FilterSorted(NewResource("http://example.org/b"), nil, nil) {
+		n++
+	}
+
+	if n != 3 {
+		t.Errorf("Expected 3 triples but got %d", n)
+	}
+}
+
+func TestSortedSerializer(t *testing.T) {
+	triples := []*Triple{
+		NewTriple(NewResource("http://example.org/b"), A, NewResource("http://example.org/C")),
+		NewTriple(NewResource("http://example.org/a"), A, NewResource("http://example.org/C")),
+	}
+
+	var outputs []string
+	for _, order := range [][]int{{0, 1}, {1, 0}} {
+		graph := NewGraph(NewListStore())
+		for _, i := range order {
+			graph.Add(triples[i])
+		}
+
+		var buf bytes.Buffer
+		err := graph.Serialize(SortedSerializer(SerializeSquirtle), &buf)
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+
+		outputs = append(outputs, buf.String())
+	}
+
+	if outputs[0] != outputs[1] {
+		t.Errorf("Output depends on input order:\n%s\n%s", outputs[0], outputs[1])
+	}
+
+	if strings.Index(outputs[0], "example.org/a") > strings.Index(outputs[0], "example.org/b") {
+		t.Errorf("Expected subjects in sorted order:\n%s", outputs[0])
+	}
+}
@@ -0,0 +1,669 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A PatchOp identifies the kind of a row in an RDF Patch.
+type PatchOp string
+
+// These are the row kinds defined by RDF Patch.
+const (
+	PatchHeader       PatchOp = "H"  // Header (name and value)
+	PatchBegin        PatchOp = "TX" // Start of a transaction
+	PatchCommit       PatchOp = "TC" // End of a transaction
+	PatchAbort        PatchOp = "TA" // End of a transaction, discarding its changes
+	PatchAddPrefix    PatchOp = "PA" // Prefix definition
+	PatchDeletePrefix PatchOp = "PD" // Prefix removal
+	PatchAdd          PatchOp = "A"  // Triple addition
+	PatchDelete       PatchOp = "D"  // Triple deletion
+)
+
+// A PatchRow is a single row of an RDF Patch.
+type PatchRow struct {
+	Op PatchOp
+
+	// The header name for PatchHeader rows, or the prefix for PatchAddPrefix and PatchDeletePrefix
+	// rows.
+	Name string
+
+	// The header value for PatchHeader rows.
+	Value Term
+
+	// The namespace URI for PatchAddPrefix rows.
+	URI string
+
+	// The triple added or deleted by PatchAdd and PatchDelete rows. Its Graph field is set if the
+	// row is a quad.
+	Triple *Triple
+}
+
+// Method String returns the RDF Patch representation of the row.
+func (row *PatchRow) String() (str string) {
+	switch row.Op {
+	case PatchHeader:
+		return fmt.Sprintf("H %s %s .", row.Name, row.Value.String())
+
+	case PatchAddPrefix:
+		return fmt.Sprintf("PA %s %s .", NewLiteral(row.Name).String(), NewLiteral(row.URI).String())
+
+	case PatchDeletePrefix:
+		return fmt.Sprintf("PD %s .", NewLiteral(row.Name).String())
+
+	case PatchAdd, PatchDelete:
+		return string(row.Op) + " " + row.Triple.QuadString()
+	}
+
+	return string(row.Op) + " ."
+}
+
+// A Patch is a set of changes to a graph, as described by RDF Patch
+// (https://afs.github.io/rdf-delta/rdf-patch.html). Blank nodes in a patch refer to the blank nodes
+// with the same IDs in the graph it is applied to.
+type Patch struct {
+	// The header entries, such as "id" and "prev".
+	Header map[string]Term
+
+	// The changes, in the order they are applied. Only PatchAdd, PatchDelete, PatchAddPrefix and
+	// PatchDeletePrefix rows appear here.
+	Changes []*PatchRow
+}
+
+// Function NewPatch creates and returns a new, empty patch.
+func NewPatch() (patch *Patch) {
+	return &Patch{
+		Header: make(map[string]Term),
+	}
+}
+
+// Method Add appends the addition of a triple to the patch.
+func (patch *Patch) Add(triple *Triple) {
+	patch.Changes = append(patch.Changes, &PatchRow{Op: PatchAdd, Triple: triple})
+}
+
+// Method Delete appends the deletion of a triple to the patch.
+func (patch *Patch) Delete(triple *Triple) {
+	patch.Changes = append(patch.Changes, &PatchRow{Op: PatchDelete, Triple: triple})
+}
+
+// Method Empty returns whether the patch contains no changes.
+func (patch *Patch) Empty() bool {
+	return len(patch.Changes) == 0
+}
+
+// Method Write writes the patch to w in the RDF Patch text format. The header comes first, followed
+// by the changes as a single transaction.
+func (patch *Patch) Write(w io.Writer) (err error) {
+	bw := bufio.NewWriter(w)
+
+	names := make([]string, 0, len(patch.Header))
+	for name := range patch.Header {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		row := &PatchRow{Op: PatchHeader, Name: name, Value: patch.Header[name]}
+		fmt.Fprintln(bw, row.String())
+	}
+
+	if len(patch.Changes) > 0 {
+		fmt.Fprintln(bw, "TX .")
+
+		for _, row := range patch.Changes {
+			fmt.Fprintln(bw, row.String())
+		}
+
+		fmt.Fprintln(bw, "TC .")
+	}
+
+	return bw.Flush()
+}
+
+// Method String returns the patch in the RDF Patch text format.
+func (patch *Patch) String() string {
+	var b strings.Builder
+	patch.Write(&b)
+	return b.String()
+}
+
+// Function DiffTriples computes the patch that turns one set of triples into another. Blank nodes
+// are matched up as by CompareTriples, so that renaming them does not count as a change. Deleted
+// triples use the blank node IDs of from; added triples use those of from where a counterpart
+// exists, and otherwise their own IDs, renamed if they would clash with a blank node of from.
+// Graph names are ignored.
+func DiffTriples(from []*Triple, to []*Triple) (patch *Patch) {
+	c := CompareTriples(from, to)
+	patch = NewPatch()
+
+	labels := make(map[string]string, len(c.Mapping))
+	used := make(map[string]bool)
+
+	for a, b := range c.Mapping {
+		labels[b] = a
+	}
+
+	for _, triple := range from {
+		for _, term := range []Term{triple.Subject, triple.Object} {
+			if node, ok := term.(*BlankNode); ok {
+				used[node.ID] = true
+			}
+		}
+	}
+
+	n := 0
+	relabel := func(term Term) Term {
+		node, ok := term.(*BlankNode)
+		if !ok {
+			return term
+		}
+
+		label, ok := labels[node.ID]
+		if !ok {
+			label = node.ID
+			for used[label] {
+				label = fmt.Sprintf("b%d", n)
+				n++
+			}
+
+			labels[node.ID] = label
+			used[label] = true
+		}
+
+		return NewBlankNode(label)
+	}
+
+	for _, triple := range c.OnlyInFirst {
+		patch.Delete(triple)
+	}
+
+	for _, triple := range c.OnlyInSecond {
+		patch.Add(NewTriple(relabel(triple.Subject), triple.Predicate, relabel(triple.Object)))
+	}
+
+	return patch
+}
+
+// Method Diff computes the patch that turns the graph into target, including changes to the prefix
+// map. See DiffTriples.
+func (graph *Graph) Diff(target *Graph) (patch *Patch) {
+	patch = DiffTriples(graph.triples(), target.triples())
+
+	from := invertPrefixes(graph)
+	to := invertPrefixes(target)
+
+	var removed, added []string
+	for prefix := range from {
+		if _, ok := to[prefix]; !ok {
+			removed = append(removed, prefix)
+		}
+	}
+
+	for prefix, uri := range to {
+		if from[prefix] != uri {
+			added = append(added, prefix)
+		}
+	}
+
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	for _, prefix := range removed {
+		patch.Changes = append(patch.Changes, &PatchRow{Op: PatchDeletePrefix, Name: prefix})
+	}
+
+	for _, prefix := range added {
+		patch.Changes = append(patch.Changes, &PatchRow{Op: PatchAddPrefix, Name: prefix, URI: to[prefix]})
+	}
+
+	return patch
+}
+
+// invertPrefixes returns the prefix map of a graph as a map from prefixes to URIs.
+func invertPrefixes(graph *Graph) (prefixes map[string]string) {
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	prefixes = make(map[string]string, len(graph.Prefixes))
+	for uri, prefix := range graph.Prefixes {
+		prefixes[prefix] = uri
+	}
+
+	return prefixes
+}
+
+// These are the errors that can be returned by Patch.Apply and Patch.ApplyToStore, wrapped in a
+// PatchConflictError.
+var (
+	ErrPatchMissingTriple  = errors.New("deleted triple is not present")
+	ErrPatchExistingTriple = errors.New("added triple is already present")
+	ErrPatchNamedGraph     = errors.New("patch changes a named graph")
+)
+
+// A PatchConflictError is returned when a patch does not apply cleanly.
+type PatchConflictError struct {
+	Row *PatchRow // The row that could not be applied
+	Err error     // The actual error
+}
+
+func (e *PatchConflictError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Row)
+}
+
+// Method ApplyToStore applies the additions and deletions of the patch to store; prefix changes are
+// ignored. The patch is applied as a transaction: if any row does not apply cleanly, the changes
+// already made are undone and a PatchConflictError is returned, leaving the store as it was.
+//
+// This is stricter than RDF Patch itself, which treats such rows as no-ops, so that a patch applied
+// to a graph that has diverged from the one it was computed against is detected. A row conflicts if
+// it deletes a triple that is not present, adds a triple that is already present, or has a graph
+// name.
+func (patch *Patch) ApplyToStore(store Store) (err error) {
+	for _, row := range patch.Changes {
+		if (row.Op == PatchAdd || row.Op == PatchDelete) && row.Triple.Graph != nil {
+			return &PatchConflictError{Row: row, Err: ErrPatchNamedGraph}
+		}
+	}
+
+	// The rows applied so far, so that they can be undone in reverse order. Each row's triple is the
+	// one actually added to or removed from the store.
+	var undo []*PatchRow
+
+	rollback := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undo[i].Op == PatchAdd {
+				store.Remove(undo[i].Triple)
+			} else {
+				store.Add(undo[i].Triple)
+			}
+		}
+	}
+
+	for _, row := range patch.Changes {
+		switch row.Op {
+		case PatchAdd:
+			if findTriple(store, row.Triple) != nil {
+				rollback()
+				return &PatchConflictError{Row: row, Err: ErrPatchExistingTriple}
+			}
+
+			triple := NewTriple(row.Triple.Subject, row.Triple.Predicate, row.Triple.Object)
+			store.Add(triple)
+			undo = append(undo, &PatchRow{Op: PatchAdd, Triple: triple})
+
+		case PatchDelete:
+			triple := findTriple(store, row.Triple)
+			if triple == nil {
+				rollback()
+				return &PatchConflictError{Row: row, Err: ErrPatchMissingTriple}
+			}
+
+			store.Remove(triple)
+			undo = append(undo, &PatchRow{Op: PatchDelete, Triple: triple})
+		}
+	}
+
+	return nil
+}
+
+// Method Apply applies the patch to graph, including changes to its prefix map, while holding the
+// graph's lock. See ApplyToStore.
+func (patch *Patch) Apply(graph *Graph) (err error) {
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	err = patch.ApplyToStore(graph.Store)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range patch.Changes {
+		switch row.Op {
+		case PatchAddPrefix:
+			removePrefix(graph.Prefixes, row.Name)
+			graph.Prefixes[row.URI] = row.Name
+
+		case PatchDeletePrefix:
+			removePrefix(graph.Prefixes, row.Name)
+		}
+	}
+
+	return nil
+}
+
+// removePrefix removes a prefix from a map of URIs to prefixes.
+func removePrefix(prefixes map[string]string, prefix string) {
+	for uri, p := range prefixes {
+		if p == prefix {
+			delete(prefixes, uri)
+		}
+	}
+}
+
+// findTriple returns the triple stored in store that is equal to triple, or nil if there is none.
+func findTriple(store Store, triple *Triple) (found *Triple) {
+	for t := range store.Filter(triple.Subject, triple.Predicate, triple.Object) {
+		if found == nil {
+			found = t
+		}
+	}
+
+	return found
+}
+
+// A PatchParseError is returned for parsing errors.
+// The first line is 1.  The first column is 0.
+type PatchParseError struct {
+	Line   int   // Line where the error occurred
+	Column int   // Column (rune index) where the error occurred
+	Err    error // The actual error
+}
+
+func (e *PatchParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
+}
+
+// These are the errors that can be returned in PatchParseError.Err, in addition to the ErrNT errors
+// for malformed terms.
+var (
+	ErrPatchUnknownRow              = errors.New("unknown row type")
+	ErrPatchUnterminatedRow         = errors.New("unterminated row, expecting '.'")
+	ErrPatchMisplacedHeader         = errors.New("header after the start of the patch")
+	ErrPatchNestedTransaction       = errors.New("transaction started inside another transaction")
+	ErrPatchNoTransaction           = errors.New("transaction ended without being started")
+	ErrPatchUnterminatedTransaction = errors.New("unterminated transaction, expecting TC or TA")
+)
+
+// A PatchReader reads the rows of an RDF Patch. Terms are written as in N-Quads.
+type PatchReader struct {
+	nt *NTriplesReader
+}
+
+// NewPatchReader returns a new PatchReader that reads from r.
+func NewPatchReader(r io.Reader) *PatchReader {
+	return &PatchReader{
+		nt: NewNQuadsReader(r),
+	}
+}
+
+// error creates a new PatchParseError based on err.
+func (r *PatchReader) error(err error) error {
+	return &PatchParseError{
+		Line:   r.nt.line,
+		Column: r.nt.column,
+		Err:    err,
+	}
+}
+
+// Read reads the next row. It returns io.EOF when the end of the patch has been reached.
+func (r *PatchReader) Read() (row *PatchRow, err error) {
+	row, err = r.read()
+	if pe, ok := err.(*NTriplesParseError); ok {
+		err = &PatchParseError{Line: pe.Line, Column: pe.Column, Err: pe.Err}
+	}
+
+	return row, err
+}
+
+func (r *PatchReader) read() (row *PatchRow, err error) {
+	nt := r.nt
+
+	// Skip empty lines and comments.
+	for {
+		r1, err := nt.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
+
+		if r1 == '#' {
+			err = nt.skipComment()
+			if err != nil {
+				return nil, err
+			}
+
+		} else if r1 != '\n' && r1 != '\r' {
+			break
+		}
+
+		nt.readRune()
+	}
+
+	op := PatchOp(r.readWord())
+	row = &PatchRow{Op: op}
+
+	switch op {
+	case PatchHeader:
+		nt.skipWhitespace()
+		row.Name = r.readWord()
+		if row.Name == "" {
+			nt.readRune()
+			return nil, r.error(ErrNTUnexpectedCharacter)
+		}
+
+		nt.skipWhitespace()
+		row.Value, err = nt.parseTerm(2)
+
+	case PatchAddPrefix:
+		row.Name, err = r.readPrefix()
+		if err == nil {
+			row.URI, err = r.readURI()
+		}
+
+	case PatchDeletePrefix:
+		row.Name, err = r.readPrefix()
+
+	case PatchAdd, PatchDelete:
+		row.Triple, err = r.readQuad()
+
+	case PatchBegin, PatchCommit, PatchAbort:
+
+	default:
+		return nil, r.error(ErrPatchUnknownRow)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	r1, err := nt.skipWhitespace()
+	if err == io.EOF || (err == nil && r1 != '.') {
+		return nil, r.error(ErrPatchUnterminatedRow)
+	} else if err != nil {
+		return nil, err
+	}
+
+	nt.readRune()
+
+	err = nt.readEndLine()
+	if err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
+
+// readWord reads a row type, header name or prefix: a run of letters, digits, '_', '-' and ':'.
+func (r *PatchReader) readWord() string {
+	var b strings.Builder
+
+	for {
+		r1, err := r.nt.peekRune(0)
+		if err != nil || !(unicode.IsLetter(r1) || unicode.IsDigit(r1) || r1 == '_' || r1 == '-' || r1 == ':') {
+			return b.String()
+		}
+
+		r.nt.readRune()
+		b.WriteRune(r1)
+	}
+}
+
+// readPrefix reads the prefix of a prefix row, written either as a string or as a bare prefix with
+// an optional trailing colon.
+func (r *PatchReader) readPrefix() (prefix string, err error) {
+	r1, err := r.nt.skipWhitespace()
+	if err == nil && r1 == '"' {
+		return r.readString()
+	}
+
+	return strings.TrimSuffix(r.readWord(), ":"), nil
+}
+
+// readURI reads the namespace URI of a prefix row, written either as a string or as an IRI.
+func (r *PatchReader) readURI() (uri string, err error) {
+	r1, err := r.nt.skipWhitespace()
+	if err == io.EOF {
+		return "", r.error(ErrNTUnexpectedEOF)
+	} else if err != nil {
+		return "", err
+	}
+
+	if r1 == '<' {
+		return r.nt.parseIRI()
+	} else if r1 == '"' {
+		return r.readString()
+	}
+
+	r.nt.readRune()
+	return "", r.error(ErrNTUnexpectedCharacter)
+}
+
+// readString reads a plain string literal and returns its value.
+func (r *PatchReader) readString() (str string, err error) {
+	term, err := r.nt.parseLiteral()
+	if err != nil {
+		return "", err
+	}
+
+	literal := term.(*Literal)
+	if literal.Language != "" || literal.Datatype != nil {
+		return "", r.error(ErrNTUnexpectedCharacter)
+	}
+
+	return literal.Value, nil
+}
+
+// readQuad reads the three or four terms of an addition or deletion row.
+func (r *PatchReader) readQuad() (triple *Triple, err error) {
+	var terms []Term
+
+	for len(terms) < 4 {
+		r1, err := r.nt.skipWhitespace()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if r1 == '.' && len(terms) > 0 {
+			break
+		}
+
+		term, err := r.nt.parseTerm(len(terms))
+		if err != nil {
+			return nil, err
+		}
+
+		terms = append(terms, term)
+	}
+
+	if len(terms) < 3 {
+		return nil, r.error(ErrNTTermCount)
+	}
+
+	if len(terms) == 4 {
+		return NewQuad(terms[0], terms[1], terms[2], terms[3]), nil
+	}
+
+	return NewTriple(terms[0], terms[1], terms[2]), nil
+}
+
+// Function ParsePatch reads a whole RDF Patch from r. Changes within a transaction are only kept if
+// the transaction is committed with TC; those of a transaction ended with TA are discarded, as are
+// those of a transaction left unterminated, which is reported as an error. Changes outside any
+// transaction are kept as they are.
+func ParsePatch(r io.Reader) (patch *Patch, err error) {
+	pr := NewPatchReader(r)
+	patch = NewPatch()
+
+	var pending []*PatchRow
+	inTransaction := false
+	started := false
+
+	for {
+		row, err := pr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch row.Op {
+		case PatchHeader:
+			if started {
+				return nil, pr.error(ErrPatchMisplacedHeader)
+			}
+
+			patch.Header[row.Name] = row.Value
+
+		case PatchBegin:
+			if inTransaction {
+				return nil, pr.error(ErrPatchNestedTransaction)
+			}
+
+			inTransaction = true
+
+		case PatchCommit, PatchAbort:
+			if !inTransaction {
+				return nil, pr.error(ErrPatchNoTransaction)
+			}
+
+			if row.Op == PatchCommit {
+				patch.Changes = append(patch.Changes, pending...)
+			}
+
+			pending = nil
+			inTransaction = false
+
+		default:
+			if inTransaction {
+				pending = append(pending, row)
+			} else {
+				patch.Changes = append(patch.Changes, row)
+			}
+		}
+
+		started = started || row.Op != PatchHeader
+	}
+
+	if inTransaction {
+		return nil, pr.error(ErrPatchUnterminatedTransaction)
+	}
+
+	return patch, nil
+}
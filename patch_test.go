@@ -0,0 +1,200 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseTurtleGraph(t *testing.T, doc string) (graph *Graph) {
+	graph = NewGraph(NewListStore())
+
+	err := graph.Parse(ParseTurtle, strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Parsing %q: unexpected error %s", doc, err)
+	}
+
+	return graph
+}
+
+func TestPatchDiffAndApply(t *testing.T) {
+	from := parseTurtleGraph(t, `@prefix ex: <http://example.org/> .
+@prefix old: <http://old.example.org/> .
+ex:s ex:p ex:o ; ex:q [ ex:name "a" ] .
+ex:t ex:p _:x .
+_:x ex:name "x" .`)
+
+	to := parseTurtleGraph(t, `@prefix ex: <http://example.org/> .
+@prefix new: <http://new.example.org/> .
+ex:s ex:p ex:o2 ; ex:q [ ex:name "a" ] .
+ex:t ex:p _:y .
+_:y ex:name "x" ; ex:age 3 .
+ex:u ex:p [ ex:name "b" ] .`)
+
+	patch := from.Diff(to)
+
+	var adds, deletes, prefixes int
+	for _, row := range patch.Changes {
+		switch row.Op {
+		case PatchAdd:
+			adds++
+		case PatchDelete:
+			deletes++
+		default:
+			prefixes++
+		}
+	}
+
+	if adds != 4 || deletes != 1 || prefixes != 2 {
+		t.Errorf("Expected 4 additions, 1 deletion and 2 prefix changes but got:\n%s", patch)
+	}
+
+	parsed, err := ParsePatch(strings.NewReader(patch.String()))
+	if err != nil {
+		t.Fatalf("Parsing patch: unexpected error %s\n%s", err, patch)
+	}
+
+	if parsed.String() != patch.String() {
+		t.Errorf("Patch did not round trip:\nexpected:\n%s\ngot:\n%s", patch, parsed)
+	}
+
+	err = parsed.Apply(from)
+	if err != nil {
+		t.Fatalf("Applying patch: unexpected error %s", err)
+	}
+
+	if c := from.Compare(to); !c.Isomorphic() {
+		t.Errorf("Patched graph differs from target:\n%s", c)
+	}
+
+	if from.Prefixes["http://new.example.org/"] != "new" || from.Prefixes["http://old.example.org/"] != "" {
+		t.Errorf("Prefixes were not patched: %v", from.Prefixes)
+	}
+
+	if !from.Diff(to).Empty() {
+		t.Errorf("Expected no differences after patching but got:\n%s", from.Diff(to))
+	}
+}
+
+func TestPatchApplyConflict(t *testing.T) {
+	graph := parseTurtleGraph(t, `<http://example.org/s> <http://example.org/p> "a" .`)
+	before := graph.triples()
+
+	patch, err := ParsePatch(strings.NewReader(`H id <uuid:0686c69d-8f89-4496-acb5-744f0157a8db> .
+TX .
+D <http://example.org/s> <http://example.org/p> "a" .
+A <http://example.org/s> <http://example.org/p> "b" .
+D <http://example.org/s> <http://example.org/p> "c" .
+TC .
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	err = patch.Apply(graph)
+	if ce, ok := err.(*PatchConflictError); !ok || ce.Err != ErrPatchMissingTriple {
+		t.Fatalf("Expected ErrPatchMissingTriple but got %v", err)
+	}
+
+	if !CompareTriples(before, graph.triples()).Isomorphic() {
+		t.Errorf("Expected the graph to be left unchanged but got %v", graph.triples())
+	}
+}
+
+func TestPatchApplyConflictRollbackOrder(t *testing.T) {
+	patch, err := ParsePatch(strings.NewReader(`A <http://example.org/s> <http://example.org/p> "a" .
+D <http://example.org/s> <http://example.org/p> "a" .
+A <http://example.org/s> <http://example.org/p> "b" .
+D <http://example.org/s> <http://example.org/p> "b" .
+A <http://example.org/s> <http://example.org/p> "b" .
+D <http://example.org/s> <http://example.org/p> "missing" .
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	for _, newStore := range txStores {
+		store := newStore()
+
+		err = patch.ApplyToStore(store)
+		if ce, ok := err.(*PatchConflictError); !ok || ce.Err != ErrPatchMissingTriple {
+			t.Fatalf("%T: expected ErrPatchMissingTriple but got %v", store, err)
+		}
+
+		if store.Num() != 0 {
+			t.Errorf("%T: expected the store to be left empty but got %d triples", store, store.Num())
+		}
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	patch, err := ParsePatch(strings.NewReader(`# A patch.
+H id <uuid:1> .
+H prev <uuid:0> .
+PA "ex" "http://example.org/" .
+TX .
+A <http://example.org/s> <http://example.org/p> _:b <http://example.org/g> .
+TA .
+TX .
+PD ex: .
+D _:b <http://example.org/p> "x"@en .   # trailing comment
+TC .
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expected := `H id <uuid:1> .
+H prev <uuid:0> .
+TX .
+PA "ex" "http://example.org/" .
+PD "ex" .
+D _:b <http://example.org/p> "x"@en .
+TC .
+`
+
+	if patch.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, patch)
+	}
+}
+
+var patchNegativeCases = map[string]error{
+	`X <http://example.org/s> .`: ErrPatchUnknownRow,
+	`A <http://example.org/s> <http://example.org/p> <http://example.org/o>`: ErrPatchUnterminatedRow,
+	`A <http://example.org/s> <http://example.org/p> .`:                      ErrNTTermCount,
+	"TX .\nTX .": ErrPatchNestedTransaction,
+	"TC .":       ErrPatchNoTransaction,
+	"TX .\nA <http://example.org/s> <http://example.org/p> \"o\" .": ErrPatchUnterminatedTransaction,
+	"TX .\nTC .\nH id <uuid:1> .":                                   ErrPatchMisplacedHeader,
+	`PA "ex" 1 .`:                                                   ErrNTUnexpectedCharacter,
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	for doc, expected := range patchNegativeCases {
+		_, err := ParsePatch(strings.NewReader(doc))
+
+		if err == nil {
+			t.Errorf("Expected %s for %q but no error reported", expected, doc)
+		} else if pe, ok := err.(*PatchParseError); !ok || pe.Err != expected {
+			t.Errorf("Expected %s for %q but got error %s", expected, doc, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+// A QuotedTriple is an RDF-star quoted triple: a triple used as the subject or object of another
+// triple, so that statements can be made about it. Quoting a triple does not assert it.
+type QuotedTriple struct {
+	Subject   Term
+	Predicate Term
+	Object    Term
+}
+
+// Function NewQuotedTriple returns a new quoted triple with the given subject, predicate and object.
+func NewQuotedTriple(subject Term, predicate Term, object Term) (term Term) {
+	return Term(&QuotedTriple{Subject: subject, Predicate: predicate, Object: object})
+}
+
+// Method Quote returns the quoted form of this triple, for use as a term. The graph name is not
+// included.
+func (triple Triple) Quote() (term Term) {
+	return NewQuotedTriple(triple.Subject, triple.Predicate, triple.Object)
+}
+
+// Method Triple returns the triple that is quoted.
+func (term QuotedTriple) Triple() (triple *Triple) {
+	return NewTriple(term.Subject, term.Predicate, term.Object)
+}
+
+// Method String returns the N-Triples-star representation of the quoted triple.
+func (term QuotedTriple) String() (str string) {
+	return "<< " + term.Subject.String() + " " + term.Predicate.String() + " " + term.Object.String() + " >>"
+}
+
+// Method Equal returns whether this quoted triple is equivalent to another.
+func (term QuotedTriple) Equal(other Term) bool {
+	if spec, ok := other.(*QuotedTriple); ok {
+		return term.Subject.Equal(spec.Subject) && term.Predicate.Equal(spec.Predicate) && term.Object.Equal(spec.Object)
+	}
+
+	return false
+}
+
+// Method blankNodes returns the blank nodes appearing in the quoted triple, including those of
+// nested quoted triples.
+func (term QuotedTriple) blankNodes() (nodes []*BlankNode) {
+	for _, t := range []Term{term.Subject, term.Object} {
+		switch t := t.(type) {
+		case *BlankNode:
+			nodes = append(nodes, t)
+		case *QuotedTriple:
+			nodes = append(nodes, t.blankNodes()...)
+		}
+	}
+
+	return nodes
+}
+
+// Method Annotate asserts triple in the graph, unless it is already present, and adds a statement
+// about it with the given predicate and object, whose subject is the quoted triple. The quoted
+// triple is returned so that further statements can be made about it.
+func (graph *Graph) Annotate(triple *Triple, predicate Term, object Term) (quoted Term) {
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	triple = NewTriple(triple.Subject, triple.Predicate, triple.Object)
+	if findTriple(graph.Store, triple) == nil {
+		graph.Store.Add(triple)
+	}
+
+	quoted = triple.Quote()
+	graph.Store.Add(NewTriple(quoted, predicate, object))
+	return quoted
+}
+
+// Method Annotations returns the statements made about triple, i.e. the triples whose subject is
+// the quoted form of triple.
+func (graph *Graph) Annotations(triple *Triple) (annotations []*Triple) {
+	for t := range graph.Filter(triple.Quote(), nil, nil) {
+		annotations = append(annotations, t)
+	}
+
+	return annotations
+}
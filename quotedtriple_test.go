@@ -0,0 +1,106 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQuotedTripleEqual(t *testing.T) {
+	s, p := NewResource("http://example.org/s"), NewResource("http://example.org/p")
+	a := NewQuotedTriple(s, p, NewLiteral("o"))
+
+	if !a.Equal(NewTriple(s, p, NewLiteral("o")).Quote()) {
+		t.Errorf("Expected %s to equal its copy", a)
+	}
+
+	if a.Equal(NewQuotedTriple(s, p, NewLiteral("x"))) || a.Equal(s) {
+		t.Errorf("Expected %s to differ from other terms", a)
+	}
+
+	expected := `<< <http://example.org/s> <http://example.org/p> "o" >>`
+	if a.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, a)
+	}
+}
+
+func TestGraphAnnotate(t *testing.T) {
+	for _, store := range []Store{NewListStore(), NewIndexStore()} {
+		graph := NewGraph(store)
+		ex := NewNamespace("http://example.org/")
+		triple := NewTriple(ex.Get("alice"), ex.Get("knows"), ex.Get("bob"))
+
+		quoted := graph.Annotate(triple, ex.Get("since"), NewLiteral("2012"))
+		graph.Annotate(triple, ex.Get("source"), ex.Get("survey"))
+
+		if graph.Num() != 3 {
+			t.Errorf("%T: expected 3 triples but got %d", store, graph.Num())
+		}
+
+		annotations := graph.Annotations(triple)
+		if len(annotations) != 2 || !annotations[0].Subject.Equal(quoted) {
+			t.Errorf("%T: expected 2 annotations of %s but got %v", store, quoted, annotations)
+		}
+
+	}
+}
+
+func TestTurtleStarRoundTrip(t *testing.T) {
+	doc := `@prefix ex: <http://example.org/> .
+<< ex:s ex:p _:x >> ex:source ex:web .
+_:x ex:name "x" .
+ex:s ex:p _:x .
+ex:a ex:says << << ex:s ex:p "o" >> ex:q 1 >> .`
+
+	graph := parseTurtleGraph(t, doc)
+
+	var buf bytes.Buffer
+	err := graph.Serialize(SerializeTurtle, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if !strings.Contains(buf.String(), "<< ex:s ex:p _:x >> ex:source ex:web .") {
+		t.Errorf("Expected the quoted triple to be abbreviated, got:\n%s", buf.String())
+	}
+
+	other := parseTurtleGraph(t, buf.String())
+	if other.Num() != graph.Num() {
+		t.Errorf("Expected %d triples after a round trip but got %d:\n%s", graph.Num(), other.Num(), buf.String())
+	}
+
+	buf.Reset()
+	err = graph.Serialize(SerializeNTriples, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	other = NewGraph(NewListStore())
+	err = other.Parse(ParseNTriples, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if other.Num() != graph.Num() {
+		t.Errorf("Expected %d triples after an N-Triples round trip but got %d", graph.Num(), other.Num())
+	}
+}
@@ -30,11 +30,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	// These packages register their parsers/serializers in argo.Formats, so we don't actually need
 	// to directly reference the package.
+	_ "github.com/kierdavis/argo/jsonld"
 	_ "github.com/kierdavis/argo/rdfaparser"
 )
 
@@ -42,6 +44,7 @@ var LookupCacheFile = filepath.Join(os.Getenv("HOME"), ".prefixes.gob")
 
 var TriplesProcessed uint
 var Rewritten uint
+var Normalized uint
 
 var Parsers, Serializers []string
 
@@ -66,6 +69,12 @@ type Args struct {
 	InputFormat       string
 	StdinFormat       string
 	ShowFormats       bool
+	Pretty            bool
+	Normalize         bool
+	Seed              string
+	Skolemize         string
+	SharedBlankNodes  bool
+	Deterministic     bool
 	Rewrites          []string
 	SubjectRewrites   []string
 	PredicateRewrites []string
@@ -80,19 +89,40 @@ func msg(style ansi.Attribute, format string, args ...interface{}) {
 	ansi.Fprintf(os.Stderr, style, format, args...)
 }
 
-func pipe(src chan *argo.Triple, dest chan *argo.Triple) {
+// pipe forwards triples from src to dest, renaming their blank nodes through scope unless it is
+// nil.
+func pipe(src chan *argo.Triple, dest chan *argo.Triple, scope *argo.BlankNodeScope) {
 	for triple := range src {
+		if scope != nil {
+			triple = scope.RenameTriple(triple)
+		}
+
 		dest <- triple
 	}
 }
 
+// newScope returns the blank node scope for the input source with the given index: a fresh one, so
+// that blank nodes of different sources are kept apart, or nil if the user asked for labels to be
+// shared. Blank nodes are numbered per source, so the labels do not depend on the order in which
+// the sources are loaded.
+func newScope(args *Args, source int) *argo.BlankNodeScope {
+	if args.SharedBlankNodes {
+		return nil
+	}
+
+	return argo.NewBlankNodeScope(argo.NewCounterAllocator(fmt.Sprintf("s%db", source)))
+}
+
 func read(output chan *argo.Triple, errorOutput chan error, prefixMap map[string]string, args *Args) {
 	// Concurrent loading, gives a minimal speed gain:
 
 	var wg sync.WaitGroup
+	sources := 0
 
 	for _, url := range args.URLs {
 		wg.Add(1)
+		scope := newScope(args, sources)
+		sources++
 
 		go func() {
 			defer wg.Done()
@@ -127,7 +157,7 @@ func read(output chan *argo.Triple, errorOutput chan error, prefixMap map[string
 
 			wg.Add(1)
 			go func() {
-				pipe(tripleChan, output)
+				pipe(tripleChan, output, scope)
 				wg.Done()
 			}()
 
@@ -147,6 +177,9 @@ func read(output chan *argo.Triple, errorOutput chan error, prefixMap map[string
 		wg.Add(1)
 
 		if file == "-" {
+			scope := newScope(args, sources)
+			sources++
+
 			go func() {
 				defer wg.Done()
 
@@ -166,7 +199,7 @@ func read(output chan *argo.Triple, errorOutput chan error, prefixMap map[string
 
 				wg.Add(1)
 				go func() {
-					pipe(tripleChan, output)
+					pipe(tripleChan, output, scope)
 					wg.Done()
 				}()
 
@@ -189,6 +222,9 @@ func read(output chan *argo.Triple, errorOutput chan error, prefixMap map[string
 			}
 
 			for _, match := range matches {
+				scope := newScope(args, sources)
+				sources++
+
 				go func() {
 					defer wg.Done()
 
@@ -213,7 +249,7 @@ func read(output chan *argo.Triple, errorOutput chan error, prefixMap map[string
 
 					wg.Add(1)
 					go func() {
-						pipe(tripleChan, output)
+						pipe(tripleChan, output, scope)
 						wg.Done()
 					}()
 
@@ -283,6 +319,15 @@ func rewrite(termPtr *argo.Term, rewrites1, rewrites2 []Rewrite) {
 	}
 }
 
+// normalize replaces a literal with its canonical form, counting it in Normalized if it changed.
+func normalize(termPtr *argo.Term) {
+	normalized := argo.CanonicalTerm(*termPtr)
+	if !normalized.Equal(*termPtr) {
+		*termPtr = normalized
+		Normalized++
+	}
+}
+
 func main() {
 	argo.LoadLookupCache(LookupCacheFile)
 	defer argo.SaveLookupCache(LookupCacheFile)
@@ -299,6 +344,12 @@ func main() {
 	p.Option('I', "input-format", "InputFormat", 1, argparse.Choice(argparse.Store, Parsers...), "FORMAT", "The format to parse all input sources as. Default: determine by the file extension, or fall back to rdfxml if unavailable.")
 	p.Option('i', "stdin-format", "StdinFormat", 1, argparse.Choice(argparse.Store, Parsers...), "FORMAT", "The format to parse stdin as. The formats for all other sources (files and URLs) are still determined by their file extensions. Default: rdfxml.")
 	p.Option('O', "output-format", "OutputFormat", 1, argparse.Choice(argparse.Store, Serializers...), "FORMAT", "The format to write output to. Default: determine by the file extension, or fall back to rdfxml if unavailable.")
+	p.Option('p', "pretty", "Pretty", 0, argparse.StoreConst(true), "", "Use the abbreviated, human-friendly serializer of the output format, if it has one.")
+	p.Option('n', "normalize", "Normalize", 0, argparse.StoreConst(true), "", "Rewrite literals to the canonical form of their value (e.g. \"01\"^^xsd:integer to \"1\"^^xsd:integer), and normalize the case of language tags.")
+	p.Option('s', "seed", "Seed", 1, argparse.Store, "SEED", "Generate the IDs of anonymous blank nodes pseudo-randomly from the integer SEED, so that repeated runs produce the same output. Default: use random UUIDs.")
+	p.Option('k', "skolemize", "Skolemize", 1, argparse.Store, "BASE", "Replace blank nodes with skolem IRIs under BASE/.well-known/genid/, e.g. for publishing data.")
+	p.Option('b', "shared-bnodes", "SharedBlankNodes", 0, argparse.StoreConst(true), "", "Keep blank node labels as they are, so that equal labels in different input sources denote the same node. Default: standardize the blank nodes of each source apart.")
+	p.Option('d', "deterministic", "Deterministic", 0, argparse.StoreConst(true), "", "Sort the triples before serializing them, so that the same input always gives the same output. With -b/--shared-bnodes, also use -s/--seed to make the IDs of anonymous blank nodes reproducible.")
 	p.Option('F', "formats", "ShowFormats", 0, argparse.StoreConst(true), "", "Display a list of formats.")
 	p.Option('r', "rewrite", "Rewrites", 2, argparse.Append, "FIND REPLACE", "Replaces all URIs and blank nodes that match the standard regular expression FIND with the URI REPLACE. Within REPLACE, patterns such as $1, $2 etc. expanding to the text of the first and second submatch respectively. This option can be used multiple times. Input and output strings that have the prefix '_:' are interpreted as blank nodes; otherwise they are URIs.")
 	p.Option(0, "rewrite-subject", "SubjectRewrites", 2, argparse.Append, "FIND REPLACE", "Like -r/--rewrite, but only applies to subject terms.")
@@ -330,6 +381,26 @@ func main() {
 
 	// =============================================================================================
 
+	if args.Seed != "" {
+		seed, err := strconv.ParseInt(args.Seed, 10, 64)
+		if err != nil {
+			msg(ansi.RedBold, "Invalid seed '%s': %s\n", args.Seed, err.Error())
+			os.Exit(1)
+		}
+
+		argo.DefaultAllocator = argo.NewSeededAllocator(seed)
+	}
+
+	var skolemizer *argo.Skolemizer
+
+	if args.Skolemize != "" {
+		skolemizer, err = argo.NewSkolemizer(args.Skolemize)
+		if err != nil {
+			msg(ansi.RedBold, "Invalid skolemization base '%s': %s\n", args.Skolemize, err.Error())
+			os.Exit(1)
+		}
+	}
+
 	var rewrites, subjectRewrites, predicateRewrites, objectRewrites []Rewrite
 
 	if args.Rewrites != nil {
@@ -394,9 +465,18 @@ func main() {
 		format = argo.Formats[args.OutputFormat]
 	}
 
+	serializer := format.Serializer
+	if args.Pretty && format.PrettySerializer != nil {
+		serializer = format.PrettySerializer
+	}
+
+	if args.Deterministic {
+		serializer = argo.SortedSerializer(serializer)
+	}
+
 	msg(ansi.White, "Serializing as %s...\n", format.Name)
 	go read(parseChan, parseErrChan, prefixMap, args)
-	go format.Serializer(output, serializeChan, serializeErrChan, prefixMap)
+	go serializer(output, serializeChan, serializeErrChan, prefixMap)
 
 	go func() {
 		for triple := range parseChan {
@@ -404,6 +484,19 @@ func main() {
 			rewrite(&triple.Predicate, rewrites, predicateRewrites)
 			rewrite(&triple.Object, rewrites, objectRewrites)
 
+			if triple.Graph != nil {
+				rewrite(&triple.Graph, rewrites, nil)
+			}
+
+			if args.Normalize {
+				normalize(&triple.Subject)
+				normalize(&triple.Object)
+			}
+
+			if skolemizer != nil {
+				triple = skolemizer.SkolemizeTriple(triple)
+			}
+
 			serializeChan <- triple
 			TriplesProcessed++
 		}
@@ -422,4 +515,8 @@ func main() {
 	ms := float64(time.Since(startTime).Nanoseconds()) / 1000000.0
 	msg(ansi.White, "\n%d triples processed in %.3f seconds (%.3f ms)\n", TriplesProcessed, ms/1000.0, ms)
 	msg(ansi.White, "%d terms rewritten\n", Rewritten)
+
+	if args.Normalize {
+		msg(ansi.White, "%d literals normalized\n", Normalized)
+	}
 }
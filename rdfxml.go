@@ -20,313 +20,398 @@
 package argo
 
 import (
-	"bytes"
-	"encoding/xml"
+	"bufio"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
-// State IDs for the state machine.
-const (
-	stateTop = iota
-	stateDescriptions
-	stateProperties
-	statePropertyValue
-)
+// A rxSerializer holds the state of an RDF/XML serialization. In pretty mode, blank nodes that are
+// referenced only once are nested inside the element that references them, and well-formed lists
+// are written with rdf:parseType="Collection".
+type rxSerializer struct {
+	w      *bufio.Writer
+	pretty bool
 
-// XML tag & attribute names used by the parser.
-var (
-	rdfNs = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	*tripleIndex
+	written map[string]bool   // Subjects that have already been written
+	lists   map[string][]Term // Items of each list, keyed by the String() of its head node
 
-	rdfRdf         = xml.Name{rdfNs, "RDF"}
-	rdfDescription = xml.Name{rdfNs, "Description"}
-	rdfAbout       = xml.Name{rdfNs, "about"}
-	rdfNodeID      = xml.Name{rdfNs, "nodeID"}
-	rdfResource    = xml.Name{rdfNs, "resource"}
-	rdfDatatype    = xml.Name{rdfNs, "datatype"}
-	rdfParseType   = xml.Name{rdfNs, "parseType"}
+	namespaces map[string]string // Namespace URI to prefix
+	nodeIDs    map[string]string // Blank node ID to rdf:nodeID value
+	usedIDs    map[string]bool
+}
 
-	xmlLang = xml.Name{"xml", "lang"}
-)
+// splitQName splits an IRI into a namespace and a local name that is a valid XML name, as required
+// for element names.
+func splitQName(iri string) (namespace string, local string, ok bool) {
+	i := len(iri)
+	for i > 0 {
+		c := rune(iri[i-1])
+		if c >= 0x80 || !(c == '_' || c == '-' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			break
+		}
 
-func escapeXML(s string) (res string) {
-	var buf bytes.Buffer
-	xml.Escape(&buf, []byte(s))
-	return string(buf.Bytes())
-}
+		i--
+	}
+
+	for i < len(iri) && !(iri[i] == '_' || unicode.IsLetter(rune(iri[i]))) {
+		i++
+	}
 
-// Converts an xml.Name into a IRI reference term (used for predicate and inline type parsing).
-func name2Term(name xml.Name) (term Term) {
-	return NewResource(name.Space + name.Local)
+	if i == 0 || i == len(iri) {
+		return "", "", false
+	}
+
+	return iri[:i], iri[i:], true
 }
 
-// Function ParseRDFXML parses RDF/XML from r and sends parsed triples on tripleChan and errors on
-// errChan. Both channels are closed when execution is done.
-func ParseRDFXML(r io.Reader, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
-	defer close(tripleChan)
-	defer close(errChan)
+// newRXSerializer collects the triples from tripleChan and assigns namespace prefixes.
+func newRXSerializer(w io.Writer, tripleChan chan *Triple, prefixes map[string]string, pretty bool) (s *rxSerializer) {
+	s = &rxSerializer{
+		w:           bufio.NewWriter(w),
+		pretty:      pretty,
+		tripleIndex: newTripleIndex().addAll(tripleChan),
+		written:     make(map[string]bool),
+		lists:       make(map[string][]Term),
+		namespaces:  map[string]string{rdfNs: "rdf"},
+		nodeIDs:     make(map[string]string),
+		usedIDs:     make(map[string]bool),
+	}
 
-	//var err error
+	seen := make(map[string]bool)
+	var namespaces []string
 
-	decoder := xml.NewDecoder(r)
-	state := stateTop
+	addNamespace := func(iri string) {
+		if ns, _, ok := splitQName(iri); ok && ns != rdfNs && !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
 
-	var subject, predicate, datatype Term
-	var language string
+	for _, triples := range s.properties {
+		for _, triple := range triples {
+			if predicate, ok := triple.Predicate.(*Resource); ok {
+				addNamespace(predicate.URI)
+			}
 
-loop:
-	for {
-		itok, err := decoder.Token()
-		if err != nil {
-			if err != io.EOF {
-				errChan <- err
+			if object, ok := triple.Object.(*Resource); ok && triple.Predicate.Equal(A) {
+				addNamespace(object.URI)
 			}
+		}
+	}
 
-			break loop
+	sort.Sort(termsByKind(s.subjects))
+	sort.Strings(namespaces)
+
+	// Only namespaces that are used are declared. Prefixes are taken from the prefix map where
+	// possible, considering namespaces in sorted order so that clashes are resolved consistently.
+	used := map[string]bool{"rdf": true}
+	for _, ns := range namespaces {
+		prefix, ok := prefixes[ns]
+		if ok && prefix != "" && isNCName(prefix) && !strings.HasPrefix(strings.ToLower(prefix), "xml") && !used[prefix] {
+			s.namespaces[ns] = prefix
+			used[prefix] = true
 		}
+	}
 
-		switch state {
-		case stateTop:
-			switch tok := itok.(type) {
-			case xml.StartElement:
-				if tok.Name != rdfRdf {
-					errChan <- fmt.Errorf("Syntax error: expected <rdf:RDF>")
-					break loop
-				}
+	n := 0
+	for _, ns := range namespaces {
+		if _, ok := s.namespaces[ns]; ok {
+			continue
+		}
 
-				state = stateDescriptions
-			}
+		prefix := fmt.Sprintf("ns%d", n)
+		for used[prefix] {
+			n++
+			prefix = fmt.Sprintf("ns%d", n)
+		}
 
-		case stateDescriptions:
-			switch tok := itok.(type) {
-			case xml.StartElement:
-				subject = nil
-				extraAttrs := make([]xml.Attr, 0)
-
-				for _, attr := range tok.Attr {
-					if attr.Name == rdfAbout {
-						subject = NewResource(attr.Value)
-					} else if attr.Name == rdfNodeID {
-						subject = NewBlankNode(attr.Value)
-					} else {
-						extraAttrs = append(extraAttrs, attr)
-					}
-				}
+		s.namespaces[ns] = prefix
+		used[prefix] = true
+		n++
+	}
 
-				if subject == nil {
-					subject = NewAnonNode()
-				}
+	for _, triples := range s.properties {
+		sort.Sort(triplesByPredicate(triples))
+	}
 
-				if tok.Name != rdfDescription {
-					tripleChan <- NewTriple(subject, A, name2Term(tok.Name))
-				}
+	if pretty {
+		s.findLists()
+	}
 
-				for _, attr := range extraAttrs {
-					tripleChan <- NewTriple(subject, name2Term(attr.Name), NewLiteral(attr.Value))
-				}
+	return s
+}
+
+// findLists records the well-formed lists whose head is referenced exactly once, and whose items can
+// be written as node elements.
+func (s *rxSerializer) findLists() {
+	for _, subject := range s.subjects {
+		if _, ok := subject.(*BlankNode); !ok || s.refs[subject.String()] != 1 {
+			continue
+		}
 
-				state = stateProperties
+		items, nodes, ok := s.list(subject, false)
+		if ok {
+			s.lists[subject.String()] = items
 
-			case xml.EndElement: // Must be the toplevel tag (</rdf:RDF>)
-				break loop
+			for _, key := range nodes[1:] {
+				s.written[key] = true
 			}
+		}
+	}
+}
 
-		case stateProperties:
-			switch tok := itok.(type) {
-			case xml.StartElement:
-				predicate = name2Term(tok.Name)
-				language = ""
-				datatype = nil
-				state = statePropertyValue
-
-				for _, attr := range tok.Attr {
-					if attr.Name == rdfResource {
-						tripleChan <- NewTriple(subject, predicate, NewResource(attr.Value))
-						continue loop
-
-					} else if attr.Name == rdfNodeID {
-						tripleChan <- NewTriple(subject, predicate, NewBlankNode(attr.Value))
-						continue loop
-
-					} else if attr.Name == rdfDatatype {
-						datatype = NewResource(attr.Value)
-
-					} else if attr.Name == xmlLang {
-						language = attr.Value
-
-					} else {
-						errChan <- fmt.Errorf("Invalid attribute on property tag: %s:%s", attr.Name.Space, attr.Name.Local)
-						break loop
-					}
-				}
+// nodeID returns the rdf:nodeID value for a blank node, replacing IDs that are not valid XML names.
+func (s *rxSerializer) nodeID(node *BlankNode) string {
+	if id, ok := s.nodeIDs[node.ID]; ok {
+		return id
+	}
 
-			case xml.EndElement: // Must be a description tag (</rdf:Description>)
-				state = stateDescriptions
-			}
+	id := node.ID
+	for i := 0; !isNCName(id) || s.usedIDs[id]; i++ {
+		id = "genid" + strconv.Itoa(i)
+	}
 
-		case statePropertyValue:
-			switch tok := itok.(type) {
-			case xml.CharData:
-				tripleChan <- NewTriple(subject, predicate, NewLiteralWithLanguageAndDatatype(string(tok), language, datatype))
+	s.nodeIDs[node.ID] = id
+	s.usedIDs[id] = true
+	return id
+}
 
-			case xml.EndElement: // Must be a property tag (</foaf:name>)
-				state = stateProperties
-			}
-		}
+// qname returns the prefixed name for an IRI, or false if it cannot be written as an element name.
+func (s *rxSerializer) qname(iri string) (string, bool) {
+	ns, local, ok := splitQName(iri)
+	if !ok {
+		return "", false
 	}
+
+	prefix, ok := s.namespaces[ns]
+	if !ok {
+		return "", false
+	}
+
+	return prefix + ":" + local, true
 }
 
-// Function SerializeRDFXML writes RDF/XML to w, sourcing triples from tripleChan and sending errors
-// to errChan. errChan is closed when execution is done.
-func SerializeRDFXML(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
-	defer close(errChan)
+// nestable returns whether a blank node object is to be written inline.
+func (s *rxSerializer) nestable(term Term) bool {
+	key := term.String()
+	_, isBlank := term.(*BlankNode)
+
+	return s.pretty && isBlank && s.refs[key] == 1 && !s.written[key]
+}
+
+// indent writes the indentation for the given depth.
+func (s *rxSerializer) indent(depth int) {
+	s.w.WriteString(strings.Repeat("  ", depth))
+}
 
-	var err error
+// write writes the whole document.
+func (s *rxSerializer) write() (err error) {
+	s.w.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<rdf:RDF")
 
-	triplesBySubject := make(map[Term][]*Triple)
-	types := make(map[Term]Term)
+	uris := make([]string, 0, len(s.namespaces))
+	for uri := range s.namespaces {
+		uris = append(uris, uri)
+	}
+
+	sort.Slice(uris, func(i, j int) bool {
+		return s.namespaces[uris[i]] < s.namespaces[uris[j]]
+	})
+
+	for _, uri := range uris {
+		fmt.Fprintf(s.w, "\n    xmlns:%s=\"%s\"", s.namespaces[uri], escapeXMLAttr(uri))
+	}
 
-	for triple := range tripleChan {
-		if triple.Predicate == A {
-			_, alreadySet := types[triple.Subject]
-			_, isResource := triple.Object.(*Resource)
+	s.w.WriteString(">\n")
 
-			if !alreadySet && isResource {
-				types[triple.Subject] = triple.Object
-				continue
+	// Subjects that will be nested are skipped here, and any left over (because they are only
+	// reachable through a cycle of nested blank nodes) are written afterwards.
+	for _, subject := range s.subjects {
+		if !s.written[subject.String()] && !s.nestable(subject) {
+			err = s.writeNode(subject, 1, true)
+			if err != nil {
+				return err
 			}
 		}
-
-		triplesBySubject[triple.Subject] = append(triplesBySubject[triple.Subject], triple)
 	}
 
-	_, err = fmt.Fprintf(w, "<rdf:RDF\n  xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\"\n")
-	if err != nil {
-		errChan <- err
-		return
+	for _, subject := range s.subjects {
+		if !s.written[subject.String()] {
+			err = s.writeNode(subject, 1, true)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	for uri, prefix := range prefixes {
-		if prefix != "rdf" {
-			_, err = fmt.Fprintf(w, "  xmlns:%s=\"%s\"\n", escapeXML(prefix), escapeXML(uri))
+	s.w.WriteString("</rdf:RDF>\n")
+	return s.w.Flush()
+}
+
+// writeNode writes a node element describing subject. Blank nodes are identified with rdf:nodeID
+// only if topLevel is true.
+func (s *rxSerializer) writeNode(subject Term, depth int, topLevel bool) (err error) {
+	key := subject.String()
+	s.written[key] = true
+	triples := s.properties[key]
+
+	tag := "rdf:Description"
+	typeIndex := -1
+
+	for i, triple := range triples {
+		if object, ok := triple.Object.(*Resource); ok && triple.Predicate.Equal(A) {
+			if qname, ok := s.qname(object.URI); ok {
+				tag = qname
+				typeIndex = i
+				break
+			}
 		}
 	}
 
-	_, err = fmt.Fprintf(w, ">\n")
-	if err != nil {
-		errChan <- err
-		return
+	s.indent(depth)
+	s.w.WriteString("<" + tag)
+
+	switch t := subject.(type) {
+	case *Resource:
+		fmt.Fprintf(s.w, " rdf:about=\"%s\"", escapeXMLAttr(t.URI))
+	case *BlankNode:
+		if topLevel {
+			fmt.Fprintf(s.w, " rdf:nodeID=\"%s\"", s.nodeID(t))
+		}
+	default:
+		return fmt.Errorf("cannot serialize %s as the subject of a triple in RDF/XML", subject)
 	}
 
-	for subject, triples := range triplesBySubject {
-		t, hasType := types[subject]
-		subjResource, isResource := subject.(*Resource)
-		subjNode, _ := subject.(*BlankNode)
+	if len(triples) == 0 || (len(triples) == 1 && typeIndex == 0) {
+		s.w.WriteString("/>\n")
+		return nil
+	}
 
-		var subjStr string
+	s.w.WriteString(">\n")
 
-		if isResource {
-			subjStr = fmt.Sprintf("rdf:about=\"%s\"", escapeXML(subjResource.URI))
-		} else {
-			subjStr = fmt.Sprintf("rdf:nodeID=\"%s\"", escapeXML(subjNode.ID))
+	for i, triple := range triples {
+		if i != typeIndex {
+			err = s.writeProperty(triple, depth+1)
+			if err != nil {
+				return err
+			}
 		}
+	}
 
-		var tbase, tname, tprefix string
-		var thasPrefix bool
+	s.indent(depth)
+	s.w.WriteString("</" + tag + ">\n")
+	return nil
+}
 
-		if hasType {
-			tbase, tname = SplitPrefix(t.(*Resource).URI)
-			tprefix, thasPrefix = prefixes[tbase]
+// writeProperty writes a property element.
+func (s *rxSerializer) writeProperty(triple *Triple, depth int) (err error) {
+	predicate, ok := triple.Predicate.(*Resource)
+	if !ok {
+		return fmt.Errorf("cannot serialize %s as a predicate in RDF/XML", triple.Predicate)
+	}
 
-			if thasPrefix {
-				_, err = fmt.Fprintf(w, "  <%s:%s %s>\n", escapeXML(tprefix), escapeXML(tname), subjStr)
+	tag, ok := s.qname(predicate.URI)
+	if !ok {
+		return fmt.Errorf("cannot serialize predicate %s in RDF/XML: no valid XML name", triple.Predicate)
+	}
 
-			} else {
-				_, err = fmt.Fprintf(w, "  <%s xmlns=\"%s\" %s>\n", escapeXML(tname), escapeXML(tbase), subjStr)
-			}
+	s.indent(depth)
+	s.w.WriteString("<" + tag)
 
-		} else {
-			_, err = fmt.Fprintf(w, "  <rdf:Description %s>\n", subjStr)
-		}
+	switch object := triple.Object.(type) {
+	case *Resource:
+		fmt.Fprintf(s.w, " rdf:resource=\"%s\"/>\n", escapeXMLAttr(object.URI))
 
-		if err != nil {
-			errChan <- err
-			continue
+	case *Literal:
+		if object.Language != "" {
+			fmt.Fprintf(s.w, " xml:lang=\"%s\"", escapeXMLAttr(object.Language))
+		} else if datatype, ok := object.Datatype.(*Resource); ok {
+			fmt.Fprintf(s.w, " rdf:datatype=\"%s\"", escapeXMLAttr(datatype.URI))
 		}
 
-		for _, triple := range triples {
-			pbase, pname := SplitPrefix(triple.Predicate.(*Resource).URI)
-			pprefix, phasPrefix := prefixes[pbase]
-			//fmt.Println(pbase, pname, pprefix, ok, graph.Prefixes)
-			if phasPrefix {
-				_, err = fmt.Fprintf(w, "    <%s:%s", escapeXML(pprefix), escapeXML(pname))
-
-			} else {
-				_, err = fmt.Fprintf(w, "    <%s xmlns=\"%s\"", escapeXML(pname), escapeXML(pbase))
-			}
+		fmt.Fprintf(s.w, ">%s</%s>\n", escapeXMLText(object.Value), tag)
 
-			if err != nil {
-				errChan <- err
-				continue
-			}
+	case *BlankNode:
+		key := object.String()
 
-			objResource, isResource := triple.Object.(*Resource)
-			objLiteral, isLiteral := triple.Object.(*Literal)
-			objNode, _ := triple.Object.(*BlankNode)
-
-			if isResource {
-				_, err = fmt.Fprintf(w, " rdf:resource=\"%s\" />\n", escapeXML(objResource.URI))
-
-			} else if isLiteral {
-				if objLiteral.Language != "" {
-					_, err = fmt.Fprintf(w, " xml:lang=\"%s\"", escapeXML(objLiteral.Language))
-					if err != nil {
-						errChan <- err
-						continue
-					}
-
-				} else if objLiteral.Datatype != nil {
-					_, err = fmt.Fprintf(w, " rdf:datatype=\"%s\"", escapeXML(objLiteral.Datatype.(*Resource).URI))
-					if err != nil {
-						errChan <- err
-						continue
-					}
-				}
+		if items, ok := s.lists[key]; ok && s.pretty {
+			s.written[key] = true
+			s.w.WriteString(" rdf:parseType=\"Collection\">\n")
 
-				if phasPrefix {
-					_, err = fmt.Fprintf(w, ">%s</%s:%s>\n", escapeXML(objLiteral.Value), escapeXML(pprefix), escapeXML(pname))
-				} else {
-					_, err = fmt.Fprintf(w, ">%s</%s>\n", escapeXML(objLiteral.Value), escapeXML(pname))
+			for _, item := range items {
+				err = s.writeItem(item, depth+1)
+				if err != nil {
+					return err
 				}
-
-			} else {
-				_, err = fmt.Fprintf(w, " rdf:nodeID=\"%s\" />\n", escapeXML(objNode.ID))
 			}
 
+			s.indent(depth)
+			s.w.WriteString("</" + tag + ">\n")
+
+		} else if s.nestable(object) {
+			s.w.WriteString(">\n")
+
+			err = s.writeNode(object, depth+1, false)
 			if err != nil {
-				errChan <- err
-				continue
+				return err
 			}
-		}
 
-		if hasType {
-			if thasPrefix {
-				_, err = fmt.Fprintf(w, "  </%s:%s>\n", escapeXML(tprefix), escapeXML(tname))
-			} else {
-				_, err = fmt.Fprintf(w, "  </%s>\n", escapeXML(tname))
-			}
+			s.indent(depth)
+			s.w.WriteString("</" + tag + ">\n")
 
 		} else {
-			_, err = fmt.Fprintf(w, "  </rdf:Description>\n")
+			fmt.Fprintf(s.w, " rdf:nodeID=\"%s\"/>\n", s.nodeID(object))
 		}
 
-		if err != nil {
-			errChan <- err
-			continue
-		}
+	default:
+		return fmt.Errorf("cannot serialize %s as an object in RDF/XML", triple.Object)
 	}
 
-	_, err = fmt.Fprintf(w, "</rdf:RDF>\n")
+	return nil
+}
+
+// writeItem writes a member of a collection as a node element.
+func (s *rxSerializer) writeItem(item Term, depth int) (err error) {
+	if s.nestable(item) {
+		return s.writeNode(item, depth, false)
+	}
+
+	s.indent(depth)
+
+	switch t := item.(type) {
+	case *Resource:
+		fmt.Fprintf(s.w, "<rdf:Description rdf:about=\"%s\"/>\n", escapeXMLAttr(t.URI))
+	case *BlankNode:
+		fmt.Fprintf(s.w, "<rdf:Description rdf:nodeID=\"%s\"/>\n", s.nodeID(t))
+	}
+
+	return nil
+}
+
+// Function SerializeRDFXML writes RDF/XML to w, sourcing triples from tripleChan and sending errors
+// to errChan. Each subject is written as a separate top-level element, in sorted order; namespaces
+// without a prefix in prefixes are given generated prefixes (ns0, ns1, ...). errChan is closed when
+// execution is done.
+func SerializeRDFXML(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(errChan)
+
+	err := newRXSerializer(w, tripleChan, prefixes, false).write()
+	if err != nil {
+		errChan <- err
+	}
+}
+
+// Function SerializePrettyRDFXML is like SerializeRDFXML, but writes abbreviated RDF/XML: blank
+// nodes referenced only once are nested inside their referencing element, and lists are written
+// using rdf:parseType="Collection".
+func SerializePrettyRDFXML(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(errChan)
+
+	err := newRXSerializer(w, tripleChan, prefixes, true).write()
 	if err != nil {
 		errChan <- err
 	}
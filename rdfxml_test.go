@@ -0,0 +1,117 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func rdfxmlTestGraph() (graph *Graph) {
+	graph = NewGraph(NewListStore())
+	graph.Bind("http://example.org/terms#", "ex")
+
+	a := NewResource("http://example.org/a")
+	p := NewResource("http://example.org/terms#p")
+	q := NewResource("http://other.org/vocab/q")
+	addr, l1, l2 := NewBlankNode("addr"), NewBlankNode("l1"), NewBlankNode("l2")
+	shared := NewBlankNode("shared")
+
+	graph.AddTriple(a, A, NewResource("http://example.org/terms#Person"))
+	graph.AddTriple(a, q, NewLiteralWithLanguage("x < y", "en"))
+	graph.AddTriple(a, p, addr)
+	graph.AddTriple(addr, p, NewLiteralWithDatatype("1", XSD.Get("integer")))
+	graph.AddTriple(a, NewResource("http://example.org/terms#list"), l1)
+	graph.AddTriple(l1, First, NewResource("http://example.org/x"))
+	graph.AddTriple(l1, Rest, l2)
+	graph.AddTriple(l2, First, shared)
+	graph.AddTriple(l2, Rest, Nil)
+	graph.AddTriple(shared, p, NewLiteral("s"))
+	graph.AddTriple(a, q, shared)
+	return graph
+}
+
+var prettyRDFXMLOutput = `<?xml version="1.0" encoding="UTF-8"?>
+<rdf:RDF
+    xmlns:ex="http://example.org/terms#"
+    xmlns:ns0="http://other.org/vocab/"
+    xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <ex:Person rdf:about="http://example.org/a">
+    <ex:list rdf:parseType="Collection">
+      <rdf:Description rdf:about="http://example.org/x"/>
+      <rdf:Description rdf:nodeID="shared"/>
+    </ex:list>
+    <ex:p>
+      <rdf:Description>
+        <ex:p rdf:datatype="http://www.w3.org/2001/XMLSchema#integer">1</ex:p>
+      </rdf:Description>
+    </ex:p>
+    <ns0:q xml:lang="en">x &lt; y</ns0:q>
+    <ns0:q rdf:nodeID="shared"/>
+  </ex:Person>
+  <rdf:Description rdf:nodeID="shared">
+    <ex:p>s</ex:p>
+  </rdf:Description>
+</rdf:RDF>
+`
+
+func TestSerializePrettyRDFXML(t *testing.T) {
+	graph := rdfxmlTestGraph()
+
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		err := graph.Serialize(SerializePrettyRDFXML, &buf)
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+
+		if buf.String() != prettyRDFXMLOutput {
+			t.Fatalf("Expected:\n%s\ngot:\n%s", prettyRDFXMLOutput, buf.String())
+		}
+	}
+}
+
+func TestRDFXMLRoundTrip(t *testing.T) {
+	graph := rdfxmlTestGraph()
+
+	var original []*Triple
+	for triple := range graph.IterTriples() {
+		original = append(original, triple)
+	}
+
+	for _, serializer := range []Serializer{SerializeRDFXML, SerializePrettyRDFXML} {
+		var buf bytes.Buffer
+		err := graph.Serialize(serializer, &buf)
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+
+		triples, err := readAllRDFXML(NewRDFXMLReader(strings.NewReader(buf.String())))
+		if err != nil {
+			t.Fatalf("Unexpected error %s parsing:\n%s", err, buf.String())
+		}
+
+		// CompareTriples ignores duplicates, so check the count to catch duplicated output.
+		if len(triples) != len(original) || !CompareTriples(original, triples).Isomorphic() {
+			t.Errorf("Round trip changed the graph:\n%s", buf.String())
+		}
+	}
+}
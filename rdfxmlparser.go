@@ -0,0 +1,935 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kierdavis/argo/iri"
+)
+
+// A RDFXMLParseError is returned for RDF/XML parsing errors.
+// The first line is 1.  The first column is 1.
+type RDFXMLParseError struct {
+	Line   int   // Line where the error occurred
+	Column int   // Column where the error occurred
+	Err    error // The actual error
+}
+
+func (e *RDFXMLParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
+}
+
+// These are the errors that can be returned in RDFXMLParseError.Err, besides the syntax errors
+// reported by encoding/xml.
+var (
+	ErrRXUnexpectedEOF         = errors.New("unexpected end of file")
+	ErrRXUnexpectedText        = errors.New("unexpected text content")
+	ErrRXUnexpectedElement     = errors.New("property element may contain only one node element")
+	ErrRXUnqualifiedName       = errors.New("element name has no namespace")
+	ErrRXInvalidElementName    = errors.New("name is not allowed here as an element")
+	ErrRXInvalidAttribute      = errors.New("attribute is not allowed here")
+	ErrRXConflictingAttributes = errors.New("conflicting attributes")
+	ErrRXInvalidID             = errors.New("invalid rdf:ID or rdf:nodeID, expecting an XML name")
+	ErrRXDuplicateID           = errors.New("rdf:ID used more than once with the same base")
+	ErrRXInvalidIRI            = errors.New("invalid IRI")
+)
+
+const (
+	rdfNs = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlNs = "http://www.w3.org/XML/1998/namespace"
+)
+
+// Names from the RDF namespace that have a special meaning in RDF/XML.
+var (
+	rdfRdf             = xml.Name{Space: rdfNs, Local: "RDF"}
+	rdfDescription     = xml.Name{Space: rdfNs, Local: "Description"}
+	rdfID              = xml.Name{Space: rdfNs, Local: "ID"}
+	rdfAbout           = xml.Name{Space: rdfNs, Local: "about"}
+	rdfNodeID          = xml.Name{Space: rdfNs, Local: "nodeID"}
+	rdfResource        = xml.Name{Space: rdfNs, Local: "resource"}
+	rdfDatatype        = xml.Name{Space: rdfNs, Local: "datatype"}
+	rdfParseType       = xml.Name{Space: rdfNs, Local: "parseType"}
+	rdfLi              = xml.Name{Space: rdfNs, Local: "li"}
+	rdfTypeName        = xml.Name{Space: rdfNs, Local: "type"}
+	rdfBagID           = xml.Name{Space: rdfNs, Local: "bagID"}
+	rdfAboutEach       = xml.Name{Space: rdfNs, Local: "aboutEach"}
+	rdfAboutEachPrefix = xml.Name{Space: rdfNs, Local: "aboutEachPrefix"}
+)
+
+// Names that may not be used for node elements, property elements and property attributes
+// respectively.
+var (
+	rxForbiddenNodeElements = map[xml.Name]bool{
+		rdfRdf: true, rdfID: true, rdfAbout: true, rdfBagID: true, rdfParseType: true, rdfResource: true,
+		rdfNodeID: true, rdfLi: true, rdfAboutEach: true, rdfAboutEachPrefix: true, rdfDatatype: true,
+	}
+
+	rxForbiddenPropertyElements = map[xml.Name]bool{
+		rdfDescription: true, rdfRdf: true, rdfID: true, rdfAbout: true, rdfBagID: true,
+		rdfParseType: true, rdfResource: true, rdfNodeID: true, rdfAboutEach: true,
+		rdfAboutEachPrefix: true, rdfDatatype: true,
+	}
+
+	rxForbiddenPropertyAttributes = map[xml.Name]bool{
+		rdfDescription: true, rdfRdf: true, rdfID: true, rdfAbout: true, rdfBagID: true,
+		rdfParseType: true, rdfResource: true, rdfNodeID: true, rdfLi: true, rdfAboutEach: true,
+		rdfAboutEachPrefix: true, rdfDatatype: true,
+	}
+)
+
+// Unqualified attribute names that are treated as being in the RDF namespace, for compatibility
+// with older documents.
+var rxLegacyAttributes = map[string]bool{
+	"ID": true, "about": true, "resource": true, "parseType": true, "type": true,
+}
+
+// A rxElement is an element of an RDF/XML document, with its xml:base and xml:lang resolved.
+type rxElement struct {
+	name    xml.Name
+	attrs   []xml.Attr        // Attributes other than namespace declarations and xml:*
+	ns      map[string]string // Namespace declarations (prefix to URI) made on this element
+	parent  *rxElement
+	content []interface{} // Child elements (*rxElement) and text (string) in document order
+	base    string
+	lang    string
+	line    int
+	column  int
+}
+
+// elements returns the child elements of el.
+func (el *rxElement) elements() (children []*rxElement) {
+	for _, item := range el.content {
+		if child, ok := item.(*rxElement); ok {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
+// text returns the concatenated text content of el.
+func (el *rxElement) text() string {
+	var buf bytes.Buffer
+
+	for _, item := range el.content {
+		if s, ok := item.(string); ok {
+			buf.WriteString(s)
+		}
+	}
+
+	return buf.String()
+}
+
+// hasText returns whether el contains any text other than whitespace.
+func (el *rxElement) hasText() bool {
+	return strings.TrimSpace(el.text()) != ""
+}
+
+// lookupPrefix returns a prefix bound to the namespace URI uri in the scope of el.
+func (el *rxElement) lookupPrefix(uri string) (prefix string, ok bool) {
+	for e := el; e != nil; e = e.parent {
+		for p, u := range e.ns {
+			if u == uri {
+				return p, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// A RDFXMLReader parses RDF/XML documents into triples.
+type RDFXMLReader struct {
+	decoder  *xml.Decoder
+	base     string
+	prefixes map[string]string
+	root     *rxElement
+	started  bool
+	done     bool
+	ids      map[string]bool
+	pending  []*Triple
+	err      error
+	alloc    BlankNodeAllocator
+}
+
+// NewRDFXMLReader returns a new RDFXMLReader that reads from r.
+func NewRDFXMLReader(r io.Reader) *RDFXMLReader {
+	return &RDFXMLReader{
+		decoder: xml.NewDecoder(r),
+		ids:     make(map[string]bool),
+	}
+}
+
+// SetBase sets the IRI against which relative IRIs are resolved, unless the document overrides it
+// with xml:base.
+func (r *RDFXMLReader) SetBase(base string) {
+	r.base = base
+}
+
+// SetPrefixMap sets a map (of namespace URIs to prefixes, like Graph.Prefixes) that will be updated
+// with the namespaces declared by the document.
+func (r *RDFXMLReader) SetPrefixMap(prefixes map[string]string) {
+	r.prefixes = prefixes
+}
+
+// SetAllocator sets the allocator used for blank nodes without an rdf:nodeID. By default,
+// DefaultAllocator is used.
+func (r *RDFXMLReader) SetAllocator(alloc BlankNodeAllocator) {
+	r.alloc = alloc
+}
+
+// newBlankNode returns a new anonymous blank node.
+func (r *RDFXMLReader) newBlankNode() Term {
+	if r.alloc != nil {
+		return r.alloc.NewBlankNode()
+	}
+
+	return DefaultAllocator.NewBlankNode()
+}
+
+// errorAt creates a new RDFXMLParseError based on err at the position of el.
+func (r *RDFXMLReader) errorAt(el *rxElement, err error) error {
+	return &RDFXMLParseError{
+		Line:   el.line,
+		Column: el.column,
+		Err:    err,
+	}
+}
+
+// error creates a new RDFXMLParseError based on err at the current position.
+func (r *RDFXMLReader) error(err error) error {
+	line, column := r.decoder.InputPos()
+
+	return &RDFXMLParseError{
+		Line:   line,
+		Column: column,
+		Err:    err,
+	}
+}
+
+// Read reads the next triple. It returns io.EOF when the end of the document has been reached.
+func (r *RDFXMLReader) Read() (t *Triple, err error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		r.err = r.parseNext()
+	}
+
+	t = r.pending[0]
+	r.pending = r.pending[1:]
+	return t, nil
+}
+
+// emit queues a triple to be returned by Read.
+func (r *RDFXMLReader) emit(subject Term, predicate Term, object Term) {
+	r.pending = append(r.pending, NewTriple(subject, predicate, object))
+}
+
+// literal returns a plain literal with the language in scope at el.
+func (r *RDFXMLReader) literal(el *rxElement, value string) Term {
+	if el.lang != "" {
+		return NewLiteralWithLanguage(value, el.lang)
+	}
+
+	return NewLiteral(value)
+}
+
+// token reads the next XML token, converting errors into parse errors.
+func (r *RDFXMLReader) token() (tok xml.Token, err error) {
+	tok, err = r.decoder.Token()
+	if err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		return nil, r.error(err)
+	}
+
+	return tok, nil
+}
+
+// parseNext parses the next top-level node element of the document.
+func (r *RDFXMLReader) parseNext() (err error) {
+	if r.done {
+		return io.EOF
+	}
+
+	if !r.started {
+		r.started = true
+		document := &rxElement{base: r.base}
+
+		for {
+			tok, err := r.token()
+			if err != nil {
+				return err
+			}
+
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name == rdfRdf {
+					r.root, err = r.openElement(t, document)
+					return err
+				}
+
+				el, err := r.readElement(t, document)
+				if err != nil {
+					return err
+				}
+
+				r.done = true
+				_, err = r.nodeElement(el)
+				return err
+
+			case xml.CharData:
+				if strings.TrimSpace(string(t)) != "" {
+					return r.error(ErrRXUnexpectedText)
+				}
+			}
+		}
+	}
+
+	for {
+		tok, err := r.token()
+		if err == io.EOF {
+			return r.error(ErrRXUnexpectedEOF)
+		} else if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el, err := r.readElement(t, r.root)
+			if err != nil {
+				return err
+			}
+
+			_, err = r.nodeElement(el)
+			return err
+
+		case xml.EndElement:
+			r.done = true
+			return io.EOF
+
+		case xml.CharData:
+			if strings.TrimSpace(string(t)) != "" {
+				return r.error(ErrRXUnexpectedText)
+			}
+		}
+	}
+}
+
+// openElement creates an element from a start tag, processing its namespace declarations, xml:base
+// and xml:lang.
+func (r *RDFXMLReader) openElement(start xml.StartElement, parent *rxElement) (el *rxElement, err error) {
+	el = &rxElement{
+		name:   start.Name,
+		ns:     make(map[string]string),
+		parent: parent,
+		base:   parent.base,
+		lang:   parent.lang,
+	}
+
+	el.line, el.column = r.decoder.InputPos()
+
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == "xmlns":
+			el.ns[attr.Name.Local] = attr.Value
+			if r.prefixes != nil {
+				r.prefixes[attr.Value] = attr.Name.Local
+			}
+
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			el.ns[""] = attr.Value
+
+		case attr.Name.Space == xmlNs:
+			switch attr.Name.Local {
+			case "base":
+				el.base, err = iri.Resolve(parent.base, attr.Value)
+				if err != nil {
+					return nil, r.errorAt(el, ErrRXInvalidIRI)
+				}
+
+				if i := strings.Index(el.base, "#"); i >= 0 {
+					el.base = el.base[:i]
+				}
+
+			case "lang":
+				el.lang = attr.Value
+			}
+
+		case attr.Name.Space == "":
+			// Other unqualified attributes are kept for XML literals, but have no meaning in RDF.
+			if rxLegacyAttributes[attr.Name.Local] {
+				attr.Name.Space = rdfNs
+			}
+
+			el.attrs = append(el.attrs, attr)
+
+		default:
+			el.attrs = append(el.attrs, attr)
+		}
+	}
+
+	return el, nil
+}
+
+// readElement reads an element and all of its content.
+func (r *RDFXMLReader) readElement(start xml.StartElement, parent *rxElement) (el *rxElement, err error) {
+	el, err = r.openElement(start, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, err := r.token()
+		if err == io.EOF {
+			return nil, r.error(ErrRXUnexpectedEOF)
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := r.readElement(t, el)
+			if err != nil {
+				return nil, err
+			}
+
+			el.content = append(el.content, child)
+
+		case xml.CharData:
+			el.content = append(el.content, string(t))
+
+		case xml.EndElement:
+			return el, nil
+		}
+	}
+}
+
+// resolve resolves an IRI reference against the base in scope at el.
+func (r *RDFXMLReader) resolve(el *rxElement, ref string) (term Term, err error) {
+	s, err := iri.Resolve(el.base, ref)
+	if err != nil {
+		return nil, r.errorAt(el, ErrRXInvalidIRI)
+	}
+
+	// Without a base, a relative reference stays relative, which is not a valid RDF term.
+	_, err = iri.ParseAbsolute(s)
+	if err != nil {
+		return nil, r.errorAt(el, ErrRXInvalidIRI)
+	}
+
+	return NewResource(s), nil
+}
+
+// resolveID returns the IRI named by an rdf:ID attribute, checking that it has not been used before.
+func (r *RDFXMLReader) resolveID(el *rxElement, id string) (iri Term, err error) {
+	if !isNCName(id) {
+		return nil, r.errorAt(el, ErrRXInvalidID)
+	}
+
+	iri, err = r.resolve(el, "#"+id)
+	if err != nil {
+		return nil, err
+	}
+
+	s := iri.(*Resource).URI
+	if r.ids[s] {
+		return nil, r.errorAt(el, ErrRXDuplicateID)
+	}
+
+	r.ids[s] = true
+	return iri, nil
+}
+
+// nodeElement parses a node element, returning its subject.
+func (r *RDFXMLReader) nodeElement(el *rxElement) (subject Term, err error) {
+	if el.name.Space == "" {
+		return nil, r.errorAt(el, ErrRXUnqualifiedName)
+	}
+
+	if rxForbiddenNodeElements[el.name] {
+		return nil, r.errorAt(el, ErrRXInvalidElementName)
+	}
+
+	var propAttrs []xml.Attr
+	identifiers := 0
+
+	for _, attr := range el.attrs {
+		switch attr.Name {
+		case rdfID:
+			identifiers++
+			subject, err = r.resolveID(el, attr.Value)
+			if err != nil {
+				return nil, err
+			}
+
+		case rdfAbout:
+			identifiers++
+			subject, err = r.resolve(el, attr.Value)
+			if err != nil {
+				return nil, err
+			}
+
+		case rdfNodeID:
+			identifiers++
+			if !isNCName(attr.Value) {
+				return nil, r.errorAt(el, ErrRXInvalidID)
+			}
+
+			subject = NewBlankNode(attr.Value)
+
+		default:
+			if attr.Name.Space == "" {
+				continue
+			}
+
+			if rxForbiddenPropertyAttributes[attr.Name] {
+				return nil, r.errorAt(el, ErrRXInvalidAttribute)
+			}
+
+			propAttrs = append(propAttrs, attr)
+		}
+	}
+
+	if identifiers > 1 {
+		return nil, r.errorAt(el, ErrRXConflictingAttributes)
+	}
+
+	if subject == nil {
+		subject = r.newBlankNode()
+	}
+
+	if el.name != rdfDescription {
+		r.emit(subject, A, NewResource(el.name.Space+el.name.Local))
+	}
+
+	err = r.propertyAttributes(el, subject, propAttrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if el.hasText() {
+		return nil, r.errorAt(el, ErrRXUnexpectedText)
+	}
+
+	li := 1
+	for _, child := range el.elements() {
+		err = r.propertyElement(child, subject, &li)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return subject, nil
+}
+
+// propertyAttributes emits the triples given by property attributes.
+func (r *RDFXMLReader) propertyAttributes(el *rxElement, subject Term, attrs []xml.Attr) (err error) {
+	for _, attr := range attrs {
+		if attr.Name == rdfTypeName {
+			class, err := r.resolve(el, attr.Value)
+			if err != nil {
+				return err
+			}
+
+			r.emit(subject, A, class)
+		} else {
+			r.emit(subject, NewResource(attr.Name.Space+attr.Name.Local), r.literal(el, attr.Value))
+		}
+	}
+
+	return nil
+}
+
+// propertyElement parses a property element of the given subject. li holds the index of the next
+// rdf:li element.
+func (r *RDFXMLReader) propertyElement(el *rxElement, subject Term, li *int) (err error) {
+	var predicate Term
+
+	if el.name.Space == "" {
+		return r.errorAt(el, ErrRXUnqualifiedName)
+	}
+
+	if el.name == rdfLi {
+		predicate = NewResource(rdfNs + "_" + strconv.Itoa(*li))
+		*li++
+
+	} else if rxForbiddenPropertyElements[el.name] {
+		return r.errorAt(el, ErrRXInvalidElementName)
+
+	} else {
+		predicate = NewResource(el.name.Space + el.name.Local)
+	}
+
+	var id, parseType, resource, nodeID, datatype *xml.Attr
+	var propAttrs []xml.Attr
+
+	for i := range el.attrs {
+		attr := &el.attrs[i]
+
+		switch attr.Name {
+		case rdfID:
+			id = attr
+		case rdfParseType:
+			parseType = attr
+		case rdfResource:
+			resource = attr
+		case rdfNodeID:
+			nodeID = attr
+		case rdfDatatype:
+			datatype = attr
+		default:
+			if attr.Name.Space == "" {
+				continue
+			}
+
+			if rxForbiddenPropertyAttributes[attr.Name] {
+				return r.errorAt(el, ErrRXInvalidAttribute)
+			}
+
+			propAttrs = append(propAttrs, *attr)
+		}
+	}
+
+	// reify emits the reification of the statement if the property element has an rdf:ID.
+	reify := func(object Term) error {
+		r.emit(subject, predicate, object)
+
+		if id == nil {
+			return nil
+		}
+
+		statement, err := r.resolveID(el, id.Value)
+		if err != nil {
+			return err
+		}
+
+		r.emit(statement, A, NewResource(rdfNs+"Statement"))
+		r.emit(statement, NewResource(rdfNs+"subject"), subject)
+		r.emit(statement, NewResource(rdfNs+"predicate"), predicate)
+		r.emit(statement, NewResource(rdfNs+"object"), object)
+		return nil
+	}
+
+	if parseType != nil {
+		if resource != nil || nodeID != nil || datatype != nil || len(propAttrs) > 0 {
+			return r.errorAt(el, ErrRXConflictingAttributes)
+		}
+
+		switch parseType.Value {
+		case "Resource":
+			if el.hasText() {
+				return r.errorAt(el, ErrRXUnexpectedText)
+			}
+
+			object := r.newBlankNode()
+			err = reify(object)
+			if err != nil {
+				return err
+			}
+
+			childLi := 1
+			for _, child := range el.elements() {
+				err = r.propertyElement(child, object, &childLi)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+
+		case "Collection":
+			if el.hasText() {
+				return r.errorAt(el, ErrRXUnexpectedText)
+			}
+
+			var items []Term
+			for _, child := range el.elements() {
+				item, err := r.nodeElement(child)
+				if err != nil {
+					return err
+				}
+
+				items = append(items, item)
+			}
+
+			var head Term = Nil
+			for i := len(items) - 1; i >= 0; i-- {
+				node := r.newBlankNode()
+				r.emit(node, First, items[i])
+				r.emit(node, Rest, head)
+				head = node
+			}
+
+			return reify(head)
+
+		default:
+			return reify(NewLiteralWithDatatype(xmlLiteral(el), NewResource(rdfNs+"XMLLiteral")))
+		}
+	}
+
+	if children := el.elements(); len(children) > 0 {
+		if len(children) > 1 {
+			return r.errorAt(children[1], ErrRXUnexpectedElement)
+		}
+
+		if el.hasText() {
+			return r.errorAt(el, ErrRXUnexpectedText)
+		}
+
+		if resource != nil || nodeID != nil || datatype != nil || len(propAttrs) > 0 {
+			return r.errorAt(el, ErrRXConflictingAttributes)
+		}
+
+		object, err := r.nodeElement(children[0])
+		if err != nil {
+			return err
+		}
+
+		return reify(object)
+	}
+
+	if text := el.text(); text != "" || datatype != nil {
+		if resource != nil || nodeID != nil || len(propAttrs) > 0 {
+			return r.errorAt(el, ErrRXConflictingAttributes)
+		}
+
+		if datatype != nil {
+			dt, err := r.resolve(el, datatype.Value)
+			if err != nil {
+				return err
+			}
+
+			return reify(NewLiteralWithDatatype(text, dt))
+		}
+
+		return reify(r.literal(el, text))
+	}
+
+	if resource == nil && nodeID == nil && len(propAttrs) == 0 {
+		return reify(r.literal(el, ""))
+	}
+
+	var object Term
+
+	switch {
+	case resource != nil && nodeID != nil:
+		return r.errorAt(el, ErrRXConflictingAttributes)
+
+	case resource != nil:
+		object, err = r.resolve(el, resource.Value)
+		if err != nil {
+			return err
+		}
+
+	case nodeID != nil:
+		if !isNCName(nodeID.Value) {
+			return r.errorAt(el, ErrRXInvalidID)
+		}
+
+		object = NewBlankNode(nodeID.Value)
+
+	default:
+		object = r.newBlankNode()
+	}
+
+	err = reify(object)
+	if err != nil {
+		return err
+	}
+
+	return r.propertyAttributes(el, object, propAttrs)
+}
+
+// isNCName returns whether s is a valid XML non-colonised name.
+func isNCName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, c := range s {
+		if c == '_' || unicode.IsLetter(c) {
+			continue
+		}
+
+		if i > 0 && (c == '-' || c == '.' || unicode.IsDigit(c) || unicode.Is(unicode.Mn, c) || unicode.Is(unicode.Mc, c) || c == '·') {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// ---- XML literals -------------------------------------------------------------------------------
+
+// xmlLiteral serializes the content of el in exclusive canonical XML form, for use as the value of
+// an rdf:XMLLiteral.
+func xmlLiteral(el *rxElement) string {
+	var buf bytes.Buffer
+	writeXMLContent(&buf, el, make(map[string]string))
+	return buf.String()
+}
+
+// writeXMLContent writes the content of el. rendered holds the namespace declarations already
+// written by enclosing elements of the literal.
+func writeXMLContent(buf *bytes.Buffer, el *rxElement, rendered map[string]string) {
+	for _, item := range el.content {
+		switch c := item.(type) {
+		case string:
+			buf.WriteString(escapeXMLText(c))
+		case *rxElement:
+			writeXMLElement(buf, c, rendered)
+		}
+	}
+}
+
+// writeXMLElement writes an element of an XML literal, declaring the namespaces it uses that are not
+// already declared.
+func writeXMLElement(buf *bytes.Buffer, el *rxElement, rendered map[string]string) {
+	scope := make(map[string]string, len(rendered))
+	for p, u := range rendered {
+		scope[p] = u
+	}
+
+	var decls []string
+
+	qualify := func(name xml.Name, isAttr bool) string {
+		if name.Space == "" {
+			if !isAttr {
+				if u, ok := scope[""]; ok && u != "" {
+					scope[""] = ""
+					decls = append(decls, "")
+				}
+			}
+
+			return name.Local
+		}
+
+		prefix, ok := el.lookupPrefix(name.Space)
+		if !ok || (isAttr && prefix == "") {
+			prefix = "ns"
+		}
+
+		if u, ok := scope[prefix]; !ok || u != name.Space {
+			scope[prefix] = name.Space
+			decls = append(decls, prefix)
+		}
+
+		if prefix == "" {
+			return name.Local
+		}
+
+		return prefix + ":" + name.Local
+	}
+
+	tag := qualify(el.name, false)
+
+	attrs := make([]xml.Attr, len(el.attrs))
+	copy(attrs, el.attrs)
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].Name.Space != attrs[j].Name.Space {
+			return attrs[i].Name.Space < attrs[j].Name.Space
+		}
+
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+
+	attrNames := make([]string, len(attrs))
+	for i, attr := range attrs {
+		attrNames[i] = qualify(attr.Name, true)
+	}
+
+	sort.Strings(decls)
+
+	buf.WriteString("<" + tag)
+
+	for _, prefix := range decls {
+		if prefix == "" {
+			buf.WriteString(" xmlns=\"" + escapeXMLAttr(scope[""]) + "\"")
+		} else {
+			buf.WriteString(" xmlns:" + prefix + "=\"" + escapeXMLAttr(scope[prefix]) + "\"")
+		}
+	}
+
+	for i, attr := range attrs {
+		buf.WriteString(" " + attrNames[i] + "=\"" + escapeXMLAttr(attr.Value) + "\"")
+	}
+
+	buf.WriteString(">")
+	writeXMLContent(buf, el, scope)
+	buf.WriteString("</" + tag + ">")
+}
+
+// escapeXMLText escapes text content as required by canonical XML.
+func escapeXMLText(s string) string {
+	s = strings.Replace(s, "&", "&amp;", -1)
+	s = strings.Replace(s, "<", "&lt;", -1)
+	s = strings.Replace(s, ">", "&gt;", -1)
+	s = strings.Replace(s, "\r", "&#xD;", -1)
+	return s
+}
+
+// escapeXMLAttr escapes an attribute value as required by canonical XML.
+func escapeXMLAttr(s string) string {
+	s = strings.Replace(s, "&", "&amp;", -1)
+	s = strings.Replace(s, "<", "&lt;", -1)
+	s = strings.Replace(s, "\"", "&quot;", -1)
+	s = strings.Replace(s, "\t", "&#x9;", -1)
+	s = strings.Replace(s, "\n", "&#xA;", -1)
+	s = strings.Replace(s, "\r", "&#xD;", -1)
+	return s
+}
+
+// Function ParseRDFXML parses RDF/XML from r and sends parsed triples on tripleChan and errors on
+// errChan. Namespaces declared by the document are added to prefixes. Both channels are closed when
+// execution is done.
+func ParseRDFXML(r io.Reader, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(tripleChan)
+	defer close(errChan)
+
+	rxr := NewRDFXMLReader(r)
+	rxr.SetPrefixMap(prefixes)
+
+	for {
+		triple, err := rxr.Read()
+		if err != nil {
+			if err != io.EOF {
+				errChan <- err
+			}
+
+			break
+		}
+
+		tripleChan <- triple
+	}
+}
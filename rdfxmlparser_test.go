@@ -0,0 +1,123 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readAllRDFXML(r *RDFXMLReader) (triples []*Triple, err error) {
+	for {
+		triple, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return triples, nil
+			}
+
+			return triples, err
+		}
+
+		triples = append(triples, triple)
+	}
+}
+
+func TestRDFXMLConformance(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "rdfxml", "*.rdf"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No test files found: %v", err)
+	}
+
+	for _, filename := range files {
+		name := strings.TrimSuffix(filepath.Base(filename), ".rdf")
+
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := NewRDFXMLReader(f)
+		r.SetBase("http://example.org/base/" + name + ".rdf")
+		triples, err := readAllRDFXML(r)
+		f.Close()
+
+		if strings.HasPrefix(name, "error-") {
+			if _, ok := err.(*RDFXMLParseError); !ok {
+				t.Errorf("%s: expected a parse error but got %v", name, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+			continue
+		}
+
+		expected, err := os.ReadFile(strings.TrimSuffix(filename, ".rdf") + ".nt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expectedTriples []*Triple
+		ntr := NewNTriplesReader(strings.NewReader(string(expected)))
+		for triple, err := ntr.Read(); err == nil; triple, err = ntr.Read() {
+			expectedTriples = append(expectedTriples, triple)
+		}
+
+		if c := CompareTriples(triples, expectedTriples); !c.Isomorphic() {
+			t.Errorf("%s: unexpected (-) and missing (+) triples:\n%s", name, c)
+		} else if len(triples) != len(expectedTriples) {
+			t.Errorf("%s: expected %d triples but got %d", name, len(expectedTriples), len(triples))
+		}
+	}
+}
+
+func TestRDFXMLParsePrefixes(t *testing.T) {
+	graph := NewGraph(NewListStore())
+	doc := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+  <ex:Thing rdf:about="http://example.org/a"/>
+</rdf:RDF>`
+
+	err := graph.Parse(ParseRDFXML, strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if graph.Num() != 1 || graph.Prefixes["http://example.org/"] != "ex" {
+		t.Errorf("Expected 1 triple and the ex prefix, got %d triples and %v", graph.Num(), graph.Prefixes)
+	}
+}
+
+func TestRDFXMLRelativeIRIWithoutBase(t *testing.T) {
+	doc := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+  <rdf:Description rdf:about="a">
+    <ex:p rdf:resource="http://example.org/b"/>
+  </rdf:Description>
+</rdf:RDF>`
+
+	_, err := readAllRDFXML(NewRDFXMLReader(strings.NewReader(doc)))
+	if pe, ok := err.(*RDFXMLParseError); !ok || pe.Err != ErrRXInvalidIRI {
+		t.Errorf("Expected %s but got %v", ErrRXInvalidIRI, err)
+	}
+}
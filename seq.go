@@ -0,0 +1,99 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"iter"
+)
+
+// A SeqStore is a Store that can also yield its triples as range-over-func iterators, without the
+// goroutine and channel that IterTriples and Filter need. Graph uses these methods when its store
+// provides them.
+type SeqStore interface {
+	Store
+
+	// Method All should return a sequence of all the triples in the store.
+	All() iter.Seq[*Triple]
+
+	// Method FilterSeq should return a sequence of the matching triples of the store; the
+	// arguments have the same meaning as for Filter.
+	FilterSeq(Term, Term, Term) iter.Seq[*Triple]
+}
+
+// Function seqChannel returns a channel that yields the triples of seq, and is closed after the
+// last one.
+func seqChannel(seq iter.Seq[*Triple]) (ch chan *Triple) {
+	ch = make(chan *Triple)
+
+	go func() {
+		defer close(ch)
+
+		for triple := range seq {
+			ch <- triple
+		}
+	}()
+
+	return ch
+}
+
+// Function channelSeq returns a sequence of the triples received from ch. If the caller stops
+// early, the rest of ch is drained in the background so that its sender is not blocked forever.
+// The sequence can only be iterated once.
+func channelSeq(ch chan *Triple) (seq iter.Seq[*Triple]) {
+	return func(yield func(*Triple) bool) {
+		for triple := range ch {
+			if !yield(triple) {
+				go drainTriples(ch)
+				return
+			}
+		}
+	}
+}
+
+// Function drainTriples discards the remaining triples on ch, so that the goroutine sending them
+// can finish.
+func drainTriples(ch chan *Triple) {
+	for _ = range ch {
+	}
+}
+
+// Function storeAllSeq returns a sequence of all the triples of store, natively if it is a
+// SeqStore.
+func storeAllSeq(store Store) (seq iter.Seq[*Triple]) {
+	if ss, ok := store.(SeqStore); ok {
+		return ss.All()
+	}
+
+	return channelSeq(store.IterTriples())
+}
+
+// Function storeFilterSeq returns a sequence of the matching triples of store, natively if it is a
+// SeqStore.
+func storeFilterSeq(store Store, subjSearch, predSearch, objSearch Term) (seq iter.Seq[*Triple]) {
+	if ss, ok := store.(SeqStore); ok {
+		if subjSearch == nil && predSearch == nil && objSearch == nil {
+			return ss.All()
+		}
+
+		return ss.FilterSeq(subjSearch, predSearch, objSearch)
+	}
+
+	return channelSeq(store.Filter(subjSearch, predSearch, objSearch))
+}
@@ -0,0 +1,187 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"fmt"
+	"testing"
+)
+
+// A legacyStore hides the SeqStore methods of the store it wraps.
+type legacyStore struct {
+	Store
+}
+
+var seqStores = []struct {
+	name string
+	new  func() Store
+}{
+	{"ListStore", func() Store { return NewListStore() }},
+	{"IndexStore", func() Store { return NewIndexStore() }},
+	{"DictionaryStore", func() Store { return NewDictionaryStore() }},
+	{"legacy", func() Store { return legacyStore{NewListStore()} }},
+}
+
+func fillSeqGraph(store Store, subjects int, predicates int) (graph *Graph) {
+	graph = NewGraph(store)
+	ex := NewNamespace("http://example.org/")
+
+	for s := 0; s < subjects; s++ {
+		for p := 0; p < predicates; p++ {
+			graph.AddTriple(ex.Get(fmt.Sprintf("s%d", s)), ex.Get(fmt.Sprintf("p%d", p)), NewLiteral(fmt.Sprint(s*p)))
+		}
+	}
+
+	return graph
+}
+
+func TestGraphSeq(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+
+	for _, st := range seqStores {
+		graph := fillSeqGraph(st.new(), 10, 5)
+
+		n := 0
+		for _ = range graph.All() {
+			n++
+		}
+
+		if n != 50 {
+			t.Errorf("%s: expected All to yield 50 triples but got %d", st.name, n)
+		}
+
+		n = 0
+		for triple := range graph.FilterSeq(ex.Get("s3"), nil, nil) {
+			if !triple.Subject.Equal(ex.Get("s3")) {
+				t.Errorf("%s: FilterSeq yielded non-matching triple %s", st.name, triple)
+			}
+
+			n++
+		}
+
+		if n != 5 {
+			t.Errorf("%s: expected FilterSeq to yield 5 triples but got %d", st.name, n)
+		}
+
+		n = 0
+		for _ = range graph.FilterSeq(nil, ex.Get("p2"), nil) {
+			n++
+			if n == 3 {
+				break
+			}
+		}
+
+		if n != 3 {
+			t.Errorf("%s: expected to stop after 3 triples but got %d", st.name, n)
+		}
+	}
+}
+
+func TestGraphListAndContainer(t *testing.T) {
+	graph := parseTurtleGraph(t, `@prefix ex: <http://example.org/> .
+@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+ex:list ex:items ( "a" "b" "c" ) ; ex:none () .
+ex:bag a rdf:Bag ; rdf:_1 "x" ; rdf:_2 "y" .`)
+
+	ex := NewNamespace("http://example.org/")
+
+	var items []string
+	for i, item := range graph.List(graph.Get(ex.Get("list"), ex.Get("items"))) {
+		if i != len(items) {
+			t.Errorf("Expected index %d but got %d", len(items), i)
+		}
+
+		items = append(items, item.(*Literal).Value)
+	}
+
+	if fmt.Sprint(items) != "[a b c]" {
+		t.Errorf("Expected [a b c] but got %v", items)
+	}
+
+	for _, item := range graph.List(graph.Get(ex.Get("list"), ex.Get("none"))) {
+		t.Errorf("Unexpected item %s in empty list", item)
+	}
+
+	items = nil
+	for item := range graph.IterContainer(ex.Get("bag")) {
+		items = append(items, item.(*Literal).Value)
+	}
+
+	if fmt.Sprint(items) != "[x y]" {
+		t.Errorf("Expected [x y] but got %v", items)
+	}
+}
+
+func TestGraphContainerNumbering(t *testing.T) {
+	graph := parseTurtleGraph(t, `@prefix ex: <http://example.org/> .
+@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+ex:seq a rdf:Seq ; rdf:_0 "zero" ; rdf:_1 "x" ; rdf:_2 "y" ; rdf:_4 "z" .`)
+
+	var items []string
+	for i, item := range graph.Container(NewResource("http://example.org/seq")) {
+		items = append(items, fmt.Sprintf("%d:%s", i, item.(*Literal).Value))
+	}
+
+	if fmt.Sprint(items) != "[0:x 1:y]" {
+		t.Errorf("Expected [0:x 1:y] but got %v", items)
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	subject := NewResource("http://example.org/s7")
+
+	for _, st := range seqStores {
+		graph := fillSeqGraph(st.new(), 100, 10)
+
+		b.Run(st.name+"/channel", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _ = range graph.Filter(subject, nil, nil) {
+				}
+			}
+		})
+
+		b.Run(st.name+"/seq", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _ = range graph.FilterSeq(subject, nil, nil) {
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkIterTriples(b *testing.B) {
+	for _, st := range seqStores {
+		graph := fillSeqGraph(st.new(), 100, 10)
+
+		b.Run(st.name+"/channel", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _ = range graph.IterTriples() {
+				}
+			}
+		})
+
+		b.Run(st.name+"/seq", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _ = range graph.All() {
+				}
+			}
+		})
+	}
+}
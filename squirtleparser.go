@@ -31,6 +31,8 @@ import (
 	"sync"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/kierdavis/argo/iri"
 )
 
 type sqtlVar struct {
@@ -304,6 +306,10 @@ func (ll *lexer) Lex(lval *sqtlSymType) (t int) {
 		ll.Next()
 		ll.Discard()
 
+		if _, err := iri.Parse(lval.s); err != nil {
+			ll.Error(err.Error())
+		}
+
 		return IRIREF
 
 	case r == '"':
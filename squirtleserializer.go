@@ -3,6 +3,7 @@ package argo
 import (
 	"fmt"
 	"io"
+	"sort"
 )
 
 func SerializeSquirtle(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
@@ -11,6 +12,7 @@ func SerializeSquirtle(w io.Writer, tripleChan chan *Triple, errChan chan error,
 	var err error
 
 	triplesBySubject := make(map[string][]*Triple)
+	var subjects []string
 
 	encodeTerm := func(iterm Term) (s string) {
 		switch term := iterm.(type) {
@@ -77,13 +79,26 @@ func SerializeSquirtle(w io.Writer, tripleChan chan *Triple, errChan chan error,
 		return true
 	}
 
+	// Subjects are described in the order they were first received, so that sorted input gives
+	// deterministic output.
 	for triple := range tripleChan {
 		s := encodeTerm(triple.Subject)
+		if _, ok := triplesBySubject[s]; !ok {
+			subjects = append(subjects, s)
+		}
+
 		triplesBySubject[s] = append(triplesBySubject[s], triple)
 	}
 
-	for base, prefix := range prefixes {
-		_, err = fmt.Fprintf(w, "name <%s> as %s\n", base, prefix)
+	bases := make([]string, 0, len(prefixes))
+	for base := range prefixes {
+		bases = append(bases, base)
+	}
+
+	sort.Strings(bases)
+
+	for _, base := range bases {
+		_, err = fmt.Fprintf(w, "name <%s> as %s\n", base, prefixes[base])
 		if err != nil {
 			errChan <- err
 			return
@@ -96,8 +111,9 @@ func SerializeSquirtle(w io.Writer, tripleChan chan *Triple, errChan chan error,
 		return
 	}
 
-	for subject, triples := range triplesBySubject {
-		if !describe(subject, triples, "") {
+	for _, subject := range subjects {
+		triples, ok := triplesBySubject[subject]
+		if ok && !describe(subject, triples, "") {
 			return
 		}
 	}
@@ -21,8 +21,9 @@ package argo
 
 import (
 	"fmt"
-	"math/rand"
 	"strings"
+
+	"github.com/kierdavis/argo/iri"
 )
 
 // A Term is the value of a subject, predicate or object i.e. a IRI reference, blank node or
@@ -45,9 +46,45 @@ func NewResource(uri string) (term Term) {
 	return Term(&Resource{URI: uri})
 }
 
-// Method String returns the NTriples representation of this resource.
+// Function NewCheckedResource returns a new resource object, or an *iri.Error if uri is not a valid
+// absolute IRI.
+func NewCheckedResource(uri string) (term Term, err error) {
+	_, err = iri.ParseAbsolute(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewResource(uri), nil
+}
+
+// Method String returns the NTriples representation of this resource. Characters that may not
+// appear in an IRI reference are written as numeric escapes.
 func (term Resource) String() (str string) {
-	return fmt.Sprintf("<%s>", term.URI)
+	if strings.IndexFunc(term.URI, isIRIUnsafe) < 0 {
+		return "<" + term.URI + ">"
+	}
+
+	return "<" + escapeIRI(term.URI) + ">"
+}
+
+// isIRIUnsafe returns whether r1 may not appear unescaped in an IRI reference.
+func isIRIUnsafe(r1 rune) bool {
+	return r1 <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", r1)
+}
+
+// escapeIRI replaces characters that may not appear in an IRI reference with numeric escapes.
+func escapeIRI(iri string) string {
+	var b strings.Builder
+
+	for _, r1 := range iri {
+		if isIRIUnsafe(r1) {
+			fmt.Fprintf(&b, "\\u%04X", r1)
+		} else {
+			b.WriteRune(r1)
+		}
+	}
+
+	return b.String()
 }
 
 // Method Equal returns whether this resource is equal to another.
@@ -89,16 +126,40 @@ func NewLiteralWithLanguageAndDatatype(value string, language string, datatype T
 	return Term(&Literal{Value: value, Language: language, Datatype: datatype})
 }
 
-// Method String returns the NTriples representation of this literal.
+// Method String returns the NTriples representation of this literal. Backspace, tab, line feed,
+// form feed, carriage return, double quote and backslash are written as escapes such as \n, and
+// other control characters as numeric escapes.
 func (term Literal) String() (str string) {
-	str = term.Value
-	str = strings.Replace(str, "\\", "\\\\", -1)
-	str = strings.Replace(str, "\"", "\\\"", -1)
-	str = strings.Replace(str, "\n", "\\n", -1)
-	str = strings.Replace(str, "\r", "\\r", -1)
-	str = strings.Replace(str, "\t", "\\t", -1)
+	var b strings.Builder
+	b.WriteByte('"')
+
+	for _, r1 := range term.Value {
+		switch r1 {
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if r1 < 0x20 || r1 == 0x7F {
+				fmt.Fprintf(&b, "\\u%04X", r1)
+			} else {
+				b.WriteRune(r1)
+			}
+		}
+	}
 
-	str = fmt.Sprintf("\"%s\"", str)
+	b.WriteByte('"')
+	str = b.String()
 
 	if term.Language != "" {
 		str += "@" + term.Language
@@ -145,9 +206,9 @@ func NewBlankNode(id string) (term Term) {
 	return Term(&BlankNode{ID: id})
 }
 
-// Function NewAnonNode returns a new blank node with a pseudo-randomly generated ID.
+// Function NewAnonNode returns a new blank node with a fresh ID from DefaultAllocator.
 func NewAnonNode() (term Term) {
-	return Term(&BlankNode{ID: fmt.Sprintf("anon%016x", rand.Int63())})
+	return DefaultAllocator.NewBlankNode()
 }
 
 // Method String returns the NTriples representation of the blank node.
@@ -21,6 +21,8 @@ package argo
 
 import (
 	"testing"
+
+	"github.com/kierdavis/argo/iri"
 )
 
 func TestResourceEqual(t *testing.T) {
@@ -32,3 +34,17 @@ func TestResourceEqual(t *testing.T) {
 	}
 
 }
+
+func TestNewCheckedResource(t *testing.T) {
+	_, err := NewCheckedResource("http://example.org/a")
+	if err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+
+	for _, uri := range []string{"a", "http://example.org/a b", "http://example.org/%zz", ""} {
+		_, err := NewCheckedResource(uri)
+		if _, ok := err.(*iri.Error); !ok {
+			t.Errorf("Expected an *iri.Error for %q but got %v", uri, err)
+		}
+	}
+}
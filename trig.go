@@ -0,0 +1,231 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"io"
+	"strings"
+)
+
+// Function NewTriGReader returns a new TurtleReader that reads TriG from r. Triples in named graphs
+// have their Graph field set to the graph name.
+func NewTriGReader(r io.Reader) *TurtleReader {
+	ttr := NewTurtleReader(r)
+	ttr.trig = true
+	return ttr
+}
+
+// parseBlock parses a TriG block outside of any graph: either the start of a graph, or triples in
+// the default graph.
+func (r *TurtleReader) parseBlock() (err error) {
+	tok, err := r.peekToken()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case tok.isPunct("{"):
+		return r.openGraph(nil)
+
+	case tok.kind == ttKeyword && strings.EqualFold(tok.text, "GRAPH"):
+		r.nextToken()
+
+		label, err := r.parseGraphLabel()
+		if err != nil {
+			return err
+		}
+
+		return r.openGraph(label)
+
+	case tok.kind == ttIRIRef || tok.kind == ttPrefixedName || tok.kind == ttBlankNodeLabel:
+		label, err := r.parseSubject()
+		if err != nil {
+			return err
+		}
+
+		tok, err = r.peekToken()
+		if err != nil {
+			return err
+		}
+
+		if tok.isPunct("{") {
+			return r.openGraph(label)
+		}
+
+		err = r.parsePredicateObjectList(label)
+		if err != nil {
+			return err
+		}
+
+		return r.expectPunct(".")
+
+	case tok.isPunct("["):
+		node, empty, err := r.parseBlankNodePropertyList()
+		if err != nil {
+			return err
+		}
+
+		tok, err = r.peekToken()
+		if err != nil {
+			return err
+		}
+
+		if empty && tok.isPunct("{") {
+			return r.openGraph(node)
+		}
+
+		// A non-empty blank node property list may stand on its own.
+		if !empty && tok.isPunct(".") {
+			r.nextToken()
+			return nil
+		}
+
+		err = r.parsePredicateObjectList(node)
+		if err != nil {
+			return err
+		}
+
+		return r.expectPunct(".")
+	}
+
+	err = r.parseTriples()
+	if err != nil {
+		return err
+	}
+
+	return r.expectPunct(".")
+}
+
+// parseGraphLabel parses the IRI or blank node naming a graph after the GRAPH keyword.
+func (r *TurtleReader) parseGraphLabel() (label Term, err error) {
+	tok, err := r.peekToken()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tok.kind == ttIRIRef || tok.kind == ttPrefixedName:
+		return r.parseIRI()
+
+	case tok.kind == ttBlankNodeLabel:
+		r.nextToken()
+		return NewBlankNode(tok.text), nil
+
+	case tok.isPunct("["):
+		r.nextToken()
+		return r.newBlankNode(), r.expectPunct("]")
+	}
+
+	return nil, r.unexpected(tok)
+}
+
+// openGraph consumes the '{' starting a graph block. Triples are added to the graph with the given
+// name (nil for the default graph) until the block is closed.
+func (r *TurtleReader) openGraph(label Term) (err error) {
+	err = r.expectPunct("{")
+	if err != nil {
+		return err
+	}
+
+	r.inGraph = true
+	r.graph = label
+	return nil
+}
+
+// parseGraphStatement parses a set of triples inside a graph block, or the '}' closing it. The '.'
+// after the last set of triples in a block is optional.
+func (r *TurtleReader) parseGraphStatement() (err error) {
+	tok, err := r.peekToken()
+	if err != nil {
+		return err
+	}
+
+	if tok.isPunct("}") {
+		r.nextToken()
+		r.inGraph = false
+		r.graph = nil
+		return nil
+	}
+
+	err = r.parseTriples()
+	if err != nil {
+		return err
+	}
+
+	tok, err = r.peekToken()
+	if err != nil {
+		return err
+	}
+
+	if tok.isPunct(".") {
+		r.nextToken()
+		return nil
+	}
+
+	if !tok.isPunct("}") {
+		return r.unexpected(tok)
+	}
+
+	return nil
+}
+
+// Function ParseTriG parses TriG from r and sends parsed triples on tripleChan and errors on
+// errChan. Triples in named graphs have their Graph field set. Prefixes declared by the document
+// are added to prefixes. Both channels are closed when execution is done.
+func ParseTriG(r io.Reader, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(tripleChan)
+	defer close(errChan)
+
+	ttr := NewTriGReader(r)
+	ttr.SetPrefixMap(prefixes)
+
+	for {
+		triple, err := ttr.Read()
+		if err != nil {
+			if err != io.EOF {
+				errChan <- err
+			}
+
+			break
+		}
+
+		tripleChan <- triple
+	}
+}
+
+// Function SerializeTriG serializes triples to the specified Writer in TriG format, recieving
+// triples from tripleChan until closed and sending errors to errChan (closing errChan when done).
+// Triples are written to the graph named by their Graph field, or to the default graph if they have
+// none. Prefixes is a map of namespace URIs to prefix names that is used to produce compact output.
+func SerializeTriG(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(errChan)
+
+	tw := NewTriGWriter(w)
+	tw.SetPrefixMap(prefixes)
+
+	for triple := range tripleChan {
+		tw.Write(triple)
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		errChan <- err
+	}
+}
@@ -0,0 +1,220 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var trigDocument = `@prefix ex: <http://example.org/> .
+
+ex:s ex:p ex:o .
+
+{ ex:s ex:p "default" }
+
+ex:g1 { ex:s ex:p ex:o1 . ex:s ex:q _:b . }
+
+GRAPH ex:g2 {
+    ex:s ex:p ( ex:a ) .
+    _:b ex:p ex:o2
+}
+
+_:g { [ ex:p "anon" ] . }
+`
+
+var trigQuads = `<http://example.org/s> <http://example.org/p> <http://example.org/o> .
+<http://example.org/s> <http://example.org/p> "default" .
+<http://example.org/s> <http://example.org/p> <http://example.org/o1> <http://example.org/g1> .
+<http://example.org/s> <http://example.org/q> _:b <http://example.org/g1> .
+_:l <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/a> <http://example.org/g2> .
+_:l <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> <http://example.org/g2> .
+<http://example.org/s> <http://example.org/p> _:l <http://example.org/g2> .
+_:b <http://example.org/p> <http://example.org/o2> <http://example.org/g2> .
+_:x <http://example.org/p> "anon" _:g .`
+
+var trigNegativeCases = map[string]error{
+	`<http://example.org/g> { <http://example.org/s> <http://example.org/p> <http://example.org/o> .`:   ErrTTUnexpectedEOF,
+	`<http://example.org/g> { <http://example.org/s> <http://example.org/p> <http://example.org/o> ; }`: nil,
+	`{ <http://example.org/s> <http://example.org/p> <http://example.org/o> <http://example.org/x> }`:   ErrTTUnexpectedToken,
+	`GRAPH { <http://example.org/s> <http://example.org/p> <http://example.org/o> }`:                    ErrTTUnexpectedToken,
+	`{ @prefix ex: <http://example.org/> . }`:                                                           ErrTTUnexpectedToken,
+	`<g> { <http://example.org/s> <http://example.org/p> <http://example.org/o> }`:                      ErrTTInvalidIri,
+}
+
+func readAllTriG(doc string) (triples []*Triple, err error) {
+	r := NewTriGReader(strings.NewReader(doc))
+
+	for {
+		triple, err := r.Read()
+		if err == io.EOF {
+			return triples, nil
+		} else if err != nil {
+			return triples, err
+		}
+
+		triples = append(triples, triple)
+	}
+}
+
+func TestTriGRead(t *testing.T) {
+	triples, err := readAllTriG(trigDocument)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	// The list and anonymous nodes are given fixed labels so that the output can be compared.
+	var lines []string
+	for _, triple := range triples {
+		s, o := triple.Subject, triple.Object
+		if node, ok := s.(*BlankNode); ok && node.ID != "b" {
+			if triple.Graph.Equal(NewBlankNode("g")) {
+				s = NewBlankNode("x")
+			} else {
+				s = NewBlankNode("l")
+			}
+		}
+
+		if node, ok := o.(*BlankNode); ok && node.ID != "b" {
+			o = NewBlankNode("l")
+		}
+
+		lines = append(lines, NewQuad(s, triple.Predicate, o, triple.Graph).QuadString())
+	}
+
+	got := strings.Join(lines, "\n")
+	if got != trigQuads {
+		t.Errorf("Expected:\n%s\ngot:\n%s", trigQuads, got)
+	}
+}
+
+func TestTriGReadErrors(t *testing.T) {
+	for doc, expected := range trigNegativeCases {
+		_, err := readAllTriG(doc)
+
+		if expected == nil {
+			if err != nil {
+				t.Errorf("Unexpected error %s for %q", err, doc)
+			}
+
+		} else if err == nil {
+			t.Errorf("Expected %s for %q but no error reported", expected, doc)
+		} else if pe, ok := err.(*TurtleParseError); !ok || pe.Err != expected {
+			t.Errorf("Expected %s for %q but got error %s", expected, doc, err)
+		}
+	}
+}
+
+var trigOutput = `@prefix ex: <http://example.org/> .
+
+ex:s ex:p ex:o .
+
+_:g2 {
+    ex:s ex:q _:shared .
+}
+
+_:shared {
+    _:shared ex:p ex:o .
+}
+
+ex:g1 {
+    ex:s ex:p ex:o1 ;
+        ex:q [
+            ex:p "x" ;
+            ex:q """two
+lines"""
+        ] .
+}
+`
+
+func TestSerializeTriG(t *testing.T) {
+	g1, g2, shared, inline := NewResource("http://example.org/g1"), NewBlankNode("g2"), NewBlankNode("shared"), NewBlankNode("inline")
+	s, p, q := NewResource("http://example.org/s"), NewResource("http://example.org/p"), NewResource("http://example.org/q")
+
+	dataset := NewDataset(func() Store { return NewListStore() })
+	dataset.Prefixes["http://example.org/"] = "ex"
+
+	dataset.AddQuad(shared, p, NewResource("http://example.org/o"), shared)
+	dataset.AddQuad(s, q, shared, g2)
+	dataset.AddQuad(inline, q, NewLiteral("two\nlines"), g1)
+	dataset.AddQuad(inline, p, NewLiteral("x"), g1)
+	dataset.AddQuad(s, q, inline, g1)
+	dataset.AddQuad(s, p, NewResource("http://example.org/o1"), g1)
+	dataset.AddQuad(s, p, NewResource("http://example.org/o"), nil)
+
+	var buf bytes.Buffer
+	err := dataset.Serialize(SerializeTriG, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if buf.String() != trigOutput {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", trigOutput, buf.String())
+	}
+
+	triples, err := readAllTriG(buf.String())
+	if err != nil {
+		t.Fatalf("Unexpected error %s parsing:\n%s", err, buf.String())
+	}
+
+	var expected, got []string
+	for triple := range dataset.IterTriples() {
+		expected = append(expected, triple.QuadString())
+	}
+
+	for _, triple := range triples {
+		if triple.Subject.Equal(s) && triple.Predicate.Equal(q) && triple.Graph.Equal(g1) {
+			triple.Object = inline
+		} else if !triple.Subject.Equal(s) && triple.Graph.Equal(g1) {
+			triple.Subject = inline
+		}
+
+		got = append(got, triple.QuadString())
+	}
+
+	sort.Strings(expected)
+	sort.Strings(got)
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Round trip changed the dataset:\nexpected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestTriGFormat(t *testing.T) {
+	graph := NewGraph(NewListStore())
+
+	err := graph.Parse(Formats["trig"].Parser, strings.NewReader(trigDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	var buf bytes.Buffer
+	err = graph.Serialize(Formats["trig"].Serializer, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if !strings.Contains(buf.String(), "ex:g2 {") {
+		t.Errorf("Expected graph boundaries to be preserved but got:\n%s", buf.String())
+	}
+}
@@ -23,11 +23,14 @@ import (
 	"fmt"
 )
 
-// A Triple contains a subject, a predicate and an object term.
+// A Triple contains a subject, a predicate and an object term. A triple that belongs to a named graph
+// of a dataset (i.e. a quad) also carries the name of that graph; this is nil for triples in the
+// default graph.
 type Triple struct {
 	Subject   Term
 	Predicate Term
 	Object    Term
+	Graph     Term
 }
 
 // Function NewTriple returns a new triple with the given subject, predicate and object.
@@ -39,6 +42,17 @@ func NewTriple(subject Term, predicate Term, object Term) (triple *Triple) {
 	}
 }
 
+// Function NewQuad returns a new triple with the given subject, predicate and object, belonging to
+// the named graph given by graph (or to the default graph if graph is nil).
+func NewQuad(subject Term, predicate Term, object Term, graph Term) (triple *Triple) {
+	return &Triple{
+		Subject:   subject,
+		Predicate: predicate,
+		Object:    object,
+		Graph:     graph,
+	}
+}
+
 // Method String returns the NTriples representation of this triple.
 func (triple Triple) String() (str string) {
 	subj_str := "nil"
@@ -59,9 +73,35 @@ func (triple Triple) String() (str string) {
 	return fmt.Sprintf("%s %s %s .", subj_str, pred_str, obj_str)
 }
 
-// Method Equal returns this triple is equivalent to the argument.
+// Method QuadString returns the NQuads representation of this triple, including the graph name if
+// it has one.
+func (triple Triple) QuadString() (str string) {
+	if triple.Graph == nil {
+		return triple.String()
+	}
+
+	str = triple.String()
+	return fmt.Sprintf("%s %s .", str[:len(str)-2], triple.Graph.String())
+}
+
+// Method Equal returns this triple is equivalent to the argument. The graph names are not compared;
+// use EqualQuad for that.
 func (triple Triple) Equal(other *Triple) bool {
 	return triple.Subject.Equal(other.Subject) &&
 		triple.Predicate.Equal(other.Predicate) &&
 		triple.Object.Equal(other.Object)
 }
+
+// Method EqualQuad returns whether this triple is equivalent to the argument and belongs to the same
+// graph.
+func (triple Triple) EqualQuad(other *Triple) bool {
+	if !triple.Equal(other) {
+		return false
+	}
+
+	if triple.Graph == nil || other.Graph == nil {
+		return triple.Graph == nil && other.Graph == nil
+	}
+
+	return triple.Graph.Equal(other.Graph)
+}
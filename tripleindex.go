@@ -0,0 +1,146 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+// A tripleIndex groups triples by subject, for serializers that write all statements about a
+// subject together. Duplicate triples are dropped.
+type tripleIndex struct {
+	subjects   []Term               // Subjects, in the order they were first seen
+	properties map[string][]*Triple // Triples keyed by the String() of their subject
+	refs       map[string]int       // Number of times each blank node is used as an object
+}
+
+// newTripleIndex creates and returns an empty tripleIndex.
+func newTripleIndex() (idx *tripleIndex) {
+	return &tripleIndex{
+		properties: make(map[string][]*Triple),
+		refs:       make(map[string]int),
+	}
+}
+
+// Method addAll adds all triples received from tripleChan to the index, and returns the index.
+func (idx *tripleIndex) addAll(tripleChan chan *Triple) *tripleIndex {
+	for triple := range tripleChan {
+		idx.add(triple)
+	}
+
+	return idx
+}
+
+// Method add adds a triple to the index, unless it is already present.
+func (idx *tripleIndex) add(triple *Triple) {
+	key := triple.Subject.String()
+	triples, ok := idx.properties[key]
+	if !ok {
+		idx.subjects = append(idx.subjects, triple.Subject)
+	}
+
+	for _, t := range triples {
+		if t.Equal(triple) {
+			return
+		}
+	}
+
+	idx.properties[key] = append(triples, triple)
+
+	if _, ok := triple.Object.(*BlankNode); ok {
+		idx.refs[triple.Object.String()]++
+	}
+}
+
+// Method objects returns the objects of the triples with the given subject and predicate.
+func (idx *tripleIndex) objects(subject Term, predicate Term) (objects []Term) {
+	for _, triple := range idx.properties[subject.String()] {
+		if triple.Predicate.Equal(predicate) {
+			objects = append(objects, triple.Object)
+		}
+	}
+
+	return objects
+}
+
+// Method list returns the items of the list starting at head, and the String()s of the blank nodes
+// making it up, if head is a well-formed list that can be written in abbreviated form: every node is
+// a blank node referenced exactly once, with exactly one rdf:first and one rdf:rest and no other
+// properties. If allowLiterals is false, lists containing literals are rejected.
+func (idx *tripleIndex) list(head Term, allowLiterals bool) (items []Term, nodes []string, ok bool) {
+	visited := make(map[string]bool)
+
+	for node := head; !node.Equal(Nil); {
+		key := node.String()
+		triples := idx.properties[key]
+
+		if _, isBlank := node.(*BlankNode); !isBlank || visited[key] || idx.refs[key] != 1 || len(triples) != 2 {
+			return nil, nil, false
+		}
+
+		visited[key] = true
+		nodes = append(nodes, key)
+
+		first, rest := triples[0], triples[1]
+		if first.Predicate.Equal(Rest) {
+			first, rest = rest, first
+		}
+
+		if !first.Predicate.Equal(First) || !rest.Predicate.Equal(Rest) {
+			return nil, nil, false
+		}
+
+		if _, isLiteral := first.Object.(*Literal); isLiteral && !allowLiterals {
+			return nil, nil, false
+		}
+
+		items = append(items, first.Object)
+		node = rest.Object
+	}
+
+	return items, nodes, true
+}
+
+// termsByKind sorts terms with resources first, then blank nodes, each group ordered by their
+// N-Triples representation.
+type termsByKind []Term
+
+func (t termsByKind) Len() int      { return len(t) }
+func (t termsByKind) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t termsByKind) Less(i, j int) bool {
+	_, iBlank := t[i].(*BlankNode)
+	_, jBlank := t[j].(*BlankNode)
+
+	if iBlank != jBlank {
+		return jBlank
+	}
+
+	return t[i].String() < t[j].String()
+}
+
+// triplesByPredicate sorts triples by predicate and then by object.
+type triplesByPredicate []*Triple
+
+func (t triplesByPredicate) Len() int      { return len(t) }
+func (t triplesByPredicate) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t triplesByPredicate) Less(i, j int) bool {
+	pi, pj := t[i].Predicate.String(), t[j].Predicate.String()
+	if pi != pj {
+		return pi < pj
+	}
+
+	return t[i].Object.String() < t[j].Object.String()
+}
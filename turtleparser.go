@@ -0,0 +1,1437 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kierdavis/argo/iri"
+)
+
+// A TurtleParseError is returned for Turtle parsing errors.
+// The first line is 1.  The first column is 0.
+type TurtleParseError struct {
+	Line   int   // Line where the error occurred
+	Column int   // Column (rune index) where the error occurred
+	Err    error // The actual error
+}
+
+func (e *TurtleParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
+}
+
+// These are the errors that can be returned in TurtleParseError.Err
+var (
+	ErrTTUnexpectedCharacter = errors.New("unexpected character")
+	ErrTTUnexpectedEOF       = errors.New("unexpected end of file")
+	ErrTTUnexpectedToken     = errors.New("unexpected token")
+	ErrTTInvalidEscape       = errors.New("invalid escape sequence")
+	ErrTTUndefinedPrefix     = errors.New("undefined prefix")
+	ErrTTUnterminatedIri     = errors.New("unterminated IRI, expecting '>'")
+	ErrTTInvalidIri          = errors.New("invalid IRI")
+	ErrTTUnterminatedLiteral = errors.New("unterminated literal")
+	ErrTTUnterminatedTriple  = errors.New("unterminated triple, expecting '.'")
+)
+
+// Token kinds produced by the Turtle lexer.
+const (
+	ttEOF = iota
+	ttIRIRef
+	ttPrefixedName
+	ttBlankNodeLabel
+	ttString
+	ttLangTag
+	ttInteger
+	ttDecimal
+	ttDouble
+	ttKeyword
+	ttPunct
+)
+
+// A ttToken is a single lexical token of a Turtle document.
+type ttToken struct {
+	kind   int
+	text   string // IRI, local name, label, string value, tag, number, keyword or punctuation
+	prefix string // Prefix of a prefixed name
+	line   int
+	column int
+}
+
+// A TurtleReader parses Turtle documents into triples. RDF-star quoted triples and annotations
+// (Turtle-star) are supported.
+type TurtleReader struct {
+	r      *bufio.Reader
+	ahead  []rune
+	line   int
+	column int
+	buf    bytes.Buffer
+
+	tok     ttToken
+	haveTok bool
+
+	base       string
+	namespaces map[string]string
+	prefixes   map[string]string
+	pending    []*Triple
+	err        error
+	alloc      BlankNodeAllocator
+
+	trig    bool // Whether graph blocks are allowed (TriG)
+	inGraph bool // Whether a graph block is open
+	graph   Term // Name of the open graph block, or nil for the default graph
+}
+
+// NewTurtleReader returns a new TurtleReader that reads from r.
+func NewTurtleReader(r io.Reader) *TurtleReader {
+	return &TurtleReader{
+		r:          bufio.NewReader(r),
+		line:       1,
+		namespaces: make(map[string]string),
+	}
+}
+
+// SetBase sets the IRI against which relative IRIs are resolved until the document declares its
+// own base with @base.
+func (r *TurtleReader) SetBase(base string) {
+	r.base = base
+}
+
+// SetPrefixMap sets a map (of namespace URIs to prefixes, like Graph.Prefixes) that will be updated
+// with the prefixes declared by the document.
+func (r *TurtleReader) SetPrefixMap(prefixes map[string]string) {
+	r.prefixes = prefixes
+}
+
+// SetAllocator sets the allocator used for anonymous blank nodes ('[]' and collections). By default,
+// DefaultAllocator is used. Labelled blank nodes keep the labels given in the document.
+func (r *TurtleReader) SetAllocator(alloc BlankNodeAllocator) {
+	r.alloc = alloc
+}
+
+// newBlankNode returns a new anonymous blank node.
+func (r *TurtleReader) newBlankNode() Term {
+	if r.alloc != nil {
+		return r.alloc.NewBlankNode()
+	}
+
+	return DefaultAllocator.NewBlankNode()
+}
+
+// errorAt creates a new TurtleParseError based on err at the given position.
+func (r *TurtleReader) errorAt(line int, column int, err error) error {
+	return &TurtleParseError{
+		Line:   line,
+		Column: column,
+		Err:    err,
+	}
+}
+
+// error creates a new TurtleParseError based on err at the current position.
+func (r *TurtleReader) error(err error) error {
+	return r.errorAt(r.line, r.column, err)
+}
+
+// Read reads the next triple. It returns io.EOF when the end of the document has been reached.
+func (r *TurtleReader) Read() (t *Triple, err error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		err = r.parseStatement()
+		if err != nil {
+			r.err = err
+		}
+	}
+
+	t = r.pending[0]
+	r.pending = r.pending[1:]
+	return t, nil
+}
+
+// emit queues a triple to be returned by Read.
+func (r *TurtleReader) emit(subject Term, predicate Term, object Term) {
+	r.pending = append(r.pending, NewQuad(subject, predicate, object, r.graph))
+}
+
+// ---- Character level ----------------------------------------------------------------------------
+
+// peekRune returns the rune i positions ahead of the current position without consuming it.
+func (r *TurtleReader) peekRune(i int) (rune, error) {
+	for len(r.ahead) <= i {
+		r1, _, err := r.r.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+
+		r.ahead = append(r.ahead, r1)
+	}
+
+	return r.ahead[i], nil
+}
+
+// readRune consumes one rune, keeping track of the line and column.
+func (r *TurtleReader) readRune() (r1 rune, err error) {
+	r1, err = r.peekRune(0)
+	if err != nil {
+		return 0, err
+	}
+
+	r.ahead = r.ahead[1:]
+
+	if r1 == '\n' {
+		r.line++
+		r.column = 0
+	} else {
+		r.column++
+	}
+
+	return r1, nil
+}
+
+// mustReadRune consumes one rune, converting io.EOF into a parse error.
+func (r *TurtleReader) mustReadRune() (r1 rune, err error) {
+	r1, err = r.readRune()
+	if err == io.EOF {
+		return 0, r.error(ErrTTUnexpectedEOF)
+	}
+
+	return r1, err
+}
+
+// skipWhitespace skips whitespace and comments.
+func (r *TurtleReader) skipWhitespace() (err error) {
+	for {
+		r1, err := r.peekRune(0)
+		if err != nil {
+			return err
+		}
+
+		switch r1 {
+		case ' ', '\t', '\r', '\n':
+			r.readRune()
+
+		case '#':
+			for r1 != '\n' {
+				r1, err = r.readRune()
+				if err != nil {
+					return err
+				}
+			}
+
+		default:
+			return nil
+		}
+	}
+}
+
+func isPNCharsBase(r1 rune) bool {
+	return (r1 >= 'A' && r1 <= 'Z') || (r1 >= 'a' && r1 <= 'z') ||
+		(r1 >= 0x00C0 && r1 <= 0x00D6) || (r1 >= 0x00D8 && r1 <= 0x00F6) ||
+		(r1 >= 0x00F8 && r1 <= 0x02FF) || (r1 >= 0x0370 && r1 <= 0x037D) ||
+		(r1 >= 0x037F && r1 <= 0x1FFF) || (r1 >= 0x200C && r1 <= 0x200D) ||
+		(r1 >= 0x2070 && r1 <= 0x218F) || (r1 >= 0x2C00 && r1 <= 0x2FEF) ||
+		(r1 >= 0x3001 && r1 <= 0xD7FF) || (r1 >= 0xF900 && r1 <= 0xFDCF) ||
+		(r1 >= 0xFDF0 && r1 <= 0xFFFD) || (r1 >= 0x10000 && r1 <= 0xEFFFF)
+}
+
+func isPNCharsU(r1 rune) bool {
+	return isPNCharsBase(r1) || r1 == '_'
+}
+
+func isPNChars(r1 rune) bool {
+	return isPNCharsU(r1) || r1 == '-' || (r1 >= '0' && r1 <= '9') || r1 == 0x00B7 ||
+		(r1 >= 0x0300 && r1 <= 0x036F) || (r1 >= 0x203F && r1 <= 0x2040)
+}
+
+func isDigit(r1 rune) bool {
+	return r1 >= '0' && r1 <= '9'
+}
+
+func isHexDigit(r1 rune) bool {
+	return isDigit(r1) || (r1 >= 'a' && r1 <= 'f') || (r1 >= 'A' && r1 <= 'F')
+}
+
+func hexValue(r1 rune) rune {
+	switch {
+	case r1 >= '0' && r1 <= '9':
+		return r1 - '0'
+	case r1 >= 'a' && r1 <= 'f':
+		return r1 - 'a' + 10
+	}
+
+	return r1 - 'A' + 10
+}
+
+// readUChar reads the hexadecimal digits of a \u or \U escape (the backslash and letter having
+// already been consumed) and returns the code point.
+func (r *TurtleReader) readUChar(n int) (codepoint rune, err error) {
+	for i := 0; i < n; i++ {
+		r1, err := r.mustReadRune()
+		if err != nil {
+			return 0, err
+		}
+
+		if !isHexDigit(r1) {
+			return 0, r.error(ErrTTInvalidEscape)
+		}
+
+		codepoint = codepoint<<4 | hexValue(r1)
+	}
+
+	if codepoint > 0x10FFFF || (codepoint >= 0xD800 && codepoint <= 0xDFFF) {
+		return 0, r.error(ErrTTInvalidEscape)
+	}
+
+	return codepoint, nil
+}
+
+// ---- Token level --------------------------------------------------------------------------------
+
+// peekToken returns the next token without consuming it.
+func (r *TurtleReader) peekToken() (tok ttToken, err error) {
+	if !r.haveTok {
+		r.tok, err = r.lex()
+		if err != nil {
+			return tok, err
+		}
+
+		r.haveTok = true
+	}
+
+	return r.tok, nil
+}
+
+// nextToken consumes and returns the next token.
+func (r *TurtleReader) nextToken() (tok ttToken, err error) {
+	tok, err = r.peekToken()
+	r.haveTok = false
+	return tok, err
+}
+
+// isPunct returns whether the token is the given punctuation.
+func (tok ttToken) isPunct(p string) bool {
+	return tok.kind == ttPunct && tok.text == p
+}
+
+// unexpected returns an error describing an unexpected token.
+func (r *TurtleReader) unexpected(tok ttToken) error {
+	if tok.kind == ttEOF {
+		return r.errorAt(tok.line, tok.column, ErrTTUnexpectedEOF)
+	}
+
+	return r.errorAt(tok.line, tok.column, ErrTTUnexpectedToken)
+}
+
+// expectPunct consumes the next token, which must be the given punctuation.
+func (r *TurtleReader) expectPunct(p string) (err error) {
+	tok, err := r.nextToken()
+	if err != nil {
+		return err
+	}
+
+	if !tok.isPunct(p) {
+		if p == "." && tok.kind == ttEOF {
+			return r.errorAt(tok.line, tok.column, ErrTTUnterminatedTriple)
+		}
+
+		return r.unexpected(tok)
+	}
+
+	return nil
+}
+
+// lex reads the next token from the input.
+func (r *TurtleReader) lex() (tok ttToken, err error) {
+	err = r.skipWhitespace()
+	tok.line, tok.column = r.line, r.column
+
+	if err == io.EOF {
+		tok.kind = ttEOF
+		return tok, nil
+	} else if err != nil {
+		return tok, err
+	}
+
+	r1, _ := r.peekRune(0)
+	r.buf.Reset()
+
+	r2, _ := r.peekRune(1)
+
+	switch {
+	case (r1 == '<' && r2 == '<') || (r1 == '>' && r2 == '>') || (r1 == '{' && r2 == '|') || (r1 == '|' && r2 == '}'):
+		r.readRune()
+		r.readRune()
+		tok.kind, tok.text = ttPunct, string([]rune{r1, r2})
+
+	case r1 == '<':
+		r.readRune()
+		tok.kind = ttIRIRef
+		tok.text, err = r.lexIRIRef()
+
+	case r1 == '"' || r1 == '\'':
+		tok.kind = ttString
+		tok.text, err = r.lexString()
+
+	case r1 == '@':
+		r.readRune()
+		tok.kind = ttLangTag
+		tok.text, err = r.lexLangTag()
+
+	case r1 == '_':
+		r.readRune()
+		r2, err := r.mustReadRune()
+		if err != nil {
+			return tok, err
+		}
+
+		if r2 != ':' {
+			return tok, r.error(ErrTTUnexpectedCharacter)
+		}
+
+		tok.kind = ttBlankNodeLabel
+		tok.text, err = r.lexBlankNodeLabel()
+
+	case isDigit(r1) || r1 == '+' || r1 == '-':
+		tok.kind, tok.text, err = r.lexNumber()
+
+	case r1 == '.':
+		if isDigit(r2) {
+			tok.kind, tok.text, err = r.lexNumber()
+		} else {
+			r.readRune()
+			tok.kind, tok.text = ttPunct, "."
+		}
+
+	case r1 == '^':
+		r.readRune()
+		r2, err := r.mustReadRune()
+		if err != nil {
+			return tok, err
+		}
+
+		if r2 != '^' {
+			return tok, r.error(ErrTTUnexpectedCharacter)
+		}
+
+		tok.kind, tok.text = ttPunct, "^^"
+
+	case strings.ContainsRune(";,[](){}", r1):
+		r.readRune()
+		tok.kind, tok.text = ttPunct, string(r1)
+
+	case r1 == ':' || isPNCharsBase(r1):
+		err = r.lexName(&tok)
+
+	default:
+		r.readRune()
+		return tok, r.error(ErrTTUnexpectedCharacter)
+	}
+
+	return tok, err
+}
+
+// lexIRIRef reads an IRI reference (the opening '<' having already been consumed).
+func (r *TurtleReader) lexIRIRef() (iri string, err error) {
+	for {
+		r1, err := r.readRune()
+		if err == io.EOF {
+			return "", r.error(ErrTTUnterminatedIri)
+		} else if err != nil {
+			return "", err
+		}
+
+		switch {
+		case r1 == '>':
+			return r.buf.String(), nil
+
+		case r1 == '\\':
+			r2, err := r.mustReadRune()
+			if err != nil {
+				return "", err
+			}
+
+			var codepoint rune
+
+			switch r2 {
+			case 'u':
+				codepoint, err = r.readUChar(4)
+			case 'U':
+				codepoint, err = r.readUChar(8)
+			default:
+				return "", r.error(ErrTTInvalidEscape)
+			}
+
+			if err != nil {
+				return "", err
+			}
+
+			if codepoint <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", codepoint) {
+				return "", r.error(ErrTTUnexpectedCharacter)
+			}
+
+			r.buf.WriteRune(codepoint)
+
+		case r1 <= 0x20 || strings.ContainsRune("<\"{}|^`", r1):
+			return "", r.error(ErrTTUnexpectedCharacter)
+
+		default:
+			r.buf.WriteRune(r1)
+		}
+	}
+}
+
+// lexString reads a quoted string in any of its four forms and returns the unescaped value.
+func (r *TurtleReader) lexString() (value string, err error) {
+	quote, _ := r.readRune()
+	long := false
+
+	r1, err1 := r.peekRune(0)
+	r2, err2 := r.peekRune(1)
+
+	if err1 == nil && r1 == quote {
+		if err2 == nil && r2 == quote {
+			r.readRune()
+			r.readRune()
+			long = true
+		} else {
+			r.readRune()
+			return "", nil
+		}
+	}
+
+	for {
+		r1, err = r.readRune()
+		if err == io.EOF {
+			return "", r.error(ErrTTUnterminatedLiteral)
+		} else if err != nil {
+			return "", err
+		}
+
+		switch {
+		case r1 == quote:
+			if !long {
+				return r.buf.String(), nil
+			}
+
+			r2, err2 := r.peekRune(0)
+			r3, err3 := r.peekRune(1)
+			if err2 == nil && err3 == nil && r2 == quote && r3 == quote {
+				r.readRune()
+				r.readRune()
+				return r.buf.String(), nil
+			}
+
+			r.buf.WriteRune(r1)
+
+		case r1 == '\\':
+			r1, err = r.readEscape()
+			if err != nil {
+				return "", err
+			}
+
+			r.buf.WriteRune(r1)
+
+		case !long && (r1 == '\n' || r1 == '\r'):
+			return "", r.error(ErrTTUnterminatedLiteral)
+
+		default:
+			r.buf.WriteRune(r1)
+		}
+	}
+}
+
+// readEscape reads a string escape sequence (the backslash having already been consumed).
+func (r *TurtleReader) readEscape() (r1 rune, err error) {
+	r1, err = r.mustReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	switch r1 {
+	case 't':
+		return '\t', nil
+	case 'b':
+		return '\b', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 'f':
+		return '\f', nil
+	case '"', '\'', '\\':
+		return r1, nil
+	case 'u':
+		return r.readUChar(4)
+	case 'U':
+		return r.readUChar(8)
+	}
+
+	return 0, r.error(ErrTTInvalidEscape)
+}
+
+// lexLangTag reads a language tag (the '@' having already been consumed). Directive names such as
+// "prefix" and "base" are also lexed as language tags and disambiguated by the parser.
+func (r *TurtleReader) lexLangTag() (tag string, err error) {
+	r1, err := r.peekRune(0)
+	if err != nil || !((r1 >= 'a' && r1 <= 'z') || (r1 >= 'A' && r1 <= 'Z')) {
+		return "", r.error(ErrTTUnexpectedCharacter)
+	}
+
+	for {
+		r1, err = r.peekRune(0)
+		if err != nil {
+			break
+		}
+
+		if (r1 >= 'a' && r1 <= 'z') || (r1 >= 'A' && r1 <= 'Z') {
+			r.readRune()
+			r.buf.WriteRune(r1)
+
+		} else if r1 == '-' {
+			r2, err := r.peekRune(1)
+			if err != nil || !((r2 >= 'a' && r2 <= 'z') || (r2 >= 'A' && r2 <= 'Z') || isDigit(r2)) {
+				return "", r.error(ErrTTUnexpectedCharacter)
+			}
+
+			r.readRune()
+			r.readRune()
+			r.buf.WriteRune(r1)
+			r.buf.WriteRune(r2)
+
+		} else if isDigit(r1) && strings.Contains(r.buf.String(), "-") {
+			r.readRune()
+			r.buf.WriteRune(r1)
+
+		} else {
+			break
+		}
+	}
+
+	return r.buf.String(), nil
+}
+
+// lexBlankNodeLabel reads a blank node label (the "_:" having already been consumed).
+func (r *TurtleReader) lexBlankNodeLabel() (label string, err error) {
+	r1, err := r.mustReadRune()
+	if err != nil {
+		return "", err
+	}
+
+	if !isPNCharsU(r1) && !isDigit(r1) {
+		return "", r.error(ErrTTUnexpectedCharacter)
+	}
+
+	r.buf.WriteRune(r1)
+
+	for {
+		r1, err = r.peekRune(0)
+		if err != nil {
+			break
+		}
+
+		if isPNChars(r1) {
+			r.readRune()
+			r.buf.WriteRune(r1)
+
+		} else if r1 == '.' {
+			// A label may contain but not end with dots.
+			n := 1
+			r2, err := r.peekRune(n)
+			for err == nil && r2 == '.' {
+				n++
+				r2, err = r.peekRune(n)
+			}
+
+			if err != nil || !isPNChars(r2) {
+				break
+			}
+
+			for ; n > 0; n-- {
+				r.readRune()
+				r.buf.WriteRune('.')
+			}
+
+		} else {
+			break
+		}
+	}
+
+	return r.buf.String(), nil
+}
+
+// lexNumber reads an integer, decimal or double literal.
+func (r *TurtleReader) lexNumber() (kind int, text string, err error) {
+	kind = ttInteger
+
+	r1, _ := r.peekRune(0)
+	if r1 == '+' || r1 == '-' {
+		r.readRune()
+		r.buf.WriteRune(r1)
+	}
+
+	digits := 0
+	for {
+		r1, err = r.peekRune(0)
+		if err != nil || !isDigit(r1) {
+			break
+		}
+
+		r.readRune()
+		r.buf.WriteRune(r1)
+		digits++
+	}
+
+	r1, err = r.peekRune(0)
+	if err == nil && r1 == '.' {
+		r2, err2 := r.peekRune(1)
+		if err2 == nil && isDigit(r2) {
+			kind = ttDecimal
+			r.readRune()
+			r.buf.WriteRune('.')
+
+			for {
+				r1, err = r.peekRune(0)
+				if err != nil || !isDigit(r1) {
+					break
+				}
+
+				r.readRune()
+				r.buf.WriteRune(r1)
+				digits++
+			}
+
+		} else if err2 == nil && (r2 == 'e' || r2 == 'E') && digits > 0 && r.isExponentAt(1) {
+			r.readRune()
+			r.buf.WriteRune('.')
+		}
+	}
+
+	if digits == 0 {
+		return kind, "", r.error(ErrTTUnexpectedCharacter)
+	}
+
+	if r.isExponentAt(0) {
+		kind = ttDouble
+		r1, _ = r.readRune()
+		r.buf.WriteRune(r1)
+
+		r1, _ = r.peekRune(0)
+		if r1 == '+' || r1 == '-' {
+			r.readRune()
+			r.buf.WriteRune(r1)
+		}
+
+		for {
+			r1, err = r.peekRune(0)
+			if err != nil || !isDigit(r1) {
+				break
+			}
+
+			r.readRune()
+			r.buf.WriteRune(r1)
+		}
+	}
+
+	return kind, r.buf.String(), nil
+}
+
+// isExponentAt returns whether an exponent ('e', optional sign, digits) begins i runes ahead.
+func (r *TurtleReader) isExponentAt(i int) bool {
+	r1, err := r.peekRune(i)
+	if err != nil || (r1 != 'e' && r1 != 'E') {
+		return false
+	}
+
+	r1, err = r.peekRune(i + 1)
+	if err == nil && (r1 == '+' || r1 == '-') {
+		r1, err = r.peekRune(i + 2)
+	}
+
+	return err == nil && isDigit(r1)
+}
+
+// lexName reads a prefixed name or a bare keyword.
+func (r *TurtleReader) lexName(tok *ttToken) (err error) {
+	r1, _ := r.peekRune(0)
+
+	if r1 != ':' {
+		r.readRune()
+		r.buf.WriteRune(r1)
+
+		for {
+			r1, err = r.peekRune(0)
+			if err != nil {
+				break
+			}
+
+			if isPNChars(r1) {
+				r.readRune()
+				r.buf.WriteRune(r1)
+
+			} else if r1 == '.' {
+				r2, err := r.peekRune(1)
+				if err != nil || !(isPNChars(r2) || r2 == '.') {
+					break
+				}
+
+				r.readRune()
+				r.buf.WriteRune(r1)
+
+			} else {
+				break
+			}
+		}
+
+		r1, err = r.peekRune(0)
+		if err != nil || r1 != ':' {
+			// A bare word, such as 'a', 'true' or 'PREFIX'.
+			tok.kind = ttKeyword
+			tok.text = r.buf.String()
+
+			if strings.HasSuffix(tok.text, ".") {
+				return r.error(ErrTTUnexpectedCharacter)
+			}
+
+			return nil
+		}
+	}
+
+	r.readRune()
+	tok.kind = ttPrefixedName
+	tok.prefix = r.buf.String()
+	r.buf.Reset()
+
+	if strings.HasSuffix(tok.prefix, ".") {
+		return r.error(ErrTTUnexpectedCharacter)
+	}
+
+	first := true
+
+	for {
+		r1, err = r.peekRune(0)
+		if err != nil {
+			break
+		}
+
+		if r1 == '%' {
+			r.readRune()
+			h1, err := r.mustReadRune()
+			if err != nil {
+				return err
+			}
+
+			h2, err := r.mustReadRune()
+			if err != nil {
+				return err
+			}
+
+			if !isHexDigit(h1) || !isHexDigit(h2) {
+				return r.error(ErrTTInvalidEscape)
+			}
+
+			r.buf.WriteRune('%')
+			r.buf.WriteRune(h1)
+			r.buf.WriteRune(h2)
+
+		} else if r1 == '\\' {
+			r.readRune()
+			r2, err := r.mustReadRune()
+			if err != nil {
+				return err
+			}
+
+			if !strings.ContainsRune("_~.-!$&'()*+,;=/?#@%", r2) {
+				return r.error(ErrTTInvalidEscape)
+			}
+
+			r.buf.WriteRune(r2)
+
+		} else if r1 == ':' || isPNChars(r1) || (first && isDigit(r1)) {
+			if first && (r1 == '-' || r1 == 0x00B7 || (r1 >= 0x0300 && r1 <= 0x036F) || (r1 >= 0x203F && r1 <= 0x2040)) {
+				break
+			}
+
+			r.readRune()
+			r.buf.WriteRune(r1)
+
+		} else if r1 == '.' && !first {
+			// A local name may contain but not end with dots.
+			n := 1
+			r2, err := r.peekRune(n)
+			for err == nil && r2 == '.' {
+				n++
+				r2, err = r.peekRune(n)
+			}
+
+			if err != nil || !(isPNChars(r2) || r2 == ':' || r2 == '%' || r2 == '\\') {
+				break
+			}
+
+			for ; n > 0; n-- {
+				r.readRune()
+				r.buf.WriteRune('.')
+			}
+
+		} else {
+			break
+		}
+
+		first = false
+	}
+
+	tok.text = r.buf.String()
+	return nil
+}
+
+// ---- Grammar level ------------------------------------------------------------------------------
+
+// parseStatement parses a directive or a set of triples terminated by '.'.
+func (r *TurtleReader) parseStatement() (err error) {
+	tok, err := r.peekToken()
+	if err != nil {
+		return err
+	}
+
+	if r.inGraph {
+		return r.parseGraphStatement()
+	}
+
+	switch {
+	case tok.kind == ttEOF:
+		return io.EOF
+
+	case tok.kind == ttLangTag && (tok.text == "prefix" || tok.text == "base"):
+		r.nextToken()
+		err = r.parseDirective(tok.text)
+		if err != nil {
+			return err
+		}
+
+		return r.expectPunct(".")
+
+	case tok.kind == ttKeyword && (strings.EqualFold(tok.text, "PREFIX") || strings.EqualFold(tok.text, "BASE")):
+		r.nextToken()
+		return r.parseDirective(strings.ToLower(tok.text))
+	}
+
+	if r.trig {
+		return r.parseBlock()
+	}
+
+	err = r.parseTriples()
+	if err != nil {
+		return err
+	}
+
+	return r.expectPunct(".")
+}
+
+// parseDirective parses the body of a prefix or base directive.
+func (r *TurtleReader) parseDirective(directive string) (err error) {
+	var prefix string
+
+	if directive == "prefix" {
+		tok, err := r.nextToken()
+		if err != nil {
+			return err
+		}
+
+		if tok.kind != ttPrefixedName || tok.text != "" {
+			return r.unexpected(tok)
+		}
+
+		prefix = tok.prefix
+	}
+
+	tok, err := r.nextToken()
+	if err != nil {
+		return err
+	}
+
+	if tok.kind != ttIRIRef {
+		return r.unexpected(tok)
+	}
+
+	uri, err := r.resolve(tok, tok.text)
+	if err != nil {
+		return err
+	}
+
+	if directive == "base" {
+		r.base = uri
+		return nil
+	}
+
+	r.namespaces[prefix] = uri
+	if r.prefixes != nil && prefix != "" {
+		r.prefixes[uri] = prefix
+	}
+
+	return nil
+}
+
+// parseTriples parses a subject followed by a predicate-object list.
+func (r *TurtleReader) parseTriples() (err error) {
+	tok, err := r.peekToken()
+	if err != nil {
+		return err
+	}
+
+	if tok.isPunct("[") {
+		subject, empty, err := r.parseBlankNodePropertyList()
+		if err != nil {
+			return err
+		}
+
+		tok, err = r.peekToken()
+		if err != nil {
+			return err
+		}
+
+		// A non-empty blank node property list may stand on its own.
+		if !empty && tok.isPunct(".") {
+			return nil
+		}
+
+		return r.parsePredicateObjectList(subject)
+	}
+
+	subject, err := r.parseSubject()
+	if err != nil {
+		return err
+	}
+
+	return r.parsePredicateObjectList(subject)
+}
+
+// parseSubject parses an IRI, blank node or collection in subject position.
+func (r *TurtleReader) parseSubject() (subject Term, err error) {
+	tok, err := r.peekToken()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tok.kind == ttIRIRef || tok.kind == ttPrefixedName:
+		return r.parseIRI()
+
+	case tok.kind == ttBlankNodeLabel:
+		r.nextToken()
+		return NewBlankNode(tok.text), nil
+
+	case tok.isPunct("("):
+		return r.parseCollection()
+
+	case tok.isPunct("<<"):
+		return r.parseQuotedTriple()
+	}
+
+	return nil, r.unexpected(tok)
+}
+
+// parsePredicateObjectList parses verb-objectList pairs separated by semicolons.
+func (r *TurtleReader) parsePredicateObjectList(subject Term) (err error) {
+	for {
+		predicate, err := r.parseVerb()
+		if err != nil {
+			return err
+		}
+
+		err = r.parseObjectList(subject, predicate)
+		if err != nil {
+			return err
+		}
+
+		tok, err := r.peekToken()
+		if err != nil {
+			return err
+		}
+
+		if !tok.isPunct(";") {
+			return nil
+		}
+
+		// Any number of semicolons may follow, optionally with a trailing verb-objectList.
+		for tok.isPunct(";") {
+			r.nextToken()
+
+			tok, err = r.peekToken()
+			if err != nil {
+				return err
+			}
+		}
+
+		if tok.isPunct(".") || tok.isPunct("]") || tok.isPunct("}") || tok.isPunct("|}") || tok.kind == ttEOF {
+			return nil
+		}
+	}
+}
+
+// parseVerb parses a predicate IRI or the keyword 'a'.
+func (r *TurtleReader) parseVerb() (predicate Term, err error) {
+	tok, err := r.peekToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok.kind == ttKeyword && tok.text == "a" {
+		r.nextToken()
+		return A, nil
+	}
+
+	if tok.kind != ttIRIRef && tok.kind != ttPrefixedName {
+		return nil, r.unexpected(tok)
+	}
+
+	return r.parseIRI()
+}
+
+// parseObjectList parses one or more objects separated by commas.
+func (r *TurtleReader) parseObjectList(subject Term, predicate Term) (err error) {
+	for {
+		object, err := r.parseObject()
+		if err != nil {
+			return err
+		}
+
+		r.emit(subject, predicate, object)
+
+		tok, err := r.peekToken()
+		if err != nil {
+			return err
+		}
+
+		// An annotation makes statements about the triple just asserted.
+		if tok.isPunct("{|") {
+			r.nextToken()
+
+			err = r.parsePredicateObjectList(NewQuotedTriple(subject, predicate, object))
+			if err != nil {
+				return err
+			}
+
+			err = r.expectPunct("|}")
+			if err != nil {
+				return err
+			}
+
+			tok, err = r.peekToken()
+			if err != nil {
+				return err
+			}
+		}
+
+		if !tok.isPunct(",") {
+			return nil
+		}
+
+		r.nextToken()
+	}
+}
+
+// parseObject parses a term in object position.
+func (r *TurtleReader) parseObject() (object Term, err error) {
+	tok, err := r.peekToken()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tok.kind == ttIRIRef || tok.kind == ttPrefixedName:
+		return r.parseIRI()
+
+	case tok.kind == ttBlankNodeLabel:
+		r.nextToken()
+		return NewBlankNode(tok.text), nil
+
+	case tok.isPunct("["):
+		object, _, err = r.parseBlankNodePropertyList()
+		return object, err
+
+	case tok.isPunct("("):
+		return r.parseCollection()
+
+	case tok.isPunct("<<"):
+		return r.parseQuotedTriple()
+	}
+
+	return r.parseLiteral()
+}
+
+// parseQuotedTriple parses an RDF-star quoted triple, '<<' subject verb object '>>'. The subject
+// and object may only be IRIs, blank nodes (including '[]'), quoted triples or, for the object,
+// literals.
+func (r *TurtleReader) parseQuotedTriple() (term Term, err error) {
+	err = r.expectPunct("<<")
+	if err != nil {
+		return nil, err
+	}
+
+	var terms [3]Term
+
+	for i := range terms {
+		tok, err := r.peekToken()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case i == 1:
+			terms[i], err = r.parseVerb()
+
+		case tok.isPunct("["):
+			r.nextToken()
+			terms[i], err = r.newBlankNode(), r.expectPunct("]")
+
+		case tok.isPunct("("):
+			err = r.unexpected(tok)
+
+		case i == 0:
+			terms[i], err = r.parseSubject()
+
+		default:
+			terms[i], err = r.parseObject()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = r.expectPunct(">>")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewQuotedTriple(terms[0], terms[1], terms[2]), nil
+}
+
+// parseLiteral parses a string, numeric or boolean literal.
+func (r *TurtleReader) parseLiteral() (literal Term, err error) {
+	tok, err := r.nextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.kind {
+	case ttInteger:
+		return NewLiteralWithDatatype(tok.text, XSD.Get("integer")), nil
+
+	case ttDecimal:
+		return NewLiteralWithDatatype(tok.text, XSD.Get("decimal")), nil
+
+	case ttDouble:
+		return NewLiteralWithDatatype(tok.text, XSD.Get("double")), nil
+
+	case ttKeyword:
+		if tok.text == "true" || tok.text == "false" {
+			return NewLiteralWithDatatype(tok.text, XSD.Get("boolean")), nil
+		}
+
+	case ttString:
+		next, err := r.peekToken()
+		if err != nil {
+			return nil, err
+		}
+
+		if next.kind == ttLangTag {
+			r.nextToken()
+			return NewLiteralWithLanguage(tok.text, next.text), nil
+		}
+
+		if next.isPunct("^^") {
+			r.nextToken()
+
+			next, err = r.peekToken()
+			if err != nil {
+				return nil, err
+			}
+
+			if next.kind != ttIRIRef && next.kind != ttPrefixedName {
+				return nil, r.unexpected(next)
+			}
+
+			datatype, err := r.parseIRI()
+			if err != nil {
+				return nil, err
+			}
+
+			return NewLiteralWithDatatype(tok.text, datatype), nil
+		}
+
+		return NewLiteral(tok.text), nil
+	}
+
+	return nil, r.unexpected(tok)
+}
+
+// parseIRI parses an IRI reference or prefixed name and returns the resource it denotes.
+func (r *TurtleReader) parseIRI() (term Term, err error) {
+	tok, err := r.nextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.kind {
+	case ttIRIRef:
+		uri, err := r.resolve(tok, tok.text)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewResource(uri), nil
+
+	case ttPrefixedName:
+		ns, ok := r.namespaces[tok.prefix]
+		if !ok {
+			return nil, r.errorAt(tok.line, tok.column, ErrTTUndefinedPrefix)
+		}
+
+		_, err = iri.ParseAbsolute(ns + tok.text)
+		if err != nil {
+			return nil, r.errorAt(tok.line, tok.column, ErrTTInvalidIri)
+		}
+
+		return NewResource(ns + tok.text), nil
+	}
+
+	return nil, r.unexpected(tok)
+}
+
+// parseBlankNodePropertyList parses '[' predicateObjectList? ']' and returns the blank node it
+// denotes, and whether the list was empty.
+func (r *TurtleReader) parseBlankNodePropertyList() (node Term, empty bool, err error) {
+	err = r.expectPunct("[")
+	if err != nil {
+		return nil, false, err
+	}
+
+	node = r.newBlankNode()
+
+	tok, err := r.peekToken()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if tok.isPunct("]") {
+		r.nextToken()
+		return node, true, nil
+	}
+
+	err = r.parsePredicateObjectList(node)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return node, false, r.expectPunct("]")
+}
+
+// parseCollection parses '(' object* ')' and returns the head of the RDF list it denotes.
+func (r *TurtleReader) parseCollection() (head Term, err error) {
+	err = r.expectPunct("(")
+	if err != nil {
+		return nil, err
+	}
+
+	head = Nil
+	var last Term
+
+	for {
+		tok, err := r.peekToken()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok.isPunct(")") {
+			r.nextToken()
+			break
+		}
+
+		// The list node must be allocated before the item is parsed, so that the triples of any
+		// nested structure follow the list structure that refers to them.
+		node := r.newBlankNode()
+		if last == nil {
+			head = node
+		} else {
+			r.emit(last, Rest, node)
+		}
+
+		object, err := r.parseObject()
+		if err != nil {
+			return nil, err
+		}
+
+		r.emit(node, First, object)
+		last = node
+	}
+
+	if last != nil {
+		r.emit(last, Rest, Nil)
+	}
+
+	return head, nil
+}
+
+// resolve resolves an IRI reference against the current base IRI, reporting invalid IRIs at the
+// position of tok.
+func (r *TurtleReader) resolve(tok ttToken, ref string) (s string, err error) {
+	s, err = iri.Resolve(r.base, ref)
+	if err != nil {
+		return "", r.errorAt(tok.line, tok.column, ErrTTInvalidIri)
+	}
+
+	// Without a base, a relative reference stays relative, which is not a valid RDF term.
+	_, err = iri.ParseAbsolute(s)
+	if err != nil {
+		return "", r.errorAt(tok.line, tok.column, ErrTTInvalidIri)
+	}
+
+	return s, nil
+}
+
+// Function ParseTurtle parses Turtle from r and sends parsed triples on tripleChan and errors on
+// errChan. Prefixes declared by the document are added to prefixes. Both channels are closed when
+// execution is done.
+func ParseTurtle(r io.Reader, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(tripleChan)
+	defer close(errChan)
+
+	ttr := NewTurtleReader(r)
+	ttr.SetPrefixMap(prefixes)
+
+	for {
+		triple, err := ttr.Read()
+		if err != nil {
+			if err != io.EOF {
+				errChan <- err
+			}
+
+			break
+		}
+
+		tripleChan <- triple
+	}
+}
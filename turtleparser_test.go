@@ -0,0 +1,228 @@
+/*
+   Copyright (c) 2012 Kier Davis
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+   associated documentation files (the "Software"), to deal in the Software without restriction,
+   including without limitation the rights to use, copy, modify, merge, publish, distribute,
+   sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all copies or substantial
+   portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+   NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+   NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+   OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+   CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Each Turtle document maps to the N-Triples it should produce. Blank nodes in the expected output
+// are numbered in order of first appearance.
+var turtleTestCases = map[string]string{
+	`<http://example.org/s> <http://example.org/p> <http://example.org/o> .`: `<http://example.org/s> <http://example.org/p> <http://example.org/o> .`,
+
+	`@prefix ex: <http://example.org/> .
+ex:s ex:p ex:o .`: `<http://example.org/s> <http://example.org/p> <http://example.org/o> .`,
+
+	`PREFIX ex: <http://example.org/>
+prefix : <http://example.org/default#>
+ex:s :p :o .`: `<http://example.org/s> <http://example.org/default#p> <http://example.org/default#o> .`,
+
+	`@base <http://example.org/dir/doc> .
+<s> <#p> <../o> .
+BASE <http://other.org/>
+<s> <p> <o?q> .`: `<http://example.org/dir/s> <http://example.org/dir/doc#p> <http://example.org/o> .
+<http://other.org/s> <http://other.org/p> <http://other.org/o?q> .`,
+
+	`@prefix ex: <http://example.org/> .
+ex:s a ex:C ;
+     ex:p ex:o1 , ex:o2 ;
+     ex:q "x" ;
+     .`: `<http://example.org/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/C> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o1> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o2> .
+<http://example.org/s> <http://example.org/q> "x" .`,
+
+	`@prefix ex: <http://example.org/> .
+ex:s ex:p [ ex:q "a" ; ex:r [] ] .`: `_:b0 <http://example.org/q> "a" .
+_:b0 <http://example.org/r> _:b1 .
+<http://example.org/s> <http://example.org/p> _:b0 .`,
+
+	`@prefix ex: <http://example.org/> .
+[ ex:q "a" ] .
+[] ex:p _:x .`: `_:b0 <http://example.org/q> "a" .
+_:b1 <http://example.org/p> _:x .`,
+
+	`@prefix ex: <http://example.org/> .
+ex:s ex:p ( ex:a "b" () ) .`: `_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/a> .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b1 .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "b" .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b2 .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+<http://example.org/s> <http://example.org/p> _:b0 .`,
+
+	`<http://example.org/s> <http://example.org/p> 1, -2, +3.5, .5, 1e10, 1.E-2, true, false .`: `<http://example.org/s> <http://example.org/p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/s> <http://example.org/p> "-2"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/s> <http://example.org/p> "+3.5"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<http://example.org/s> <http://example.org/p> ".5"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<http://example.org/s> <http://example.org/p> "1e10"^^<http://www.w3.org/2001/XMLSchema#double> .
+<http://example.org/s> <http://example.org/p> "1.E-2"^^<http://www.w3.org/2001/XMLSchema#double> .
+<http://example.org/s> <http://example.org/p> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<http://example.org/s> <http://example.org/p> "false"^^<http://www.w3.org/2001/XMLSchema#boolean> .`,
+
+	`@prefix ex: <http://example.org/> .
+ex:s ex:p 'single', "tab\there", """long
+"quoted" text""", '''it's''', "chat"@fr, "x"^^ex:dt, "\u00E9\U0001F600" .`: `<http://example.org/s> <http://example.org/p> "single" .
+<http://example.org/s> <http://example.org/p> "tab\there" .
+<http://example.org/s> <http://example.org/p> "long\n\"quoted\" text" .
+<http://example.org/s> <http://example.org/p> "it's" .
+<http://example.org/s> <http://example.org/p> "chat"@fr .
+<http://example.org/s> <http://example.org/p> "x"^^<http://example.org/dt> .
+<http://example.org/s> <http://example.org/p> "` + "\u00E9\U0001F600" + `" .`,
+
+	`@prefix ex: <http://example.org/> .
+ex:a.b ex:c\-d ex:e%20f. # comment
+_:n.1 ex:p ex:o.`: `<http://example.org/a.b> <http://example.org/c-d> <http://example.org/e%20f> .
+_:n1 <http://example.org/p> <http://example.org/o> .`,
+
+	`@prefix ex: <http://example.org/> .
+<< ex:s ex:p [] >> ex:source << _:x a "lit" >> .
+ex:s ex:p ex:o {| ex:certainty 0.9 ; ex:source ex:web |}, ex:o2 .`: `<< <http://example.org/s> <http://example.org/p> _:b0 >> <http://example.org/source> << _:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> "lit" >> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o> .
+<< <http://example.org/s> <http://example.org/p> <http://example.org/o> >> <http://example.org/certainty> "0.9"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<< <http://example.org/s> <http://example.org/p> <http://example.org/o> >> <http://example.org/source> <http://example.org/web> .
+<http://example.org/s> <http://example.org/p> <http://example.org/o2> .`,
+}
+
+var turtleNegativeCases = map[string]error{
+	`<http://example.org/s> <http://example.org/p> <http://example.org/o>`:                 ErrTTUnterminatedTriple,
+	`<http://example.org/s> <http://example.org/p> <http://example.org/o`:                  ErrTTUnterminatedIri,
+	`<http://example.org/s> <http://example.org/p> "o .`:                                   ErrTTUnterminatedLiteral,
+	`<http://example.org/s> <http://example.org/p> "o\q" .`:                                ErrTTInvalidEscape,
+	`ex:s <http://example.org/p> <http://example.org/o> .`:                                 ErrTTUndefinedPrefix,
+	`<http://example.org/s> <http://example.org/p> , <http://example.org/o>`:               ErrTTUnexpectedToken,
+	`<http://example.org/s> "p" <http://example.org/o> .`:                                  ErrTTUnexpectedToken,
+	`<http://example.org/s> <http://example.org/p> ( <http://example.org/o>`:               ErrTTUnexpectedEOF,
+	`<< <http://example.org/s> <http://example.org/p> ( ) >> <http://example.org/p> "o" .`: ErrTTUnexpectedToken,
+	`<< <http://example.org/s> <http://example.org/p> "o" > <http://example.org/p> "o" .`:  ErrTTUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> "o" {| <http://example.org/p> "o" .`:    ErrTTUnexpectedToken,
+	`<http://example.org/s> <http://exa mple.org/p> <http://example.org/o> .`:              ErrTTUnexpectedCharacter,
+	`<http://example.org/s> <http://example.org/p> <http://example.org/%zz> .`:             ErrTTInvalidIri,
+	"@base <http://[example.org]/> .":                                                      ErrTTInvalidIri,
+	"<s> <p> <o> .":                                                                        ErrTTInvalidIri,
+	"@prefix ex: <rel/> . ex:s ex:p ex:o .":                                                ErrTTInvalidIri,
+}
+
+// relabelBlankNodes returns the N-Triples representation of triples, with blank nodes (including
+// those in quoted triples) renamed b0, b1, ... in order of first appearance.
+func relabelBlankNodes(triples []*Triple) (lines []string) {
+	labels := make(map[string]string)
+
+	var relabel func(term Term) Term
+	relabel = func(term Term) Term {
+		if quoted, ok := term.(*QuotedTriple); ok {
+			return NewQuotedTriple(relabel(quoted.Subject), quoted.Predicate, relabel(quoted.Object))
+		}
+
+		if node, ok := term.(*BlankNode); ok {
+			label, ok := labels[node.ID]
+			if !ok {
+				label = fmt.Sprintf("b%d", len(labels))
+				labels[node.ID] = label
+			}
+
+			return NewBlankNode(label)
+		}
+
+		return term
+	}
+
+	for _, triple := range triples {
+		lines = append(lines, NewTriple(relabel(triple.Subject), relabel(triple.Predicate), relabel(triple.Object)).String())
+	}
+
+	return lines
+}
+
+func readAllTurtle(doc string) (triples []*Triple, err error) {
+	r := NewTurtleReader(strings.NewReader(doc))
+
+	for {
+		triple, err := r.Read()
+		if err == io.EOF {
+			return triples, nil
+		} else if err != nil {
+			return triples, err
+		}
+
+		triples = append(triples, triple)
+	}
+}
+
+func TestTurtleRead(t *testing.T) {
+	for doc, expected := range turtleTestCases {
+		triples, err := readAllTurtle(doc)
+		if err != nil {
+			t.Errorf("Parsing %q: unexpected error %s", doc, err)
+			continue
+		}
+
+		var expectedTriples []*Triple
+		ntr := NewNTriplesReader(strings.NewReader(expected))
+		for triple, err := ntr.Read(); err == nil; triple, err = ntr.Read() {
+			expectedTriples = append(expectedTriples, triple)
+		}
+
+		got := strings.Join(relabelBlankNodes(triples), "\n")
+		want := strings.Join(relabelBlankNodes(expectedTriples), "\n")
+
+		if got != want {
+			t.Errorf("Parsing %q:\nexpected:\n%s\ngot:\n%s", doc, want, got)
+		}
+	}
+}
+
+func TestTurtleReadErrors(t *testing.T) {
+	for doc, expected := range turtleNegativeCases {
+		_, err := readAllTurtle(doc)
+
+		if err == nil {
+			t.Errorf("Expected %s for %q but no error reported", expected, doc)
+		} else if pe, ok := err.(*TurtleParseError); !ok || pe.Err != expected {
+			t.Errorf("Expected %s for %q but got error %s", expected, doc, err)
+		}
+	}
+}
+
+func TestTurtleParsePrefixes(t *testing.T) {
+	graph := NewGraph(NewListStore())
+
+	err := graph.Parse(ParseTurtle, strings.NewReader("@prefix ex: <http://example.org/> .\nex:s ex:p ex:o ."))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if graph.Num() != 1 {
+		t.Errorf("Expected 1 triple but got %d", graph.Num())
+	}
+
+	if graph.Prefixes["http://example.org/"] != "ex" {
+		t.Errorf("Expected prefix 'ex' to be recorded but got %v", graph.Prefixes)
+	}
+
+	err = graph.Parse(ParseTurtle, strings.NewReader("<http://example.org/s> <http://example.org/p> ."))
+	if err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+}
@@ -1,82 +1,588 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
 package argo
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kierdavis/argo/iri"
 )
 
-// Function SerializeTurtle serializes triples to the specified Writer in Turtle format, recieving
-// triples from tripleChan until closed and sending errors to errChan (closing errChan when done).
-// Prefixes is a map of namespace URIs to prefix names that is to produce compact output.
-func SerializeTurtle(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
-	defer close(errChan)
+var (
+	ttIntegerRegexp = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	ttDecimalRegexp = regexp.MustCompile(`^[+-]?[0-9]*\.[0-9]+$`)
+	ttDoubleRegexp  = regexp.MustCompile(`^[+-]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)[eE][+-]?[0-9]+$`)
+)
 
-	var err error
+// A TurtleWriter writes triples in Turtle or TriG format. Since the output groups all statements
+// about a subject together, triples are buffered until Flush is called.
+//
+// The output is deterministic: subjects are sorted (see CompareTerms), rdf:type is written
+// as 'a' before the other predicates (which are sorted), and objects of the same predicate are
+// sorted and separated with ','. Blank nodes referenced only once are written inline as '[ ... ]'
+// and well-formed lists as '( ... )'. Only the prefixes that are used are declared.
+type TurtleWriter struct {
+	w        *bufio.Writer
+	base     string
+	prefixes map[string]string
+	trig     bool
 
-	triplesBySubject := make(map[string][]*Triple)
+	graphs map[string]*tripleIndex // Triples of each graph, keyed by the String() of its name
+	names  []Term                  // Names of the named graphs
+	seen   map[string]string       // Graph in which each blank node was first seen
+	shared map[string]bool         // Blank nodes used in more than one graph, or as a graph name
 
-	encodeTerm := func(iterm Term) (s string) {
-		switch term := iterm.(type) {
-		case *Resource:
-			base, local := SplitPrefix(term.URI)
-			prefix, ok := prefixes[base]
-			if ok {
-				return fmt.Sprintf("%s:%s", prefix, local)
-			} else {
-				return fmt.Sprintf("<%s>", term.URI)
+	idx        *tripleIndex // The graph being written
+	margin     string       // Indentation of the graph being written
+	written    map[string]bool
+	usable     map[string]string // Namespace URI to prefix, for the prefixes that may be declared
+	namespaces map[string]string // Namespace URI to prefix, for the prefixes that are declared
+	labels     map[string]string // Blank node ID to label
+	usedLabels map[string]bool
+}
+
+// Function NewTurtleWriter creates and returns a new TurtleWriter writing Turtle to w. Graph names
+// are ignored.
+func NewTurtleWriter(w io.Writer) (tw *TurtleWriter) {
+	tw = &TurtleWriter{w: bufio.NewWriter(w)}
+	tw.reset()
+	return tw
+}
+
+// Function NewTriGWriter creates and returns a new TurtleWriter writing TriG to w. Triples are
+// written to the graph named by their Graph field, or to the default graph if they have none.
+func NewTriGWriter(w io.Writer) (tw *TurtleWriter) {
+	tw = NewTurtleWriter(w)
+	tw.trig = true
+	return tw
+}
+
+// Method SetBase sets the base IRI of the document. It is written as an @base directive, and IRIs
+// are written relative to it where possible.
+func (tw *TurtleWriter) SetBase(base string) {
+	tw.base = base
+}
+
+// Method SetPrefixMap sets the map of namespace URIs to prefix names used to abbreviate IRIs.
+func (tw *TurtleWriter) SetPrefixMap(prefixes map[string]string) {
+	tw.prefixes = prefixes
+}
+
+// reset clears the buffered triples.
+func (tw *TurtleWriter) reset() {
+	tw.graphs = map[string]*tripleIndex{"": newTripleIndex()}
+	tw.names = nil
+	tw.seen = make(map[string]string)
+	tw.shared = make(map[string]bool)
+}
+
+// Method Write adds a triple to the document.
+func (tw *TurtleWriter) Write(triple *Triple) {
+	graph := ""
+	if tw.trig && triple.Graph != nil {
+		graph = triple.Graph.String()
+	}
+
+	idx, ok := tw.graphs[graph]
+	if !ok {
+		idx = newTripleIndex()
+		tw.graphs[graph] = idx
+		tw.names = append(tw.names, triple.Graph)
+		tw.share(triple.Graph, "")
+	}
+
+	idx.add(triple)
+	tw.share(triple.Subject, graph)
+	tw.share(triple.Object, graph)
+}
+
+// share records that term is used in the given graph. Blank nodes that are used in more than one
+// graph are never written inline, so that they keep their identity.
+func (tw *TurtleWriter) share(term Term, graph string) {
+	// Blank nodes in quoted triples are referred to by label, so they must not be inlined either.
+	if quoted, ok := term.(*QuotedTriple); ok {
+		for _, node := range quoted.blankNodes() {
+			tw.shared[node.String()] = true
+		}
+
+		return
+	}
+
+	if _, ok := term.(*BlankNode); !ok {
+		return
+	}
+
+	key := term.String()
+	if first, ok := tw.seen[key]; !ok {
+		tw.seen[key] = graph
+	} else if first != graph {
+		tw.shared[key] = true
+	}
+}
+
+// Method Flush writes the document containing all triples added so far and clears the buffer.
+func (tw *TurtleWriter) Flush() (err error) {
+	tw.usable = make(map[string]string)
+	tw.namespaces = make(map[string]string)
+	tw.labels = make(map[string]string)
+	tw.usedLabels = make(map[string]bool)
+
+	// If several namespaces share a prefix name, the first in sorted order gets it.
+	uris := make([]string, 0, len(tw.prefixes))
+	for uri, prefix := range tw.prefixes {
+		if isPNPrefix(prefix) {
+			uris = append(uris, uri)
+		}
+	}
+
+	sort.Strings(uris)
+
+	taken := make(map[string]bool)
+	for _, uri := range uris {
+		if prefix := tw.prefixes[uri]; !taken[prefix] {
+			taken[prefix] = true
+			tw.usable[uri] = prefix
+		}
+	}
+
+	// Render the body first, so that only the prefixes that were used are declared.
+	var body strings.Builder
+
+	tw.writeGraph(&body, tw.graphs[""])
+
+	SortTerms(tw.names)
+	for _, name := range tw.names {
+		if body.Len() > 0 {
+			body.WriteString("\n")
+		}
+
+		body.WriteString(tw.term(name, false))
+		body.WriteString(" {\n")
+
+		tw.margin = "    "
+		tw.writeGraph(&body, tw.graphs[name.String()])
+		tw.margin = ""
+
+		body.WriteString("}\n")
+	}
+
+	if tw.base != "" {
+		fmt.Fprintf(tw.w, "@base %s .\n", tw.iriRef(tw.base))
+	}
+
+	uris = uris[:0]
+	for uri := range tw.namespaces {
+		uris = append(uris, uri)
+	}
+
+	sort.Slice(uris, func(i, j int) bool {
+		return tw.namespaces[uris[i]] < tw.namespaces[uris[j]]
+	})
+
+	for _, uri := range uris {
+		fmt.Fprintf(tw.w, "@prefix %s: %s .\n", tw.namespaces[uri], tw.iriRef(uri))
+	}
+
+	if (tw.base != "" || len(uris) > 0) && body.Len() > 0 {
+		tw.w.WriteString("\n")
+	}
+
+	tw.w.WriteString(body.String())
+	tw.reset()
+	return tw.w.Flush()
+}
+
+// writeGraph writes the statements of one graph.
+func (tw *TurtleWriter) writeGraph(b *strings.Builder, idx *tripleIndex) {
+	tw.idx = idx
+	tw.written = make(map[string]bool)
+
+	SortTerms(idx.subjects)
+	for _, triples := range idx.properties {
+		sort.Sort(triplesByPredicate(triples))
+	}
+
+	// Subjects that will be written inline are skipped here, and any left over (because they are
+	// only reachable through a cycle of inline blank nodes) are written afterwards.
+	first := true
+	for pass := 0; pass < 2; pass++ {
+		for _, subject := range idx.subjects {
+			if tw.written[subject.String()] || (pass == 0 && tw.inline(subject)) {
+				continue
 			}
 
-		case *Literal:
-			return term.String()
+			if !first {
+				b.WriteString("\n")
+			}
 
-		case *BlankNode:
-			return term.String()
+			tw.writeSubject(b, subject)
+			first = false
 		}
+	}
+}
+
+// inline returns whether a blank node is to be written inline where it is referenced.
+func (tw *TurtleWriter) inline(term Term) bool {
+	key := term.String()
+	_, isBlank := term.(*BlankNode)
 
-		return ""
+	return isBlank && tw.idx.refs[key] == 1 && !tw.written[key] && !tw.shared[key]
+}
+
+// newline starts a new line indented to depth.
+func (tw *TurtleWriter) newline(b *strings.Builder, depth int) {
+	b.WriteString("\n")
+	b.WriteString(tw.margin)
+	b.WriteString(strings.Repeat("    ", depth))
+}
+
+// writeSubject writes a statement describing subject.
+func (tw *TurtleWriter) writeSubject(b *strings.Builder, subject Term) {
+	key := subject.String()
+	tw.written[key] = true
+	b.WriteString(tw.margin)
+
+	if _, isBlank := subject.(*BlankNode); isBlank && tw.idx.refs[key] == 0 && !tw.shared[key] {
+		b.WriteString("[]")
+	} else {
+		b.WriteString(tw.term(subject, false))
 	}
 
-	for triple := range tripleChan {
-		s := encodeTerm(triple.Subject)
-		triplesBySubject[s] = append(triplesBySubject[s], triple)
+	b.WriteString(" ")
+	tw.writePredicates(b, tw.idx.properties[key], 1)
+	b.WriteString(" .\n")
+}
+
+// writePredicates writes a predicate-object list. Continuation lines are indented to depth.
+func (tw *TurtleWriter) writePredicates(b *strings.Builder, triples []*Triple, depth int) {
+	// rdf:type comes first; the rest are already sorted.
+	ordered := make([]*Triple, 0, len(triples))
+	for _, triple := range triples {
+		if triple.Predicate.Equal(A) {
+			ordered = append(ordered, triple)
+		}
 	}
 
-	for base, prefix := range prefixes {
-		_, err = fmt.Fprintf(w, "@prefix %s: <%s> .\n", prefix, base)
-		if err != nil {
-			errChan <- err
-			return
+	for _, triple := range triples {
+		if !triple.Predicate.Equal(A) {
+			ordered = append(ordered, triple)
 		}
 	}
 
-	_, err = fmt.Fprint(w, "\n")
-	if err != nil {
-		errChan <- err
+	for i, triple := range ordered {
+		if i > 0 && triple.Predicate.Equal(ordered[i-1].Predicate) {
+			b.WriteString(", ")
+
+		} else {
+			if i > 0 {
+				b.WriteString(" ;")
+				tw.newline(b, depth)
+			}
+
+			if triple.Predicate.Equal(A) {
+				b.WriteString("a")
+			} else {
+				b.WriteString(tw.term(triple.Predicate, false))
+			}
+
+			b.WriteString(" ")
+		}
+
+		tw.writeObject(b, triple.Object, depth)
+	}
+}
+
+// writeObject writes an object, writing blank nodes inline where possible.
+func (tw *TurtleWriter) writeObject(b *strings.Builder, object Term, depth int) {
+	if object.Equal(Nil) {
+		b.WriteString("()")
 		return
 	}
 
-	for subject, triples := range triplesBySubject {
-		_, err = fmt.Fprintf(w, "%s\n", subject)
-		if err != nil {
-			errChan <- err
-			return
+	if !tw.inline(object) {
+		b.WriteString(tw.term(object, true))
+		return
+	}
+
+	key := object.String()
+
+	if items, nodes, ok := tw.idx.list(object, true); ok && !tw.anyWritten(nodes) {
+		for _, node := range nodes {
+			tw.written[node] = true
 		}
 
-		for _, triple := range triples {
-			p := encodeTerm(triple.Predicate)
-			o := encodeTerm(triple.Object)
+		b.WriteString("(")
+		for _, item := range items {
+			b.WriteString(" ")
+			tw.writeObject(b, item, depth)
+		}
 
-			_, err = fmt.Fprintf(w, "  %s %s ;\n", p, o)
-			if err != nil {
-				errChan <- err
-				return
-			}
+		b.WriteString(" )")
+		return
+	}
+
+	tw.written[key] = true
+	triples := tw.idx.properties[key]
+
+	switch {
+	case len(triples) == 0:
+		b.WriteString("[]")
+
+	case len(triples) == 1 && !tw.inline(triples[0].Object):
+		b.WriteString("[ ")
+		tw.writePredicates(b, triples, depth+1)
+		b.WriteString(" ]")
+
+	default:
+		b.WriteString("[")
+		tw.newline(b, depth+1)
+		tw.writePredicates(b, triples, depth+1)
+		tw.newline(b, depth)
+		b.WriteString("]")
+	}
+}
+
+// anyWritten returns whether any of the given subjects has already been written.
+func (tw *TurtleWriter) anyWritten(keys []string) bool {
+	for _, key := range keys {
+		if tw.written[key] {
+			return true
 		}
+	}
+
+	return false
+}
+
+// term returns the Turtle representation of a term. Numbers and booleans are only abbreviated in
+// the object position.
+func (tw *TurtleWriter) term(term Term, object bool) string {
+	switch t := term.(type) {
+	case *Resource:
+		return tw.iri(t.URI)
+
+	case *BlankNode:
+		return "_:" + tw.label(t)
+
+	case *Literal:
+		return tw.literal(t, object)
 
-		_, err = fmt.Fprintf(w, "  .\n\n")
-		if err != nil {
-			errChan <- err
-			return
+	case *QuotedTriple:
+		return "<< " + tw.term(t.Subject, false) + " " + tw.term(t.Predicate, false) + " " + tw.term(t.Object, true) + " >>"
+	}
+
+	return term.String()
+}
+
+// iri returns the prefixed name of an IRI, or an IRI reference (relative to the base IRI where
+// possible) if no prefix applies.
+func (tw *TurtleWriter) iri(iri string) string {
+	best := ""
+	for uri := range tw.usable {
+		if len(uri) > len(best) && strings.HasPrefix(iri, uri) && isPNLocal(iri[len(uri):]) {
+			best = uri
 		}
 	}
+
+	if best != "" {
+		prefix := tw.usable[best]
+		tw.namespaces[best] = prefix
+		return prefix + ":" + iri[len(best):]
+	}
+
+	if tw.base != "" {
+		if rel, ok := relativeIRI(tw.base, iri); ok {
+			return tw.iriRef(rel)
+		}
+	}
+
+	return tw.iriRef(iri)
+}
+
+// iriRef returns an IRI reference enclosed in angle brackets, escaping characters that may not
+// appear in it.
+func (tw *TurtleWriter) iriRef(iri string) string {
+	return "<" + escapeIRI(iri) + ">"
+}
+
+// label returns the label of a blank node, replacing IDs that are not valid Turtle labels.
+func (tw *TurtleWriter) label(node *BlankNode) string {
+	if label, ok := tw.labels[node.ID]; ok {
+		return label
+	}
+
+	label := node.ID
+	for i := 0; !isBlankNodeLabel(label) || tw.usedLabels[label]; i++ {
+		label = "b" + strconv.Itoa(i)
+	}
+
+	tw.labels[node.ID] = label
+	tw.usedLabels[label] = true
+	return label
+}
+
+// literal returns the Turtle representation of a literal.
+func (tw *TurtleWriter) literal(lit *Literal, object bool) string {
+	if object && lit.Language == "" && lit.Datatype != nil {
+		switch {
+		case lit.Datatype.Equal(XSD.Get("integer")) && ttIntegerRegexp.MatchString(lit.Value),
+			lit.Datatype.Equal(XSD.Get("decimal")) && ttDecimalRegexp.MatchString(lit.Value),
+			lit.Datatype.Equal(XSD.Get("double")) && ttDoubleRegexp.MatchString(lit.Value),
+			lit.Datatype.Equal(XSD.Get("boolean")) && (lit.Value == "true" || lit.Value == "false"):
+			return lit.Value
+		}
+	}
+
+	var str string
+	if strings.Contains(lit.Value, "\n") {
+		str = `"""` + escapeTurtleString(lit.Value, true) + `"""`
+	} else {
+		str = `"` + escapeTurtleString(lit.Value, false) + `"`
+	}
+
+	if lit.Language != "" {
+		str += "@" + lit.Language
+	} else if lit.Datatype != nil {
+		str += "^^" + tw.term(lit.Datatype, false)
+	}
+
+	return str
+}
+
+// escapeTurtleString escapes a string for use in a quoted literal. In long strings, newlines are
+// written as they are.
+func escapeTurtleString(s string, long bool) string {
+	var b strings.Builder
+
+	for _, r1 := range s {
+		switch {
+		case r1 == '\\':
+			b.WriteString(`\\`)
+		case r1 == '"':
+			b.WriteString(`\"`)
+		case r1 == '\n' && long:
+			b.WriteString("\n")
+		case r1 == '\n':
+			b.WriteString(`\n`)
+		case r1 == '\r':
+			b.WriteString(`\r`)
+		case r1 == '\t':
+			b.WriteString(`\t`)
+		case r1 < 0x20 || r1 == 0x7F:
+			fmt.Fprintf(&b, "\\u%04X", r1)
+		default:
+			b.WriteRune(r1)
+		}
+	}
+
+	return b.String()
+}
+
+// isPNPrefix returns whether s is a valid prefix name (PN_PREFIX, or empty).
+func isPNPrefix(s string) bool {
+	for i, r1 := range s {
+		if (i == 0 && !isPNCharsBase(r1)) || (i > 0 && !isPNChars(r1) && r1 != '.') {
+			return false
+		}
+	}
+
+	return !strings.HasSuffix(s, ".")
+}
+
+// isPNLocal returns whether s can be written as the local part of a prefixed name without escapes.
+func isPNLocal(s string) bool {
+	for i, r1 := range s {
+		if i == 0 && !(isPNCharsU(r1) || isDigit(r1) || r1 == ':') {
+			return false
+		}
+
+		if i > 0 && !(isPNChars(r1) || r1 == ':' || r1 == '.') {
+			return false
+		}
+	}
+
+	return !strings.HasSuffix(s, ".")
+}
+
+// isBlankNodeLabel returns whether s is a valid blank node label.
+func isBlankNodeLabel(s string) bool {
+	for i, r1 := range s {
+		if (i == 0 && !isPNCharsU(r1) && !isDigit(r1)) || (i > 0 && !isPNChars(r1) && r1 != '.') {
+			return false
+		}
+	}
+
+	return s != "" && !strings.HasSuffix(s, ".")
+}
+
+// Function relativeIRI returns a reference that resolves to uri against base, if there is a shorter
+// one than uri itself.
+func relativeIRI(base string, uri string) (rel string, ok bool) {
+	if i := strings.IndexByte(base, '#'); i >= 0 {
+		base = base[:i]
+	}
+
+	var candidates []string
+
+	if uri == base {
+		candidates = append(candidates, "")
+	}
+
+	if strings.HasPrefix(uri, base+"#") {
+		candidates = append(candidates, uri[len(base):])
+	}
+
+	if i := strings.LastIndexByte(base, '/'); i >= 0 && strings.HasPrefix(uri, base[:i+1]) {
+		candidates = append(candidates, uri[i+1:])
+	}
+
+	for _, candidate := range candidates {
+		if resolved, err := iri.Resolve(base, candidate); err == nil && resolved == uri {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// Function SerializeTurtle serializes triples to the specified Writer in Turtle format, recieving
+// triples from tripleChan until closed and sending errors to errChan (closing errChan when done).
+// Prefixes is a map of namespace URIs to prefix names that is to produce compact output. See
+// TurtleWriter for details of the output.
+func SerializeTurtle(w io.Writer, tripleChan chan *Triple, errChan chan error, prefixes map[string]string) {
+	defer close(errChan)
+
+	tw := NewTurtleWriter(w)
+	tw.SetPrefixMap(prefixes)
+
+	for triple := range tripleChan {
+		tw.Write(triple)
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		errChan <- err
+	}
 }
@@ -0,0 +1,129 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func turtleTestGraph() (graph *Graph) {
+	graph = NewGraph(NewListStore())
+	graph.Bind("http://example.org/terms#", "ex")
+	graph.Bind("http://unused.org/", "unused")
+
+	a := NewResource("http://example.org/doc/a")
+	b := NewResource("http://example.org/doc/b")
+	p := NewResource("http://example.org/terms#p")
+	q := NewResource("http://example.org/terms#q")
+	addr, l1, l2 := NewBlankNode("addr"), NewBlankNode("l1"), NewBlankNode("l2")
+	shared, anon := NewBlankNode("shared"), NewBlankNode("not a label")
+
+	graph.AddTriple(a, A, NewResource("http://example.org/terms#Person"))
+	graph.AddTriple(a, q, NewLiteral("line 1\nline \"2\""))
+	graph.AddTriple(a, q, NewLiteralWithLanguage("tab\there", "en"))
+	graph.AddTriple(a, p, addr)
+	graph.AddTriple(addr, p, NewLiteralWithDatatype("1", XSD.Get("integer")))
+	graph.AddTriple(addr, q, NewLiteralWithDatatype("1.0e0", XSD.Get("double")))
+	graph.AddTriple(a, p, b)
+	graph.AddTriple(a, NewResource("http://example.org/terms#list"), l1)
+	graph.AddTriple(l1, First, NewResource("http://other.org/x"))
+	graph.AddTriple(l1, Rest, l2)
+	graph.AddTriple(l2, First, shared)
+	graph.AddTriple(l2, Rest, Nil)
+	graph.AddTriple(a, NewResource("http://example.org/terms#empty"), Nil)
+	graph.AddTriple(shared, p, NewLiteralWithDatatype("x", XSD.Get("integer")))
+	graph.AddTriple(b, q, shared)
+	graph.AddTriple(b, p, NewResource("http://example.org/doc/c#frag"))
+	graph.AddTriple(anon, p, NewLiteralWithDatatype("true", XSD.Get("boolean")))
+	graph.AddTriple(anon, q, anon)
+	return graph
+}
+
+var turtleOutput = `@base <http://example.org/doc/> .
+@prefix ex: <http://example.org/terms#> .
+
+_:shared ex:p "x"^^<http://www.w3.org/2001/XMLSchema#integer> .
+
+<a> a ex:Person ;
+    ex:empty () ;
+    ex:list ( <http://other.org/x> _:shared ) ;
+    ex:p <b>, [
+        ex:p 1 ;
+        ex:q 1.0e0
+    ] ;
+    ex:q """line 1
+line \"2\"""", "tab\there"@en .
+
+<b> ex:p <c#frag> ;
+    ex:q _:shared .
+
+_:b0 ex:p true ;
+    ex:q _:b0 .
+`
+
+func TestSerializeTurtle(t *testing.T) {
+	graph := turtleTestGraph()
+
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		tw := NewTurtleWriter(&buf)
+		tw.SetBase("http://example.org/doc/")
+		tw.SetPrefixMap(graph.Prefixes)
+
+		for triple := range graph.IterTriples() {
+			tw.Write(triple)
+		}
+
+		err := tw.Flush()
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+
+		if buf.String() != turtleOutput {
+			t.Fatalf("Expected:\n%s\ngot:\n%s", turtleOutput, buf.String())
+		}
+	}
+}
+
+func TestTurtleRoundTrip(t *testing.T) {
+	graph := turtleTestGraph()
+
+	var original []*Triple
+	for triple := range graph.IterTriples() {
+		original = append(original, triple)
+	}
+
+	var buf bytes.Buffer
+	err := graph.Serialize(SerializeTurtle, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	triples, err := readAllTurtle(buf.String())
+	if err != nil {
+		t.Fatalf("Unexpected error %s parsing:\n%s", err, buf.String())
+	}
+
+	// CompareTriples ignores duplicates, so check the count to catch duplicated output.
+	if len(triples) != len(original) || !CompareTriples(original, triples).Isomorphic() {
+		t.Errorf("Round trip changed the graph:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,404 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"errors"
+	"iter"
+)
+
+var (
+	ErrTxDone        = errors.New("transaction has already been committed or rolled back")
+	ErrTxUnsupported = errors.New("store does not support transactions")
+)
+
+// A Tx is a transaction on a store. It is itself a store: reads see the contents of the underlying
+// store when the transaction began (a snapshot, unaffected by later changes to the store) together
+// with the transaction's own changes, and writes are held back until Commit applies them all at
+// once. Using a Tx after it has been committed or rolled back causes a panic with ErrTxDone.
+//
+// Only reads are isolated: conflicts are not detected. Commit replays the transaction's changes on
+// top of the store as it is at that time, so changes made since the transaction began (including
+// by other transactions) are kept, and where both change the same triple, the last to commit wins.
+type Tx interface {
+	SeqStore
+
+	// Method Commit should apply the transaction's changes to the underlying store, in the order
+	// they were made, without checking for conflicting changes.
+	Commit() error
+
+	// Method Rollback should discard the transaction's changes.
+	Rollback() error
+}
+
+// A TxStore is a Store that supports transactions.
+type TxStore interface {
+	Store
+
+	// Method Begin should start a new transaction on the store.
+	Begin() (Tx, error)
+}
+
+// A tripleKey identifies a triple, including its graph name, by value. The default graph has the
+// zero dictKey, which no term produces.
+type tripleKey [4]dictKey
+
+// The kinds of change recorded by a snapshotTx.
+const (
+	txAdd = iota
+	txRemove
+	txClear
+)
+
+// A txChange is a change recorded by a snapshotTx.
+type txChange struct {
+	kind   int
+	triple *Triple
+}
+
+// SnapshotTxOptions describe how a transaction made by NewSnapshotTx compares and counts triples.
+// They should match the behaviour of the underlying store.
+type SnapshotTxOptions struct {
+	// If set, literals are compared by value (see ValueEqual).
+	ValueEquality bool
+
+	// If set, the store is a bag that may hold a triple more than once, like ListStore: adding a
+	// triple within the transaction adds another copy, and removing one removes a single copy.
+	// Otherwise the store is a set, and removing a triple removes it entirely.
+	Bag bool
+}
+
+// A snapshotTx is a transaction that reads from a snapshot overlaid with its own changes.
+type snapshotTx struct {
+	store    Store
+	snapshot SeqStore
+	options  SnapshotTxOptions
+	changes  []txChange
+
+	cleared bool                // Whether the snapshot is hidden by a Clear
+	removed map[tripleKey]int   // Number of copies of triples of the snapshot hidden by a Remove
+	added   []*Triple           // Triples added by the transaction, with nil for removed ones
+	addedAt map[tripleKey][]int // Indices into added
+	done    bool
+}
+
+// Function NewSnapshotTx returns a transaction on store that reads from snapshot, which must hold
+// the contents of store when the transaction begins and must not change afterwards. Stores that
+// can make such a snapshot cheaply can use this to implement TxStore. Triples in different graphs
+// are distinct; options says how else triples are compared and counted. Commit does not lock store;
+// the caller is responsible for making sure nothing else uses it at the same time.
+func NewSnapshotTx(store Store, snapshot SeqStore, options SnapshotTxOptions) (tx Tx) {
+	return &snapshotTx{
+		store:    store,
+		snapshot: snapshot,
+		options:  options,
+		removed:  make(map[tripleKey]int),
+		addedAt:  make(map[tripleKey][]int),
+	}
+}
+
+// Method termKey returns the key of a term, taking the transaction's equality into account.
+func (tx *snapshotTx) termKey(term Term) (key dictKey) {
+	if tx.options.ValueEquality {
+		term = CanonicalTerm(term)
+	}
+
+	return newDictKey(term)
+}
+
+// Method key returns the key of a triple. Triples that are equal (by value, if the ValueEquality
+// option is set) and in the same graph have the same key.
+func (tx *snapshotTx) key(triple *Triple) (key tripleKey) {
+	key = tripleKey{tx.termKey(triple.Subject), tx.termKey(triple.Predicate), tx.termKey(triple.Object)}
+
+	if triple.Graph != nil {
+		key[3] = tx.termKey(triple.Graph)
+	}
+
+	return key
+}
+
+// Method equal compares two terms, by value if the ValueEquality option is set.
+func (tx *snapshotTx) equal(a Term, b Term) bool {
+	if tx.options.ValueEquality {
+		return ValueEqual(a, b)
+	}
+
+	return a.Equal(b)
+}
+
+// Method match returns whether triple matches the given search terms, where nil matches anything.
+func (tx *snapshotTx) match(triple *Triple, subjSearch, predSearch, objSearch Term) bool {
+	return (subjSearch == nil || tx.equal(subjSearch, triple.Subject)) &&
+		(predSearch == nil || tx.equal(predSearch, triple.Predicate)) &&
+		(objSearch == nil || tx.equal(objSearch, triple.Object))
+}
+
+// Method check panics if the transaction has finished.
+func (tx *snapshotTx) check() {
+	if tx.done {
+		panic(ErrTxDone)
+	}
+}
+
+// Method Add adds the given triple within the transaction.
+func (tx *snapshotTx) Add(triple *Triple) {
+	tx.check()
+	tx.changes = append(tx.changes, txChange{txAdd, triple})
+
+	key := tx.key(triple)
+	if !tx.options.Bag {
+		if len(tx.addedAt[key]) > 0 {
+			return
+		}
+
+		delete(tx.removed, key)
+	}
+
+	tx.addedAt[key] = append(tx.addedAt[key], len(tx.added))
+	tx.added = append(tx.added, triple)
+}
+
+// Method Remove removes the given triple within the transaction.
+func (tx *snapshotTx) Remove(triple *Triple) {
+	tx.check()
+	tx.changes = append(tx.changes, txChange{txRemove, triple})
+
+	key := tx.key(triple)
+
+	// In a bag, the most recently added copy goes first, then those of the snapshot one by one.
+	if indices := tx.addedAt[key]; len(indices) > 0 {
+		tx.added[indices[len(indices)-1]] = nil
+		tx.addedAt[key] = indices[:len(indices)-1]
+
+		if tx.options.Bag {
+			return
+		}
+	}
+
+	if tx.options.Bag {
+		tx.removed[key]++
+	} else {
+		tx.removed[key] = 1
+	}
+}
+
+// Method Clear removes all triples within the transaction.
+func (tx *snapshotTx) Clear() {
+	tx.check()
+	tx.changes = append(tx.changes, txChange{txClear, nil})
+
+	tx.cleared = true
+	tx.removed = make(map[tripleKey]int)
+	tx.added = nil
+	tx.addedAt = make(map[tripleKey][]int)
+}
+
+// Method Num returns the number of triples visible within the transaction.
+func (tx *snapshotTx) Num() (n int) {
+	for range tx.All() {
+		n++
+	}
+
+	return n
+}
+
+// Method IterTriples returns a channel that will yield the triples visible within the transaction.
+func (tx *snapshotTx) IterTriples() (ch chan *Triple) {
+	return seqChannel(tx.All())
+}
+
+// Method Filter returns a channel that will yield the matching triples visible within the
+// transaction.
+func (tx *snapshotTx) Filter(subjSearch, predSearch, objSearch Term) (ch chan *Triple) {
+	return seqChannel(tx.FilterSeq(subjSearch, predSearch, objSearch))
+}
+
+// Method All returns a sequence of the triples visible within the transaction.
+func (tx *snapshotTx) All() (seq iter.Seq[*Triple]) {
+	return tx.FilterSeq(nil, nil, nil)
+}
+
+// Method FilterSeq returns a sequence of the matching triples visible within the transaction: those
+// of the snapshot that have not been removed, followed by those added by the transaction.
+func (tx *snapshotTx) FilterSeq(subjSearch, predSearch, objSearch Term) (seq iter.Seq[*Triple]) {
+	tx.check()
+
+	return func(yield func(*Triple) bool) {
+		if !tx.cleared {
+			hidden := make(map[tripleKey]int)
+
+			for triple := range tx.snapshot.FilterSeq(subjSearch, predSearch, objSearch) {
+				key := tx.key(triple)
+				if hidden[key] < tx.removed[key] {
+					hidden[key]++
+					continue
+				}
+
+				// In a set, a triple added again by the transaction is yielded from added.
+				if !tx.options.Bag && len(tx.addedAt[key]) > 0 {
+					continue
+				}
+
+				if !yield(triple) {
+					return
+				}
+			}
+		}
+
+		for _, triple := range tx.added {
+			if triple == nil || !tx.match(triple, subjSearch, predSearch, objSearch) {
+				continue
+			}
+
+			if !yield(triple) {
+				return
+			}
+		}
+	}
+}
+
+// Method Commit applies the transaction's changes to the underlying store.
+func (tx *snapshotTx) Commit() (err error) {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	tx.done = true
+
+	for _, change := range tx.changes {
+		switch change.kind {
+		case txAdd:
+			tx.store.Add(change.triple)
+		case txRemove:
+			tx.removeFromStore(change.triple)
+		case txClear:
+			tx.store.Clear()
+		}
+	}
+
+	return nil
+}
+
+// Method removeFromStore removes the triples of the underlying store that have the same key as the
+// given one, or only the first of them if the store is a bag. Some stores (such as ListStore) only
+// remove the very triple passed to Remove, so the stored copies are looked up first.
+func (tx *snapshotTx) removeFromStore(triple *Triple) {
+	key := tx.key(triple)
+
+	var matches []*Triple
+	for stored := range storeFilterSeq(tx.store, triple.Subject, triple.Predicate, triple.Object) {
+		if tx.key(stored) == key {
+			matches = append(matches, stored)
+
+			if tx.options.Bag {
+				break
+			}
+		}
+	}
+
+	for _, stored := range matches {
+		tx.store.Remove(stored)
+	}
+}
+
+// Method Rollback discards the transaction's changes.
+func (tx *snapshotTx) Rollback() (err error) {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	tx.done = true
+	tx.changes = nil
+	return nil
+}
+
+// A graphTx is a transaction started through a Graph, which holds the graph's lock while
+// committing.
+type graphTx struct {
+	Tx
+	graph *Graph
+}
+
+// Method Commit applies the transaction's changes to the graph's store.
+func (tx *graphTx) Commit() (err error) {
+	tx.graph.Mutex.Lock()
+	defer tx.graph.Mutex.Unlock()
+
+	return tx.Tx.Commit()
+}
+
+// Method Begin starts a transaction on the graph's store, which must implement TxStore; otherwise
+// ErrTxUnsupported is returned. The transaction's changes are applied to the graph atomically when
+// it is committed.
+func (graph *Graph) Begin() (tx Tx, err error) {
+	graph.Mutex.Lock()
+	defer graph.Mutex.Unlock()
+
+	txStore, ok := graph.Store.(TxStore)
+	if !ok {
+		return nil, ErrTxUnsupported
+	}
+
+	tx, err = txStore.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &graphTx{tx, graph}, nil
+}
+
+// Method Update runs f in a transaction. f is given a graph backed by the transaction, so it sees
+// a snapshot of the graph together with its own changes. If f returns nil the changes (including
+// any prefixes it binds) are committed; otherwise they are rolled back and the error is returned.
+// As with Tx, concurrent updates are not detected: the last one to commit wins.
+func (graph *Graph) Update(f func(tx *Graph) error) (err error) {
+	tx, err := graph.Begin()
+	if err != nil {
+		return err
+	}
+
+	txGraph := &Graph{
+		Store:     tx,
+		Prefixes:  make(map[string]string, len(graph.Prefixes)),
+		Allocator: graph.Allocator,
+	}
+
+	for uri, prefix := range graph.Prefixes {
+		txGraph.Prefixes[uri] = prefix
+	}
+
+	err = f(txGraph)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for uri, prefix := range txGraph.Prefixes {
+		graph.Prefixes[uri] = prefix
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+/*
+	Copyright (c) 2012 Kier Davis
+
+	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+	associated documentation files (the "Software"), to deal in the Software without restriction,
+	including without limitation the rights to use, copy, modify, merge, publish, distribute,
+	sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is
+	furnished to do so, subject to the following conditions:
+
+	The above copyright notice and this permission notice shall be included in all copies or substantial
+	portions of the Software.
+
+	THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT
+	NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+	NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES
+	OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+	CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package argo
+
+import (
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+)
+
+var txStores = []func() Store{
+	func() Store { return NewListStore() },
+	func() Store { return NewIndexStore() },
+	func() Store { return NewDictionaryStore() },
+}
+
+func countTriples(graph *Graph, subject Term) (n int) {
+	for range graph.FilterSeq(subject, nil, nil) {
+		n++
+	}
+
+	return n
+}
+
+func TestTxIsolation(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+
+	for _, newStore := range txStores {
+		graph := NewGraph(newStore())
+		graph.AddTriple(ex.Get("a"), ex.Get("p"), NewLiteral("1"))
+		graph.AddTriple(ex.Get("a"), ex.Get("p"), NewLiteral("2"))
+
+		tx, err := graph.Begin()
+		if err != nil {
+			t.Fatalf("%T: unexpected error %s", graph.Store, err)
+		}
+
+		txGraph := NewGraph(tx)
+		txGraph.AddTriple(ex.Get("b"), ex.Get("p"), NewLiteral("3"))
+
+		for triple := range txGraph.FilterSeq(nil, nil, NewLiteral("1")) {
+			txGraph.Remove(triple)
+		}
+
+		// Changes made outside the transaction are not visible inside it, and vice versa.
+		graph.AddTriple(ex.Get("c"), ex.Get("p"), NewLiteral("4"))
+		graph.Remove(firstTriple(graph.FilterSeq(nil, nil, NewLiteral("2"))))
+
+		if n := countTriples(txGraph, nil); n != 2 || countTriples(txGraph, ex.Get("c")) != 0 {
+			t.Errorf("%T: expected the transaction to see 2 triples from its snapshot but got %d", graph.Store, n)
+		}
+
+		if countTriples(graph, ex.Get("b")) != 0 {
+			t.Errorf("%T: uncommitted triple is visible in the graph", graph.Store)
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("%T: unexpected error %s", graph.Store, err)
+		}
+
+		// Both sides' changes are kept: a's triples were removed, and b and c added.
+		if countTriples(graph, nil) != 2 || countTriples(graph, ex.Get("b")) != 1 || countTriples(graph, ex.Get("a")) != 0 {
+			t.Errorf("%T: unexpected contents after commit (%d triples)", graph.Store, graph.Num())
+		}
+
+		if err := tx.Rollback(); err != ErrTxDone {
+			t.Errorf("%T: expected %q but got %v", graph.Store, ErrTxDone, err)
+		}
+	}
+}
+
+func firstTriple(seq iter.Seq[*Triple]) *Triple {
+	for triple := range seq {
+		return triple
+	}
+
+	return nil
+}
+
+func TestGraphUpdate(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+
+	for _, newStore := range txStores {
+		graph := NewGraph(newStore())
+		graph.AddTriple(ex.Get("a"), ex.Get("p"), NewLiteral("1"))
+
+		invalid := errors.New("validation failed")
+
+		err := graph.Update(func(tx *Graph) error {
+			tx.Clear()
+
+			err := tx.Parse(ParseTurtle, strings.NewReader(`@prefix ex: <http://example.org/> . ex:b ex:p "2" .`))
+			if err != nil {
+				return err
+			}
+
+			if tx.Num() != 1 {
+				t.Errorf("%T: expected 1 triple inside the transaction but got %d", graph.Store, tx.Num())
+			}
+
+			return invalid
+		})
+
+		if err != invalid || graph.Num() != 1 || countTriples(graph, ex.Get("a")) != 1 {
+			t.Errorf("%T: expected the update to be rolled back, got %v and %d triples", graph.Store, err, graph.Num())
+		}
+
+		if _, ok := graph.Prefixes["http://example.org/"]; ok {
+			t.Errorf("%T: prefix from rolled back update was kept", graph.Store)
+		}
+
+		err = graph.Update(func(tx *Graph) error {
+			tx.Clear()
+			return tx.Parse(ParseTurtle, strings.NewReader(`@prefix ex: <http://example.org/> . ex:b ex:p "2" .`))
+		})
+
+		if err != nil || graph.Num() != 1 || countTriples(graph, ex.Get("b")) != 1 {
+			t.Errorf("%T: expected the update to be committed, got %v and %d triples", graph.Store, err, graph.Num())
+		}
+
+		if graph.Prefixes["http://example.org/"] != "ex" {
+			t.Errorf("%T: prefix from committed update was not kept", graph.Store)
+		}
+	}
+
+	if err := NewGraph(legacyStore{NewListStore()}).Update(func(*Graph) error { return nil }); err != ErrTxUnsupported {
+		t.Errorf("Expected %q but got %v", ErrTxUnsupported, err)
+	}
+}
+
+func TestTxEquality(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+
+	for _, newStore := range txStores {
+		graph := NewGraph(newStore())
+		graph.Add(NewQuad(ex.Get("a"), ex.Get("p"), ex.Get("b"), ex.Get("g1")))
+		graph.Add(NewQuad(ex.Get("a"), ex.Get("p"), ex.Get("b"), ex.Get("g2")))
+
+		tx, err := graph.Begin()
+		if err != nil {
+			t.Fatalf("%T: unexpected error %s", graph.Store, err)
+		}
+
+		// Removing the triple from one graph leaves the copy in the other.
+		tx.Remove(NewQuad(ex.Get("a"), ex.Get("p"), ex.Get("b"), ex.Get("g1")))
+
+		for triple := range tx.All() {
+			if triple.Graph == nil || !triple.Graph.Equal(ex.Get("g2")) {
+				t.Errorf("%T: unexpected triple %s in the transaction", graph.Store, triple.QuadString())
+			}
+		}
+
+		if tx.Num() != 1 {
+			t.Errorf("%T: expected 1 triple in the transaction but got %d", graph.Store, tx.Num())
+		}
+
+		tx.Rollback()
+	}
+
+	for _, store := range []Store{&ListStore{ValueEquality: true}, &IndexStore{ValueEquality: true}} {
+		store.Clear()
+		graph := NewGraph(store)
+		graph.AddTriple(ex.Get("a"), ex.Get("p"), NewLiteralWithDatatype("01", XSD.Get("integer")))
+
+		tx, err := graph.Begin()
+		if err != nil {
+			t.Fatalf("%T: unexpected error %s", store, err)
+		}
+
+		// Triples are matched by value, as the store does. A set store already holds the triple.
+		tx.Add(NewTriple(ex.Get("b"), ex.Get("p"), NewLiteralWithDatatype("2", XSD.Get("integer"))))
+		if _, bag := store.(*ListStore); !bag {
+			tx.Add(NewTriple(ex.Get("a"), ex.Get("p"), NewLiteralWithDatatype("1", XSD.Get("integer"))))
+		}
+
+		if tx.Num() != 2 || firstTriple(tx.FilterSeq(nil, nil, NewLiteralWithDatatype("+2", XSD.Get("integer")))) == nil {
+			t.Errorf("%T: expected 2 triples matched by value but got %d", store, tx.Num())
+		}
+
+		tx.Remove(NewTriple(ex.Get("a"), ex.Get("p"), NewLiteralWithDatatype("1", XSD.Get("integer"))))
+
+		if tx.Num() != 1 {
+			t.Errorf("%T: expected the triple to be removed by value but got %d triples", store, tx.Num())
+		}
+
+		tx.Rollback()
+	}
+}
+
+func TestGraphUpdateRemove(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+
+	for _, newStore := range txStores {
+		graph := NewGraph(newStore())
+		graph.AddTriple(ex.Get("a"), ex.Get("p"), NewLiteral("1"))
+		graph.AddTriple(ex.Get("a"), ex.Get("p"), NewLiteral("2"))
+
+		// The removed triple is a fresh copy, not the one held by the store.
+		err := graph.Update(func(tx *Graph) error {
+			tx.RemoveTriple(ex.Get("a"), ex.Get("p"), NewLiteral("1"))
+			return nil
+		})
+
+		if err != nil || graph.Num() != 1 || firstTriple(graph.FilterSeq(nil, nil, NewLiteral("1"))) != nil {
+			t.Errorf("%T: expected the triple to be removed by value, got %v and %d triples", graph.Store, err, graph.Num())
+		}
+	}
+}
+
+func TestListStoreTxDuplicates(t *testing.T) {
+	ex := NewNamespace("http://example.org/")
+	store := NewListStore()
+	store.Add(NewTriple(ex.Get("a"), ex.Get("p"), ex.Get("b")))
+	store.Add(NewTriple(ex.Get("a"), ex.Get("p"), ex.Get("b")))
+
+	tx, err := store.Begin()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if n := tx.Num(); n != 2 {
+		t.Errorf("Expected both copies to be visible in the transaction but got %d", n)
+	}
+
+	// Removing a triple removes a single copy, as ListStore does.
+	tx.Remove(NewTriple(ex.Get("a"), ex.Get("p"), ex.Get("b")))
+	tx.Add(NewTriple(ex.Get("a"), ex.Get("p"), ex.Get("b")))
+	tx.Add(NewTriple(ex.Get("a"), ex.Get("p"), ex.Get("b")))
+	tx.Remove(NewTriple(ex.Get("a"), ex.Get("p"), ex.Get("b")))
+
+	if n := tx.Num(); n != 2 {
+		t.Errorf("Expected 2 copies in the transaction but got %d", n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if n := store.Num(); n != 2 {
+		t.Errorf("Expected 2 copies after commit but got %d", n)
+	}
+}